@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/posilva/simpleidentity/internal/adapters/input/grpcapi"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// authenticateRequest is the body sent to the authenticate endpoint
+type authenticateRequest struct {
+	ProviderType string            `json:"provider_type"`
+	AuthData     map[string]string `json:"auth_data"`
+}
+
+// authenticateResponse is the body returned by the authenticate endpoint
+type authenticateResponse struct {
+	AccountID string `json:"account_id"`
+	IsNew     bool   `json:"is_new"`
+}
+
+// authCmd authenticates against a running server for smoke testing and debugging
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Authenticate against a running server",
+	Long: `Authenticate against a running SimpleIdentity server and print the
+resulting account id.
+
+The provider authentication data is built from repeated --data flags, e.g.
+  simpleidentity auth --provider guest --data id=abc --addr localhost:8090
+
+Use --grpc to call the gRPC API instead, e.g.
+  simpleidentity auth --grpc --provider guest --data id=abc --addr localhost:9090
+
+Exit Codes:
+  0 - Authentication succeeded
+  1 - Authentication failed or the server is unreachable`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		provider, _ := cmd.Flags().GetString("provider")
+		data, _ := cmd.Flags().GetStringArray("data")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		useGRPC, _ := cmd.Flags().GetBool("grpc")
+
+		authData, err := parseAuthData(data)
+		if err != nil {
+			return err
+		}
+		if useGRPC {
+			return authenticateGRPC(cmd.Context(), cmd.OutOrStdout(), addr, provider, authData, timeout)
+		}
+		return authenticate(cmd.Context(), cmd.OutOrStdout(), addr, provider, authData, timeout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+
+	authCmd.Flags().String("addr", "localhost:8090", "API server address, the gRPC one (e.g. localhost:9090) with --grpc")
+	authCmd.Flags().Bool("grpc", false, "Call the gRPC API instead of the HTTP API")
+	authCmd.Flags().String("provider", "", "Provider type (e.g. guest, google, apple)")
+	authCmd.Flags().StringArray("data", nil, "Provider authentication data as key=value, can be repeated")
+	authCmd.Flags().Duration("timeout", 5*time.Second, "Request timeout")
+	_ = authCmd.MarkFlagRequired("provider")
+}
+
+// parseAuthData builds the authentication data from the key=value pairs
+func parseAuthData(pairs []string) (map[string]string, error) {
+	data := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --data %q: expected key=value", pair)
+		}
+		data[key] = value
+	}
+	return data, nil
+}
+
+func authenticate(ctx context.Context, w io.Writer, addr string, provider string, authData map[string]string, timeout time.Duration) error {
+	body, err := json.Marshal(authenticateRequest{ProviderType: provider, AuthData: authData})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s/v1/authenticate", addr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("authentication failed: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var out authenticateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	fmt.Fprintf(w, "account_id: %s\nis_new: %t\n", out.AccountID, out.IsNew)
+	return nil
+}
+
+func authenticateGRPC(ctx context.Context, w io.Writer, addr string, provider string, authData map[string]string, timeout time.Duration) error {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var out grpcapi.AuthenticateResponse
+	req := &grpcapi.AuthenticateRequest{ProviderType: domain.ProviderType(provider), AuthData: authData}
+	if err := conn.Invoke(ctx, grpcapi.AuthenticateMethod, req, &out, grpc.CallContentSubtype(grpcapi.CodecName)); err != nil {
+		st := status.Convert(err)
+		return fmt.Errorf("authentication failed: %s: %s", st.Code(), st.Message())
+	}
+
+	fmt.Fprintf(w, "account_id: %s\nis_new: %t\n", out.AccountID, out.IsNew)
+	return nil
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/posilva/simpleidentity/internal/adapters/input/grpcapi"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/logger"
+)
+
+// runAuthCmd executes the auth command with the arguments and returns its output
+func runAuthCmd(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs(append([]string{"auth"}, args...))
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+		// flag values persist in the global command between executions
+		data := authCmd.Flags().Lookup("data")
+		_ = data.Value.(interface{ Replace([]string) error }).Replace(nil)
+		data.Changed = false
+		_ = authCmd.Flags().Set("grpc", "false")
+	})
+	err := rootCmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func TestAuthCmd_PrintsAccount(t *testing.T) {
+	var received authenticateRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/v1/authenticate", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		_ = json.NewEncoder(w).Encode(authenticateResponse{AccountID: "account_1", IsNew: true})
+	}))
+	defer srv.Close()
+
+	out, err := runAuthCmd(t, "--provider", "guest", "--data", "id=abc", "--data", "token=a=b",
+		"--addr", strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+	require.Equal(t, "account_id: account_1\nis_new: true\n", out)
+	require.Equal(t, authenticateRequest{
+		ProviderType: "guest",
+		AuthData:     map[string]string{"id": "abc", "token": "a=b"},
+	}, received)
+}
+
+func TestAuthCmd_FailsOnRejectedAuthentication(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := runAuthCmd(t, "--provider", "google", "--data", "token=expired",
+		"--addr", strings.TrimPrefix(srv.URL, "http://"))
+	require.ErrorContains(t, err, "status 401: invalid token")
+}
+
+func TestParseAuthData(t *testing.T) {
+	data, err := parseAuthData([]string{"id=abc", "empty="})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"id": "abc", "empty": ""}, data)
+
+	for _, pair := range []string{"id", "=abc"} {
+		_, err := parseAuthData([]string{pair})
+		require.ErrorContains(t, err, "expected key=value")
+	}
+}
+
+func TestAuthCmd_GRPC(t *testing.T) {
+	svc := &fakeAuthService{output: &domain.AuthenticateOutput{AccountID: "account_1", IsNew: false}}
+	addr := serveGRPCAPI(t, svc)
+
+	out, err := runAuthCmd(t, "--grpc", "--provider", "guest", "--data", "id=abc", "--addr", addr)
+	require.NoError(t, err)
+	require.Equal(t, "account_id: account_1\nis_new: false\n", out)
+	require.Equal(t, domain.ProviderTypeGuest, svc.input.ProviderType)
+	require.Equal(t, map[string]string{"id": "abc"}, svc.input.AuthData)
+}
+
+func TestAuthCmd_GRPC_FailsOnRejectedAuthentication(t *testing.T) {
+	addr := serveGRPCAPI(t, &fakeAuthService{err: domain.ErrTokenExpired})
+
+	_, err := runAuthCmd(t, "--grpc", "--provider", "google", "--data", "token=expired", "--addr", addr)
+	require.ErrorContains(t, err, "authentication failed: Unauthenticated: token expired")
+}
+
+// serveGRPCAPI serves the gRPC API on a loopback listener until the test ends and returns its address
+func serveGRPCAPI(t *testing.T, svc ports.AuthService) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- grpcapi.NewServer("", svc, logger.NewWithWriter(io.Discard, "error")).Serve(ctx, lis)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-served)
+	})
+	return lis.Addr().String()
+}
+
+// fakeAuthService records the input it is called with and returns the configured outcome
+type fakeAuthService struct {
+	input  domain.AuthenticateInput
+	output *domain.AuthenticateOutput
+	err    error
+}
+
+func (f *fakeAuthService) Authenticate(_ context.Context, input domain.AuthenticateInput) (*domain.AuthenticateOutput, error) {
+	f.input = input
+	return f.output, f.err
+}
+
+func (f *fakeAuthService) AuthenticateBatch(context.Context, []domain.AuthenticateInput) ([]domain.AuthenticateResult, error) {
+	return nil, errors.New("not implemented")
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/posilva/simpleidentity/pkg/config"
+)
+
+// configCmd groups the configuration commands
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the service configuration",
+}
+
+// configPrintCmd prints the effective configuration
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective configuration",
+	Long: `Print the effective configuration after merging defaults, the config file
+and environment variables.
+
+Sensitive settings (client secrets, private keys, headers) are redacted
+unless --show-secrets is set.`,
+	RunE: runConfigPrint,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configPrintCmd)
+
+	configPrintCmd.Flags().String("format", config.DumpFormatJSON, "Output format (json, yaml)")
+	configPrintCmd.Flags().Bool("show-secrets", false, "Print sensitive settings without redaction")
+}
+
+func runConfigPrint(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	showSecrets, _ := cmd.Flags().GetBool("show-secrets")
+
+	configMgr := config.Global()
+	if _, err := configMgr.Load(); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	return configMgr.Dump(cmd.OutOrStdout(), format, showSecrets)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/posilva/simpleidentity/pkg/config"
+)
+
+// newDynamoDBClient creates a DynamoDB client from the default AWS configuration,
+// the region and endpoint are overridden when configured and the options applied last
+func newDynamoDBClient(ctx context.Context, c config.DynamoDBConfig, optFns ...func(*dynamodb.Options)) (*dynamodb.Client, error) {
+	var loadOpts []func(*awsconfig.LoadOptions) error
+	if c.Region != "" {
+		loadOpts = append(loadOpts, awsconfig.WithRegion(c.Region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return dynamodb.NewFromConfig(cfg, append([]func(*dynamodb.Options){func(o *dynamodb.Options) {
+		if c.Endpoint != "" {
+			o.BaseEndpoint = aws.String(c.Endpoint)
+		}
+	}}, optFns...)...), nil
+}
+
+// withoutSDKRetries makes every call a single attempt so the retries are left to the accounts repository
+// retry policy, the SDK retryer also retries the throttled calls and would multiply the attempts of the policy
+func withoutSDKRetries(o *dynamodb.Options) {
+	o.RetryMaxAttempts = 1
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+
+	"github.com/posilva/simpleidentity/pkg/config"
+)
+
+func TestNewDynamoDBClient_Overrides(t *testing.T) {
+	client, err := newDynamoDBClient(context.Background(), config.DynamoDBConfig{
+		Endpoint: "http://localhost:8000",
+		Region:   "eu-west-1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, aws.String("http://localhost:8000"), client.Options().BaseEndpoint)
+	require.Equal(t, "eu-west-1", client.Options().Region)
+}
+
+func TestNewDynamoDBClient_DefaultEndpoint(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	client, err := newDynamoDBClient(context.Background(), config.DynamoDBConfig{})
+	require.NoError(t, err)
+	require.Nil(t, client.Options().BaseEndpoint)
+	require.Equal(t, "us-east-1", client.Options().Region)
+}
+
+func TestNewDynamoDBClient_WithoutSDKRetries(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	client, err := newDynamoDBClient(context.Background(), config.DynamoDBConfig{})
+	require.NoError(t, err)
+	require.Equal(t, 3, client.Options().Retryer.MaxAttempts())
+
+	client, err = newDynamoDBClient(context.Background(), config.DynamoDBConfig{}, withoutSDKRetries)
+	require.NoError(t, err)
+	require.Equal(t, 1, client.Options().Retryer.MaxAttempts())
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/spf13/cobra"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/repository"
+	"github.com/posilva/simpleidentity/pkg/config"
+)
+
+// migrateCmd creates the DynamoDB accounts table
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Create the DynamoDB accounts table",
+	Long: `Create the DynamoDB accounts table with the PK/SK key schema and wait
+until it is active.
+
+The command is idempotent, running it against an existing table is a no-op.
+AWS credentials and region are resolved from the default AWS configuration,
+use --endpoint to target DynamoDB Local. The table, region and endpoint default to the
+dynamodb-table, dynamodb-region and dynamodb-endpoint configuration keys.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dynamoCfg, err := migrateDynamoDBConfig(cmd)
+		if err != nil {
+			return err
+		}
+		billingMode, _ := cmd.Flags().GetString("billing-mode")
+		readCapacity, _ := cmd.Flags().GetInt64("read-capacity")
+		writeCapacity, _ := cmd.Flags().GetInt64("write-capacity")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		opts, err := tableOptions(billingMode, readCapacity, writeCapacity)
+		if err != nil {
+			return err
+		}
+		client, err := newDynamoDBClient(cmd.Context(), dynamoCfg)
+		if err != nil {
+			return err
+		}
+		return migrate(cmd.Context(), cmd.OutOrStdout(), client, dynamoCfg.Table, opts, timeout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().String("table", "accounts", "DynamoDB table name, overrides dynamodb-table")
+	migrateCmd.Flags().String("region", "", "AWS region, overrides dynamodb-region")
+	migrateCmd.Flags().String("endpoint", "", "DynamoDB endpoint override (e.g. http://localhost:8000), overrides dynamodb-endpoint")
+	migrateCmd.Flags().String("billing-mode", string(types.BillingModePayPerRequest), "Billing mode (PAY_PER_REQUEST or PROVISIONED)")
+	migrateCmd.Flags().Int64("read-capacity", 5, "Read capacity units for provisioned billing")
+	migrateCmd.Flags().Int64("write-capacity", 5, "Write capacity units for provisioned billing")
+	migrateCmd.Flags().Duration("timeout", 2*time.Minute, "Time to wait for the table to be active")
+}
+
+// migrateDynamoDBConfig returns the DynamoDB configuration with the flags set on the command line applied on top
+func migrateDynamoDBConfig(cmd *cobra.Command) (config.DynamoDBConfig, error) {
+	cfg, err := config.Global().Load()
+	if err != nil {
+		return config.DynamoDBConfig{}, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	dynamoCfg := cfg.DynamoDB
+	if cmd.Flags().Changed("table") {
+		dynamoCfg.Table, _ = cmd.Flags().GetString("table")
+	}
+	if cmd.Flags().Changed("region") {
+		dynamoCfg.Region, _ = cmd.Flags().GetString("region")
+	}
+	if cmd.Flags().Changed("endpoint") {
+		dynamoCfg.Endpoint, _ = cmd.Flags().GetString("endpoint")
+	}
+	return dynamoCfg, nil
+}
+
+// tableOptions validates the billing flags
+func tableOptions(billingMode string, readCapacity int64, writeCapacity int64) (repository.TableOptions, error) {
+	mode := types.BillingMode(strings.ToUpper(billingMode))
+	if !slices.Contains(mode.Values(), mode) {
+		return repository.TableOptions{}, fmt.Errorf("invalid billing mode %q: expected one of %v", billingMode, mode.Values())
+	}
+	if mode == types.BillingModeProvisioned && (readCapacity < 1 || writeCapacity < 1) {
+		return repository.TableOptions{}, fmt.Errorf("provisioned billing requires positive read and write capacity")
+	}
+	return repository.TableOptions{
+		BillingMode:   mode,
+		ReadCapacity:  readCapacity,
+		WriteCapacity: writeCapacity,
+	}, nil
+}
+
+func migrate(ctx context.Context, w io.Writer, client repository.TableAPI, table string, opts repository.TableOptions, timeout time.Duration) error {
+	created, err := repository.CreateTable(ctx, client, table, opts, timeout)
+	if err != nil {
+		return err
+	}
+	if created {
+		fmt.Fprintf(w, "Table %s created\n", table)
+	} else {
+		fmt.Fprintf(w, "Table %s already exists\n", table)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableOptions(t *testing.T) {
+	opts, err := tableOptions("provisioned", 5, 10)
+	require.NoError(t, err)
+	require.Equal(t, types.BillingModeProvisioned, opts.BillingMode)
+	require.Equal(t, int64(5), opts.ReadCapacity)
+	require.Equal(t, int64(10), opts.WriteCapacity)
+
+	opts, err = tableOptions("PAY_PER_REQUEST", 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, types.BillingModePayPerRequest, opts.BillingMode)
+
+	_, err = tableOptions("free", 5, 5)
+	require.ErrorContains(t, err, "invalid billing mode")
+
+	_, err = tableOptions("PROVISIONED", 0, 5)
+	require.ErrorContains(t, err, "requires positive read and write capacity")
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// profileEndpoints maps the supported profile kinds to their pprof server endpoint
+var profileEndpoints = map[string]string{
+	"cpu":       "/debug/pprof/profile",
+	"heap":      "/debug/pprof/heap",
+	"goroutine": "/debug/pprof/goroutine",
+	"trace":     "/debug/pprof/trace",
+}
+
+// profileKinds lists the supported profile kinds in a stable order
+var profileKinds = []string{"cpu", "heap", "goroutine", "trace"}
+
+// profileCmd fetches a profile from the pprof debug server of a running instance
+var profileCmd = &cobra.Command{
+	Use:   "profile <cpu|heap|goroutine|trace>",
+	Short: "Fetch a profile from a running server",
+	Long: `Fetch a profile from the pprof debug server of a running SimpleIdentity
+instance and write it to a file, e.g.
+  simpleidentity profile cpu --addr localhost:6060 --seconds 30 --out cpu.pprof
+
+The cpu and trace profiles are recorded for --seconds, heap and goroutine
+are snapshots. Inspect the result with go tool pprof or go tool trace.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: profileKinds,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		seconds, _ := cmd.Flags().GetInt("seconds")
+		out, _ := cmd.Flags().GetString("out")
+		token, _ := cmd.Flags().GetString("token")
+
+		kind := args[0]
+		if out == "" {
+			out = defaultProfileOut(kind)
+		}
+		return fetchProfile(cmd.Context(), cmd.OutOrStdout(), addr, kind, seconds, out, token)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+
+	profileCmd.Flags().String("addr", "localhost:6060", "pprof debug server address")
+	profileCmd.Flags().Int("seconds", 30, "Duration of the cpu and trace profiles")
+	profileCmd.Flags().String("out", "", "Output file, defaults to <kind>.pprof or trace.out")
+	profileCmd.Flags().String("token", "", "Bearer token of the pprof debug server")
+}
+
+// defaultProfileOut returns the default output file of the profile kind
+func defaultProfileOut(kind string) string {
+	if kind == "trace" {
+		return "trace.out"
+	}
+	return kind + ".pprof"
+}
+
+func fetchProfile(ctx context.Context, w io.Writer, addr string, kind string, seconds int, out string, token string) error {
+	endpoint, ok := profileEndpoints[kind]
+	if !ok {
+		return fmt.Errorf("invalid profile kind %q: expected one of %v", kind, profileKinds)
+	}
+	if seconds < 1 {
+		return fmt.Errorf("invalid --seconds %d: must be positive", seconds)
+	}
+
+	url := fmt.Sprintf("http://%s%s", addr, endpoint)
+	// the timed profiles hold the request open for the whole duration
+	timeout := 10 * time.Second
+	if slices.Contains([]string{"cpu", "trace"}, kind) {
+		url += fmt.Sprintf("?seconds=%d", seconds)
+		timeout += time.Duration(seconds) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s profile from %s: %w", kind, addr, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to fetch %s profile: status %d: %s", kind, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	n, err := io.Copy(f, resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// do not leave a truncated profile behind
+		_ = os.Remove(out)
+		return fmt.Errorf("failed to write %s profile: %w", kind, err)
+	}
+
+	fmt.Fprintf(w, "Wrote %s profile (%d bytes) to %s\n", kind, n, out)
+	return nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchProfile_WritesProfile(t *testing.T) {
+	tests := []struct {
+		kind      string
+		wantPath  string
+		wantQuery string
+	}{
+		{kind: "cpu", wantPath: "/debug/pprof/profile", wantQuery: "seconds=5"},
+		{kind: "trace", wantPath: "/debug/pprof/trace", wantQuery: "seconds=5"},
+		{kind: "heap", wantPath: "/debug/pprof/heap"},
+		{kind: "goroutine", wantPath: "/debug/pprof/goroutine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, tt.wantPath, r.URL.Path)
+				require.Equal(t, tt.wantQuery, r.URL.RawQuery)
+				require.Equal(t, "Bearer debug-token", r.Header.Get("Authorization"))
+				_, _ = w.Write([]byte("canned " + tt.kind + " profile"))
+			}))
+			defer srv.Close()
+
+			out := filepath.Join(t.TempDir(), defaultProfileOut(tt.kind))
+			var stdout bytes.Buffer
+			err := fetchProfile(context.Background(), &stdout, strings.TrimPrefix(srv.URL, "http://"), tt.kind, 5, out, "debug-token")
+			require.NoError(t, err)
+			require.Contains(t, stdout.String(), out)
+
+			data, err := os.ReadFile(out)
+			require.NoError(t, err)
+			require.Equal(t, "canned "+tt.kind+" profile", string(data))
+		})
+	}
+}
+
+func TestFetchProfile_Failures(t *testing.T) {
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	defer unauthorized.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		name    string
+		addr    string
+		kind    string
+		seconds int
+		wantErr string
+	}{
+		{name: "invalid kind", addr: "localhost:0", kind: "mutex", seconds: 5, wantErr: `invalid profile kind "mutex"`},
+		{name: "invalid seconds", addr: "localhost:0", kind: "cpu", seconds: 0, wantErr: "invalid --seconds 0"},
+		{name: "unreachable server", addr: strings.TrimPrefix(unreachable.URL, "http://"), kind: "heap", seconds: 5, wantErr: "failed to fetch heap profile from"},
+		{name: "rejected request", addr: strings.TrimPrefix(unauthorized.URL, "http://"), kind: "heap", seconds: 5, wantErr: "status 401: Unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "profile.pprof")
+			err := fetchProfile(context.Background(), &bytes.Buffer{}, tt.addr, tt.kind, tt.seconds, out, "")
+			require.ErrorContains(t, err, tt.wantErr)
+			require.NoFileExists(t, out)
+		})
+	}
+}
@@ -48,6 +48,8 @@ func ExecuteContext(ctx context.Context) error {
 
 func init() {
 	cobra.OnInitialize(initConfig)
+
+	rootCmd.PersistentFlags().String("config", "", "Config file path (.yaml, .yml or .json), environment variables take precedence")
 }
 
 // initConfig sets up environment variable binding for 12-factor app compliance
@@ -63,4 +65,9 @@ func initConfig() {
 
 	// Set defaults through the config manager
 	configMgr.SetDefaults()
+
+	// Load the config file if provided
+	if path := viper.GetString("config"); path != "" {
+		cobra.CheckErr(configMgr.LoadFile(path))
+	}
 }
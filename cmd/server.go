@@ -3,19 +3,38 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 
+	"github.com/posilva/simpleidentity/internal/adapters/input/grpcapi"
+	"github.com/posilva/simpleidentity/internal/adapters/input/httpapi"
+	"github.com/posilva/simpleidentity/internal/adapters/output/nonce"
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers"
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers/certs"
+	"github.com/posilva/simpleidentity/internal/adapters/output/repository"
+	"github.com/posilva/simpleidentity/internal/adapters/output/secrets"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/internal/core/services"
 	"github.com/posilva/simpleidentity/pkg/config"
 	"github.com/posilva/simpleidentity/pkg/health"
 	"github.com/posilva/simpleidentity/pkg/logger"
 	"github.com/posilva/simpleidentity/pkg/pprof"
 	"github.com/posilva/simpleidentity/pkg/shutdown"
+	"github.com/posilva/simpleidentity/pkg/telemetry"
 )
 
+// readinessCheckInterval is the period the readiness checks run until they pass once at startup
+const readinessCheckInterval = 2 * time.Second
+
 // serverCmd represents the server command
 var serverCmd = &cobra.Command{
 	Use:   "server",
@@ -38,20 +57,34 @@ func init() {
 	// Server configuration flags
 	serverCmd.Flags().String("log-level", "info", "Log level (debug, info, warn, error)")
 	serverCmd.Flags().Bool("log-pretty", false, "Enable pretty logging for development")
+	serverCmd.Flags().Bool("log-caller", true, "Add the caller file and line to every log line")
 	serverCmd.Flags().String("health-addr", ":8080", "Health check server address")
-	serverCmd.Flags().String("pprof-addr", ":6060", "pprof debug server address")
+	serverCmd.Flags().String("pprof-addr", "localhost:6060", "pprof debug server address, loopback only by default")
+	serverCmd.Flags().String("pprof-bearer-token", "", "Bearer token required by the pprof debug server, empty disables the check")
 	serverCmd.Flags().String("grpc-addr", ":9090", "gRPC server address")
 	serverCmd.Flags().String("http-addr", ":8090", "HTTP server address")
+	serverCmd.Flags().Int64("http-max-body-bytes", 1<<20, "Maximum size of an HTTP API request body")
+	serverCmd.Flags().Duration("http-request-timeout", 30*time.Second, "Maximum time an HTTP API request is allowed to take")
+	serverCmd.Flags().Int("http-compression-min-bytes", 1024, "Size from which an HTTP API response is gzip compressed")
+	serverCmd.Flags().String("secrets-provider", "", "Secrets provider resolving the secret:// credentials (aws-secrets-manager), empty disables it")
+	serverCmd.Flags().Duration("secrets-cache-ttl", 5*time.Minute, "Time a resolved secret is kept before it is fetched again")
+	serverCmd.Flags().Duration("credentials-refresh-interval", 0, "Period the provider credentials are resolved again, 0 disables the refresh")
 	serverCmd.Flags().Duration("shutdown-timeout", 30*time.Second, "Graceful shutdown timeout")
 	serverCmd.Flags().String("version", "dev", "Service version")
-
-	// Bind flags to viper for environment variable support
-	viper.BindPFlags(serverCmd.Flags())
+	serverCmd.Flags().Int("goroutine-soft-cap", 10000, "Number of goroutines above which a warning is logged")
+	serverCmd.Flags().Int("certs-cache-max-entries", 100, "Maximum number of public keys cached per provider, the least recently used is evicted")
+	serverCmd.Flags().Duration("certs-cache-max-ttl", 24*time.Hour, "Maximum time a provider public key is cached regardless of its advertised expiry")
+	serverCmd.Flags().Int("nonce-store-max-entries", 100000, "Maximum number of consumed nonces kept to reject replayed tokens, the oldest is evicted")
 }
 
 func runServer(cmd *cobra.Command, args []string) error {
-	// Initialize configuration manager
-	configMgr := config.NewManager()
+	// Use the global configuration manager, it holds the config file loaded by the root command
+	configMgr := config.Global()
+
+	// Bind flags to the configuration manager
+	if err := configMgr.BindFlags(cmd.Flags()); err != nil {
+		return err
+	}
 
 	// Load configuration
 	cfg, err := configMgr.Load()
@@ -60,7 +93,7 @@ func runServer(cmd *cobra.Command, args []string) error {
 	}
 
 	// Initialize logger
-	log := logger.New(cfg.LogLevel, cfg.LogPretty)
+	log := logger.New(cfg.LogLevel, cfg.LogPretty, logger.WithCaller(cfg.LogCaller))
 
 	log.Info().
 		Str("version", cfg.Version).
@@ -82,21 +115,113 @@ func runServer(cmd *cobra.Command, args []string) error {
 	// Initialize shutdown manager
 	shutdownMgr := shutdown.NewManager(cfg.ShutdownTimeout, log)
 
-	// Initialize health checker
+	// Initialize health checker, the readiness gate keeps the pod out of rotation until the startup completes
 	healthChecker := health.NewChecker(log, cfg.Version)
+	readinessGate := health.NewReadinessGate()
 
 	// Add basic health checks
 	healthChecker.AddCheck("self", func(ctx context.Context) error {
 		return nil // Always healthy for now
 	})
+	healthChecker.AddCheck("goroutines", health.GoroutineCheck(cfg.GoroutineSoftCap, log))
+
+	// Install the meter provider exported on the health server /metrics endpoint, the instruments
+	// created through the global otel meter are recorded by it too
+	meterProvider, metricsHandler, err := telemetry.NewPrometheusMeterProvider("simpleidentity", cfg.Version,
+		telemetry.WithLatencyBoundaries(cfg.MetricsLatencyBuckets),
+	)
+	if err != nil {
+		return err
+	}
+	otel.SetMeterProvider(meterProvider)
+	meter := meterProvider.Meter(telemetry.MeterName)
+
+	if _, err := health.RegisterGoroutineGauge(meter); err != nil {
+		return fmt.Errorf("failed to register goroutines gauge: %w", err)
+	}
+
+	// The consumed nonces are kept in memory so the replay protection only covers this instance
+	nonceStore := nonce.NewMemoryStore(nonce.WithMaxEntries(cfg.NonceStoreMaxEntries))
+	if _, err := nonce.RegisterSizeGauge(meter, nonceStore); err != nil {
+		return fmt.Errorf("failed to register nonce store gauge: %w", err)
+	}
+
+	// Build the providers from the configuration, every provider gets its own instrumented public keys cache
+	providerOpts := []providers.BuildOption{
+		providers.WithCertificatesCaches(newCertsCaches(cfg.CertsCache, meter)),
+		providers.WithAppleOptions(providers.WithNonceStore(nonceStore)),
+	}
+	if cfg.SecretsProvider == config.SecretsProviderAWSSecretsManager {
+		secretsProvider, err := newSecretsManagerProvider(ctx, cfg.SecretsCacheTTL)
+		if err != nil {
+			return err
+		}
+		providerOpts = append(providerOpts, providers.WithSecretsProvider(secretsProvider))
+	}
+	providerFactory, err := providers.BuildFactoryFromConfig(cfg, providerOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to build providers: %w", err)
+	}
+	log.Info().
+		Any("providers", providerFactory.List()).
+		Msg("Authentication providers configured")
+
+	// The readiness reflects the ability to verify the tokens of the providers publishing signing keys
+	for _, providerType := range providerFactory.List() {
+		provider, err := providerFactory.Get(providerType)
+		if err != nil {
+			return fmt.Errorf("failed to get provider %s: %w", providerType, err)
+		}
+		healthChecker.AddCheck("jwks_"+string(providerType), providers.JWKSHealthCheck(provider))
+	}
+
+	// Build the accounts repository on the table, region and endpoint of the configuration
+	dynamoClient, err := newDynamoDBClient(ctx, cfg.DynamoDB, withoutSDKRetries)
+	if err != nil {
+		return err
+	}
+	// the spans go to the global tracer provider, the DynamoDB metrics to the exported meter provider
+	accountsRepository := repository.NewDynamoDBAccountsRepository(dynamoClient, cfg.DynamoDB.Table,
+		repository.WithInstrumentation(meter, nil),
+	)
+	healthChecker.AddCheck("dynamodb", repository.DynamoDBHealthCheck(dynamoClient, cfg.DynamoDB.Table))
+	authService := services.NewAuthService(providerFactory, accountsRepository, services.WithLogger(log), services.WithMeter(meter))
 
 	// Create servers
-	healthServer := health.NewServer(cfg.HealthAddr, healthChecker, log)
-	pprofServer := pprof.NewServer(cfg.PprofAddr, log)
+	apiServer := httpapi.NewServer(cfg.HttpAddr, providerFactory, log,
+		httpapi.WithAuthService(authService),
+		httpapi.WithMaxBodyBytes(cfg.HttpMaxBodyBytes),
+		httpapi.WithRequestTimeout(cfg.HttpRequestTimeout),
+		httpapi.WithCompressionMinBytes(cfg.HttpCompressionMinBytes),
+		httpapi.WithCORS(httpapi.CORSConfig{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		}),
+	)
+	grpcServer := grpcapi.NewServer(cfg.GrpcAddr, authService, log)
+	healthServer := health.NewServer(cfg.HealthAddr, healthChecker, log,
+		health.WithReadinessGate(readinessGate),
+		health.WithMetricsHandler(metricsHandler),
+	)
+	pprofServer := pprof.NewServerWithAuth(cfg.PprofAddr, cfg.PprofBearerToken, log)
+
+	// Bind the API listeners first so the readiness only flips once they accept connections
+	httpListener, err := net.Listen("tcp", cfg.HttpAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.HttpAddr, err)
+	}
+	grpcListener, err := net.Listen("tcp", cfg.GrpcAddr)
+	if err != nil {
+		httpListener.Close()
+		return fmt.Errorf("failed to listen on %s: %w", cfg.GrpcAddr, err)
+	}
 
 	// Start servers concurrently
 	var wg sync.WaitGroup
-	errChan := make(chan error, 3)
+	errChan := make(chan error, 4)
 
 	// Start health server
 	wg.Add(1)
@@ -116,22 +241,55 @@ func runServer(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	// TODO: Start main application servers (gRPC, HTTP)
-	// This will be implemented when we add the actual API handlers
+	// Start the provider credentials refresher, rotated secrets are picked up without a restart
+	if cfg.CredentialsRefreshInterval > 0 {
+		refresher := providers.NewCredentialsRefresher(cfg, providerFactory, cfg.CredentialsRefreshInterval, log, providerOpts...)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			refresher.Start(ctx)
+		}()
+	}
+
+	// Start HTTP API server
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := apiServer.Serve(ctx, httpListener); err != nil {
+			errChan <- fmt.Errorf("http api server error: %w", err)
+		}
+	}()
+
+	// Start gRPC API server
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		log.Info().Msg("Main application servers will be started here")
-		// For now, just wait for context cancellation
-		<-ctx.Done()
+		if err := grpcServer.Serve(ctx, grpcListener); err != nil {
+			errChan <- fmt.Errorf("grpc api server error: %w", err)
+		}
+	}()
+
+	// The pod receives traffic once the listeners are bound and every check, DynamoDB included, passed once
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := readinessGate.SetReadyWhenHealthy(ctx, healthChecker, readinessCheckInterval); err != nil {
+			log.Warn().Err(err).Msg("Stopped waiting for the readiness checks")
+			return
+		}
+		log.Info().Msg("Server ready to receive traffic")
 	}()
 
 	// Add shutdown hooks
 	shutdownMgr.AddHook(shutdown.ContextCancelHook(cancel, "main-context"))
+	shutdownMgr.AddHook(shutdown.CustomHook("meter-provider", telemetry.FlushAndShutdown(meterProvider)))
 
 	log.Info().
 		Str("health_addr", cfg.HealthAddr).
 		Str("pprof_addr", cfg.PprofAddr).
+		Str("http_addr", cfg.HttpAddr).
+		Str("grpc_addr", cfg.GrpcAddr).
+		Str("dynamodb_table", cfg.DynamoDB.Table).
 		Msg("All servers started successfully")
 
 	// Wait for shutdown signal or server errors
@@ -144,11 +302,44 @@ func runServer(cmd *cobra.Command, args []string) error {
 	}()
 
 	// Wait for shutdown
-	shutdownMgr.Wait(ctx)
+	shutdownErr := shutdownMgr.Wait(ctx)
 
 	// Wait for all goroutines to finish
 	wg.Wait()
 	close(errChan)
 
+	if shutdownErr != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", shutdownErr)
+	}
 	return nil
 }
+
+// newCertsCaches returns the function creating the public keys cache of a provider, tagged by provider in the
+// metrics. A provider gets the same cache on every call so the credentials refresh keeps the cached keys
+func newCertsCaches(c config.CertsCacheConfig, meter metric.Meter) func(domain.ProviderType) certs.CacheManager {
+	var mutex sync.Mutex
+	caches := make(map[domain.ProviderType]certs.CacheManager)
+	return func(providerType domain.ProviderType) certs.CacheManager {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if cache, ok := caches[providerType]; ok {
+			return cache
+		}
+		cache := certs.NewInstrumentedCacheManager(
+			certs.NewSimpleCacheManager(certs.WithMaxEntries(c.MaxEntries), certs.WithMaxTTL(c.MaxTTL)),
+			meter,
+			attribute.String(services.ProviderAttributeKey, string(providerType)),
+		)
+		caches[providerType] = cache
+		return cache
+	}
+}
+
+// newSecretsManagerProvider creates the AWS Secrets Manager secrets provider from the default AWS configuration
+func newSecretsManagerProvider(ctx context.Context, cacheTTL time.Duration) (ports.SecretsProvider, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return secrets.NewAWSSecretsManagerProvider(secretsmanager.NewFromConfig(cfg), secrets.WithCacheTTL(cacheTTL)), nil
+}
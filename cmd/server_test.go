@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/pkg/config"
+)
+
+func TestNewCertsCaches_OneCachePerProvider(t *testing.T) {
+	newCache := newCertsCaches(config.CertsCacheConfig{MaxEntries: 10, MaxTTL: time.Hour}, noop.NewMeterProvider().Meter("test"))
+
+	google := newCache(domain.ProviderTypeGoogle)
+	require.Same(t, google, newCache(domain.ProviderTypeGoogle))
+	require.NotSame(t, google, newCache(domain.ProviderTypeApple))
+}
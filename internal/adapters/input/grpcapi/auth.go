@@ -0,0 +1,69 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// publicMethods are the methods called without a session token
+var publicMethods = map[string]bool{
+	AuthenticateMethod: true,
+}
+
+// WithSessionVerifier sets the verifier of the session tokens required by the methods that are not public
+func WithSessionVerifier(v ports.SessionVerifier) ServerOption {
+	return func(s *Server) {
+		s.sessionVerifier = v
+	}
+}
+
+// requireAccount rejects with Unauthenticated the calls of the methods that are not public without a valid
+// bearer session token in the authorization metadata, the token subject is injected in the call context as
+// the authenticated account, see domain.AccountFromContext.
+// Every such call is rejected when no session verifier is configured.
+func (s *Server) requireAccount(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return nil, unauthenticated(ctx, "missing session token")
+	}
+	if s.sessionVerifier == nil {
+		return nil, unauthenticated(ctx, "invalid session token")
+	}
+	claims, err := s.sessionVerifier.Verify(ctx, token)
+	if err != nil || claims.Subject == "" {
+		s.logger.Debug().Err(err).Str("method", info.FullMethod).Msg("Rejected gRPC API session token")
+		return nil, unauthenticated(ctx, "invalid session token")
+	}
+	return handler(domain.ContextWithAccount(ctx, domain.AccountID(claims.Subject)), req)
+}
+
+// unauthenticated returns the Unauthenticated status of a rejected call with its error code trailer
+func unauthenticated(ctx context.Context, reason string) error {
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(ErrorCodeTrailer, string(domain.ErrorCodeUnauthenticated)))
+	return status.Error(codes.Unauthenticated, reason)
+}
+
+// bearerToken returns the token of the authorization bearer metadata
+func bearerToken(ctx context.Context) (string, bool) {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return "", false
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,182 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ovechkin-dm/mockio/v2/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/logger"
+	"github.com/posilva/simpleidentity/pkg/tokens"
+)
+
+const (
+	testSessionKeyID = "session-key"
+	whoAmIMethod     = "/simpleidentity.test.Protected/WhoAmI"
+)
+
+// whoAmIResponse is the response of the protected test method
+type whoAmIResponse struct {
+	AccountID domain.AccountID `json:"account_id"`
+}
+
+// protectedServiceDesc describes a test service whose method returns the authenticated account
+var protectedServiceDesc = grpc.ServiceDesc{
+	ServiceName: "simpleidentity.test.Protected",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "WhoAmI",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			var req struct{}
+			if err := dec(&req); err != nil {
+				return nil, err
+			}
+			whoAmI := func(ctx context.Context, _ any) (any, error) {
+				accountID, _ := domain.AccountFromContext(ctx)
+				return &whoAmIResponse{AccountID: accountID}, nil
+			}
+			return interceptor(ctx, &req, &grpc.UnaryServerInfo{Server: srv, FullMethod: whoAmIMethod}, whoAmI)
+		},
+	}},
+}
+
+// newProtectedTestClient serves the auth service and the protected test service and returns a client connected to them
+func newProtectedTestClient(t *testing.T, svc ports.AuthService, opts ...ServerOption) *grpc.ClientConn {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := NewServer("", svc, logger.NewWithWriter(io.Discard, "error"), opts...)
+	s.server.RegisterService(&protectedServiceDesc, struct{}{})
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx, lis)
+	}()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+		cancel()
+		require.NoError(t, <-served)
+	})
+	return conn
+}
+
+func signSessionToken(t *testing.T, key *rsa.PrivateKey, subject string, exp time.Time) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub": subject,
+		"exp": exp.Unix(),
+	})
+	token.Header["kid"] = testSessionKeyID
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestServer_RequireAccount(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	verifier := tokens.NewVerifierWithKeySet(tokens.KeySet{testSessionKeyID: &key.PublicKey})
+
+	tests := []struct {
+		name          string
+		opts          []ServerOption
+		authorization string
+		wantAccount   domain.AccountID
+		wantMessage   string
+	}{
+		{
+			name:          "valid token",
+			opts:          []ServerOption{WithSessionVerifier(verifier)},
+			authorization: "Bearer " + signSessionToken(t, key, "account-1", time.Now().Add(time.Hour)),
+			wantAccount:   "account-1",
+		},
+		{
+			name:        "missing token",
+			opts:        []ServerOption{WithSessionVerifier(verifier)},
+			wantMessage: "missing session token",
+		},
+		{
+			name:          "not a bearer token",
+			opts:          []ServerOption{WithSessionVerifier(verifier)},
+			authorization: "Basic dXNlcjpwYXNz",
+			wantMessage:   "missing session token",
+		},
+		{
+			name:          "expired token",
+			opts:          []ServerOption{WithSessionVerifier(verifier)},
+			authorization: "Bearer " + signSessionToken(t, key, "account-1", time.Now().Add(-time.Hour)),
+			wantMessage:   "invalid session token",
+		},
+		{
+			name:          "token signed by another key",
+			opts:          []ServerOption{WithSessionVerifier(verifier)},
+			authorization: "Bearer " + signSessionToken(t, otherKey, "account-1", time.Now().Add(time.Hour)),
+			wantMessage:   "invalid session token",
+		},
+		{
+			name:          "no session verifier",
+			authorization: "Bearer " + signSessionToken(t, key, "account-1", time.Now().Add(time.Hour)),
+			wantMessage:   "invalid session token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newProtectedTestClient(t, nil, tt.opts...)
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tt.authorization)
+			}
+
+			var resp whoAmIResponse
+			var trailer metadata.MD
+			err := conn.Invoke(ctx, whoAmIMethod, struct{}{}, &resp, grpc.CallContentSubtype(CodecName), grpc.Trailer(&trailer))
+			if tt.wantMessage == "" {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantAccount, resp.AccountID)
+				return
+			}
+			require.Equal(t, codes.Unauthenticated, status.Code(err))
+			require.Equal(t, tt.wantMessage, status.Convert(err).Message())
+			require.Equal(t, []string{string(domain.ErrorCodeUnauthenticated)}, trailer.Get(ErrorCodeTrailer))
+		})
+	}
+}
+
+func TestServer_RequireAccount_AuthenticateIsPublic(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ctrl := mock.NewMockController(t)
+	svc := mock.Mock[ports.AuthService](ctrl)
+	mock.WhenDouble(svc.Authenticate(mock.Any[context.Context](), mock.Any[domain.AuthenticateInput]())).
+		ThenReturn(&domain.AuthenticateOutput{AccountID: "account_1"}, nil)
+	conn := newProtectedTestClient(t, svc,
+		WithSessionVerifier(tokens.NewVerifierWithKeySet(tokens.KeySet{testSessionKeyID: &key.PublicKey})))
+
+	var resp AuthenticateResponse
+	err = conn.Invoke(context.Background(), AuthenticateMethod, &AuthenticateRequest{
+		ProviderType: domain.ProviderTypeGuest,
+		AuthData:     map[string]string{"id": "abc"},
+	}, &resp, grpc.CallContentSubtype(CodecName))
+	require.NoError(t, err)
+	require.Equal(t, domain.AccountID("account_1"), resp.AccountID)
+}
@@ -0,0 +1,57 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// ErrorCodeTrailer is the trailer carrying the domain error code of a failed call, clients branch on it
+const ErrorCodeTrailer = "error-code"
+
+// errorStatuses maps the error codes to the status codes of their calls, the codes not listed fail with Internal
+var errorStatuses = map[domain.ErrorCode]codes.Code{
+	domain.ErrorCodeProviderNotFound:        codes.NotFound,
+	domain.ErrorCodeAccountNotFound:         codes.NotFound,
+	domain.ErrorCodeAccountAlreadyExists:    codes.AlreadyExists,
+	domain.ErrorCodeMissingProviderAuthData: codes.InvalidArgument,
+	domain.ErrorCodeInvalidProviderAuthData: codes.InvalidArgument,
+	domain.ErrorCodeInsufficientScope:       codes.PermissionDenied,
+	domain.ErrorCodeInvalidTenantID:         codes.InvalidArgument,
+	domain.ErrorCodeAttestationFailed:       codes.PermissionDenied,
+	domain.ErrorCodeInvalidInput:            codes.InvalidArgument,
+	domain.ErrorCodeProviderUnavailable:     codes.Unavailable,
+	domain.ErrorCodeTokenMalformed:          codes.Unauthenticated,
+	domain.ErrorCodeTokenExpired:            codes.Unauthenticated,
+	domain.ErrorCodeTokenNotYetValid:        codes.Unauthenticated,
+	domain.ErrorCodeTokenInvalidSignature:   codes.Unauthenticated,
+	domain.ErrorCodeTokenInvalidIssuer:      codes.Unauthenticated,
+	domain.ErrorCodeTokenInvalidAudience:    codes.Unauthenticated,
+	domain.ErrorCodeTokenInvalidNonce:       codes.Unauthenticated,
+	domain.ErrorCodeNonceReused:             codes.Unauthenticated,
+	domain.ErrorCodeTokenInvalidClaims:      codes.Unauthenticated,
+	domain.ErrorCodeThrottled:               codes.Unavailable,
+	domain.ErrorCodeTransactionConflict:     codes.Aborted,
+	domain.ErrorCodeValidation:              codes.InvalidArgument,
+	domain.ErrorCodeTimeout:                 codes.DeadlineExceeded,
+	domain.ErrorCodeUnauthenticated:         codes.Unauthenticated,
+}
+
+// statusError returns the status of an error returned by the core, the message of the errors without a
+// known code is not exposed to the client
+func (s *Server) statusError(ctx context.Context, err error) error {
+	code := domain.CodeFor(err)
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(ErrorCodeTrailer, string(code)))
+
+	statusCode, ok := errorStatuses[code]
+	if !ok {
+		s.logger.Error().Err(err).Msg("gRPC API call failed")
+		return status.Error(codes.Internal, "internal error")
+	}
+	return status.Error(statusCode, err.Error())
+}
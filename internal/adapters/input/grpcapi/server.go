@@ -0,0 +1,156 @@
+// Package grpcapi provides the gRPC input adapter exposing the public API.
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/logger"
+)
+
+const (
+	// ServiceName is the full name of the authentication service
+	ServiceName = "simpleidentity.v1.AuthService"
+	// AuthenticateMethod is the full name of the authenticate method
+	AuthenticateMethod = "/" + ServiceName + "/Authenticate"
+)
+
+// gracefulStopTimeout bounds the time the in flight calls are given to complete on shutdown
+const gracefulStopTimeout = 5 * time.Second
+
+// AuthenticateRequest represents the request of the authenticate method
+type AuthenticateRequest struct {
+	ProviderType domain.ProviderType `json:"provider_type"`
+	AuthData     map[string]string   `json:"auth_data"`
+	// TenantID is the game or tenant the account belongs to, empty uses the default tenant
+	TenantID domain.TenantID `json:"tenant_id,omitempty"`
+}
+
+// AuthenticateResponse represents the response of the authenticate method
+type AuthenticateResponse struct {
+	AccountID domain.AccountID `json:"account_id"`
+	IsNew     bool             `json:"is_new"`
+}
+
+// authServiceServer is the handler type of the service description
+type authServiceServer interface {
+	authenticate(ctx context.Context, req *AuthenticateRequest) (*AuthenticateResponse, error)
+}
+
+// Safeguard check to ensure Server implements the authServiceServer interface
+var _ authServiceServer = (*Server)(nil)
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*authServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Authenticate", Handler: authenticateHandler},
+	},
+	Metadata: "simpleidentity/v1/auth",
+}
+
+// Server represents the public gRPC API server
+type Server struct {
+	addr   string
+	server *grpc.Server
+	auth   ports.AuthService
+	logger logger.Logger
+	// sessionVerifier verifies the session tokens of the methods that are not public
+	sessionVerifier ports.SessionVerifier
+}
+
+// ServerOption configures optional settings of the gRPC API server
+type ServerOption func(*Server)
+
+// NewServer creates a new gRPC API server
+func NewServer(addr string, auth ports.AuthService, logger logger.Logger, opts ...ServerOption) *Server {
+	s := &Server{
+		addr:   addr,
+		auth:   auth,
+		logger: logger,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.server = grpc.NewServer(grpc.ChainUnaryInterceptor(s.requireAccount))
+	s.server.RegisterService(&serviceDesc, s)
+	return s
+}
+
+// Start starts the gRPC API server
+func (s *Server) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("grpc api server error: %w", err)
+	}
+	return s.Serve(ctx, lis)
+}
+
+// Serve serves the calls accepted by the listener until the context is done
+func (s *Server) Serve(ctx context.Context, lis net.Listener) error {
+	s.logger.Info().
+		Str("addr", lis.Addr().String()).
+		Msg("Starting gRPC API server")
+
+	go func() {
+		<-ctx.Done()
+		s.logger.Info().Msg("Shutting down gRPC API server")
+		stopped := time.AfterFunc(gracefulStopTimeout, s.server.Stop)
+		s.server.GracefulStop()
+		stopped.Stop()
+	}()
+
+	if err := s.server.Serve(lis); err != nil {
+		return fmt.Errorf("grpc api server error: %w", err)
+	}
+	return nil
+}
+
+func authenticateHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	req := new(AuthenticateRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	s := srv.(authServiceServer)
+	if interceptor == nil {
+		return s.authenticate(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: AuthenticateMethod}
+	return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+		return s.authenticate(ctx, req.(*AuthenticateRequest))
+	})
+}
+
+// authenticate authenticates the client with the provider of the request and returns its account
+func (s *Server) authenticate(ctx context.Context, req *AuthenticateRequest) (*AuthenticateResponse, error) {
+	output, err := s.auth.Authenticate(ctx, domain.AuthenticateInput{
+		ProviderType: req.ProviderType,
+		AuthData:     req.AuthData,
+		TenantID:     req.TenantID,
+		SourceIP:     sourceIP(ctx),
+	})
+	if err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+	return &AuthenticateResponse{AccountID: output.AccountID, IsNew: output.IsNew}, nil
+}
+
+// sourceIP returns the address of the client connected to the server
+func sourceIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
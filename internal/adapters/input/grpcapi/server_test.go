@@ -0,0 +1,113 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/ovechkin-dm/mockio/v2/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/logger"
+)
+
+// newTestClient serves the auth service on a loopback listener and returns a client connected to it
+func newTestClient(t *testing.T, svc ports.AuthService) *grpc.ClientConn {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- NewServer("", svc, logger.NewWithWriter(io.Discard, "error")).Serve(ctx, lis)
+	}()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+		cancel()
+		require.NoError(t, <-served)
+	})
+	return conn
+}
+
+func TestServer_Authenticate_ReturnsAccount(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	svc := mock.Mock[ports.AuthService](ctrl)
+	captor := mock.Captor[domain.AuthenticateInput]()
+	mock.WhenDouble(svc.Authenticate(mock.Any[context.Context](), captor.Capture())).
+		ThenReturn(&domain.AuthenticateOutput{AccountID: "account_1", IsNew: true}, nil)
+	conn := newTestClient(t, svc)
+
+	var resp AuthenticateResponse
+	err := conn.Invoke(context.Background(), AuthenticateMethod, &AuthenticateRequest{
+		ProviderType: domain.ProviderTypeGuest,
+		AuthData:     map[string]string{"id": "abc"},
+		TenantID:     "game1",
+	}, &resp, grpc.CallContentSubtype(CodecName))
+	require.NoError(t, err)
+
+	require.Equal(t, AuthenticateResponse{AccountID: "account_1", IsNew: true}, resp)
+	require.Equal(t, domain.AuthenticateInput{
+		ProviderType: domain.ProviderTypeGuest,
+		AuthData:     map[string]string{"id": "abc"},
+		TenantID:     "game1",
+		SourceIP:     "127.0.0.1",
+	}, captor.Last())
+}
+
+func TestServer_Authenticate_MapsErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    codes.Code
+		wantTrailer domain.ErrorCode
+		wantMessage string
+	}{
+		{
+			name:        "token expired",
+			err:         fmt.Errorf("failed to authenticate: %w", domain.ErrTokenExpired),
+			wantCode:    codes.Unauthenticated,
+			wantTrailer: domain.ErrorCodeTokenExpired,
+			wantMessage: "failed to authenticate: token expired",
+		},
+		{
+			name:        "unknown error",
+			err:         errors.New("connection reset by peer"),
+			wantCode:    codes.Internal,
+			wantTrailer: domain.ErrorCodeInternal,
+			wantMessage: "internal error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := mock.NewMockController(t)
+			svc := mock.Mock[ports.AuthService](ctrl)
+			mock.WhenDouble(svc.Authenticate(mock.Any[context.Context](), mock.Any[domain.AuthenticateInput]())).
+				ThenReturn(nil, tt.err)
+			conn := newTestClient(t, svc)
+
+			var trailer metadata.MD
+			err := conn.Invoke(context.Background(), AuthenticateMethod, &AuthenticateRequest{ProviderType: domain.ProviderTypeGoogle},
+				&AuthenticateResponse{}, grpc.CallContentSubtype(CodecName), grpc.Trailer(&trailer))
+
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			require.Equal(t, tt.wantCode, st.Code())
+			require.Equal(t, tt.wantMessage, st.Message())
+			require.Equal(t, []string{string(tt.wantTrailer)}, trailer.Get(ErrorCodeTrailer))
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// SessionVerifier verifies the session tokens presented to the protected endpoints, it is shared with the gRPC API
+type SessionVerifier = ports.SessionVerifier
+
+// WithSessionVerifier sets the verifier of the session tokens required by the endpoints wrapped with RequireAccount
+func WithSessionVerifier(v SessionVerifier) ServerOption {
+	return func(s *Server) {
+		s.sessionVerifier = v
+	}
+}
+
+// RequireAccount rejects with 401 the requests without a valid bearer session token, the token subject is
+// injected in the request context as the authenticated account, see domain.AccountFromContext.
+// Every request is rejected when no session verifier is configured.
+func (s *Server) RequireAccount(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			s.unauthorized(w, "missing session token")
+			return
+		}
+		if s.sessionVerifier == nil {
+			s.unauthorized(w, "invalid session token")
+			return
+		}
+		claims, err := s.sessionVerifier.Verify(r.Context(), token)
+		if err != nil || claims.Subject == "" {
+			s.logger.Debug().Err(err).Msg("Rejected HTTP API session token")
+			s.unauthorized(w, "invalid session token")
+			return
+		}
+		ctx := domain.ContextWithAccount(r.Context(), domain.AccountID(claims.Subject))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// unauthorized writes a 401 response challenging the client for a bearer token
+func (s *Server) unauthorized(w http.ResponseWriter, reason string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	s.writeErrorCode(w, http.StatusUnauthorized, domain.ErrorCodeUnauthenticated, reason)
+}
+
+// bearerToken returns the token of the Authorization bearer header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
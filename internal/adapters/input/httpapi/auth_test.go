@@ -0,0 +1,119 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/pkg/logger"
+	"github.com/posilva/simpleidentity/pkg/tokens"
+	"github.com/stretchr/testify/require"
+)
+
+const testSessionKeyID = "session-key"
+
+func signSessionToken(t *testing.T, key *rsa.PrivateKey, subject string, exp time.Time) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub": subject,
+		"exp": exp.Unix(),
+	})
+	token.Header["kid"] = testSessionKeyID
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestServer_RequireAccount(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	verifier := tokens.NewVerifierWithKeySet(tokens.KeySet{testSessionKeyID: &key.PublicKey})
+
+	tests := []struct {
+		name          string
+		opts          []ServerOption
+		authorization string
+		wantStatus    int
+		wantError     string
+	}{
+		{
+			name:          "valid token",
+			opts:          []ServerOption{WithSessionVerifier(verifier)},
+			authorization: "Bearer " + signSessionToken(t, key, "account-1", time.Now().Add(time.Hour)),
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:       "missing token",
+			opts:       []ServerOption{WithSessionVerifier(verifier)},
+			wantStatus: http.StatusUnauthorized,
+			wantError:  "missing session token",
+		},
+		{
+			name:          "not a bearer token",
+			opts:          []ServerOption{WithSessionVerifier(verifier)},
+			authorization: "Basic dXNlcjpwYXNz",
+			wantStatus:    http.StatusUnauthorized,
+			wantError:     "missing session token",
+		},
+		{
+			name:          "token signed by another key",
+			opts:          []ServerOption{WithSessionVerifier(verifier)},
+			authorization: "Bearer " + signSessionToken(t, otherKey, "account-1", time.Now().Add(time.Hour)),
+			wantStatus:    http.StatusUnauthorized,
+			wantError:     "invalid session token",
+		},
+		{
+			name:          "expired token",
+			opts:          []ServerOption{WithSessionVerifier(verifier)},
+			authorization: "Bearer " + signSessionToken(t, key, "account-1", time.Now().Add(-time.Hour)),
+			wantStatus:    http.StatusUnauthorized,
+			wantError:     "invalid session token",
+		},
+		{
+			name:          "no session verifier",
+			authorization: "Bearer " + signSessionToken(t, key, "account-1", time.Now().Add(time.Hour)),
+			wantStatus:    http.StatusUnauthorized,
+			wantError:     "invalid session token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error"), tt.opts...)
+			var accountID domain.AccountID
+			handler := s.RequireAccount(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var ok bool
+				accountID, ok = domain.AccountFromContext(r.Context())
+				require.True(t, ok)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				require.Equal(t, domain.AccountID("account-1"), accountID)
+				return
+			}
+			require.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+			var body ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+			require.Equal(t, tt.wantError, body.Message)
+			require.Equal(t, domain.ErrorCodeUnauthenticated, body.Code)
+			require.Empty(t, accountID)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// AuthenticateRequest represents the body of the authenticate endpoint
+type AuthenticateRequest struct {
+	ProviderType domain.ProviderType `json:"provider_type"`
+	AuthData     map[string]string   `json:"auth_data"`
+	// TenantID is the game or tenant the account belongs to, empty uses the default tenant
+	TenantID domain.TenantID `json:"tenant_id,omitempty"`
+}
+
+// AuthenticateResponse represents the response of the authenticate endpoint
+type AuthenticateResponse struct {
+	AccountID domain.AccountID `json:"account_id"`
+	IsNew     bool             `json:"is_new"`
+}
+
+// WithAuthService sets the service behind POST /v1/authenticate, the endpoint is not served without it
+func WithAuthService(svc ports.AuthService) ServerOption {
+	return func(s *Server) {
+		s.auth = svc
+	}
+}
+
+// authenticateHandler authenticates the client with the provider of the request and returns its account
+func (s *Server) authenticateHandler(w http.ResponseWriter, r *http.Request) {
+	var req AuthenticateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.writeErrorCode(w, http.StatusRequestEntityTooLarge, domain.ErrorCodeRequestTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", s.maxBodyBytes))
+			return
+		}
+		s.writeErrorCode(w, http.StatusBadRequest, domain.ErrorCodeInvalidInput, "invalid request body")
+		return
+	}
+
+	output, err := s.auth.Authenticate(r.Context(), domain.AuthenticateInput{
+		ProviderType: req.ProviderType,
+		AuthData:     req.AuthData,
+		TenantID:     req.TenantID,
+		SourceIP:     sourceIP(r),
+	})
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, AuthenticateResponse{AccountID: output.AccountID, IsNew: output.IsNew})
+}
+
+// sourceIP returns the address of the client connected to the server
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,97 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ovechkin-dm/mockio/v2/mock"
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuthenticateTestServer(t *testing.T, svc ports.AuthService) *httptest.Server {
+	t.Helper()
+	s := NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error"), WithAuthService(svc))
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestServer_Authenticate_ReturnsAccount(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	svc := mock.Mock[ports.AuthService](ctrl)
+	captor := mock.Captor[domain.AuthenticateInput]()
+	mock.WhenDouble(svc.Authenticate(mock.Any[context.Context](), captor.Capture())).
+		ThenReturn(&domain.AuthenticateOutput{AccountID: "account_1", IsNew: true}, nil)
+	srv := newAuthenticateTestServer(t, svc)
+
+	resp, err := http.Post(srv.URL+"/v1/authenticate", "application/json",
+		strings.NewReader(`{"provider_type":"guest","auth_data":{"id":"abc"},"tenant_id":"game1"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var body AuthenticateResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, AuthenticateResponse{AccountID: "account_1", IsNew: true}, body)
+	require.Equal(t, domain.AuthenticateInput{
+		ProviderType: domain.ProviderTypeGuest,
+		AuthData:     map[string]string{"id": "abc"},
+		TenantID:     "game1",
+		SourceIP:     "127.0.0.1",
+	}, captor.Last())
+}
+
+func TestServer_Authenticate_MapsErrors(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	svc := mock.Mock[ports.AuthService](ctrl)
+	mock.WhenDouble(svc.Authenticate(mock.Any[context.Context](), mock.Any[domain.AuthenticateInput]())).
+		ThenReturn(nil, fmt.Errorf("failed to authenticate: %w", domain.ErrTokenExpired))
+	srv := newAuthenticateTestServer(t, svc)
+
+	resp, err := http.Post(srv.URL+"/v1/authenticate", "application/json",
+		strings.NewReader(`{"provider_type":"google","auth_data":{"code":"expired"}}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	var body ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, domain.ErrorCodeTokenExpired, body.Code)
+}
+
+func TestServer_Authenticate_RejectsInvalidBody(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	svc := mock.Mock[ports.AuthService](ctrl)
+	srv := newAuthenticateTestServer(t, svc)
+
+	resp, err := http.Post(srv.URL+"/v1/authenticate", "application/json", strings.NewReader(`{"provider_type":`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var body ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, domain.ErrorCodeInvalidInput, body.Code)
+	mock.VerifyNoMoreInteractions(svc)
+}
+
+func TestServer_Authenticate_NotServedWithoutAuthService(t *testing.T) {
+	srv := httptest.NewServer(NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error")).Handler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/v1/authenticate", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
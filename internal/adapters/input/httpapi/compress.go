@@ -0,0 +1,140 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressionMinBytes is the default size from which a response is compressed
+const DefaultCompressionMinBytes = 1024
+
+// WithCompressionMinBytes sets the size from which a response is gzip compressed, smaller responses are not worth it
+func WithCompressionMinBytes(n int) ServerOption {
+	return func(s *Server) {
+		s.compressionMinBytes = n
+	}
+}
+
+// compress gzip compresses the responses larger than the threshold when the client accepts it
+func (s *Server) compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, minBytes: s.compressionMinBytes}
+		defer func() {
+			if err := gw.close(); err != nil {
+				s.logger.Error().Err(err).Msg("Error compressing HTTP API response")
+			}
+		}()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip returns true when the Accept-Encoding header value allows gzip
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.TrimSpace(coding)
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		// a zero quality value means the coding is not acceptable
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(v, 64)
+			return err == nil && q > 0
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the response until it reaches the threshold, then it is streamed compressed,
+// responses completing below the threshold are written as is
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minBytes int
+	buf      []byte
+	status   int
+	started  bool
+	gz       *gzip.Writer
+}
+
+// WriteHeader records the status code, it is sent once the response is known to be compressed or not
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.started || w.status != 0 {
+		return
+	}
+	w.status = code
+}
+
+// Write buffers the body until the threshold is reached
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.started {
+		if w.gz != nil {
+			return w.gz.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) < w.minBytes {
+		return len(p), nil
+	}
+	if err := w.start(w.compressible()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// compressible returns false when the handler already encoded the body or the status has no body
+func (w *gzipResponseWriter) compressible() bool {
+	if w.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+	return w.status != http.StatusNoContent && w.status != http.StatusNotModified
+}
+
+// start sends the headers and the buffered body, compressed or not
+func (w *gzipResponseWriter) start(compressed bool) error {
+	w.started = true
+	if compressed {
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close flushes the response once the handler returns
+func (w *gzipResponseWriter) close() error {
+	if !w.started {
+		return w.start(false)
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers"
+	"github.com/posilva/simpleidentity/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func serveCompressed(t *testing.T, body string, status int, acceptEncoding string) *httptest.ResponseRecorder {
+	s := NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error"), WithCompressionMinBytes(64))
+	handler := s.compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, err := io.WriteString(w, body)
+		require.NoError(t, err)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_Compress_LargeResponse(t *testing.T) {
+	body := strings.Repeat("x", 128)
+	rec := serveCompressed(t, body, http.StatusCreated, "br, gzip")
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	require.Equal(t, body, string(decompressed))
+}
+
+func TestServer_Compress_SkipsSmallResponse(t *testing.T) {
+	rec := serveCompressed(t, `{"ok":true}`, http.StatusOK, "gzip")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("Content-Encoding"))
+	require.Equal(t, `{"ok":true}`, rec.Body.String())
+}
+
+func TestServer_Compress_SkipsWhenNotAccepted(t *testing.T) {
+	body := strings.Repeat("x", 128)
+
+	for _, acceptEncoding := range []string{"", "br", "gzip;q=0"} {
+		rec := serveCompressed(t, body, http.StatusOK, acceptEncoding)
+
+		require.Empty(t, rec.Header().Get("Content-Encoding"), acceptEncoding)
+		require.Equal(t, body, rec.Body.String(), acceptEncoding)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	require.True(t, acceptsGzip("gzip"))
+	require.True(t, acceptsGzip("deflate, gzip;q=0.5"))
+	require.True(t, acceptsGzip("*"))
+	require.False(t, acceptsGzip(""))
+	require.False(t, acceptsGzip("identity"))
+	require.False(t, acceptsGzip("gzip;q=0"))
+}
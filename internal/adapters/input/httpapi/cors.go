@@ -0,0 +1,86 @@
+package httpapi
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig defines the cross origin policy of the API, no allowed origins disables CORS
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to call the API, "*" allows any origin
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and authorization headers
+	AllowCredentials bool
+	// MaxAge is the number of seconds browsers may cache a preflight response, zero omits it
+	MaxAge int
+}
+
+// DefaultCORSConfig returns the locked down policy, no origin is allowed
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-Request-ID"},
+	}
+}
+
+// WithCORS sets the cross origin policy of the API
+func WithCORS(c CORSConfig) ServerOption {
+	return func(s *Server) {
+		s.cors = c
+	}
+}
+
+// allowsOrigin reports whether the origin may call the API
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	return slices.Contains(c.AllowedOrigins, "*") || slices.Contains(c.AllowedOrigins, origin)
+}
+
+// handleCORS answers the preflight requests and adds the CORS headers to the requests of the allowed origins,
+// the requests without an Origin header are not cross origin and pass through untouched
+func (s *Server) handleCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := s.cors.allowsOrigin(origin)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if preflight {
+			if !allowed {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			s.setCORSOriginHeaders(w, origin)
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(s.cors.AllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(s.cors.AllowedHeaders, ", "))
+			if s.cors.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(s.cors.MaxAge))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if allowed {
+			s.setCORSOriginHeaders(w, origin)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setCORSOriginHeaders sets the headers shared by the preflight and actual responses,
+// the origin is echoed instead of "*" as browsers reject the wildcard with credentials
+func (s *Server) setCORSOriginHeaders(w http.ResponseWriter, origin string) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if s.cors.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
+}
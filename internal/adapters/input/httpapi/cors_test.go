@@ -0,0 +1,90 @@
+package httpapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers"
+	"github.com/posilva/simpleidentity/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func newCORSTestServer(t *testing.T, c CORSConfig) *httptest.Server {
+	t.Helper()
+	s := NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error"), WithCORS(c))
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func doCORSRequest(t *testing.T, method string, url string, origin string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestServer_CORS_Preflight(t *testing.T) {
+	c := DefaultCORSConfig()
+	c.AllowedOrigins = []string{"https://play.example.com"}
+	c.AllowCredentials = true
+	c.MaxAge = 600
+	srv := newCORSTestServer(t, c)
+
+	resp := doCORSRequest(t, http.MethodOptions, srv.URL+"/v1/providers", "https://play.example.com")
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "https://play.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET, POST", resp.Header.Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "Content-Type, Authorization, X-Request-ID", resp.Header.Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+	require.Equal(t, "Origin", resp.Header.Get("Vary"))
+
+	resp = doCORSRequest(t, http.MethodOptions, srv.URL+"/v1/providers", "https://evil.example.com")
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestServer_CORS_ActualRequest(t *testing.T) {
+	c := DefaultCORSConfig()
+	c.AllowedOrigins = []string{"https://play.example.com"}
+	srv := newCORSTestServer(t, c)
+
+	resp := doCORSRequest(t, http.MethodGet, srv.URL+"/v1/providers", "https://play.example.com")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "https://play.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "X-Request-ID", resp.Header.Get("Access-Control-Expose-Headers"))
+
+	// the request is served but the browser blocks the response without the CORS headers
+	resp = doCORSRequest(t, http.MethodGet, srv.URL+"/v1/providers", "https://evil.example.com")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestServer_CORS_LockedDownByDefault(t *testing.T) {
+	srv := httptest.NewServer(NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error")).Handler())
+	defer srv.Close()
+
+	resp := doCORSRequest(t, http.MethodOptions, srv.URL+"/v1/providers", "https://play.example.com")
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestServer_CORS_WildcardOrigin(t *testing.T) {
+	c := DefaultCORSConfig()
+	c.AllowedOrigins = []string{"*"}
+	srv := newCORSTestServer(t, c)
+
+	resp := doCORSRequest(t, http.MethodGet, srv.URL+"/v1/providers", "https://any.example.com")
+	require.Equal(t, "https://any.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,63 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// errorStatuses maps the error codes to the status of their responses, the codes not listed are answered with 500
+var errorStatuses = map[domain.ErrorCode]int{
+	domain.ErrorCodeProviderNotFound:        http.StatusNotFound,
+	domain.ErrorCodeAccountNotFound:         http.StatusNotFound,
+	domain.ErrorCodeAccountAlreadyExists:    http.StatusConflict,
+	domain.ErrorCodeMissingProviderAuthData: http.StatusBadRequest,
+	domain.ErrorCodeInvalidProviderAuthData: http.StatusBadRequest,
+	domain.ErrorCodeInsufficientScope:       http.StatusForbidden,
+	domain.ErrorCodeInvalidTenantID:         http.StatusBadRequest,
+	domain.ErrorCodeInvalidCursor:           http.StatusBadRequest,
+	domain.ErrorCodeAttestationFailed:       http.StatusForbidden,
+	domain.ErrorCodeInvalidInput:            http.StatusBadRequest,
+	domain.ErrorCodeProviderUnavailable:     http.StatusBadGateway,
+	domain.ErrorCodeTokenMalformed:          http.StatusUnauthorized,
+	domain.ErrorCodeTokenExpired:            http.StatusUnauthorized,
+	domain.ErrorCodeTokenNotYetValid:        http.StatusUnauthorized,
+	domain.ErrorCodeTokenInvalidSignature:   http.StatusUnauthorized,
+	domain.ErrorCodeTokenInvalidIssuer:      http.StatusUnauthorized,
+	domain.ErrorCodeTokenInvalidAudience:    http.StatusUnauthorized,
+	domain.ErrorCodeTokenInvalidNonce:       http.StatusUnauthorized,
+	domain.ErrorCodeNonceReused:             http.StatusUnauthorized,
+	domain.ErrorCodeTokenInvalidClaims:      http.StatusUnauthorized,
+	domain.ErrorCodeThrottled:               http.StatusServiceUnavailable,
+	domain.ErrorCodeTransactionConflict:     http.StatusConflict,
+	domain.ErrorCodeValidation:              http.StatusBadRequest,
+	domain.ErrorCodeTimeout:                 http.StatusGatewayTimeout,
+	domain.ErrorCodeUnauthenticated:         http.StatusUnauthorized,
+	domain.ErrorCodeRequestTooLarge:         http.StatusRequestEntityTooLarge,
+}
+
+// statusFor returns the response status of an error code
+func statusFor(code domain.ErrorCode) int {
+	if status, ok := errorStatuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError writes the error response of an error returned by the core, the message of the errors without a
+// known code is not exposed to the client
+func (s *Server) writeError(w http.ResponseWriter, err error) {
+	code := domain.CodeFor(err)
+	status := statusFor(code)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		s.logger.Error().Err(err).Msg("HTTP API request failed")
+		message = http.StatusText(status)
+	}
+	s.writeErrorCode(w, status, code, message)
+}
+
+// writeErrorCode writes an error response with the given status, code and message
+func (s *Server) writeErrorCode(w http.ResponseWriter, status int, code domain.ErrorCode, message string) {
+	s.writeJSON(w, status, ErrorResponse{Code: code, Message: message})
+}
@@ -0,0 +1,69 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_WriteError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    domain.ErrorCode
+		wantMessage string
+	}{
+		{
+			name:        "provider not found",
+			err:         fmt.Errorf("provider 'twitter': %w", domain.ErrProviderNotFound),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    domain.ErrorCodeProviderNotFound,
+			wantMessage: "provider 'twitter': provider not found",
+		},
+		{
+			name:        "missing auth data",
+			err:         domain.ErrMissingRequiredProviderAuthData,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    domain.ErrorCodeMissingProviderAuthData,
+			wantMessage: "missing required provider authentication data",
+		},
+		{
+			name:        "token expired",
+			err:         fmt.Errorf("%w: %w", domain.ErrTokenExpired, errors.New("exp claim")),
+			wantStatus:  http.StatusUnauthorized,
+			wantCode:    domain.ErrorCodeTokenExpired,
+			wantMessage: "token expired: exp claim",
+		},
+		{
+			name:        "unknown error",
+			err:         errors.New("connection reset by peer"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    domain.ErrorCodeInternal,
+			wantMessage: "Internal Server Error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error"))
+			rec := httptest.NewRecorder()
+			s.writeError(rec, tt.err)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			var body ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+			require.Equal(t, tt.wantCode, body.Code)
+			require.Equal(t, tt.wantMessage, body.Message)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Package httpapi provides the HTTP input adapter exposing the public API.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/logger"
+	"github.com/posilva/simpleidentity/pkg/reqid"
+)
+
+// DefaultMaxBodyBytes is the default maximum size of a request body
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+// ErrorResponse represents the body of an error response, clients branch on the code
+type ErrorResponse struct {
+	Code    domain.ErrorCode `json:"code"`
+	Message string           `json:"message"`
+}
+
+// ProvidersResponse represents the response of the providers discovery endpoint
+type ProvidersResponse struct {
+	Providers []domain.ProviderType `json:"providers"`
+	// RequiredFields lists the authentication data fields each provider requires
+	RequiredFields map[domain.ProviderType][]string `json:"required_fields"`
+}
+
+// Server represents the public HTTP API server
+type Server struct {
+	server       *http.Server
+	providers    ports.AuthProviderFactory
+	logger       logger.Logger
+	maxBodyBytes int64
+	cors         CORSConfig
+	// requestTimeout bounds the time every request is allowed to take
+	requestTimeout time.Duration
+	// compressionMinBytes is the size from which a response is compressed
+	compressionMinBytes int
+	// sessionVerifier verifies the session tokens of the endpoints wrapped with RequireAccount
+	sessionVerifier SessionVerifier
+	// auth authenticates the clients of POST /v1/authenticate, nil leaves the endpoint unregistered
+	auth ports.AuthService
+}
+
+// ServerOption configures optional settings of the HTTP API server
+type ServerOption func(*Server)
+
+// WithMaxBodyBytes sets the maximum size of a request body, larger bodies are rejected with 413
+func WithMaxBodyBytes(n int64) ServerOption {
+	return func(s *Server) {
+		s.maxBodyBytes = n
+	}
+}
+
+// NewServer creates a new HTTP API server
+func NewServer(addr string, providers ports.AuthProviderFactory, logger logger.Logger, opts ...ServerOption) *Server {
+	s := &Server{
+		server: &http.Server{
+			Addr:              addr,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		providers:           providers,
+		logger:              logger,
+		maxBodyBytes:        DefaultMaxBodyBytes,
+		cors:                DefaultCORSConfig(),
+		requestTimeout:      DefaultRequestTimeout,
+		compressionMinBytes: DefaultCompressionMinBytes,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.server.Handler = s.Handler()
+
+	return s
+}
+
+// Handler returns the HTTP handler with every API route registered
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/providers", s.providersHandler)
+	if s.auth != nil {
+		mux.HandleFunc("POST /v1/authenticate", s.authenticateHandler)
+	}
+	return reqid.Middleware(s.handleCORS(s.compress(s.limitBody(s.timeout(mux)))))
+}
+
+// limitBody rejects the requests declaring a body larger than the limit and caps the bodies of unknown length,
+// handlers reading past the limit get an *http.MaxBytesError and must answer with 413 too
+func (s *Server) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > s.maxBodyBytes {
+			s.writeErrorCode(w, http.StatusRequestEntityTooLarge, domain.ErrorCodeRequestTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", s.maxBodyBytes))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Start starts the HTTP API server
+func (s *Server) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return fmt.Errorf("http api server error: %w", err)
+	}
+	return s.Serve(ctx, lis)
+}
+
+// Serve serves the requests accepted by the listener until the context is done
+func (s *Server) Serve(ctx context.Context, lis net.Listener) error {
+	s.logger.Info().
+		Str("addr", lis.Addr().String()).
+		Msg("Starting HTTP API server")
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		s.logger.Info().Msg("Shutting down HTTP API server")
+		if err := s.server.Shutdown(shutdownCtx); err != nil {
+			s.logger.Error().Err(err).Msg("Error shutting down HTTP API server")
+		}
+	}()
+
+	if err := s.server.Serve(lis); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("http api server error: %w", err)
+	}
+
+	return nil
+}
+
+// providersHandler lists the providers configured at runtime so clients can discover the supported login methods
+func (s *Server) providersHandler(w http.ResponseWriter, r *http.Request) {
+	response := ProvidersResponse{
+		Providers:      s.providers.List(),
+		RequiredFields: make(map[domain.ProviderType][]string),
+	}
+	for _, providerType := range response.Providers {
+		// a provider removed since List is skipped, the next discovery request won't list it
+		provider, err := s.providers.Get(providerType)
+		if err != nil {
+			continue
+		}
+		response.RequiredFields[providerType] = provider.RequiredFields()
+	}
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// writeJSON writes the value as a JSON response with the given status code
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Error().Err(err).Msg("Error encoding HTTP API response")
+	}
+}
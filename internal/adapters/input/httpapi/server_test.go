@@ -0,0 +1,129 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ovechkin-dm/mockio/v2/mock"
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/logger"
+	"github.com/posilva/simpleidentity/pkg/reqid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_Providers_ListsRegisteredProviders(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	authProviderMock := mock.Mock[ports.AuthProvider](ctrl)
+	mock.WhenSingle(authProviderMock.RequiredFields()).ThenReturn([]string{"identityToken", "nonce"})
+
+	factory := providers.NewDefaultFactory()
+	require.NoError(t, factory.Add(domain.ProviderTypeGuest, providers.NewGuestProvider()))
+	require.NoError(t, factory.Add(domain.ProviderTypeApple, authProviderMock))
+
+	srv := httptest.NewServer(NewServer(":0", factory, logger.NewWithWriter(io.Discard, "error")).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/providers")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var body ProvidersResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, []domain.ProviderType{domain.ProviderTypeApple, domain.ProviderTypeGuest}, body.Providers)
+	require.Equal(t, map[domain.ProviderType][]string{
+		domain.ProviderTypeApple: {"identityToken", "nonce"},
+		domain.ProviderTypeGuest: {providers.GuestIDFieldName},
+	}, body.RequiredFields)
+}
+
+func TestServer_Providers_RejectsOtherMethods(t *testing.T) {
+	srv := httptest.NewServer(NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error")).Handler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/v1/providers", "application/json", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestServer_EchoesRequestID(t *testing.T) {
+	srv := httptest.NewServer(NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error")).Handler())
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/providers", nil)
+	require.NoError(t, err)
+	req.Header.Set(reqid.HeaderName, "client-request-1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "client-request-1", resp.Header.Get(reqid.HeaderName))
+
+	resp, err = http.Get(srv.URL + "/v1/providers")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NotEmpty(t, resp.Header.Get(reqid.HeaderName))
+}
+
+func TestServer_RejectsOversizedBody(t *testing.T) {
+	s := NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error"), WithMaxBodyBytes(16))
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/v1/providers", "application/json", strings.NewReader(`{"provider_type":"guest","auth_data":{}}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var body ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, "request body exceeds 16 bytes", body.Message)
+	require.Equal(t, domain.ErrorCodeRequestTooLarge, body.Code)
+}
+
+func TestServer_LimitBody_CapsBodiesOfUnknownLength(t *testing.T) {
+	s := NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error"), WithMaxBodyBytes(16))
+	var readErr error
+	handler := s.limitBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("x", 32)))
+	req.ContentLength = -1
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var maxBytesErr *http.MaxBytesError
+	require.ErrorAs(t, readErr, &maxBytesErr)
+}
+
+func TestServer_Serve_StopsWhenContextIsDone(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := NewServer("", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx, lis)
+	}()
+
+	resp, err := http.Get("http://" + lis.Addr().String() + "/v1/providers")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	require.NoError(t, <-served)
+}
@@ -0,0 +1,128 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// DefaultRequestTimeout is the default maximum time a request is allowed to take
+const DefaultRequestTimeout = 30 * time.Second
+
+// WithRequestTimeout sets the maximum time a request is allowed to take, slower requests are answered with 504,
+// a non positive value disables the timeout
+func WithRequestTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.requestTimeout = d
+	}
+}
+
+// timeout bounds the request context with the request timeout so the downstream calls are cancelled with it,
+// the handler response is buffered and discarded when the deadline is reached first
+func (s *Server) timeout(next http.Handler) http.Handler {
+	if s.requestTimeout <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		tw := &timeoutWriter{header: make(http.Header)}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			next.ServeHTTP(tw, r)
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			dst := w.Header()
+			for k, vv := range tw.header {
+				dst[k] = vv
+			}
+			if !tw.wroteHeader {
+				tw.code = http.StatusOK
+			}
+			w.WriteHeader(tw.code)
+			if _, err := w.Write(tw.buf.Bytes()); err != nil {
+				s.logger.Error().Err(err).Msg("Error writing HTTP API response")
+			}
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.timedOut = true
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				// the client went away, there is no one to answer to
+				return
+			}
+			s.logger.Warn().
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Dur("timeout", s.requestTimeout).
+				Msg("HTTP API request timed out")
+			s.writeErrorCode(w, http.StatusGatewayTimeout, domain.ErrorCodeTimeout, "request timed out")
+		}
+	})
+}
+
+// timeoutWriter buffers the handler response until it completes within the request timeout
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+	timedOut    bool
+}
+
+// Header returns the buffered response headers
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write buffers the response body, after the timeout it fails with http.ErrHandlerTimeout
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.buf.Write(p)
+}
+
+// WriteHeader records the response status code
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	if tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.code = code
+}
@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func newTimeoutTestServer(d time.Duration) *Server {
+	return NewServer(":0", providers.NewDefaultFactory(), logger.NewWithWriter(io.Discard, "error"), WithRequestTimeout(d))
+}
+
+func TestServer_Timeout_RespondsGatewayTimeout(t *testing.T) {
+	s := newTimeoutTestServer(20 * time.Millisecond)
+	handlerCtxErr := make(chan error, 1)
+	handler := s.timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			handlerCtxErr <- r.Context().Err()
+		case <-time.After(time.Second):
+			handlerCtxErr <- nil
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var body ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	require.Equal(t, "request timed out", body.Message)
+	require.Equal(t, domain.ErrorCodeTimeout, body.Code)
+	require.ErrorIs(t, <-handlerCtxErr, context.DeadlineExceeded)
+}
+
+func TestServer_Timeout_PassesFastResponsesThrough(t *testing.T) {
+	s := newTimeoutTestServer(time.Second)
+	handler := s.timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		require.True(t, hasDeadline)
+		w.Header().Set("X-Test", "value")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "value", rec.Header().Get("X-Test"))
+	require.Equal(t, "created", rec.Body.String())
+}
+
+func TestServer_Timeout_DisabledWhenNotPositive(t *testing.T) {
+	s := newTimeoutTestServer(0)
+	handler := s.timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		require.False(t, hasDeadline)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+}
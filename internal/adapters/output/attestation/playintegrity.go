@@ -0,0 +1,65 @@
+// Package attestation provides adapters to verify device attestation tokens.
+package attestation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// PlayIntegrityVerdict is the subset of the decoded Play Integrity token payload checked by the verifier
+type PlayIntegrityVerdict struct {
+	// RequestPackageName is the package name of the app that requested the token
+	RequestPackageName string
+	// Nonce is the nonce the app bound to the token request
+	Nonce string
+}
+
+// PlayIntegrityDecoder decodes an integrity token, e.g. through the Play Integrity decodeIntegrityToken API
+type PlayIntegrityDecoder interface {
+	Decode(ctx context.Context, token string) (*PlayIntegrityVerdict, error)
+}
+
+// NonceValidator checks that the nonce was issued by the server and was not used before
+type NonceValidator func(ctx context.Context, nonce string) error
+
+// playIntegrityVerifier verifies Play Integrity tokens of an Android app
+type playIntegrityVerifier struct {
+	packageName   string
+	decoder       PlayIntegrityDecoder
+	validateNonce NonceValidator
+}
+
+// Safeguard check to ensure playIntegrityVerifier implements the AttestationVerifier interface
+var _ ports.AttestationVerifier = (*playIntegrityVerifier)(nil)
+
+// NewPlayIntegrityVerifier creates a verifier accepting the tokens requested by the given package with a valid nonce.
+// NOTE: it is a skeleton, the device and app recognition verdicts are not checked yet
+func NewPlayIntegrityVerifier(packageName string, decoder PlayIntegrityDecoder, validateNonce NonceValidator) ports.AttestationVerifier {
+	return &playIntegrityVerifier{
+		packageName:   packageName,
+		decoder:       decoder,
+		validateNonce: validateNonce,
+	}
+}
+
+// Verify decodes the token and checks its package name and nonce, a token that can't be decoded
+// is not reported as an attestation failure as the decoding API may be unavailable
+func (v *playIntegrityVerifier) Verify(ctx context.Context, providerType domain.ProviderType, attestation string) error {
+	verdict, err := v.decoder.Decode(ctx, attestation)
+	if err != nil {
+		return fmt.Errorf("failed to decode integrity token: %w", err)
+	}
+	if verdict.RequestPackageName != v.packageName {
+		return fmt.Errorf("%w: unexpected package name %q", domain.ErrAttestationFailed, verdict.RequestPackageName)
+	}
+	if verdict.Nonce == "" {
+		return fmt.Errorf("%w: missing nonce", domain.ErrAttestationFailed)
+	}
+	if err := v.validateNonce(ctx, verdict.Nonce); err != nil {
+		return fmt.Errorf("%w: invalid nonce: %w", domain.ErrAttestationFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// decoderFunc adapts a function to the PlayIntegrityDecoder interface
+type decoderFunc func(ctx context.Context, token string) (*PlayIntegrityVerdict, error)
+
+func (f decoderFunc) Decode(ctx context.Context, token string) (*PlayIntegrityVerdict, error) {
+	return f(ctx, token)
+}
+
+func TestPlayIntegrityVerifier_Verify(t *testing.T) {
+	errDecode := errors.New("decode failed")
+	errNonce := errors.New("nonce already used")
+	validateNonce := func(_ context.Context, nonce string) error {
+		if nonce != "server-nonce" {
+			return errNonce
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name       string
+		verdict    *PlayIntegrityVerdict
+		decodeErr  error
+		wantErr    error
+		wantFailed bool
+	}{
+		{
+			name:    "valid token",
+			verdict: &PlayIntegrityVerdict{RequestPackageName: "com.example.game", Nonce: "server-nonce"},
+		},
+		{
+			name:       "other package",
+			verdict:    &PlayIntegrityVerdict{RequestPackageName: "com.example.clone", Nonce: "server-nonce"},
+			wantFailed: true,
+		},
+		{
+			name:       "missing nonce",
+			verdict:    &PlayIntegrityVerdict{RequestPackageName: "com.example.game"},
+			wantFailed: true,
+		},
+		{
+			name:       "replayed nonce",
+			verdict:    &PlayIntegrityVerdict{RequestPackageName: "com.example.game", Nonce: "old-nonce"},
+			wantErr:    errNonce,
+			wantFailed: true,
+		},
+		{
+			name:      "decode failure",
+			decodeErr: errDecode,
+			wantErr:   errDecode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := decoderFunc(func(_ context.Context, token string) (*PlayIntegrityVerdict, error) {
+				require.Equal(t, "integrity-token", token)
+				return tt.verdict, tt.decodeErr
+			})
+			verifier := NewPlayIntegrityVerifier("com.example.game", decoder, validateNonce)
+
+			err := verifier.Verify(context.Background(), domain.ProviderTypeGoogle, "integrity-token")
+			if tt.wantErr == nil && !tt.wantFailed {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+			require.Equal(t, tt.wantFailed, errors.Is(err, domain.ErrAttestationFailed))
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package audit provides adapters to emit authentication audit events.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// jsonAuditRecord is the SIEM facing schema of an audit event, one JSON document per line
+type jsonAuditRecord struct {
+	Actor     string `json:"actor"`
+	Action    string `json:"action"`
+	Result    string `json:"result"`
+	SourceIP  string `json:"source_ip"`
+	Provider  string `json:"provider"`
+	Timestamp string `json:"timestamp"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// jsonAuditLogger writes audit events as JSON lines to a dedicated writer
+type jsonAuditLogger struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// Safeguard check to ensure jsonAuditLogger implements the AuditLogger interface
+var _ ports.AuditLogger = (*jsonAuditLogger)(nil)
+
+// NewJSONAuditLogger creates an audit logger that writes JSON lines to w, decoupled from the operational logger
+func NewJSONAuditLogger(w io.Writer) ports.AuditLogger {
+	return &jsonAuditLogger{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// Record writes the audit event to the underlying writer
+func (l *jsonAuditLogger) Record(_ context.Context, event domain.AuditEvent) error {
+	ts := event.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	record := jsonAuditRecord{
+		Actor:     string(event.Actor),
+		Action:    string(event.Action),
+		Result:    string(event.Result),
+		SourceIP:  event.SourceIP,
+		Provider:  string(event.Provider),
+		Timestamp: ts.UTC().Format(time.RFC3339Nano),
+		Reason:    event.Reason,
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if err := l.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONAuditLogger_Record_WritesSchema(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		event    domain.AuditEvent
+		expected map[string]any
+	}{
+		{
+			name: "success",
+			event: domain.AuditEvent{
+				Actor:     "account_id",
+				Action:    domain.AuditActionAuthenticate,
+				Result:    domain.AuditResultSuccess,
+				SourceIP:  "10.0.0.1",
+				Provider:  domain.ProviderTypeGoogle,
+				Timestamp: ts,
+			},
+			expected: map[string]any{
+				"actor":     "account_id",
+				"action":    "authenticate",
+				"result":    "success",
+				"source_ip": "10.0.0.1",
+				"provider":  "google",
+				"timestamp": "2024-01-02T03:04:05Z",
+			},
+		},
+		{
+			name: "failure",
+			event: domain.AuditEvent{
+				Action:    domain.AuditActionAuthenticate,
+				Result:    domain.AuditResultFailure,
+				SourceIP:  "10.0.0.2",
+				Provider:  domain.ProviderTypeApple,
+				Timestamp: ts,
+				Reason:    "invalid nonce",
+			},
+			expected: map[string]any{
+				"actor":     "",
+				"action":    "authenticate",
+				"result":    "failure",
+				"source_ip": "10.0.0.2",
+				"provider":  "apple",
+				"timestamp": "2024-01-02T03:04:05Z",
+				"reason":    "invalid nonce",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := NewJSONAuditLogger(&buf)
+
+			err := l.Record(context.Background(), tt.event)
+			require.NoError(t, err)
+
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			require.Equal(t, tt.expected, record)
+		})
+	}
+}
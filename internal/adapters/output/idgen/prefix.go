@@ -0,0 +1,31 @@
+package idgen
+
+import (
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+type prefixedGenerator struct {
+	inner  ports.IDGenerator
+	prefix string
+}
+
+// WithPrefix wraps the generator so the ids start with the prefix, e.g. acct_<ksuid>. The prefix is the same for
+// every id so the ids stay unique and sort like the ids of the wrapped generator
+func WithPrefix(gen ports.IDGenerator, prefix string) *prefixedGenerator {
+	return &prefixedGenerator{
+		inner:  gen,
+		prefix: prefix,
+	}
+}
+
+var _ ports.IDGenerator = (*prefixedGenerator)(nil)
+
+// GenerateID generates a new id of the wrapped generator prefixed with the prefix.
+func (g *prefixedGenerator) GenerateID() string {
+	return g.GenerateIDWithPrefix(g.prefix)
+}
+
+// GenerateIDWithPrefix generates a new id of the wrapped generator prefixed with the given prefix.
+func (g *prefixedGenerator) GenerateIDWithPrefix(prefix string) string {
+	return prefix + g.inner.GenerateID()
+}
@@ -0,0 +1,75 @@
+package idgen
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/require"
+)
+
+type uuidGenerator struct{}
+
+func (uuidGenerator) GenerateID() string {
+	return uuid.NewString()
+}
+
+// sequenceGenerator returns the ids in order
+type sequenceGenerator struct {
+	ids []string
+}
+
+func (g *sequenceGenerator) GenerateID() string {
+	id := g.ids[0]
+	g.ids = g.ids[1:]
+	return id
+}
+
+func TestWithPrefix_KSUID(t *testing.T) {
+	gen := WithPrefix(NewKSUIDGenerator(), "acct_")
+
+	seen := map[string]bool{}
+	for range 100 {
+		id := gen.GenerateID()
+		require.True(t, strings.HasPrefix(id, "acct_"))
+		_, err := ksuid.Parse(strings.TrimPrefix(id, "acct_"))
+		require.NoError(t, err)
+		require.False(t, seen[id])
+		seen[id] = true
+	}
+}
+
+func TestWithPrefix_UUID(t *testing.T) {
+	gen := WithPrefix(uuidGenerator{}, "acct_")
+
+	id := gen.GenerateID()
+	require.True(t, strings.HasPrefix(id, "acct_"))
+	_, err := uuid.Parse(strings.TrimPrefix(id, "acct_"))
+	require.NoError(t, err)
+}
+
+func TestWithPrefix_GenerateIDWithPrefix(t *testing.T) {
+	gen := WithPrefix(NewKSUIDGenerator(), "acct_")
+
+	id := gen.GenerateIDWithPrefix("dev_")
+	require.True(t, strings.HasPrefix(id, "dev_"))
+	_, err := ksuid.Parse(strings.TrimPrefix(id, "dev_"))
+	require.NoError(t, err)
+}
+
+func TestWithPrefix_PreservesOrder(t *testing.T) {
+	ids := make([]string, 10)
+	for i := range ids {
+		ids[i] = ksuid.New().String()
+	}
+	slices.Sort(ids)
+
+	gen := WithPrefix(&sequenceGenerator{ids: slices.Clone(ids)}, "acct_")
+	prefixed := make([]string, len(ids))
+	for i := range prefixed {
+		prefixed[i] = gen.GenerateID()
+	}
+	require.True(t, slices.IsSorted(prefixed))
+}
@@ -0,0 +1,161 @@
+// Package nonce provides the stores remembering the nonces already used to protect against token replays.
+package nonce
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+const (
+	// DefaultSweepInterval is the default interval between the removals of the expired nonces
+	DefaultSweepInterval = time.Minute
+	// DefaultMaxEntries is the default maximum number of nonces kept
+	DefaultMaxEntries = 100000
+)
+
+// SizeGaugeName is the name of the gauge reporting the number of nonces kept
+const SizeGaugeName = "nonce.store.size"
+
+// SizedStore is a nonce store reporting the number of nonces it keeps
+type SizedStore interface {
+	ports.NonceStore
+	// Len returns the number of nonces kept
+	Len() int
+}
+
+// nonceEntry is a consumed nonce and the time it expires at
+type nonceEntry struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+// memoryStore keeps the consumed nonces in memory, it only protects a single instance
+type memoryStore struct {
+	mutex sync.Mutex
+	// order keeps the nonces in the order they were consumed, the oldest at the front
+	order   *list.List
+	nonces  map[string]*list.Element
+	now     func() time.Time
+	sweptAt time.Time
+	// sweepInterval is the minimum time between two removals of the expired nonces
+	sweepInterval time.Duration
+	// maxEntries bounds the number of nonces kept, a non positive value keeps them all
+	maxEntries int
+}
+
+// Safeguard check to ensure memoryStore implements the SizedStore interface
+var _ SizedStore = (*memoryStore)(nil)
+
+// MemoryStoreOption configures the in memory nonce store
+type MemoryStoreOption func(*memoryStore)
+
+// WithClock sets the function returning the current time the nonces expiry is checked against, defaults to time.Now
+func WithClock(now func() time.Time) MemoryStoreOption {
+	return func(s *memoryStore) {
+		s.now = now
+	}
+}
+
+// WithSweepInterval sets the minimum time between two removals of the expired nonces
+func WithSweepInterval(d time.Duration) MemoryStoreOption {
+	return func(s *memoryStore) {
+		s.sweepInterval = d
+	}
+}
+
+// WithMaxEntries bounds the number of nonces kept, the oldest nonce is evicted when the bound is reached.
+// An evicted nonce can be replayed until its token expires, so the bound must cover the expected traffic
+func WithMaxEntries(n int) MemoryStoreOption {
+	return func(s *memoryStore) {
+		s.maxEntries = n
+	}
+}
+
+// NewMemoryStore creates a nonce store keeping the consumed nonces in memory until they expire
+func NewMemoryStore(opts ...MemoryStoreOption) SizedStore {
+	s := &memoryStore{
+		order:         list.New(),
+		nonces:        make(map[string]*list.Element),
+		now:           time.Now,
+		sweepInterval: DefaultSweepInterval,
+		maxEntries:    DefaultMaxEntries,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.sweptAt = s.now()
+	return s
+}
+
+// ConsumeOnce records the nonce for the ttl, it returns false when the nonce was already consumed and has not expired
+func (s *memoryStore) ConsumeOnce(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	if el, ok := s.nonces[nonce]; ok {
+		if now.Before(el.Value.(*nonceEntry).expiresAt) {
+			return false, nil
+		}
+		s.remove(el)
+	}
+
+	s.nonces[nonce] = s.order.PushBack(&nonceEntry{nonce: nonce, expiresAt: now.Add(ttl)})
+	if s.maxEntries > 0 {
+		for s.order.Len() > s.maxEntries {
+			s.remove(s.order.Front())
+		}
+	}
+	return true, nil
+}
+
+// Len returns the number of nonces kept
+func (s *memoryStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.order.Len()
+}
+
+// sweep removes the expired nonces at most once per sweep interval, the lock must be held
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < s.sweepInterval {
+		return
+	}
+	s.sweptAt = now
+	for el := s.order.Front(); el != nil; {
+		next := el.Next()
+		if !now.Before(el.Value.(*nonceEntry).expiresAt) {
+			s.remove(el)
+		}
+		el = next
+	}
+}
+
+// remove deletes the nonce from both the order list and the index, the lock must be held
+func (s *memoryStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.nonces, el.Value.(*nonceEntry).nonce)
+}
+
+// RegisterSizeGauge registers an observable gauge reporting the number of nonces kept by the store
+func RegisterSizeGauge(meter metric.Meter, store SizedStore) (metric.Registration, error) {
+	gauge, err := meter.Int64ObservableGauge(SizeGaugeName,
+		metric.WithDescription("Number of consumed nonces kept to reject replays"),
+		metric.WithUnit("{nonce}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(gauge, int64(store.Len()))
+		return nil
+	}, gauge)
+}
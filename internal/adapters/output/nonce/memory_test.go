@@ -0,0 +1,150 @@
+package nonce
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// testClock is a frozen clock the tests advance explicitly
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestMemoryStore_ConsumeOnce_RejectsReplayWithinTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore(WithClock(clock.Now))
+
+	first, err := s.ConsumeOnce(ctx, "nonce-1", time.Minute)
+	require.NoError(t, err)
+	require.True(t, first)
+
+	clock.Advance(time.Minute - time.Nanosecond)
+	replayed, err := s.ConsumeOnce(ctx, "nonce-1", time.Minute)
+	require.NoError(t, err)
+	require.False(t, replayed)
+
+	other, err := s.ConsumeOnce(ctx, "nonce-2", time.Minute)
+	require.NoError(t, err)
+	require.True(t, other)
+}
+
+func TestMemoryStore_ConsumeOnce_AcceptsAfterTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore(WithClock(clock.Now))
+
+	first, err := s.ConsumeOnce(ctx, "nonce-1", time.Minute)
+	require.NoError(t, err)
+	require.True(t, first)
+
+	clock.Advance(time.Minute)
+	again, err := s.ConsumeOnce(ctx, "nonce-1", time.Minute)
+	require.NoError(t, err)
+	require.True(t, again)
+}
+
+func TestMemoryStore_SweepsExpiredNonces(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore(WithClock(clock.Now), WithSweepInterval(time.Minute))
+
+	_, err := s.ConsumeOnce(ctx, "short", time.Second)
+	require.NoError(t, err)
+	_, err = s.ConsumeOnce(ctx, "long", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, s.Len())
+
+	clock.Advance(time.Minute)
+	_, err = s.ConsumeOnce(ctx, "new", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, 2, s.Len())
+}
+
+func TestMemoryStore_ConsumeOnce_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := s.ConsumeOnce(ctx, "nonce-1", time.Minute); ok {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), accepted.Load())
+}
+
+func TestMemoryStore_ConsumeOnce_EvictsOldestAtMaxEntries(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(WithMaxEntries(2))
+
+	for _, nonce := range []string{"nonce-1", "nonce-2", "nonce-3"} {
+		ok, err := s.ConsumeOnce(ctx, nonce, time.Hour)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	require.Equal(t, 2, s.Len())
+
+	// the oldest nonce was evicted so it is accepted again, the others are still rejected
+	replayed, err := s.ConsumeOnce(ctx, "nonce-3", time.Hour)
+	require.NoError(t, err)
+	require.False(t, replayed)
+	evicted, err := s.ConsumeOnce(ctx, "nonce-1", time.Hour)
+	require.NoError(t, err)
+	require.True(t, evicted)
+	require.Equal(t, 2, s.Len())
+}
+
+func TestRegisterSizeGauge(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	s := NewMemoryStore(WithMaxEntries(2))
+
+	_, err := RegisterSizeGauge(meter, s)
+	require.NoError(t, err)
+
+	size := func() int64 {
+		var rm metricdata.ResourceMetrics
+		require.NoError(t, reader.Collect(ctx, &rm))
+		require.Len(t, rm.ScopeMetrics, 1)
+		require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+		m := rm.ScopeMetrics[0].Metrics[0]
+		require.Equal(t, SizeGaugeName, m.Name)
+		gauge, ok := m.Data.(metricdata.Gauge[int64])
+		require.True(t, ok)
+		require.Len(t, gauge.DataPoints, 1)
+		return gauge.DataPoints[0].Value
+	}
+
+	require.Equal(t, int64(0), size())
+	_, err = s.ConsumeOnce(ctx, "nonce-1", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), size())
+	for _, nonce := range []string{"nonce-2", "nonce-3"} {
+		_, err = s.ConsumeOnce(ctx, nonce, time.Hour)
+		require.NoError(t, err)
+	}
+	require.Equal(t, int64(2), size())
+}
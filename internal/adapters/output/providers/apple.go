@@ -3,18 +3,18 @@ package providers
 import (
 	"context"
 	"crypto/rsa"
-	"encoding/base64"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"math/big"
 	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/posilva/simpleidentity/internal/adapters/output/providers/certs"
+	"github.com/posilva/simpleidentity/internal/core/domain"
 	"github.com/posilva/simpleidentity/internal/core/ports"
 )
 
@@ -30,6 +30,8 @@ const (
 	AppleUserIDFieldName            = "userID"
 	AppleNonceFieldName             = "nonce"
 	AppleEmailFieldName             = "email"
+	// AppleFullNameFieldName is optional, Apple only shares the user name with the client on the first login
+	AppleFullNameFieldName = "fullName"
 )
 
 type AppleCredentials struct {
@@ -43,26 +45,47 @@ type AppleCredentials struct {
 	IDTokenExpectedIssuer   string
 }
 
+// Validate checks the required credentials are set and the URLs are valid
+func (c AppleCredentials) Validate() error {
+	return errors.Join(
+		requireFields(
+			credentialField{"ClientID", c.ClientID},
+			credentialField{"ClientSecret", c.ClientSecret},
+			credentialField{"CertsURL", c.CertsURL},
+			credentialField{"AuthTokensURL", c.AuthTokensURL},
+			credentialField{"IDTokenExpectedAudience", c.IDTokenExpectedAudience},
+			credentialField{"IDTokenExpectedIssuer", c.IDTokenExpectedIssuer},
+		),
+		requireURLs(
+			credentialField{"CertsURL", c.CertsURL},
+			credentialField{"AuthTokensURL", c.AuthTokensURL},
+		),
+	)
+}
+
 type appleProvider struct {
-	credentials  AppleCredentials
-	cacheManager certs.CacheManager
+	providerOptions
+	credentials AppleCredentials
 }
 
+// Safeguard check to ensure appleProvider implements the AuthProvider interface
+var _ ports.AuthProvider = (*appleProvider)(nil)
+
 type appleAuthResult struct {
-	ID string
+	ID      string
+	Profile domain.ProviderProfile
 }
 type appleIDTokenClaims struct {
 	Issuer         string `json:"iss"`
 	Subject        string `json:"sub"`
 	Audience       string `json:"aud"`
-	IssuedAt       int64  `json:"iat"`
 	Email          string `json:"email"`
-	Expiry         int64  `json:"exp"`
 	Nonce          string `json:"nonce"`
 	NonceSupported bool   `json:"nonce_supported"`
 	EmailVerified  bool   `json:"email_verified"`
 	IsPrivateEmail bool   `json:"is_private_email"`
 	RealUserStatus int    `json:"real_user_status"`
+	// iat and exp are not shadowed so the embedded registered claims validate them
 	jwt.RegisteredClaims
 }
 
@@ -79,77 +102,83 @@ type exchangeTokenResponseError struct {
 	ErrorDescription string `json:"error_description"`
 }
 
-type appleJWK struct {
-	Kty string `json:"kty"`
-	Kid string `json:"kid"`
-	Use string `json:"use"`
-	Alg string `json:"alg"`
-	N   string `json:"n"`
-	E   string `json:"e"`
-}
-
-type appleJWKS struct {
-	Keys []appleJWK `json:"keys"`
-}
-
-// TODO: implement the options pattern if it makes sense like we have done for google provider
-
 // NewAppleProvider creates a new Apple provider
-func NewAppleProvider(cp AppleCredentials) ports.AuthProvider {
+func NewAppleProvider(cp AppleCredentials, opts ...AppleProviderOption) ports.AuthProvider {
 	return &appleProvider{
-		credentials:  cp,
-		cacheManager: certs.NewSimpleCacheManager(),
+		providerOptions: newProviderOptions(opts),
+		credentials:     cp,
 	}
 }
 
+// Safeguard check to ensure appleAuthResult implements the AuthResult interface
+var _ ports.AuthResult = (*appleAuthResult)(nil)
+
 func (r *appleAuthResult) GetID() string {
 	return r.ID
 }
 
+func (r *appleAuthResult) GetProfile() domain.ProviderProfile {
+	return r.Profile
+}
+
+// RequiredFields returns the fields Sign in with Apple always shares with the client, the email and full name are optional
+func (p *appleProvider) RequiredFields() []string {
+	return []string{
+		AppleIdentityTokenFieldName,
+		AppleAuthorizationCodeFieldName,
+		AppleUserIDFieldName,
+		AppleNonceFieldName,
+	}
+}
+
 func (p *appleProvider) Authenticate(ctx context.Context, data map[string]string) (ports.AuthResult, error) {
 	_, ok := data[AppleIdentityTokenFieldName]
 	if !ok {
-		return nil, fmt.Errorf("missing required field: %s", AppleIdentityTokenFieldName)
+		return nil, fmt.Errorf("missing required field %s: %w", AppleIdentityTokenFieldName, domain.ErrMissingRequiredProviderAuthData)
 	}
 	authCode, ok := data[AppleAuthorizationCodeFieldName]
 	if !ok {
-		return nil, fmt.Errorf("missing required field: %s", AppleAuthorizationCodeFieldName)
+		return nil, fmt.Errorf("missing required field %s: %w", AppleAuthorizationCodeFieldName, domain.ErrMissingRequiredProviderAuthData)
 	}
 	userID, ok := data[AppleUserIDFieldName]
 	if !ok {
-		return nil, fmt.Errorf("missing required field: %s", AppleUserIDFieldName)
+		return nil, fmt.Errorf("missing required field %s: %w", AppleUserIDFieldName, domain.ErrMissingRequiredProviderAuthData)
 	}
 	nonce, ok := data[AppleNonceFieldName]
 	if !ok {
-		return nil, fmt.Errorf("missing required field: %s", AppleNonceFieldName)
-	}
-	email, ok := data[AppleEmailFieldName]
-	if !ok {
-		return nil, fmt.Errorf("missing required field: %s", AppleEmailFieldName)
+		return nil, fmt.Errorf("missing required field %s: %w", AppleNonceFieldName, domain.ErrMissingRequiredProviderAuthData)
 	}
+	// Apple only shares the email on the first authorization so it is optional
+	email := data[AppleEmailFieldName]
 	/*
 		  * TODO: this must be enough to authenticate a user
-			claims, err := p.verifyIDToken(idToken, nonce, email)
+			claims, err := p.verifyIDToken(ctx, idToken, nonce, email)
 			if err != nil {
 				return nil, fmt.Errorf("failed to verify direct id token: %w", err)
 			}
 	*/
-	exchangeResponse, err := p.exchangeAuthCodeByRefreshToken(authCode)
+	exchangeResponse, err := p.exchangeAuthCodeByRefreshToken(ctx, authCode)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange auth code: %w", err)
 	}
 
-	claims, err := p.verifyIDToken(exchangeResponse.IDToken, nonce, email)
+	claims, err := p.verifyIDToken(ctx, exchangeResponse.IDToken, nonce, email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify id token: %w", err)
 	}
 	if userID != claims.Subject {
-		return nil, fmt.Errorf("userID mismatch")
+		return nil, fmt.Errorf("userID mismatch: %w", domain.ErrTokenInvalidClaims)
+	}
+	if err := p.consumeNonce(ctx, nonce, claims); err != nil {
+		return nil, err
 	}
-	return &appleAuthResult{ID: claims.Subject}, nil
+	return &appleAuthResult{
+		ID:      claims.Subject,
+		Profile: domain.ProviderProfile{DisplayName: data[AppleFullNameFieldName]},
+	}, nil
 }
 
-func (p *appleProvider) exchangeAuthCodeByRefreshToken(authCode string) (*exchangeTokenResponse, error) {
+func (p *appleProvider) exchangeAuthCodeByRefreshToken(ctx context.Context, authCode string) (*exchangeTokenResponse, error) {
 	// send a form encoded data
 	form := url.Values{}
 	form.Add("code", authCode)
@@ -158,9 +187,9 @@ func (p *appleProvider) exchangeAuthCodeByRefreshToken(authCode string) (*exchan
 	form.Add("redirect_uri", "")
 	form.Add("grant_type", "authorization_code")
 
-	resp, err := http.PostForm(p.credentials.AuthTokensURL, form)
+	resp, err := postForm(ctx, p.httpClient, p.credentials.AuthTokensURL, form)
 	if err != nil {
-		return nil, fmt.Errorf("failed to exchange auth code: %w", err)
+		return nil, fmt.Errorf("failed to exchange auth code: %w: %w", domain.ErrProviderUnavailable, err)
 	}
 
 	defer func() {
@@ -177,10 +206,10 @@ func (p *appleProvider) exchangeAuthCodeByRefreshToken(authCode string) (*exchan
 		var errorBody exchangeTokenResponseError
 		err = json.Unmarshal(body, &errorBody)
 		if err != nil {
-			err = fmt.Errorf("error: %s: %s", errorBody.Error, errorBody.ErrorDescription)
 			return nil, fmt.Errorf("failed to unmarshal error body: %w", err)
 		}
-		return nil, fmt.Errorf("failed to exchange auth code with wrong status code %d: %w", resp.StatusCode, err)
+		return nil, fmt.Errorf("failed to exchange auth code with wrong status code %d: error: %s: %s: %w",
+			resp.StatusCode, errorBody.Error, errorBody.ErrorDescription, statusCodeError(resp.StatusCode))
 	}
 
 	// or handle the response
@@ -197,126 +226,84 @@ func (p *appleProvider) exchangeAuthCodeByRefreshToken(authCode string) (*exchan
 	return &exchangeTokenResponse, nil
 }
 
-func (p *appleProvider) verifyIDToken(idToken string, nonce string, email string) (*appleIDTokenClaims, error) {
+func (p *appleProvider) verifyIDToken(ctx context.Context, idToken string, nonce string, email string) (*appleIDTokenClaims, error) {
 	token, err := jwt.ParseWithClaims(idToken, &appleIDTokenClaims{}, func(token *jwt.Token) (any, error) {
 		kid, ok := token.Header["kid"].(string)
 		if !ok {
-			return nil, errors.New("no kid found in token header")
+			return nil, fmt.Errorf("no kid found in token header: %w", domain.ErrTokenMalformed)
 		}
 
-		pubKey, err := p.fetchPublicKeyByID(kid)
+		pubKey, err := p.fetchPublicKeyByID(ctx, kid)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get public keys: %w", err)
 		}
 		return pubKey, nil
 	},
-		jwt.WithLeeway(30*time.Second))
+		jwt.WithLeeway(p.clockSkew))
 	if err != nil {
-		return nil, fmt.Errorf("token parser error: %w", err)
+		return nil, fmt.Errorf("token parser error: %w", classifyTokenError(err))
 	}
 
 	if !token.Valid {
-		return nil, errors.New("invalid token")
+		return nil, fmt.Errorf("invalid token: %w", domain.ErrTokenInvalidClaims)
 	}
 
 	claims, ok := token.Claims.(*appleIDTokenClaims)
 
 	if !ok {
-		return nil, errors.New("invalid claims format")
+		return nil, fmt.Errorf("invalid claims format: %w", domain.ErrTokenInvalidClaims)
 	}
 
 	if claims.Issuer != p.credentials.IDTokenExpectedIssuer {
-		return nil, errors.New("invalid issuer")
+		return nil, fmt.Errorf("invalid issuer: %w", domain.ErrTokenInvalidIssuer)
 	}
 	if claims.Audience != p.credentials.IDTokenExpectedAudience {
-		return nil, errors.New("invalid audience")
+		return nil, fmt.Errorf("invalid audience: %w", domain.ErrTokenInvalidAudience)
 	}
 
-	if claims.Nonce != nonce {
-		return nil, errors.New("invalid nonce")
+	if claims.Nonce != p.expectedNonce(nonce) {
+		return nil, fmt.Errorf("invalid nonce: %w", domain.ErrTokenInvalidNonce)
 	}
 
-	if email != "" && email != claims.Email {
-		return nil, errors.New("invalid email")
+	if p.emailVerification && email != "" && email != claims.Email {
+		return nil, fmt.Errorf("invalid email: %w", domain.ErrTokenInvalidClaims)
 	}
 	return claims, nil
 }
 
-// TODO: this method is similar to the one on google provider so maybe we can
-// factorise this in a single one
-func (p *appleProvider) fetchPublicKeyByID(id string) (*rsa.PublicKey, error) {
-	key := p.cacheManager.Get(id)
-	if key == nil {
-		resp, err := http.Get(p.credentials.CertsURL)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch public keys from certs url: %w", err)
-		}
-		defer func() {
-			_ = resp.Body.Close()
-		}()
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read body from apple keys endpoint: %w", err)
-		}
-		var jwks appleJWKS
-		if err := json.Unmarshal(body, &jwks); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
-		}
-
-		for _, jwk := range jwks.Keys {
-			k, err := createPublicKeyFromJWK(jwk)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create public key from JWK key id %s: %w", jwk.Kid, err)
-			}
-			expireAt := time.Now().Add(1 * time.Hour)
-			_ = p.cacheManager.Add(jwk.Kid, k, expireAt)
-		}
-
-		key = p.cacheManager.Get(id)
-		if key == nil {
-			return nil, fmt.Errorf("public key id '%s' not found", id)
-		}
+// expectedNonce returns the value the token nonce claim must match for the raw client nonce
+func (p *appleProvider) expectedNonce(rawNonce string) string {
+	if !p.hashedNonce {
+		return rawNonce
 	}
-	return key, nil
+	sum := sha256.Sum256([]byte(rawNonce))
+	return hex.EncodeToString(sum[:])
 }
 
-// createPublicKeyFromJWK takes a JSON string containing Apple's JWK data
-// and returns an RSA public key that can be used to verify JWT tokens
-func createPublicKeyFromJWK(jwk appleJWK) (*rsa.PublicKey, error) {
-	if jwk.Kty != "RSA" {
-		return nil, fmt.Errorf("expected RSA key type, got: %s", jwk.Kty)
+// consumeNonce rejects a nonce already used by a verified token, it is remembered until the token expires
+func (p *appleProvider) consumeNonce(ctx context.Context, nonce string, claims *appleIDTokenClaims) error {
+	if p.nonceStore == nil {
+		return nil
 	}
-
-	nBytes, err := base64URLDecode(jwk.N)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode modulus: %w", err)
+	ttl := p.clockSkew
+	if claims.ExpiresAt != nil {
+		ttl += time.Until(claims.ExpiresAt.Time)
 	}
-
-	eBytes, err := base64URLDecode(jwk.E)
+	first, err := p.nonceStore.ConsumeOnce(ctx, string(domain.ProviderTypeApple)+":"+nonce, ttl)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode exponent: %w", err)
+		return fmt.Errorf("failed to consume nonce: %w", err)
 	}
-
-	n := new(big.Int).SetBytes(nBytes)
-	e := new(big.Int).SetBytes(eBytes)
-
-	publicKey := &rsa.PublicKey{
-		N: n,
-		E: int(e.Int64()),
+	if !first {
+		return fmt.Errorf("nonce already used: %w", domain.ErrNonceReused)
 	}
-
-	return publicKey, nil
+	return nil
 }
 
-func base64URLDecode(data string) ([]byte, error) {
-	// Go's base64.URLEncoding handles the URL-safe characters automatically
-	// but we need to add padding if it's missing
-	switch len(data) % 4 {
-	case 2:
-		data += "=="
-	case 3:
-		data += "="
-	}
+func (p *appleProvider) fetchPublicKeyByID(ctx context.Context, id string) (*rsa.PublicKey, error) {
+	return fetchJWKSPublicKeyByID(ctx, p.httpClient, p.cacheManager, p.credentials.CertsURL, id)
+}
 
-	return base64.URLEncoding.DecodeString(data)
+// loadSigningKeys fetches every Apple public key into the cache and returns when they expire
+func (p *appleProvider) loadSigningKeys(ctx context.Context) (time.Time, error) {
+	return loadJWKSPublicKeys(ctx, p.httpClient, p.cacheManager, p.credentials.CertsURL)
 }
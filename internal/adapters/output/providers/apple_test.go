@@ -3,6 +3,8 @@ package providers
 import (
 	"context"
 	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,6 +13,8 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/posilva/simpleidentity/internal/adapters/output/nonce"
+	"github.com/posilva/simpleidentity/internal/core/domain"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/square/go-jose.v2"
 )
@@ -83,9 +87,289 @@ func TestProviderApple_Returns_Error(t *testing.T) {
 	require.Nil(t, res)
 }
 
+func TestProviderApple_Returns_TypedErrors(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	withClaim := func(key string, value any) string {
+		claims := appleIDTokenTestClaims(10, true, 1, true)
+		claims[key] = value
+		return generateAppleIDTokenFromClaims(keyGen.PrivateKey, claims)
+	}
+
+	tests := []struct {
+		name        string
+		idToken     string
+		expectedErr error
+	}{
+		{name: "expired", idToken: withClaim("exp", time.Now().Add(-time.Hour).Unix()), expectedErr: domain.ErrTokenExpired},
+		{name: "not yet valid", idToken: withClaim("nbf", time.Now().Add(time.Hour).Unix()), expectedErr: domain.ErrTokenNotYetValid},
+		{name: "invalid issuer", idToken: withClaim("iss", "unexpected_issuer"), expectedErr: domain.ErrTokenInvalidIssuer},
+		{name: "invalid audience", idToken: withClaim("aud", "unexpected_audience"), expectedErr: domain.ErrTokenInvalidAudience},
+		{name: "invalid nonce", idToken: withClaim("nonce", "unexpected_nonce"), expectedErr: domain.ErrTokenInvalidNonce},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/authCode", appleAuthURIHandlerWithIDToken(10, tt.idToken))
+			mux.HandleFunc("/certs", appleCertsURLHandler(keyGen.PublicKey))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			credentials := AppleCredentials{
+				AuthTokensURL:           ts.URL + "/authCode",
+				CertsURL:                ts.URL + "/certs",
+				IDTokenExpectedAudience: testExpectedAudience,
+				IDTokenExpectedIssuer:   testExpectedIssuer,
+			}
+
+			p := NewAppleProvider(credentials)
+			res, err := p.Authenticate(ctx, map[string]string{
+				AppleIdentityTokenFieldName:     tt.idToken,
+				AppleAuthorizationCodeFieldName: "auth_code",
+				AppleNonceFieldName:             testExpectedNonce,
+				AppleUserIDFieldName:            testSubject,
+				AppleEmailFieldName:             testEmail,
+			})
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Nil(t, res)
+		})
+	}
+}
+
+func TestProviderApple_Returns_ErrMissingRequiredProviderAuthData(t *testing.T) {
+	p := NewAppleProvider(AppleCredentials{})
+	res, err := p.Authenticate(context.Background(), map[string]string{})
+	require.ErrorIs(t, err, domain.ErrMissingRequiredProviderAuthData)
+	require.Nil(t, res)
+}
+
+func TestProviderApple_ClockSkew(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	// the token expired 10 seconds ago
+	idToken := generateAppleIDToken(-10, keyGen.PrivateKey, true, 1, true)
+
+	tests := []struct {
+		name      string
+		clockSkew time.Duration
+		wantErr   bool
+	}{
+		{name: "inside leeway", clockSkew: 20 * time.Second},
+		{name: "outside leeway", clockSkew: 5 * time.Second, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/authCode", appleAuthURIHandlerWithIDToken(10, idToken))
+			mux.HandleFunc("/certs", appleCertsURLHandler(keyGen.PublicKey))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			credentials := AppleCredentials{
+				AuthTokensURL:           ts.URL + "/authCode",
+				CertsURL:                ts.URL + "/certs",
+				IDTokenExpectedAudience: testExpectedAudience,
+				IDTokenExpectedIssuer:   testExpectedIssuer,
+			}
+
+			p := NewAppleProvider(credentials, WithClockSkew(tt.clockSkew))
+			res, err := p.Authenticate(ctx, map[string]string{
+				AppleIdentityTokenFieldName:     idToken,
+				AppleAuthorizationCodeFieldName: "auth_code",
+				AppleNonceFieldName:             testExpectedNonce,
+				AppleUserIDFieldName:            testSubject,
+				AppleEmailFieldName:             testEmail,
+			})
+			if tt.wantErr {
+				require.ErrorIs(t, err, domain.ErrTokenExpired)
+				require.Nil(t, res)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testSubject, res.GetID())
+		})
+	}
+}
+
+func TestProviderApple_Nonce(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	hashedNonce := sha256.Sum256([]byte(testExpectedNonce))
+
+	tests := []struct {
+		name       string
+		hashed     bool
+		tokenNonce string
+		wantErr    bool
+	}{
+		{name: "raw nonce", hashed: false, tokenNonce: testExpectedNonce},
+		{name: "hashed nonce", hashed: true, tokenNonce: hex.EncodeToString(hashedNonce[:])},
+		{name: "raw nonce in hashed mode", hashed: true, tokenNonce: testExpectedNonce, wantErr: true},
+		{name: "hashed nonce in raw mode", hashed: false, tokenNonce: hex.EncodeToString(hashedNonce[:]), wantErr: true},
+		{name: "mismatch", hashed: true, tokenNonce: "unexpected_nonce", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := appleIDTokenTestClaims(10, true, 1, true)
+			claims["nonce"] = tt.tokenNonce
+			idToken := generateAppleIDTokenFromClaims(keyGen.PrivateKey, claims)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/authCode", appleAuthURIHandlerWithIDToken(10, idToken))
+			mux.HandleFunc("/certs", appleCertsURLHandler(keyGen.PublicKey))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			credentials := AppleCredentials{
+				AuthTokensURL:           ts.URL + "/authCode",
+				CertsURL:                ts.URL + "/certs",
+				IDTokenExpectedAudience: testExpectedAudience,
+				IDTokenExpectedIssuer:   testExpectedIssuer,
+			}
+
+			p := NewAppleProvider(credentials, WithHashedNonce(tt.hashed))
+			res, err := p.Authenticate(ctx, map[string]string{
+				AppleIdentityTokenFieldName:     idToken,
+				AppleAuthorizationCodeFieldName: "auth_code",
+				AppleNonceFieldName:             testExpectedNonce,
+				AppleUserIDFieldName:            testSubject,
+				AppleEmailFieldName:             testEmail,
+			})
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Nil(t, res)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testSubject, res.GetID())
+		})
+	}
+}
+
+func TestProviderApple_NonceStore_RejectsReplay(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authCode", appleAuthURIHandler(10, keyGen.PrivateKey, true, 1, true))
+	mux.HandleFunc("/certs", appleCertsURLHandler(keyGen.PublicKey))
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	credentials := AppleCredentials{
+		AuthTokensURL:           ts.URL + "/authCode",
+		CertsURL:                ts.URL + "/certs",
+		IDTokenExpectedAudience: testExpectedAudience,
+		IDTokenExpectedIssuer:   testExpectedIssuer,
+	}
+	authData := map[string]string{
+		AppleIdentityTokenFieldName:     "id_token",
+		AppleAuthorizationCodeFieldName: "auth_code",
+		AppleNonceFieldName:             testExpectedNonce,
+		AppleUserIDFieldName:            testSubject,
+	}
+
+	p := NewAppleProvider(credentials, WithNonceStore(nonce.NewMemoryStore()))
+	res, err := p.Authenticate(ctx, authData)
+	require.NoError(t, err)
+	require.Equal(t, testSubject, res.GetID())
+
+	res, err = p.Authenticate(ctx, authData)
+	require.ErrorIs(t, err, domain.ErrNonceReused)
+	require.Nil(t, res)
+
+	// without a store the replay protection is off
+	p = NewAppleProvider(credentials)
+	for range 2 {
+		_, err = p.Authenticate(ctx, authData)
+		require.NoError(t, err)
+	}
+}
+
+func TestProviderApple_Email(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authCode", appleAuthURIHandler(10, keyGen.PrivateKey, true, 1, true))
+	mux.HandleFunc("/certs", appleCertsURLHandler(keyGen.PublicKey))
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	credentials := AppleCredentials{
+		AuthTokensURL:           ts.URL + "/authCode",
+		CertsURL:                ts.URL + "/certs",
+		IDTokenExpectedAudience: testExpectedAudience,
+		IDTokenExpectedIssuer:   testExpectedIssuer,
+	}
+
+	tests := []struct {
+		name              string
+		emailVerification bool
+		email             string
+		wantErr           error
+	}{
+		{name: "matching email with verification", emailVerification: true, email: testEmail},
+		{name: "matching email without verification", email: testEmail},
+		{name: "mismatching email with verification", emailVerification: true, email: "other@testmail.com", wantErr: domain.ErrTokenInvalidClaims},
+		{name: "mismatching email without verification", email: "other@testmail.com"},
+		{name: "absent email with verification", emailVerification: true},
+		{name: "absent email without verification"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]string{
+				AppleIdentityTokenFieldName:     generateAppleIDToken(10, keyGen.PrivateKey, true, 1, true),
+				AppleAuthorizationCodeFieldName: "auth_code",
+				AppleNonceFieldName:             testExpectedNonce,
+				AppleUserIDFieldName:            testSubject,
+			}
+			// Apple omits the email after the first authorization so the client does not send it
+			if tt.email != "" {
+				data[AppleEmailFieldName] = tt.email
+			}
+
+			p := NewAppleProvider(credentials, WithEmailVerification(tt.emailVerification))
+			res, err := p.Authenticate(ctx, data)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, res)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testSubject, res.GetID())
+		})
+	}
+}
+
 func generateAppleIDToken(secs int, privateKey *rsa.PrivateKey, isPrivateEmail bool, realUserStatus int, useNounce bool) string {
+	return generateAppleIDTokenFromClaims(privateKey, appleIDTokenTestClaims(secs, isPrivateEmail, realUserStatus, useNounce))
+}
+
+func appleIDTokenTestClaims(secs int, isPrivateEmail bool, realUserStatus int, useNounce bool) jwt.MapClaims {
 	// https://developer.apple.com/documentation/signinwithapple/authenticating-users-with-sign-in-with-apple#Retrieve-the-users-information-from-Apple-ID-servers
-	claims := jwt.MapClaims{
+	return jwt.MapClaims{
 		"iss":              testExpectedIssuer,
 		"sub":              testSubject,
 		"aud":              testExpectedAudience,
@@ -98,7 +382,9 @@ func generateAppleIDToken(secs int, privateKey *rsa.PrivateKey, isPrivateEmail b
 		"is_private_email": isPrivateEmail,
 		"real_user_status": realUserStatus,
 	}
+}
 
+func generateAppleIDTokenFromClaims(privateKey *rsa.PrivateKey, claims jwt.MapClaims) string {
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
 	token.Header["kid"] = testKeyID
 
@@ -110,6 +396,10 @@ func generateAppleIDToken(secs int, privateKey *rsa.PrivateKey, isPrivateEmail b
 }
 
 func appleAuthURIHandler(secs int, privateKey *rsa.PrivateKey, isPrivateEmail bool, realUserStatus int, useNounce bool) http.HandlerFunc {
+	return appleAuthURIHandlerWithIDToken(secs, generateAppleIDToken(secs, privateKey, isPrivateEmail, realUserStatus, useNounce))
+}
+
+func appleAuthURIHandlerWithIDToken(secs int, idToken string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/authCode" {
 			http.NotFound(w, r)
@@ -121,7 +411,7 @@ func appleAuthURIHandler(secs int, privateKey *rsa.PrivateKey, isPrivateEmail bo
 			TokenType:    "token_type",
 			ExpiresIn:    time.Now().Add(time.Duration(secs) * time.Second).Unix(),
 			RefreshToken: "refresh_token",
-			IDToken:      generateAppleIDToken(secs, privateKey, isPrivateEmail, realUserStatus, useNounce),
+			IDToken:      idToken,
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -0,0 +1,202 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers/certs"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/config"
+)
+
+// BuildOption configures BuildFactoryFromConfig, a ProviderOption is passed to every provider built
+type BuildOption interface {
+	applyBuild(o *buildOptions)
+}
+
+// buildOptions holds the settings of BuildFactoryFromConfig
+type buildOptions struct {
+	providerOpts []ProviderOption
+	// newCertsCache creates the public keys cache of a provider, nil keeps the default cache of each provider
+	newCertsCache func(providerType domain.ProviderType) certs.CacheManager
+	appleOpts     []AppleProviderOption
+	// secrets resolves the secret references of the credentials, nil rejects the references
+	secrets ports.SecretsProvider
+}
+
+// buildOption is an option only BuildFactoryFromConfig accepts
+type buildOption func(*buildOptions)
+
+func (opt buildOption) applyBuild(o *buildOptions)    { opt(o) }
+func (opt ProviderOption) applyBuild(o *buildOptions) { o.providerOpts = append(o.providerOpts, opt) }
+
+// Safeguard check to ensure the shared provider options are accepted by BuildFactoryFromConfig
+var _ BuildOption = ProviderOption(nil)
+
+// WithCertificatesCaches sets the function creating the public keys cache of each provider verifying signed tokens,
+// the providers never share a cache as the keys are only identified by their id
+func WithCertificatesCaches(newCache func(providerType domain.ProviderType) certs.CacheManager) BuildOption {
+	return buildOption(func(o *buildOptions) {
+		o.newCertsCache = newCache
+	})
+}
+
+// WithSecretsProvider sets the provider resolving the credentials holding a secret:// reference
+func WithSecretsProvider(secrets ports.SecretsProvider) BuildOption {
+	return buildOption(func(o *buildOptions) {
+		o.secrets = secrets
+	})
+}
+
+// WithAppleOptions sets the options only the Apple provider accepts, e.g. WithNonceStore
+func WithAppleOptions(opts ...AppleProviderOption) BuildOption {
+	return buildOption(func(o *buildOptions) {
+		o.appleOpts = append(o.appleOpts, opts...)
+	})
+}
+
+// verifierOptions returns the shared options followed by the public keys cache of the provider when one is created,
+// T is the option type of a provider verifying signed tokens
+func verifierOptions[T any](o buildOptions, providerType domain.ProviderType) []T {
+	opts := sharedOptions[T](o.providerOpts)
+	if o.newCertsCache == nil {
+		return opts
+	}
+	return append(opts, any(WithCertificatesCacheManager(o.newCertsCache(providerType))).(T))
+}
+
+// BuildFactoryFromConfig creates a factory with every provider configured in cfg,
+// providers without client ID or secret are skipped, configured ones must have valid credentials.
+// The secret:// references of the credentials are resolved with the secrets provider set by WithSecretsProvider.
+func BuildFactoryFromConfig(cfg *config.Config, opts ...BuildOption) (ports.AuthProviderFactory, error) {
+	factory := NewDefaultFactory()
+
+	var b buildOptions
+	for _, opt := range opts {
+		opt.applyBuild(&b)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretsResolveTimeout)
+	defer cancel()
+
+	if cfg.GuestEnabled {
+		if err := factory.Add(domain.ProviderTypeGuest, NewGuestProvider()); err != nil {
+			return nil, fmt.Errorf("failed to add guest provider: %w", err)
+		}
+	}
+
+	if cfg.Google.ClientID != "" && cfg.Google.ClientSecret != "" {
+		credentials := googleCredentialsFromConfig(cfg.Google)
+		if err := resolveSecrets(ctx, b.secrets, &credentials.ClientSecret, &credentials.PrivateKey); err != nil {
+			return nil, fmt.Errorf("invalid google provider configuration: %w", err)
+		}
+		if err := credentials.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid google provider configuration: %w", err)
+		}
+		if err := factory.Add(domain.ProviderTypeGoogle, NewGoogleProvider(credentials, verifierOptions[GoogleProviderOption](b, domain.ProviderTypeGoogle)...)); err != nil {
+			return nil, fmt.Errorf("failed to add google provider: %w", err)
+		}
+	}
+
+	if cfg.Apple.ClientID != "" && cfg.Apple.ClientSecret != "" {
+		credentials := appleCredentialsFromConfig(cfg.Apple)
+		if err := resolveSecrets(ctx, b.secrets, &credentials.ClientSecret); err != nil {
+			return nil, fmt.Errorf("invalid apple provider configuration: %w", err)
+		}
+		if err := credentials.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid apple provider configuration: %w", err)
+		}
+		appleOpts := append([]AppleProviderOption{WithEmailVerification(cfg.Apple.EmailVerification)}, verifierOptions[AppleProviderOption](b, domain.ProviderTypeApple)...)
+		appleOpts = append(appleOpts, b.appleOpts...)
+		if err := factory.Add(domain.ProviderTypeApple, NewAppleProvider(credentials, appleOpts...)); err != nil {
+			return nil, fmt.Errorf("failed to add apple provider: %w", err)
+		}
+	}
+
+	if cfg.Epic.ClientID != "" && cfg.Epic.ClientSecret != "" {
+		credentials := epicCredentialsFromConfig(cfg.Epic)
+		if err := resolveSecrets(ctx, b.secrets, &credentials.ClientSecret); err != nil {
+			return nil, fmt.Errorf("invalid epic provider configuration: %w", err)
+		}
+		if err := credentials.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid epic provider configuration: %w", err)
+		}
+		if err := factory.Add(domain.ProviderTypeEpic, NewEpicProvider(credentials, verifierOptions[EpicProviderOption](b, domain.ProviderTypeEpic)...)); err != nil {
+			return nil, fmt.Errorf("failed to add epic provider: %w", err)
+		}
+	}
+
+	if cfg.Twitch.ClientID != "" && cfg.Twitch.ClientSecret != "" {
+		credentials := twitchCredentialsFromConfig(cfg.Twitch)
+		if err := resolveSecrets(ctx, b.secrets, &credentials.ClientSecret); err != nil {
+			return nil, fmt.Errorf("invalid twitch provider configuration: %w", err)
+		}
+		if err := credentials.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid twitch provider configuration: %w", err)
+		}
+		if err := factory.Add(domain.ProviderTypeTwitch, NewTwitchProvider(credentials, sharedOptions[TwitchProviderOption](b.providerOpts)...)); err != nil {
+			return nil, fmt.Errorf("failed to add twitch provider: %w", err)
+		}
+	}
+
+	return factory, nil
+}
+
+// googleCredentialsFromConfig maps the configuration into credentials, the audience defaults to the client ID
+func googleCredentialsFromConfig(c config.GoogleProviderConfig) GoogleCredentials {
+	return GoogleCredentials{
+		ClientID:                 c.ClientID,
+		ClientSecret:             c.ClientSecret,
+		PrivateKey:               c.PrivateKey,
+		AuthURI:                  c.AuthURI,
+		CertsURL:                 c.CertsURL,
+		IDTokenExpectedIssuer:    c.ExpectedIssuer,
+		IDTokenExpectedAud:       c.ClientID,
+		IDTokenExpectedAudiences: c.ExpectedAudiences,
+	}
+}
+
+// appleCredentialsFromConfig maps the configuration into credentials, the audience defaults to the client ID
+func appleCredentialsFromConfig(c config.AppleProviderConfig) AppleCredentials {
+	audience := c.ExpectedAudience
+	if audience == "" {
+		audience = c.ClientID
+	}
+	return AppleCredentials{
+		ClientID:                c.ClientID,
+		ClientSecret:            c.ClientSecret,
+		TeamID:                  c.TeamID,
+		KeyID:                   c.KeyID,
+		CertsURL:                c.CertsURL,
+		AuthTokensURL:           c.AuthTokensURL,
+		IDTokenExpectedAudience: audience,
+		IDTokenExpectedIssuer:   c.ExpectedIssuer,
+	}
+}
+
+// epicCredentialsFromConfig maps the configuration into credentials, the audience defaults to the client ID
+func epicCredentialsFromConfig(c config.EpicProviderConfig) EpicCredentials {
+	audience := c.ExpectedAudience
+	if audience == "" {
+		audience = c.ClientID
+	}
+	return EpicCredentials{
+		ClientID:         c.ClientID,
+		ClientSecret:     c.ClientSecret,
+		TokenURL:         c.TokenURL,
+		JWKSURL:          c.JWKSURL,
+		ExpectedIssuer:   c.ExpectedIssuer,
+		ExpectedAudience: audience,
+	}
+}
+
+// twitchCredentialsFromConfig maps the configuration into credentials
+func twitchCredentialsFromConfig(c config.TwitchProviderConfig) TwitchCredentials {
+	return TwitchCredentials{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		TokenURL:     c.TokenURL,
+		ValidateURL:  c.ValidateURL,
+		RedirectURI:  c.RedirectURI,
+	}
+}
@@ -0,0 +1,204 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/nonce"
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers/certs"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildFactoryFromConfig_RegistersConfiguredProviders(t *testing.T) {
+	t.Setenv("SMPIDT_GUEST_ENABLED", "true")
+	t.Setenv("SMPIDT_GOOGLE_CLIENT_ID", "google_client_id")
+	t.Setenv("SMPIDT_GOOGLE_CLIENT_SECRET", "google_client_secret")
+	t.Setenv("SMPIDT_GOOGLE_EXPECTED_AUDIENCES", "android_client_id,ios_client_id")
+	// apple is missing the client secret so it must be skipped
+	t.Setenv("SMPIDT_APPLE_CLIENT_ID", "apple_client_id")
+	t.Setenv("SMPIDT_EPIC_CLIENT_ID", "epic_client_id")
+	t.Setenv("SMPIDT_EPIC_CLIENT_SECRET", "epic_client_secret")
+	t.Setenv("SMPIDT_TWITCH_CLIENT_ID", "twitch_client_id")
+	t.Setenv("SMPIDT_TWITCH_CLIENT_SECRET", "twitch_client_secret")
+	t.Setenv("SMPIDT_TWITCH_REDIRECT_URI", "https://game.example.com/auth/twitch")
+
+	cfg, err := config.NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, []string{"android_client_id", "ios_client_id"}, cfg.Google.ExpectedAudiences)
+
+	factory, err := BuildFactoryFromConfig(cfg)
+	require.NoError(t, err)
+
+	_, err = factory.Get(domain.ProviderTypeGuest)
+	require.NoError(t, err)
+
+	p, err := factory.Get(domain.ProviderTypeGoogle)
+	require.NoError(t, err)
+	creds := p.(*googleProvider).credentials
+	require.Equal(t, "google_client_id", creds.ClientID)
+	require.Equal(t, "google_client_id", creds.IDTokenExpectedAud)
+	require.Equal(t, "https://oauth2.googleapis.com/token", creds.AuthURI)
+
+	_, err = factory.Get(domain.ProviderTypeApple)
+	require.ErrorIs(t, err, domain.ErrProviderNotFound)
+
+	p, err = factory.Get(domain.ProviderTypeEpic)
+	require.NoError(t, err)
+	require.Equal(t, "epic_client_id", p.(*epicProvider).credentials.ExpectedAudience)
+
+	p, err = factory.Get(domain.ProviderTypeTwitch)
+	require.NoError(t, err)
+	twitchCreds := p.(*twitchProvider).credentials
+	require.Equal(t, "https://game.example.com/auth/twitch", twitchCreds.RedirectURI)
+	require.Equal(t, "https://id.twitch.tv/oauth2/token", twitchCreds.TokenURL)
+}
+
+func TestBuildFactoryFromConfig_TwitchRequiresRedirectURI(t *testing.T) {
+	t.Setenv("SMPIDT_TWITCH_CLIENT_ID", "twitch_client_id")
+	t.Setenv("SMPIDT_TWITCH_CLIENT_SECRET", "twitch_client_secret")
+
+	cfg, err := config.NewManager().Load()
+	require.NoError(t, err)
+
+	_, err = BuildFactoryFromConfig(cfg)
+	require.ErrorIs(t, err, ErrInvalidProviderCredentials)
+	require.ErrorContains(t, err, "invalid twitch provider configuration")
+	require.ErrorContains(t, err, "RedirectURI is required")
+}
+
+func TestBuildFactoryFromConfig_EmptyWhenNothingConfigured(t *testing.T) {
+	cfg, err := config.NewManager().Load()
+	require.NoError(t, err)
+
+	factory, err := BuildFactoryFromConfig(cfg)
+	require.NoError(t, err)
+
+	for _, pt := range []domain.ProviderType{domain.ProviderTypeGuest, domain.ProviderTypeGoogle, domain.ProviderTypeApple, domain.ProviderTypeEpic, domain.ProviderTypeTwitch} {
+		_, err = factory.Get(pt)
+		require.ErrorIs(t, err, domain.ErrProviderNotFound)
+	}
+}
+
+// fakeSecretsProvider resolves the references from a map
+type fakeSecretsProvider map[string]string
+
+func (f fakeSecretsProvider) Get(_ context.Context, ref string) (string, error) {
+	value, ok := f[ref]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", domain.ErrSecretNotFound, ref)
+	}
+	return value, nil
+}
+
+func TestBuildFactoryFromConfig_ResolvesSecretReferences(t *testing.T) {
+	t.Setenv("SMPIDT_GOOGLE_CLIENT_ID", "google_client_id")
+	t.Setenv("SMPIDT_GOOGLE_CLIENT_SECRET", "secret://prod/identity/google_client_secret")
+	t.Setenv("SMPIDT_EPIC_CLIENT_ID", "epic_client_id")
+	t.Setenv("SMPIDT_EPIC_CLIENT_SECRET", "epic_client_secret")
+
+	cfg, err := config.NewManager().Load()
+	require.NoError(t, err)
+
+	factory, err := BuildFactoryFromConfig(cfg, WithSecretsProvider(fakeSecretsProvider{
+		"prod/identity/google_client_secret": "resolved_google_secret",
+	}))
+	require.NoError(t, err)
+
+	p, err := factory.Get(domain.ProviderTypeGoogle)
+	require.NoError(t, err)
+	require.Equal(t, "resolved_google_secret", p.(*googleProvider).credentials.ClientSecret)
+
+	// plain values are kept as is
+	p, err = factory.Get(domain.ProviderTypeEpic)
+	require.NoError(t, err)
+	require.Equal(t, "epic_client_secret", p.(*epicProvider).credentials.ClientSecret)
+}
+
+func TestBuildFactoryFromConfig_SecretReferenceErrors(t *testing.T) {
+	t.Setenv("SMPIDT_EPIC_CLIENT_ID", "epic_client_id")
+	t.Setenv("SMPIDT_EPIC_CLIENT_SECRET", "secret://prod/identity/epic_client_secret")
+
+	cfg, err := config.NewManager().Load()
+	require.NoError(t, err)
+
+	t.Run("missing secret", func(t *testing.T) {
+		_, err := BuildFactoryFromConfig(cfg, WithSecretsProvider(fakeSecretsProvider{}))
+		require.ErrorIs(t, err, domain.ErrSecretNotFound)
+		require.ErrorContains(t, err, "invalid epic provider configuration")
+	})
+
+	t.Run("no secrets provider", func(t *testing.T) {
+		_, err := BuildFactoryFromConfig(cfg)
+		require.ErrorContains(t, err, "requires a secrets provider")
+	})
+}
+
+func TestBuildFactoryFromConfig_AppleEmailVerification(t *testing.T) {
+	t.Setenv("SMPIDT_APPLE_CLIENT_ID", "apple_client_id")
+	t.Setenv("SMPIDT_APPLE_CLIENT_SECRET", "apple_client_secret")
+
+	cfg, err := config.NewManager().Load()
+	require.NoError(t, err)
+	factory, err := BuildFactoryFromConfig(cfg)
+	require.NoError(t, err)
+	p, err := factory.Get(domain.ProviderTypeApple)
+	require.NoError(t, err)
+	require.False(t, p.(*appleProvider).emailVerification)
+
+	t.Setenv("SMPIDT_APPLE_EMAIL_VERIFICATION", "true")
+	cfg, err = config.NewManager().Load()
+	require.NoError(t, err)
+	factory, err = BuildFactoryFromConfig(cfg)
+	require.NoError(t, err)
+	p, err = factory.Get(domain.ProviderTypeApple)
+	require.NoError(t, err)
+	require.True(t, p.(*appleProvider).emailVerification)
+}
+
+func TestBuildFactoryFromConfig_AppleOptions(t *testing.T) {
+	t.Setenv("SMPIDT_APPLE_CLIENT_ID", "apple_client_id")
+	t.Setenv("SMPIDT_APPLE_CLIENT_SECRET", "apple_client_secret")
+
+	cfg, err := config.NewManager().Load()
+	require.NoError(t, err)
+	store := nonce.NewMemoryStore()
+	factory, err := BuildFactoryFromConfig(cfg, WithAppleOptions(WithNonceStore(store)))
+	require.NoError(t, err)
+	p, err := factory.Get(domain.ProviderTypeApple)
+	require.NoError(t, err)
+	require.Same(t, store, p.(*appleProvider).nonceStore)
+}
+
+func TestBuildFactoryFromConfig_CertificatesCachePerProvider(t *testing.T) {
+	t.Setenv("SMPIDT_GOOGLE_CLIENT_ID", "google_client_id")
+	t.Setenv("SMPIDT_GOOGLE_CLIENT_SECRET", "google_client_secret")
+	t.Setenv("SMPIDT_APPLE_CLIENT_ID", "apple_client_id")
+	t.Setenv("SMPIDT_APPLE_CLIENT_SECRET", "apple_client_secret")
+	t.Setenv("SMPIDT_EPIC_CLIENT_ID", "epic_client_id")
+	t.Setenv("SMPIDT_EPIC_CLIENT_SECRET", "epic_client_secret")
+
+	cfg, err := config.NewManager().Load()
+	require.NoError(t, err)
+	caches := make(map[domain.ProviderType]certs.CacheManager)
+	factory, err := BuildFactoryFromConfig(cfg, WithTimeout(time.Second), WithCertificatesCaches(func(providerType domain.ProviderType) certs.CacheManager {
+		caches[providerType] = certs.NewSimpleCacheManager()
+		return caches[providerType]
+	}))
+	require.NoError(t, err)
+	require.Len(t, caches, 3)
+
+	google, err := factory.Get(domain.ProviderTypeGoogle)
+	require.NoError(t, err)
+	require.Same(t, caches[domain.ProviderTypeGoogle], google.(*googleProvider).cacheManager)
+	require.Equal(t, time.Second, google.(*googleProvider).requestTimeout)
+	apple, err := factory.Get(domain.ProviderTypeApple)
+	require.NoError(t, err)
+	require.Same(t, caches[domain.ProviderTypeApple], apple.(*appleProvider).cacheManager)
+	epic, err := factory.Get(domain.ProviderTypeEpic)
+	require.NoError(t, err)
+	require.Same(t, caches[domain.ProviderTypeEpic], epic.(*epicProvider).cacheManager)
+}
@@ -2,56 +2,172 @@
 package certs
 
 import (
+	"container/list"
 	"crypto/rsa"
+	"sync"
 	"time"
 )
 
+const (
+	// DefaultMaxEntries is the default maximum number of keys kept in the cache
+	DefaultMaxEntries = 100
+	// DefaultMaxTTL is the default maximum time a key is kept regardless of the expiry advertised by the provider
+	DefaultMaxTTL = 24 * time.Hour
+)
+
 // CacheManager defines the interface of the cache manager for certificates
 type CacheManager interface {
 	Get(id string) *rsa.PublicKey
 	Add(id string, pub *rsa.PublicKey, expiresAt time.Time) error
 	Reset() error
+	// Len returns the current number of entries in the cache
+	Len() int
 }
 
 type cacheEntry struct {
+	id        string
 	pubKey    *rsa.PublicKey
-	expiresAt int64
+	expiresAt time.Time
+}
+
+// CacheOption configures the simple cache manager
+type CacheOption func(*simpleCacheManager)
+
+// WithMaxEntries bounds the number of entries, the least recently used entry is evicted when the bound is reached
+func WithMaxEntries(n int) CacheOption {
+	return func(cm *simpleCacheManager) {
+		cm.maxEntries = n
+	}
+}
+
+// WithMaxTTL caps the expiry of the entries to protect against far in the future expiry dates
+func WithMaxTTL(ttl time.Duration) CacheOption {
+	return func(cm *simpleCacheManager) {
+		cm.maxTTL = ttl
+	}
+}
+
+// WithClock sets the function returning the current time the entries expiry is checked against, defaults to time.Now
+func WithClock(now func() time.Time) CacheOption {
+	return func(cm *simpleCacheManager) {
+		cm.now = now
+	}
 }
 
 // SimpleCacheManager implements the CacheManager interface
 type simpleCacheManager struct {
-	cache map[string]cacheEntry
+	mutex      sync.Mutex
+	maxEntries int
+	maxTTL     time.Duration
+	// lru keeps the most recently used entries at the front
+	lru   *list.List
+	cache map[string]*list.Element
+	// onRemove is notified of the entries removed by expiry or eviction, the lock is held when called
+	onRemove func(reason removalReason)
+	// now returns the current time, tests replace it to control the expiry
+	now func() time.Time
 }
 
-func NewSimpleCacheManager() CacheManager {
-	return &simpleCacheManager{
-		cache: make(map[string]cacheEntry, 5),
+func NewSimpleCacheManager(opts ...CacheOption) CacheManager {
+	cm := &simpleCacheManager{
+		maxEntries: DefaultMaxEntries,
+		maxTTL:     DefaultMaxTTL,
+		lru:        list.New(),
+		cache:      make(map[string]*list.Element, 5),
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(cm)
 	}
+	return cm
 }
 
 func (cm *simpleCacheManager) Get(id string) *rsa.PublicKey {
-	e, ok := cm.cache[id]
-	if ok {
-		if time.Now().Unix() < e.expiresAt {
-			return e.pubKey
-		}
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	el, ok := cm.cache[id]
+	if !ok {
+		return nil
 	}
 
-	return nil
+	e := el.Value.(*cacheEntry)
+	if !cm.now().Before(e.expiresAt) {
+		cm.remove(el)
+		cm.notifyRemoval(removalExpired)
+		return nil
+	}
+
+	cm.lru.MoveToFront(el)
+	return e.pubKey
 }
 
 func (cm *simpleCacheManager) Add(id string, pub *rsa.PublicKey, expiresAt time.Time) error {
-	cm.cache[id] = cacheEntry{
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.maxTTL > 0 {
+		if maxExpiresAt := cm.now().Add(cm.maxTTL); expiresAt.After(maxExpiresAt) {
+			expiresAt = maxExpiresAt
+		}
+	}
+
+	entry := &cacheEntry{
+		id:        id,
 		pubKey:    pub,
-		expiresAt: expiresAt.UTC().Unix(),
+		expiresAt: expiresAt,
+	}
+
+	if el, ok := cm.cache[id]; ok {
+		el.Value = entry
+		cm.lru.MoveToFront(el)
+		return nil
+	}
+
+	cm.cache[id] = cm.lru.PushFront(entry)
+	if cm.maxEntries > 0 {
+		for cm.lru.Len() > cm.maxEntries {
+			cm.remove(cm.lru.Back())
+			cm.notifyRemoval(removalEvicted)
+		}
 	}
 	return nil
 }
 
 func (cm *simpleCacheManager) Reset() error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.lru.Init()
 	for k := range cm.cache {
 		delete(cm.cache, k)
 	}
 
 	return nil
 }
+
+func (cm *simpleCacheManager) Len() int {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	return cm.lru.Len()
+}
+
+// remove deletes the entry from both the lru list and the index, the lock must be held
+func (cm *simpleCacheManager) remove(el *list.Element) {
+	cm.lru.Remove(el)
+	delete(cm.cache, el.Value.(*cacheEntry).id)
+}
+
+// setRemovalObserver registers the function notified of the removed entries
+func (cm *simpleCacheManager) setRemovalObserver(fn func(reason removalReason)) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	cm.onRemove = fn
+}
+
+// notifyRemoval notifies the removal observer if any, the lock must be held
+func (cm *simpleCacheManager) notifyRemoval(reason removalReason) {
+	if cm.onRemove != nil {
+		cm.onRemove(reason)
+	}
+}
@@ -17,7 +17,7 @@ func genPubKey(t *testing.T) *rsa.PublicKey {
 
 func TestCache_SimpleCacheManager_Returns_ID(t *testing.T) {
 	cm := NewSimpleCacheManager()
-	err := cm.Add("good-pub-key", genPubKey(t), time.Now().Add(10+time.Second).UTC())
+	err := cm.Add("good-pub-key", genPubKey(t), time.Now().Add(10*time.Second).UTC())
 	require.Nil(t, err)
 	k := cm.Get("good-pub-key")
 	require.NotNil(t, k)
@@ -36,3 +36,91 @@ func TestCache_SimpleCacheManager_Returns_Nil_WhenEntryExpired(t *testing.T) {
 	k := cm.Get("good-pub-key")
 	require.Nil(t, k)
 }
+
+func TestCache_SimpleCacheManager_EvictsLeastRecentlyUsed_WhenMaxEntriesReached(t *testing.T) {
+	cm := NewSimpleCacheManager(WithMaxEntries(2))
+	expiresAt := time.Now().Add(10 * time.Second)
+
+	require.NoError(t, cm.Add("key-1", genPubKey(t), expiresAt))
+	require.NoError(t, cm.Add("key-2", genPubKey(t), expiresAt))
+	require.Equal(t, 2, cm.Len())
+
+	// touch key-1 so key-2 becomes the least recently used
+	require.NotNil(t, cm.Get("key-1"))
+
+	require.NoError(t, cm.Add("key-3", genPubKey(t), expiresAt))
+	require.Equal(t, 2, cm.Len())
+	require.NotNil(t, cm.Get("key-1"))
+	require.Nil(t, cm.Get("key-2"))
+	require.NotNil(t, cm.Get("key-3"))
+}
+
+func TestCache_SimpleCacheManager_CapsExpiry_WithMaxTTL(t *testing.T) {
+	cm := NewSimpleCacheManager(WithMaxTTL(time.Minute))
+
+	err := cm.Add("good-pub-key", genPubKey(t), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, cm.Get("good-pub-key"))
+
+	entry := cm.(*simpleCacheManager).cache["good-pub-key"].Value.(*cacheEntry)
+	require.False(t, entry.expiresAt.After(time.Now().Add(time.Minute)))
+}
+
+// testClock is a frozen clock the tests advance explicitly
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestCache_SimpleCacheManager_WithClock_ExpiresExactlyAtBoundary(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 500, time.UTC)}
+	cm := NewSimpleCacheManager(WithClock(clock.Now))
+	expiresAt := clock.now.Add(10 * time.Second)
+
+	require.NoError(t, cm.Add("good-pub-key", genPubKey(t), expiresAt))
+
+	clock.Advance(10*time.Second - time.Nanosecond)
+	require.NotNil(t, cm.Get("good-pub-key"))
+
+	clock.Advance(time.Nanosecond)
+	require.Equal(t, expiresAt, clock.now)
+	require.Nil(t, cm.Get("good-pub-key"))
+	require.Equal(t, 0, cm.Len())
+}
+
+func TestCache_SimpleCacheManager_WithClock_CapsExpiryWithMaxTTL(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	cm := NewSimpleCacheManager(WithClock(clock.Now), WithMaxTTL(time.Minute))
+
+	require.NoError(t, cm.Add("good-pub-key", genPubKey(t), clock.now.Add(time.Hour)))
+
+	clock.Advance(time.Minute - time.Nanosecond)
+	require.NotNil(t, cm.Get("good-pub-key"))
+
+	clock.Advance(time.Nanosecond)
+	require.Nil(t, cm.Get("good-pub-key"))
+}
+
+func TestCache_SimpleCacheManager_Len_ReflectsContents(t *testing.T) {
+	cm := NewSimpleCacheManager()
+	require.Equal(t, 0, cm.Len())
+
+	require.NoError(t, cm.Add("key-1", genPubKey(t), time.Now().Add(10*time.Second)))
+	require.NoError(t, cm.Add("key-1", genPubKey(t), time.Now().Add(10*time.Second)))
+	require.NoError(t, cm.Add("expired", genPubKey(t), time.Now().Add(-10*time.Second)))
+	require.Equal(t, 2, cm.Len())
+
+	// expired entries are evicted when looked up
+	require.Nil(t, cm.Get("expired"))
+	require.Equal(t, 1, cm.Len())
+
+	require.NoError(t, cm.Reset())
+	require.Equal(t, 0, cm.Len())
+}
@@ -0,0 +1,125 @@
+package certs
+
+import (
+	"context"
+	"crypto/rsa"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metric names recorded by the instrumented cache manager
+const (
+	CacheHitsMetricName        = "certs.cache.hits"
+	CacheMissesMetricName      = "certs.cache.misses"
+	CacheEvictionsMetricName   = "certs.cache.evictions"
+	CacheExpirationsMetricName = "certs.cache.expirations"
+	CacheSizeMetricName        = "certs.cache.size"
+
+	instrumentationScope = "github.com/posilva/simpleidentity/internal/adapters/output/providers/certs"
+)
+
+// removalReason tells why an entry was removed from the cache
+type removalReason int
+
+const (
+	removalExpired removalReason = iota
+	removalEvicted
+)
+
+// removalNotifier is implemented by the cache managers able to report the entries they remove
+type removalNotifier interface {
+	setRemovalObserver(fn func(reason removalReason))
+}
+
+// Safeguard check to ensure simpleCacheManager reports the removed entries
+var _ removalNotifier = (*simpleCacheManager)(nil)
+
+// instrumentedCacheManager decorates a CacheManager with hit, miss, eviction and expiry counters
+type instrumentedCacheManager struct {
+	inner  CacheManager
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+	// attrs tells apart the caches recorded on the same instruments, e.g. one per provider
+	attrs metric.MeasurementOption
+}
+
+// Safeguard check to ensure instrumentedCacheManager implements the CacheManager interface
+var _ CacheManager = (*instrumentedCacheManager)(nil)
+
+// NewInstrumentedCacheManager counts the hits and misses of Get and reports the number of entries as a gauge,
+// evictions and expirations are only counted when the inner cache reports them as the simple cache manager does.
+// The attributes are set on every measurement of the cache. A nil meter uses the global otel provider
+func NewInstrumentedCacheManager(inner CacheManager, meter metric.Meter, attrs ...attribute.KeyValue) CacheManager {
+	if meter == nil {
+		meter = otel.Meter(instrumentationScope)
+	}
+
+	// instruments are always usable even when creating them fails, so errors are only reported
+	counter := func(name string, description string) metric.Int64Counter {
+		c, err := meter.Int64Counter(name, metric.WithDescription(description), metric.WithUnit("{entry}"))
+		if err != nil {
+			otel.Handle(err)
+		}
+		return c
+	}
+
+	cm := &instrumentedCacheManager{
+		inner:  inner,
+		hits:   counter(CacheHitsMetricName, "Number of certificate cache lookups that found a key"),
+		misses: counter(CacheMissesMetricName, "Number of certificate cache lookups that did not find a key"),
+		attrs:  metric.WithAttributes(attrs...),
+	}
+	size, err := meter.Int64ObservableGauge(CacheSizeMetricName,
+		metric.WithDescription("Number of keys kept in the certificate cache"),
+		metric.WithUnit("{entry}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	// the callback is registered per cache so every cache reports its own size
+	if _, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(size, int64(inner.Len()), cm.attrs)
+		return nil
+	}, size); err != nil {
+		otel.Handle(err)
+	}
+
+	if notifier, ok := inner.(removalNotifier); ok {
+		evictions := counter(CacheEvictionsMetricName, "Number of certificate cache entries evicted to respect the max entries")
+		expirations := counter(CacheExpirationsMetricName, "Number of expired certificate cache entries removed")
+		notifier.setRemovalObserver(func(reason removalReason) {
+			switch reason {
+			case removalEvicted:
+				evictions.Add(context.Background(), 1, cm.attrs)
+			case removalExpired:
+				expirations.Add(context.Background(), 1, cm.attrs)
+			}
+		})
+	}
+	return cm
+}
+
+func (cm *instrumentedCacheManager) Get(id string) *rsa.PublicKey {
+	pub := cm.inner.Get(id)
+	if pub == nil {
+		cm.misses.Add(context.Background(), 1, cm.attrs)
+	} else {
+		cm.hits.Add(context.Background(), 1, cm.attrs)
+	}
+	return pub
+}
+
+func (cm *instrumentedCacheManager) Add(id string, pub *rsa.PublicKey, expiresAt time.Time) error {
+	return cm.inner.Add(id, pub, expiresAt)
+}
+
+func (cm *instrumentedCacheManager) Reset() error {
+	return cm.inner.Reset()
+}
+
+func (cm *instrumentedCacheManager) Len() int {
+	return cm.inner.Len()
+}
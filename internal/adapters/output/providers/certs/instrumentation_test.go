@@ -0,0 +1,145 @@
+package certs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/posilva/simpleidentity/pkg/telemetry"
+)
+
+// counterValues collects the counters and gauges by name from the reader
+func counterValues(t *testing.T, reader sdkmetric.Reader) map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	values := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					values[m.Name] += dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					values[m.Name] += dp.Value
+				}
+			default:
+				require.Failf(t, "unexpected metric type", "%s: %T", m.Name, m.Data)
+			}
+		}
+	}
+	return values
+}
+
+func TestInstrumentedCacheManager_CountsHitsAndMisses(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	cm := NewInstrumentedCacheManager(NewSimpleCacheManager(), meter)
+
+	require.Nil(t, cm.Get("kid"))
+	require.NoError(t, cm.Add("kid", genPubKey(t), time.Now().Add(time.Hour)))
+	require.NotNil(t, cm.Get("kid"))
+	require.NotNil(t, cm.Get("kid"))
+
+	values := counterValues(t, reader)
+	require.Equal(t, int64(2), values[CacheHitsMetricName])
+	require.Equal(t, int64(1), values[CacheMissesMetricName])
+	require.Zero(t, values[CacheEvictionsMetricName])
+	require.Zero(t, values[CacheExpirationsMetricName])
+}
+
+func TestInstrumentedCacheManager_CountsEvictionsAndExpirations(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	cm := NewInstrumentedCacheManager(NewSimpleCacheManager(WithMaxEntries(1)), meter)
+
+	require.NoError(t, cm.Add("expired", genPubKey(t), time.Now().Add(-time.Second)))
+	require.Nil(t, cm.Get("expired"))
+	require.NoError(t, cm.Add("first", genPubKey(t), time.Now().Add(time.Hour)))
+	require.NoError(t, cm.Add("second", genPubKey(t), time.Now().Add(time.Hour)))
+	require.Nil(t, cm.Get("first"))
+
+	values := counterValues(t, reader)
+	require.Equal(t, int64(1), values[CacheExpirationsMetricName])
+	require.Equal(t, int64(1), values[CacheEvictionsMetricName])
+	require.Equal(t, int64(2), values[CacheMissesMetricName])
+	require.Equal(t, 1, cm.Len())
+}
+
+func TestInstrumentedCacheManager_ReportsSize(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	cm := NewInstrumentedCacheManager(NewSimpleCacheManager(WithMaxEntries(2)), meter)
+
+	require.Equal(t, int64(0), counterValues(t, reader)[CacheSizeMetricName])
+	require.NoError(t, cm.Add("first", genPubKey(t), time.Now().Add(time.Hour)))
+	require.Equal(t, int64(1), counterValues(t, reader)[CacheSizeMetricName])
+	require.NoError(t, cm.Add("second", genPubKey(t), time.Now().Add(time.Hour)))
+	require.NoError(t, cm.Add("third", genPubKey(t), time.Now().Add(time.Hour)))
+	// the size stays at the bound once the least recently used key is evicted
+	require.Equal(t, int64(2), counterValues(t, reader)[CacheSizeMetricName])
+	require.NoError(t, cm.Reset())
+	require.Equal(t, int64(0), counterValues(t, reader)[CacheSizeMetricName])
+}
+
+func TestInstrumentedCacheManager_ExposedOnMetricsEndpoint(t *testing.T) {
+	provider, handler, err := telemetry.NewPrometheusMeterProvider("simpleidentity", "test")
+	require.NoError(t, err)
+	defer provider.Shutdown(context.Background())
+	cm := NewInstrumentedCacheManager(NewSimpleCacheManager(), provider.Meter(telemetry.MeterName))
+
+	require.Nil(t, cm.Get("kid"))
+	require.NoError(t, cm.Add("kid", genPubKey(t), time.Now().Add(time.Hour)))
+	require.NotNil(t, cm.Get("kid"))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "certs_cache_hits_total{")
+	require.Contains(t, rec.Body.String(), "certs_cache_misses_total{")
+}
+
+func TestInstrumentedCacheManager_TagsMeasurements(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	google := NewInstrumentedCacheManager(NewSimpleCacheManager(), meter, attribute.String("auth.provider", "google"))
+	apple := NewInstrumentedCacheManager(NewSimpleCacheManager(), meter, attribute.String("auth.provider", "apple"))
+
+	require.NoError(t, google.Add("kid", genPubKey(t), time.Now().Add(time.Hour)))
+	require.NotNil(t, google.Get("kid"))
+	require.Nil(t, apple.Get("kid"))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	values := make(map[string]int64)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		switch data := m.Data.(type) {
+		case metricdata.Sum[int64]:
+			for _, dp := range data.DataPoints {
+				provider, _ := dp.Attributes.Value("auth.provider")
+				values[m.Name+"/"+provider.AsString()] = dp.Value
+			}
+		case metricdata.Gauge[int64]:
+			for _, dp := range data.DataPoints {
+				provider, _ := dp.Attributes.Value("auth.provider")
+				values[m.Name+"/"+provider.AsString()] = dp.Value
+			}
+		}
+	}
+	require.Equal(t, map[string]int64{
+		CacheHitsMetricName + "/google":  1,
+		CacheMissesMetricName + "/apple": 1,
+		CacheSizeMetricName + "/google":  1,
+		CacheSizeMetricName + "/apple":   0,
+	}, values)
+}
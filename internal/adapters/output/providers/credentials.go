@@ -0,0 +1,42 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrInvalidProviderCredentials is returned when provider credentials are missing required fields or have malformed URLs
+var ErrInvalidProviderCredentials = errors.New("invalid provider credentials")
+
+// credentialField is a named credential value used to report which field is invalid
+type credentialField struct {
+	name  string
+	value string
+}
+
+// requireFields returns an error naming every empty field
+func requireFields(fields ...credentialField) error {
+	var errs []error
+	for _, f := range fields {
+		if f.value == "" {
+			errs = append(errs, fmt.Errorf("%w: %s is required", ErrInvalidProviderCredentials, f.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// requireURLs returns an error naming every field that is not an absolute http(s) URL, empty fields are skipped
+func requireURLs(fields ...credentialField) error {
+	var errs []error
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		u, err := url.Parse(f.value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%w: %s must be an absolute http(s) URL, got %q", ErrInvalidProviderCredentials, f.name, f.value))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/posilva/simpleidentity/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func validGoogleCredentials() GoogleCredentials {
+	return GoogleCredentials{
+		ClientID:              "google_client_id",
+		ClientSecret:          "google_client_secret",
+		AuthURI:               "https://oauth2.googleapis.com/token",
+		CertsURL:              "https://www.googleapis.com/oauth2/v1/certs",
+		IDTokenExpectedIssuer: "https://accounts.google.com",
+		IDTokenExpectedAud:    "google_client_id",
+	}
+}
+
+func validAppleCredentials() AppleCredentials {
+	return AppleCredentials{
+		ClientID:                "apple_client_id",
+		ClientSecret:            "apple_client_secret",
+		CertsURL:                "https://appleid.apple.com/auth/keys",
+		AuthTokensURL:           "https://appleid.apple.com/auth/token",
+		IDTokenExpectedAudience: "apple_client_id",
+		IDTokenExpectedIssuer:   "https://appleid.apple.com",
+	}
+}
+
+func TestGoogleCredentials_Validate(t *testing.T) {
+	require.NoError(t, validGoogleCredentials().Validate())
+
+	tests := []struct {
+		name    string
+		mutate  func(c *GoogleCredentials)
+		wantMsg string
+	}{
+		{"missing ClientID", func(c *GoogleCredentials) { c.ClientID = "" }, "ClientID is required"},
+		{"missing ClientSecret", func(c *GoogleCredentials) { c.ClientSecret = "" }, "ClientSecret is required"},
+		{"missing AuthURI", func(c *GoogleCredentials) { c.AuthURI = "" }, "AuthURI is required"},
+		{"missing CertsURL", func(c *GoogleCredentials) { c.CertsURL = "" }, "CertsURL is required"},
+		{"missing IDTokenExpectedIssuer", func(c *GoogleCredentials) { c.IDTokenExpectedIssuer = "" }, "IDTokenExpectedIssuer is required"},
+		{"missing audiences", func(c *GoogleCredentials) { c.IDTokenExpectedAud = "" }, "IDTokenExpectedAud or IDTokenExpectedAudiences is required"},
+		{"invalid AuthURI", func(c *GoogleCredentials) { c.AuthURI = "oauth2.googleapis.com/token" }, "AuthURI must be an absolute http(s) URL"},
+		{"invalid CertsURL", func(c *GoogleCredentials) { c.CertsURL = "ftp://www.googleapis.com" }, "CertsURL must be an absolute http(s) URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validGoogleCredentials()
+			tt.mutate(&c)
+			err := c.Validate()
+			require.ErrorIs(t, err, ErrInvalidProviderCredentials)
+			require.ErrorContains(t, err, tt.wantMsg)
+		})
+	}
+
+	t.Run("multiple audiences only", func(t *testing.T) {
+		c := validGoogleCredentials()
+		c.IDTokenExpectedAud = ""
+		c.IDTokenExpectedAudiences = []string{"android_client_id"}
+		require.NoError(t, c.Validate())
+	})
+}
+
+func TestAppleCredentials_Validate(t *testing.T) {
+	require.NoError(t, validAppleCredentials().Validate())
+
+	tests := []struct {
+		name    string
+		mutate  func(c *AppleCredentials)
+		wantMsg string
+	}{
+		{"missing ClientID", func(c *AppleCredentials) { c.ClientID = "" }, "ClientID is required"},
+		{"missing ClientSecret", func(c *AppleCredentials) { c.ClientSecret = "" }, "ClientSecret is required"},
+		{"missing CertsURL", func(c *AppleCredentials) { c.CertsURL = "" }, "CertsURL is required"},
+		{"missing AuthTokensURL", func(c *AppleCredentials) { c.AuthTokensURL = "" }, "AuthTokensURL is required"},
+		{"missing IDTokenExpectedAudience", func(c *AppleCredentials) { c.IDTokenExpectedAudience = "" }, "IDTokenExpectedAudience is required"},
+		{"missing IDTokenExpectedIssuer", func(c *AppleCredentials) { c.IDTokenExpectedIssuer = "" }, "IDTokenExpectedIssuer is required"},
+		{"invalid CertsURL", func(c *AppleCredentials) { c.CertsURL = "://appleid.apple.com" }, "CertsURL must be an absolute http(s) URL"},
+		{"invalid AuthTokensURL", func(c *AppleCredentials) { c.AuthTokensURL = "/auth/token" }, "AuthTokensURL must be an absolute http(s) URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validAppleCredentials()
+			tt.mutate(&c)
+			err := c.Validate()
+			require.ErrorIs(t, err, ErrInvalidProviderCredentials)
+			require.ErrorContains(t, err, tt.wantMsg)
+		})
+	}
+}
+
+func TestBuildFactoryFromConfig_FailsOnInvalidCredentials(t *testing.T) {
+	cfg := &config.Config{
+		Apple: config.AppleProviderConfig{
+			ClientID:     "apple_client_id",
+			ClientSecret: "apple_client_secret",
+		},
+	}
+
+	_, err := BuildFactoryFromConfig(cfg)
+	require.ErrorIs(t, err, ErrInvalidProviderCredentials)
+	require.ErrorContains(t, err, "invalid apple provider configuration")
+}
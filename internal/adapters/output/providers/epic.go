@@ -0,0 +1,205 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// References:
+// https://dev.epicgames.com/docs/web-api-ref/authentication
+// https://dev.epicgames.com/docs/epic-account-services/auth/auth-interface
+
+const (
+	EpicAuthCodeFieldName = "code"
+)
+
+// EpicCredentials holds the Epic Online Services client credentials and endpoints
+type EpicCredentials struct {
+	ClientID         string
+	ClientSecret     string
+	TokenURL         string
+	JWKSURL          string
+	ExpectedIssuer   string
+	ExpectedAudience string
+}
+
+// Validate checks the required credentials are set and the URLs are valid
+func (c EpicCredentials) Validate() error {
+	return errors.Join(
+		requireFields(
+			credentialField{"ClientID", c.ClientID},
+			credentialField{"ClientSecret", c.ClientSecret},
+			credentialField{"TokenURL", c.TokenURL},
+			credentialField{"JWKSURL", c.JWKSURL},
+			credentialField{"ExpectedIssuer", c.ExpectedIssuer},
+			credentialField{"ExpectedAudience", c.ExpectedAudience},
+		),
+		requireURLs(
+			credentialField{"TokenURL", c.TokenURL},
+			credentialField{"JWKSURL", c.JWKSURL},
+		),
+	)
+}
+
+type epicIDTokenClaims struct {
+	Issuer            string `json:"iss"`
+	Subject           string `json:"sub"`
+	Audience          string `json:"aud"`
+	PreferredUsername string `json:"preferred_username"`
+	// exp is not shadowed so the embedded registered claims validate the expiry
+	jwt.RegisteredClaims
+}
+
+type epicProvider struct {
+	providerOptions
+	credentials EpicCredentials
+}
+
+// Safeguard check to ensure epicProvider implements the AuthProvider interface
+var _ ports.AuthProvider = (*epicProvider)(nil)
+
+type epicAuthResult struct {
+	ID      string
+	Profile domain.ProviderProfile
+}
+
+// Safeguard check to ensure epicAuthResult implements the AuthResult interface
+var _ ports.AuthResult = (*epicAuthResult)(nil)
+
+func (r *epicAuthResult) GetID() string {
+	return r.ID
+}
+
+func (r *epicAuthResult) GetProfile() domain.ProviderProfile {
+	return r.Profile
+}
+
+// NewEpicProvider creates a new Epic Online Services provider
+func NewEpicProvider(credentials EpicCredentials, opts ...EpicProviderOption) ports.AuthProvider {
+	return &epicProvider{
+		providerOptions: newProviderOptions(opts),
+		credentials:     credentials,
+	}
+}
+
+// RequiredFields returns the authorization code field
+func (p *epicProvider) RequiredFields() []string {
+	return []string{EpicAuthCodeFieldName}
+}
+
+// Authenticate exchanges the authorization code and verifies the returned ID token, the Epic account id is the result ID
+func (p *epicProvider) Authenticate(ctx context.Context, data map[string]string) (ports.AuthResult, error) {
+	code, ok := data[EpicAuthCodeFieldName]
+	if !ok {
+		return nil, fmt.Errorf("missing required field %s: %w", EpicAuthCodeFieldName, domain.ErrMissingRequiredProviderAuthData)
+	}
+
+	resp, err := p.exchangeAuthCode(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange auth code: %w", err)
+	}
+
+	claims, err := p.verifyIDToken(ctx, resp.IDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id token: %w", err)
+	}
+
+	return &epicAuthResult{
+		ID:      claims.Subject,
+		Profile: domain.ProviderProfile{DisplayName: claims.PreferredUsername},
+	}, nil
+}
+
+// exchangeAuthCode calls the token endpoint, Epic expects the client credentials as HTTP basic auth
+func (p *epicProvider) exchangeAuthCode(ctx context.Context, code string) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Add("grant_type", "authorization_code")
+	form.Add("code", code)
+	form.Add("scope", "openid")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.credentials.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.credentials.ClientID, p.credentials.ClientSecret)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post to token endpoint: %w: %w", domain.ErrProviderUnavailable, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		var body bytes.Buffer
+		_, _ = body.ReadFrom(resp.Body)
+		return nil, fmt.Errorf("token exchange failed: %s: %w", body.String(), statusCodeError(resp.StatusCode))
+	}
+
+	var tokenResp tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	return &tokenResp, nil
+}
+
+func (p *epicProvider) verifyIDToken(ctx context.Context, idToken string) (*epicIDTokenClaims, error) {
+	token, err := jwt.ParseWithClaims(idToken, &epicIDTokenClaims{}, func(token *jwt.Token) (any, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, fmt.Errorf("no kid found in token header: %w", domain.ErrTokenMalformed)
+		}
+
+		pubKey, err := p.fetchPublicKeyByID(ctx, kid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get public keys: %w", err)
+		}
+		return pubKey, nil
+	}, jwt.WithLeeway(p.clockSkew), jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	if err != nil {
+		return nil, fmt.Errorf("token parse error: %w", classifyTokenError(err))
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token: %w", domain.ErrTokenInvalidClaims)
+	}
+
+	claims, ok := token.Claims.(*epicIDTokenClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid claims format: %w", domain.ErrTokenInvalidClaims)
+	}
+
+	if claims.Issuer != p.credentials.ExpectedIssuer {
+		return nil, fmt.Errorf("invalid issuer: %w", domain.ErrTokenInvalidIssuer)
+	}
+	if claims.Audience != p.credentials.ExpectedAudience {
+		return nil, fmt.Errorf("invalid audience: %w", domain.ErrTokenInvalidAudience)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("missing subject: %w", domain.ErrTokenInvalidClaims)
+	}
+	return claims, nil
+}
+
+func (p *epicProvider) fetchPublicKeyByID(ctx context.Context, id string) (*rsa.PublicKey, error) {
+	return fetchJWKSPublicKeyByID(ctx, p.httpClient, p.cacheManager, p.credentials.JWKSURL, id)
+}
+
+// loadSigningKeys fetches every Epic Online Services public key into the cache and returns when they expire
+func (p *epicProvider) loadSigningKeys(ctx context.Context) (time.Time, error) {
+	return loadJWKSPublicKeys(ctx, p.httpClient, p.cacheManager, p.credentials.JWKSURL)
+}
@@ -0,0 +1,226 @@
+package providers
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	testEpicClientID     = "epic_client_id"
+	testEpicClientSecret = "epic_client_secret"
+	testEpicUsername     = "epic_player"
+)
+
+// epicTestServer serves the token and JWKS endpoints, the published keys can be rotated
+type epicTestServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	keys     map[string]*rsa.PublicKey
+	idToken  string
+	jwksHits atomic.Int32
+}
+
+func newEpicTestServer(t *testing.T) *epicTestServer {
+	t.Helper()
+	s := &epicTestServer{keys: map[string]*rsa.PublicKey{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != testEpicClientID || clientSecret != testEpicClientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.mutex.Lock()
+		idToken := s.idToken
+		s.mutex.Unlock()
+		_ = json.NewEncoder(w).Encode(tokenResponse{AccessToken: "access_token", IDToken: idToken})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		s.jwksHits.Add(1)
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		keys := make([]jose.JSONWebKey, 0, len(s.keys))
+		for kid, key := range s.keys {
+			keys = append(keys, jose.JSONWebKey{Key: key, KeyID: kid, Use: "sig", Algorithm: string(jose.RS256)})
+		}
+		_ = json.NewEncoder(w).Encode(map[string][]jose.JSONWebKey{"keys": keys})
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// publish replaces the published keys with the given one
+func (s *epicTestServer) publish(kid string, key *rsa.PublicKey) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys = map[string]*rsa.PublicKey{kid: key}
+}
+
+func (s *epicTestServer) issue(idToken string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.idToken = idToken
+}
+
+func (s *epicTestServer) credentials() EpicCredentials {
+	return EpicCredentials{
+		ClientID:         testEpicClientID,
+		ClientSecret:     testEpicClientSecret,
+		TokenURL:         s.URL + "/token",
+		JWKSURL:          s.URL + "/jwks",
+		ExpectedIssuer:   testExpectedIssuer,
+		ExpectedAudience: testExpectedAudience,
+	}
+}
+
+func epicIDTokenTestClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                testExpectedIssuer,
+		"sub":                testSubject,
+		"aud":                testExpectedAudience,
+		"preferred_username": testEpicUsername,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func generateEpicIDToken(t *testing.T, kid string, privateKey *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(privateKey)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestProviderEpic_Returns_EpicAuthResult(t *testing.T) {
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+	ts := newEpicTestServer(t)
+	ts.publish(testKeyID, keyGen.PublicKey)
+	ts.issue(generateEpicIDToken(t, testKeyID, keyGen.PrivateKey, epicIDTokenTestClaims()))
+
+	p := NewEpicProvider(ts.credentials())
+	res, err := p.Authenticate(context.Background(), map[string]string{EpicAuthCodeFieldName: "auth_code"})
+	require.NoError(t, err)
+	require.Equal(t, testSubject, res.GetID())
+	require.Equal(t, testEpicUsername, res.GetProfile().DisplayName)
+
+	_, err = p.Authenticate(context.Background(), map[string]string{})
+	require.ErrorIs(t, err, domain.ErrMissingRequiredProviderAuthData)
+}
+
+func TestProviderEpic_Returns_TypedErrors(t *testing.T) {
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+	otherKeyGen := TestKeyPairGenerator{}
+	otherKeyGen.GenerateRSAKeys()
+
+	withClaim := func(name string, value any) jwt.MapClaims {
+		claims := epicIDTokenTestClaims()
+		claims[name] = value
+		return claims
+	}
+
+	tests := []struct {
+		name        string
+		idToken     string
+		expectedErr error
+	}{
+		{
+			name:        "invalid signature",
+			idToken:     generateEpicIDToken(t, testKeyID, otherKeyGen.PrivateKey, epicIDTokenTestClaims()),
+			expectedErr: domain.ErrTokenInvalidSignature,
+		},
+		{
+			name:        "unexpected issuer",
+			idToken:     generateEpicIDToken(t, testKeyID, keyGen.PrivateKey, withClaim("iss", "unexpected_issuer")),
+			expectedErr: domain.ErrTokenInvalidIssuer,
+		},
+		{
+			name:        "unexpected audience",
+			idToken:     generateEpicIDToken(t, testKeyID, keyGen.PrivateKey, withClaim("aud", "unexpected_audience")),
+			expectedErr: domain.ErrTokenInvalidAudience,
+		},
+		{
+			name:        "expired token",
+			idToken:     generateEpicIDToken(t, testKeyID, keyGen.PrivateKey, withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+			expectedErr: domain.ErrTokenExpired,
+		},
+		{
+			name:        "malformed token",
+			idToken:     "not-a-jwt",
+			expectedErr: domain.ErrTokenMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newEpicTestServer(t)
+			ts.publish(testKeyID, keyGen.PublicKey)
+			ts.issue(tt.idToken)
+
+			p := NewEpicProvider(ts.credentials())
+			res, err := p.Authenticate(context.Background(), map[string]string{EpicAuthCodeFieldName: "auth_code"})
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Nil(t, res)
+		})
+	}
+}
+
+func TestProviderEpic_RefetchesKeysOnRotation(t *testing.T) {
+	oldKey := TestKeyPairGenerator{}
+	oldKey.GenerateRSAKeys()
+	newKey := TestKeyPairGenerator{}
+	newKey.GenerateRSAKeys()
+
+	ts := newEpicTestServer(t)
+	ts.publish("old_kid", oldKey.PublicKey)
+	p := NewEpicProvider(ts.credentials())
+	data := map[string]string{EpicAuthCodeFieldName: "auth_code"}
+
+	ts.issue(generateEpicIDToken(t, "old_kid", oldKey.PrivateKey, epicIDTokenTestClaims()))
+	_, err := p.Authenticate(context.Background(), data)
+	require.NoError(t, err)
+	_, err = p.Authenticate(context.Background(), data)
+	require.NoError(t, err)
+	// the second login is served from the cache
+	require.Equal(t, int32(1), ts.jwksHits.Load())
+
+	// Epic rotates its signing key, the unknown kid triggers a new fetch
+	ts.publish("new_kid", newKey.PublicKey)
+	ts.issue(generateEpicIDToken(t, "new_kid", newKey.PrivateKey, epicIDTokenTestClaims()))
+	res, err := p.Authenticate(context.Background(), data)
+	require.NoError(t, err)
+	require.Equal(t, testSubject, res.GetID())
+	require.Equal(t, int32(2), ts.jwksHits.Load())
+}
+
+func TestEpicCredentials_Validate(t *testing.T) {
+	require.NoError(t, EpicCredentials{
+		ClientID:         testEpicClientID,
+		ClientSecret:     testEpicClientSecret,
+		TokenURL:         "https://api.epicgames.dev/epic/oauth/v2/token",
+		JWKSURL:          "https://api.epicgames.dev/epic/oauth/v2/.well-known/jwks.json",
+		ExpectedIssuer:   "https://api.epicgames.dev/epic/oauth/v2",
+		ExpectedAudience: testEpicClientID,
+	}.Validate())
+
+	err := EpicCredentials{ClientID: testEpicClientID, JWKSURL: "not-a-url"}.Validate()
+	require.ErrorIs(t, err, ErrInvalidProviderCredentials)
+	require.ErrorContains(t, err, "ClientSecret is required")
+	require.ErrorContains(t, err, "JWKSURL must be an absolute http(s) URL")
+}
@@ -0,0 +1,37 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// classifyTokenError wraps a jwt parsing error with the matching domain sentinel error
+func classifyTokenError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return fmt.Errorf("%w: %w", domain.ErrTokenExpired, err)
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return fmt.Errorf("%w: %w", domain.ErrTokenNotYetValid, err)
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return fmt.Errorf("%w: %w", domain.ErrTokenInvalidSignature, err)
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return fmt.Errorf("%w: %w", domain.ErrTokenMalformed, err)
+	case errors.Is(err, domain.ErrProviderUnavailable):
+		return err
+	default:
+		return fmt.Errorf("%w: %w", domain.ErrTokenInvalidClaims, err)
+	}
+}
+
+// statusCodeError maps a failed provider response status code to a domain error,
+// server side failures mean the provider is unavailable while client side ones mean the code was rejected
+func statusCodeError(statusCode int) error {
+	if statusCode >= http.StatusInternalServerError || statusCode == http.StatusTooManyRequests {
+		return domain.ErrProviderUnavailable
+	}
+	return domain.ErrTokenInvalidClaims
+}
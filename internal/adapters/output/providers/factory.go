@@ -2,14 +2,21 @@
 package providers
 
 import (
+	"slices"
+	"sync"
+
 	"github.com/posilva/simpleidentity/internal/core/domain"
 	"github.com/posilva/simpleidentity/internal/core/ports"
 )
 
 type defaultFactory struct {
+	mu       sync.RWMutex
 	registry map[domain.ProviderType]ports.AuthProvider
 }
 
+// Safeguard check to ensure defaultFactory implements the AuthProviderFactory interface
+var _ ports.AuthProviderFactory = (*defaultFactory)(nil)
+
 func NewDefaultFactory() ports.AuthProviderFactory {
 	return &defaultFactory{
 		registry: make(map[domain.ProviderType]ports.AuthProvider),
@@ -17,11 +24,15 @@ func NewDefaultFactory() ports.AuthProviderFactory {
 }
 
 func (d *defaultFactory) Add(providerType domain.ProviderType, provider ports.AuthProvider) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.registry[providerType] = provider
 	return nil
 }
 
 func (d *defaultFactory) Get(providerType domain.ProviderType) (ports.AuthProvider, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
 	if provider, exists := d.registry[providerType]; exists {
 		return provider, nil
 	}
@@ -29,6 +40,31 @@ func (d *defaultFactory) Get(providerType domain.ProviderType) (ports.AuthProvid
 }
 
 func (d *defaultFactory) Remove(providerType domain.ProviderType) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	delete(d.registry, providerType)
 	return nil
 }
+
+// Replace swaps a registered provider atomically, the requests holding the previous instance complete with it
+func (d *defaultFactory) Replace(providerType domain.ProviderType, provider ports.AuthProvider) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, exists := d.registry[providerType]; !exists {
+		return domain.ErrProviderNotFound
+	}
+	d.registry[providerType] = provider
+	return nil
+}
+
+// List returns the registered provider types sorted alphabetically
+func (d *defaultFactory) List() []domain.ProviderType {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	types := make([]domain.ProviderType, 0, len(d.registry))
+	for providerType := range d.registry {
+		types = append(types, providerType)
+	}
+	slices.Sort(types)
+	return types
+}
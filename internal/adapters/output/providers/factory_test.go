@@ -37,3 +37,36 @@ func TestProviderFactory_AddGetAndRemove_ReturnsProvider(t *testing.T) {
 	require.NotNil(t, err, "expected an error when provider is not found")
 	require.ErrorIs(t, err, domain.ErrProviderNotFound, "expected ErrProviderNotFound error")
 }
+
+func TestProviderFactory_List_ReflectsAdditionsAndRemovals(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	authProviderMock := mock.Mock[ports.AuthProvider](ctrl)
+
+	factory := NewDefaultFactory()
+	require.Empty(t, factory.List())
+
+	require.NoError(t, factory.Add(domain.ProviderTypeGuest, authProviderMock))
+	require.NoError(t, factory.Add(domain.ProviderTypeApple, authProviderMock))
+	require.NoError(t, factory.Add(domain.ProviderTypeGoogle, authProviderMock))
+	require.Equal(t, []domain.ProviderType{domain.ProviderTypeApple, domain.ProviderTypeGoogle, domain.ProviderTypeGuest}, factory.List())
+
+	require.NoError(t, factory.Remove(domain.ProviderTypeGoogle))
+	require.Equal(t, []domain.ProviderType{domain.ProviderTypeApple, domain.ProviderTypeGuest}, factory.List())
+}
+
+func TestProviderFactory_Replace_SwapsRegisteredProvider(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	previous := mock.Mock[ports.AuthProvider](ctrl)
+	next := mock.Mock[ports.AuthProvider](ctrl)
+
+	factory := NewDefaultFactory()
+	require.ErrorIs(t, factory.Replace(domain.ProviderTypeGuest, next), domain.ErrProviderNotFound)
+	require.Empty(t, factory.List())
+
+	require.NoError(t, factory.Add(domain.ProviderTypeGuest, previous))
+	require.NoError(t, factory.Replace(domain.ProviderTypeGuest, next))
+
+	provider, err := factory.Get(domain.ProviderTypeGuest)
+	require.NoError(t, err)
+	require.Equal(t, next, provider)
+}
@@ -9,10 +9,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/posilva/simpleidentity/internal/adapters/output/providers/certs"
 	"github.com/posilva/simpleidentity/internal/core/domain"
 	"github.com/posilva/simpleidentity/internal/core/ports"
 )
@@ -37,8 +37,14 @@ type googleIDTokenClaims struct {
 	Issuer   string `json:"iss"`
 	Subject  string `json:"sub"`
 	Audience string `json:"aud"`
-	Email    string `json:"email"`
-	Expiry   int64  `json:"exp"`
+	// AuthorizedParty is the client ID of the presenter of the token when it differs from the audience
+	AuthorizedParty string `json:"azp"`
+	Email           string `json:"email"`
+	EmailVerified   bool   `json:"email_verified"`
+	Name            string `json:"name"`
+	Picture         string `json:"picture"`
+	Locale          string `json:"locale"`
+	// exp is not shadowed so the embedded registered claims validate the expiry
 	jwt.RegisteredClaims
 }
 
@@ -60,48 +66,90 @@ type GoogleCredentials struct {
 	CertsURL              string
 	IDTokenExpectedIssuer string
 	IDTokenExpectedAud    string
+	// IDTokenExpectedAudiences allows multiple client IDs (e.g. Android, iOS and web) to mint tokens
+	// for the same backend, it is used along with IDTokenExpectedAud
+	IDTokenExpectedAudiences []string
+}
+
+// Validate checks the required credentials are set and the URLs are valid
+func (c GoogleCredentials) Validate() error {
+	errs := []error{
+		requireFields(
+			credentialField{"ClientID", c.ClientID},
+			credentialField{"ClientSecret", c.ClientSecret},
+			credentialField{"AuthURI", c.AuthURI},
+			credentialField{"CertsURL", c.CertsURL},
+			credentialField{"IDTokenExpectedIssuer", c.IDTokenExpectedIssuer},
+		),
+		requireURLs(
+			credentialField{"AuthURI", c.AuthURI},
+			credentialField{"CertsURL", c.CertsURL},
+		),
+	}
+	if len(c.allowedAudiences()) == 0 {
+		errs = append(errs, fmt.Errorf("%w: IDTokenExpectedAud or IDTokenExpectedAudiences is required", ErrInvalidProviderCredentials))
+	}
+	return errors.Join(errs...)
+}
+
+// allowedAudiences returns the set of accepted audiences from both single and multiple audience fields
+func (c GoogleCredentials) allowedAudiences() map[string]struct{} {
+	allowed := make(map[string]struct{}, len(c.IDTokenExpectedAudiences)+1)
+	if c.IDTokenExpectedAud != "" {
+		allowed[c.IDTokenExpectedAud] = struct{}{}
+	}
+	for _, aud := range c.IDTokenExpectedAudiences {
+		allowed[aud] = struct{}{}
+	}
+	return allowed
 }
 
 type googleProvider struct {
-	requestTimeout time.Duration
-	credentials    GoogleCredentials
-	cacheManager   certs.CacheManager
+	providerOptions
+	credentials GoogleCredentials
 }
 
+// Safeguard check to ensure googleProvider implements the AuthProvider interface
+var _ ports.AuthProvider = (*googleProvider)(nil)
+
 type googleAuthResult struct {
-	ID string
+	ID            string
+	Profile       domain.ProviderProfile
+	Email         string
+	EmailVerified bool
 }
 
-type GoogleProviderOption func(*googleProvider)
+// Safeguard check to ensure googleAuthResult implements the AuthResultWithEmail interface
+var _ ports.AuthResultWithEmail = (*googleAuthResult)(nil)
 
-func WithTimeout(timeout time.Duration) GoogleProviderOption {
-	return func(p *googleProvider) {
-		p.requestTimeout = timeout
-	}
+func (r *googleAuthResult) GetID() string {
+	return r.ID
 }
 
-func WithCertificatesCacheManager(cm certs.CacheManager) GoogleProviderOption {
-	return func(p *googleProvider) {
-		p.cacheManager = cm
-	}
+func (r *googleAuthResult) GetProfile() domain.ProviderProfile {
+	return r.Profile
 }
 
-func (r *googleAuthResult) GetID() string {
-	return r.ID
+func (r *googleAuthResult) GetEmail() string {
+	return r.Email
+}
+
+func (r *googleAuthResult) GetEmailVerified() bool {
+	return r.EmailVerified
 }
 
 // NewGoogleProvider creates a new GoogleProvider
 // serviceAccount is a placeholder for the Google service account credentials in json format.
 func NewGoogleProvider(credentials GoogleCredentials, opts ...GoogleProviderOption) ports.AuthProvider {
-	svc := &googleProvider{
-		requestTimeout: defaultTimeout,
-		credentials:    credentials,
-		cacheManager:   certs.NewSimpleCacheManager(),
+	return &googleProvider{
+		providerOptions: newProviderOptions(opts),
+		credentials:     credentials,
 	}
-	for _, opt := range opts {
-		opt(svc)
-	}
-	return svc
+}
+
+// RequiredFields returns the server auth code field
+func (p *googleProvider) RequiredFields() []string {
+	return []string{GoogleAuthCodeFieldName}
 }
 
 // Authenticate executes authentication with Google and returns an authresult.
@@ -110,20 +158,33 @@ func (p *googleProvider) Authenticate(ctx context.Context, data map[string]strin
 	if !ok {
 		return nil, domain.ErrMissingRequiredProviderAuthData
 	}
-	resp, err := p.exchangeAuthCode(authToken)
+	resp, err := p.exchangeAuthCode(ctx, authToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange auth code: %w", err)
 	}
 
-	claims, err := p.verifyIDToken(resp.IDToken)
+	if err := validateScopes(resp.Scope, p.requiredScopes); err != nil {
+		return nil, err
+	}
+
+	claims, err := p.verifyIDToken(ctx, resp.IDToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify id token: %w", err)
 	}
 
-	return &googleAuthResult{ID: claims.Subject}, nil
+	return &googleAuthResult{
+		ID: claims.Subject,
+		Profile: domain.ProviderProfile{
+			DisplayName: claims.Name,
+			AvatarURL:   claims.Picture,
+			Locale:      claims.Locale,
+		},
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+	}, nil
 }
 
-func (p *googleProvider) exchangeAuthCode(authCode string) (*tokenResponse, error) {
+func (p *googleProvider) exchangeAuthCode(ctx context.Context, authCode string) (*tokenResponse, error) {
 	form := url.Values{}
 	form.Add("code", authCode)
 	form.Add("client_id", p.credentials.ClientID)
@@ -131,10 +192,9 @@ func (p *googleProvider) exchangeAuthCode(authCode string) (*tokenResponse, erro
 	form.Add("redirect_uri", "") // this is mobile we can keep empty
 	form.Add("grant_type", "authorization_code")
 
-	// TODO: PMS replace by a more robust http client setup using the configured timeout
-	resp, err := http.PostForm(p.credentials.AuthURI, form)
+	resp, err := postForm(ctx, p.httpClient, p.credentials.AuthURI, form)
 	if err != nil {
-		return nil, fmt.Errorf("failed to post to token endpoint: %w", err)
+		return nil, fmt.Errorf("failed to post to token endpoint: %w: %w", domain.ErrProviderUnavailable, err)
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -143,7 +203,7 @@ func (p *googleProvider) exchangeAuthCode(authCode string) (*tokenResponse, erro
 	if resp.StatusCode != http.StatusOK {
 		var body bytes.Buffer
 		_, _ = body.ReadFrom(resp.Body)
-		return nil, fmt.Errorf("token exchange failed: %s", body.String())
+		return nil, fmt.Errorf("token exchange failed: %s: %w", body.String(), statusCodeError(resp.StatusCode))
 	}
 
 	var tokenResp tokenResponse
@@ -154,39 +214,29 @@ func (p *googleProvider) exchangeAuthCode(authCode string) (*tokenResponse, erro
 	return &tokenResp, nil
 }
 
-// fetchPublicKeyById fetches Google's public certs (PEM format)
-func (p *googleProvider) fetchPublicKeyByID(id string) (*rsa.PublicKey, error) {
-	key := p.cacheManager.Get(id)
-	if key == nil {
-		resp, err := http.Get(p.credentials.CertsURL)
-		if err != nil {
-			return nil, err
-		}
-		defer func() {
-			_ = resp.Body.Close()
-		}()
+// validateScopes checks that every required scope is present in the space separated granted scopes
+func validateScopes(granted string, required []string) error {
+	grantedSet := make(map[string]struct{})
+	for _, s := range strings.Fields(granted) {
+		grantedSet[s] = struct{}{}
+	}
 
-		expiresHeader := resp.Header.Get("Expires")
-		expiresAt, err := time.Parse(time.RFC1123, expiresHeader)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse expires header: %w", err)
+	for _, s := range required {
+		if _, ok := grantedSet[s]; !ok {
+			return fmt.Errorf("scope '%s' not granted: %w", s, domain.ErrInsufficientScope)
 		}
+	}
+	return nil
+}
 
-		certs := map[string]string{}
-		if err := json.NewDecoder(resp.Body).Decode(&certs); err != nil {
+// fetchPublicKeyById fetches Google's public certs (PEM format)
+func (p *googleProvider) fetchPublicKeyByID(ctx context.Context, id string) (*rsa.PublicKey, error) {
+	key := p.cacheManager.Get(id)
+	if key == nil {
+		if _, err := p.loadSigningKeys(ctx); err != nil {
 			return nil, err
 		}
 
-		keys := map[string]*rsa.PublicKey{}
-		for kid, certPEM := range certs {
-			block, _ := jwt.ParseRSAPublicKeyFromPEM([]byte(certPEM))
-			keys[kid] = block
-		}
-
-		for i, k := range keys {
-			_ = p.cacheManager.Add(i, k, expiresAt)
-		}
-
 		key = p.cacheManager.Get(id)
 		if key == nil {
 			return nil, fmt.Errorf("public key id '%s' not found", id)
@@ -195,38 +245,81 @@ func (p *googleProvider) fetchPublicKeyByID(id string) (*rsa.PublicKey, error) {
 	return key, nil
 }
 
-func (p *googleProvider) verifyIDToken(idToken string) (*googleIDTokenClaims, error) {
+// loadSigningKeys fetches every Google public cert into the cache and returns when they expire
+func (p *googleProvider) loadSigningKeys(ctx context.Context) (time.Time, error) {
+	resp, err := getWithContext(ctx, p.httpClient, p.credentials.CertsURL)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch public keys from certs url: %w: %w", domain.ErrProviderUnavailable, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	expiresHeader := resp.Header.Get("Expires")
+	expiresAt, err := time.Parse(time.RFC1123, expiresHeader)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse expires header: %w", err)
+	}
+
+	certs := map[string]string{}
+	if err := json.NewDecoder(resp.Body).Decode(&certs); err != nil {
+		return time.Time{}, err
+	}
+	if len(certs) == 0 {
+		return time.Time{}, fmt.Errorf("no public keys found in certs url: %w", domain.ErrProviderUnavailable)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for kid, certPEM := range certs {
+		block, _ := jwt.ParseRSAPublicKeyFromPEM([]byte(certPEM))
+		keys[kid] = block
+	}
+
+	for i, k := range keys {
+		_ = p.cacheManager.Add(i, k, expiresAt)
+	}
+	return expiresAt, nil
+}
+
+func (p *googleProvider) verifyIDToken(ctx context.Context, idToken string) (*googleIDTokenClaims, error) {
 	token, err := jwt.ParseWithClaims(idToken, &googleIDTokenClaims{}, func(token *jwt.Token) (any, error) {
 		kid, ok := token.Header["kid"].(string)
 		if !ok {
-			return nil, errors.New("no kid found in token header")
+			return nil, fmt.Errorf("no kid found in token header: %w", domain.ErrTokenMalformed)
 		}
 
-		pubKey, err := p.fetchPublicKeyByID(kid)
+		pubKey, err := p.fetchPublicKeyByID(ctx, kid)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get public keys: %w", err)
 		}
 
 		return pubKey, nil
-	}, jwt.WithLeeway(30*time.Second))
+	}, jwt.WithLeeway(p.clockSkew))
 	if err != nil {
-		return nil, fmt.Errorf("token parse error %s: %w", idToken, err)
+		return nil, fmt.Errorf("token parse error: %w", classifyTokenError(err))
 	}
 
 	if !token.Valid {
-		return nil, errors.New("invalid token")
+		return nil, fmt.Errorf("invalid token: %w", domain.ErrTokenInvalidClaims)
 	}
 
 	claims, ok := token.Claims.(*googleIDTokenClaims)
 	if !ok {
-		return nil, errors.New("invalid claims format")
+		return nil, fmt.Errorf("invalid claims format: %w", domain.ErrTokenInvalidClaims)
 	}
 
 	if claims.Issuer != p.credentials.IDTokenExpectedIssuer {
-		return nil, errors.New("invalid issuer")
+		return nil, fmt.Errorf("invalid issuer: %w", domain.ErrTokenInvalidIssuer)
+	}
+	allowed := p.credentials.allowedAudiences()
+	if _, ok := allowed[claims.Audience]; !ok {
+		return nil, fmt.Errorf("invalid audience: %w", domain.ErrTokenInvalidAudience)
 	}
-	if claims.Audience != p.credentials.IDTokenExpectedAud {
-		return nil, errors.New("invalid audience")
+	// when present the authorized party must also be one of our client IDs
+	if claims.AuthorizedParty != "" {
+		if _, ok := allowed[claims.AuthorizedParty]; !ok {
+			return nil, fmt.Errorf("invalid authorized party: %w", domain.ErrTokenInvalidAudience)
+		}
 	}
 
 	return claims, nil
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
 	"github.com/stretchr/testify/require"
 )
 
@@ -45,15 +47,349 @@ func TestProviderGoogle_Returns_GoogleAuthResult(t *testing.T) {
 	require.Equal(t, res.GetID(), testSubject)
 }
 
+func TestProviderGoogle_RequiredScopes(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	tests := []struct {
+		name          string
+		grantedScopes string
+		expectedErr   error
+	}{
+		{name: "sufficient scopes", grantedScopes: "openid email profile"},
+		{name: "insufficient scopes", grantedScopes: "openid", expectedErr: domain.ErrInsufficientScope},
+		{name: "no scopes", grantedScopes: "", expectedErr: domain.ErrInsufficientScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/authCode", googleAuthURIHandlerWithScope(10, keyGen.PrivateKey, tt.grantedScopes))
+			mux.HandleFunc("/certs", googleCertsURLHandler(keyGen.PublicKeyStr))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			credentials := GoogleCredentials{
+				AuthURI:               ts.URL + "/authCode",
+				CertsURL:              ts.URL + "/certs",
+				IDTokenExpectedAud:    testExpectedAudience,
+				IDTokenExpectedIssuer: testExpectedIssuer,
+			}
+
+			p := NewGoogleProvider(credentials, WithRequiredScopes("openid", "email"))
+			res, err := p.Authenticate(ctx, map[string]string{GoogleAuthCodeFieldName: "auth_code"})
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				require.Nil(t, res)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testSubject, res.GetID())
+		})
+	}
+}
+
+func TestProviderGoogle_MultipleAudiences(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	const (
+		androidClientID = "android_client_id"
+		iosClientID     = "ios_client_id"
+	)
+
+	tests := []struct {
+		name    string
+		aud     string
+		azp     string
+		wantErr bool
+	}{
+		{name: "android audience", aud: androidClientID},
+		{name: "ios audience", aud: iosClientID},
+		{name: "single audience field", aud: testExpectedAudience},
+		{name: "allowed authorized party", aud: testExpectedAudience, azp: androidClientID},
+		{name: "disallowed audience", aud: "unknown_client_id", wantErr: true},
+		{name: "disallowed authorized party", aud: testExpectedAudience, azp: "unknown_client_id", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idToken := generateGoogleIDTokenWithAudience(10, keyGen.PrivateKey, tt.aud, tt.azp)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/authCode", googleAuthURIHandlerWithIDToken(10, idToken, "scope"))
+			mux.HandleFunc("/certs", googleCertsURLHandler(keyGen.PublicKeyStr))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			credentials := GoogleCredentials{
+				AuthURI:                  ts.URL + "/authCode",
+				CertsURL:                 ts.URL + "/certs",
+				IDTokenExpectedAud:       testExpectedAudience,
+				IDTokenExpectedAudiences: []string{androidClientID, iosClientID},
+				IDTokenExpectedIssuer:    testExpectedIssuer,
+			}
+
+			p := NewGoogleProvider(credentials)
+			res, err := p.Authenticate(ctx, map[string]string{GoogleAuthCodeFieldName: "auth_code"})
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Nil(t, res)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testSubject, res.GetID())
+		})
+	}
+}
+
+func TestProviderGoogle_Returns_TypedErrors(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+	otherKeyGen := TestKeyPairGenerator{}
+	otherKeyGen.GenerateRSAKeys()
+
+	withClaim := func(key string, value any) string {
+		claims := googleIDTokenTestClaims(10)
+		claims[key] = value
+		return generateGoogleIDTokenFromClaims(keyGen.PrivateKey, claims)
+	}
+
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		expectedErr error
+	}{
+		{
+			name:        "expired",
+			handler:     googleAuthURIHandlerWithIDToken(10, withClaim("exp", time.Now().Add(-time.Hour).Unix()), "scope"),
+			expectedErr: domain.ErrTokenExpired,
+		},
+		{
+			name:        "not yet valid",
+			handler:     googleAuthURIHandlerWithIDToken(10, withClaim("nbf", time.Now().Add(time.Hour).Unix()), "scope"),
+			expectedErr: domain.ErrTokenNotYetValid,
+		},
+		{
+			name:        "invalid signature",
+			handler:     googleAuthURIHandlerWithIDToken(10, generateGoogleIDToken(10, otherKeyGen.PrivateKey), "scope"),
+			expectedErr: domain.ErrTokenInvalidSignature,
+		},
+		{
+			name:        "invalid issuer",
+			handler:     googleAuthURIHandlerWithIDToken(10, withClaim("iss", "unexpected_issuer"), "scope"),
+			expectedErr: domain.ErrTokenInvalidIssuer,
+		},
+		{
+			name:        "invalid audience",
+			handler:     googleAuthURIHandlerWithIDToken(10, withClaim("aud", "unexpected_audience"), "scope"),
+			expectedErr: domain.ErrTokenInvalidAudience,
+		},
+		{
+			name:        "malformed",
+			handler:     googleAuthURIHandlerWithIDToken(10, "not-a-jwt", "scope"),
+			expectedErr: domain.ErrTokenMalformed,
+		},
+		{
+			name: "provider unavailable",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			expectedErr: domain.ErrProviderUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/authCode", tt.handler)
+			mux.HandleFunc("/certs", googleCertsURLHandler(keyGen.PublicKeyStr))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			credentials := GoogleCredentials{
+				AuthURI:               ts.URL + "/authCode",
+				CertsURL:              ts.URL + "/certs",
+				IDTokenExpectedAud:    testExpectedAudience,
+				IDTokenExpectedIssuer: testExpectedIssuer,
+			}
+
+			p := NewGoogleProvider(credentials)
+			res, err := p.Authenticate(ctx, map[string]string{GoogleAuthCodeFieldName: "auth_code"})
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Nil(t, res)
+		})
+	}
+}
+
+func TestProviderGoogle_ClockSkew(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	// the token expired 10 seconds ago
+	claims := googleIDTokenTestClaims(-10)
+	idToken := generateGoogleIDTokenFromClaims(keyGen.PrivateKey, claims)
+
+	tests := []struct {
+		name      string
+		clockSkew time.Duration
+		wantErr   bool
+	}{
+		{name: "inside leeway", clockSkew: 20 * time.Second},
+		{name: "outside leeway", clockSkew: 5 * time.Second, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/authCode", googleAuthURIHandlerWithIDToken(10, idToken, "scope"))
+			mux.HandleFunc("/certs", googleCertsURLHandler(keyGen.PublicKeyStr))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			credentials := GoogleCredentials{
+				AuthURI:               ts.URL + "/authCode",
+				CertsURL:              ts.URL + "/certs",
+				IDTokenExpectedAud:    testExpectedAudience,
+				IDTokenExpectedIssuer: testExpectedIssuer,
+			}
+
+			p := NewGoogleProvider(credentials, WithClockSkew(tt.clockSkew))
+			res, err := p.Authenticate(ctx, map[string]string{GoogleAuthCodeFieldName: "auth_code"})
+			if tt.wantErr {
+				require.ErrorIs(t, err, domain.ErrTokenExpired)
+				require.Nil(t, res)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testSubject, res.GetID())
+		})
+	}
+}
+
+func TestProviderGoogle_Returns_Profile(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	claims := googleIDTokenTestClaims(10)
+	claims["name"] = "Player One"
+	claims["picture"] = "https://example.com/player01.png"
+	claims["locale"] = "pt-PT"
+	idToken := generateGoogleIDTokenFromClaims(keyGen.PrivateKey, claims)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authCode", googleAuthURIHandlerWithIDToken(10, idToken, "scope"))
+	mux.HandleFunc("/certs", googleCertsURLHandler(keyGen.PublicKeyStr))
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	credentials := GoogleCredentials{
+		AuthURI:               ts.URL + "/authCode",
+		CertsURL:              ts.URL + "/certs",
+		IDTokenExpectedAud:    testExpectedAudience,
+		IDTokenExpectedIssuer: testExpectedIssuer,
+	}
+
+	p := NewGoogleProvider(credentials)
+	res, err := p.Authenticate(ctx, map[string]string{GoogleAuthCodeFieldName: "auth_code"})
+	require.NoError(t, err)
+	require.Equal(t, domain.ProviderProfile{
+		DisplayName: "Player One",
+		AvatarURL:   "https://example.com/player01.png",
+		Locale:      "pt-PT",
+	}, res.GetProfile())
+}
+
+func TestProviderGoogle_Returns_Email(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	tests := []struct {
+		name          string
+		emailVerified any
+		wantVerified  bool
+	}{
+		{name: "verified email", emailVerified: true, wantVerified: true},
+		{name: "unverified email", emailVerified: false},
+		{name: "missing verification claim"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := googleIDTokenTestClaims(10)
+			if tt.emailVerified != nil {
+				claims["email_verified"] = tt.emailVerified
+			}
+			idToken := generateGoogleIDTokenFromClaims(keyGen.PrivateKey, claims)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/authCode", googleAuthURIHandlerWithIDToken(10, idToken, "scope"))
+			mux.HandleFunc("/certs", googleCertsURLHandler(keyGen.PublicKeyStr))
+
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			credentials := GoogleCredentials{
+				AuthURI:               ts.URL + "/authCode",
+				CertsURL:              ts.URL + "/certs",
+				IDTokenExpectedAud:    testExpectedAudience,
+				IDTokenExpectedIssuer: testExpectedIssuer,
+			}
+
+			p := NewGoogleProvider(credentials)
+			res, err := p.Authenticate(ctx, map[string]string{GoogleAuthCodeFieldName: "auth_code"})
+			require.NoError(t, err)
+			require.Equal(t, testSubject, res.GetID())
+
+			withEmail, ok := res.(ports.AuthResultWithEmail)
+			require.True(t, ok)
+			require.Equal(t, "player01@example.com", withEmail.GetEmail())
+			require.Equal(t, tt.wantVerified, withEmail.GetEmailVerified())
+		})
+	}
+}
+
 func generateGoogleIDToken(secs int, privateKey *rsa.PrivateKey) string {
-	claims := jwt.MapClaims{
+	return generateGoogleIDTokenWithAudience(secs, privateKey, testExpectedAudience, "")
+}
+
+func generateGoogleIDTokenWithAudience(secs int, privateKey *rsa.PrivateKey, aud string, azp string) string {
+	claims := googleIDTokenTestClaims(secs)
+	claims["aud"] = aud
+	if azp != "" {
+		claims["azp"] = azp
+	}
+	return generateGoogleIDTokenFromClaims(privateKey, claims)
+}
+
+func googleIDTokenTestClaims(secs int) jwt.MapClaims {
+	return jwt.MapClaims{
 		"sub":   testSubject,
 		"exp":   time.Now().Add(time.Second * time.Duration(secs)).Unix(),
 		"email": "player01@example.com",
 		"aud":   testExpectedAudience,
 		"iss":   testExpectedIssuer,
 	}
+}
 
+func generateGoogleIDTokenFromClaims(privateKey *rsa.PrivateKey, claims jwt.MapClaims) string {
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
 	token.Header["kid"] = testKeyID
 
@@ -67,14 +403,22 @@ func generateGoogleIDToken(secs int, privateKey *rsa.PrivateKey) string {
 // Helper functions to generate test http handlers data
 
 func googleAuthURIHandler(secs int, privateKey *rsa.PrivateKey) http.HandlerFunc {
+	return googleAuthURIHandlerWithScope(secs, privateKey, "scope")
+}
+
+func googleAuthURIHandlerWithScope(secs int, privateKey *rsa.PrivateKey, scope string) http.HandlerFunc {
+	return googleAuthURIHandlerWithIDToken(secs, generateGoogleIDToken(10, privateKey), scope)
+}
+
+func googleAuthURIHandlerWithIDToken(secs int, idToken string, scope string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		t := tokenResponse{
 			AccessToken:  "access_token",
 			ExpiresIn:    time.Now().Add(time.Duration(secs) * time.Second).Unix(),
 			RefreshToken: "refresh_token",
-			Scope:        "scope",
+			Scope:        scope,
 			TokenType:    "token_type",
-			IDToken:      generateGoogleIDToken(10, privateKey),
+			IDToken:      idToken,
 		}
 
 		b, _ := json.Marshal(t)
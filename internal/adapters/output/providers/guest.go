@@ -2,26 +2,102 @@ package providers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"github.com/google/uuid"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
 	"github.com/posilva/simpleidentity/internal/core/ports"
 )
 
-type GuestProvider struct{}
+const (
+	// GuestIDFieldName is the client generated device or installation id identifying the guest
+	GuestIDFieldName = "id"
+	// GuestIDMaxLength is the maximum accepted length of a guest id
+	GuestIDMaxLength = 128
+)
+
+// ErrInvalidGuestID is returned by the guest id validators when the id does not follow the expected format
+var ErrInvalidGuestID = errors.New("invalid guest id")
+
+// GuestIDValidator checks a client generated guest id, a non nil error rejects the id
+type GuestIDValidator func(id string) error
+
+// UUIDGuestValidator accepts only guest ids in the canonical UUID text form
+func UUIDGuestValidator(id string) error {
+	// uuid.Parse also accepts the urn and braced forms which would give the same device several ids
+	if len(id) != 36 {
+		return fmt.Errorf("%w: expected a canonical UUID", ErrInvalidGuestID)
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidGuestID, err)
+	}
+	return nil
+}
+
+type GuestProvider struct {
+	idValidator GuestIDValidator
+}
+
+// GuestProviderOption configures the guest provider
+type GuestProviderOption func(*GuestProvider)
+
+// WithIDValidator sets a validator run on every guest id before it is accepted
+func WithIDValidator(validator GuestIDValidator) GuestProviderOption {
+	return func(p *GuestProvider) {
+		p.idValidator = validator
+	}
+}
+
+// Safeguard check to ensure GuestProvider implements the AuthProvider interface
+var _ ports.AuthProvider = (*GuestProvider)(nil)
 
 type guestAuthResult struct {
 	ID string
 }
 
+// Safeguard check to ensure guestAuthResult implements the AuthResult interface
+var _ ports.AuthResult = (*guestAuthResult)(nil)
+
 func (r *guestAuthResult) GetID() string {
 	return r.ID
 }
 
-func NewGuestProvider() *GuestProvider {
-	return &GuestProvider{}
+// GetProfile returns an empty profile as guests do not share any profile information
+func (r *guestAuthResult) GetProfile() domain.ProviderProfile {
+	return domain.ProviderProfile{}
+}
+
+func NewGuestProvider(opts ...GuestProviderOption) *GuestProvider {
+	p := &GuestProvider{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// RequiredFields returns the guest id field
+func (p *GuestProvider) RequiredFields() []string {
+	return []string{GuestIDFieldName}
 }
 
+// Authenticate returns the client generated id as the guest id, the id is the permanent key of the
+// guest account so it must be unique per device or installation
 func (p *GuestProvider) Authenticate(ctx context.Context, data map[string]string) (ports.AuthResult, error) {
+	id, ok := data[GuestIDFieldName]
+	if !ok || id == "" {
+		return nil, fmt.Errorf("missing required field %s: %w", GuestIDFieldName, domain.ErrMissingRequiredProviderAuthData)
+	}
+	if len(id) > GuestIDMaxLength {
+		return nil, fmt.Errorf("field %s exceeds %d characters: %w", GuestIDFieldName, GuestIDMaxLength, domain.ErrInvalidProviderAuthData)
+	}
+	if p.idValidator != nil {
+		if err := p.idValidator(id); err != nil {
+			return nil, fmt.Errorf("field %s rejected: %w: %w", GuestIDFieldName, domain.ErrInvalidProviderAuthData, err)
+		}
+	}
 	return &guestAuthResult{
-		ID: "guest-id",
+		ID: id,
 	}, nil
 }
@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderGuest_Returns_ClientGeneratedID(t *testing.T) {
+	p := NewGuestProvider()
+
+	first, err := p.Authenticate(context.Background(), map[string]string{GuestIDFieldName: "some_client_generated_id"})
+	require.NoError(t, err)
+	require.Equal(t, "some_client_generated_id", first.GetID())
+
+	second, err := p.Authenticate(context.Background(), map[string]string{GuestIDFieldName: "another_client_generated_id"})
+	require.NoError(t, err)
+	require.NotEqual(t, first.GetID(), second.GetID())
+}
+
+func TestProviderGuest_Returns_EmptyProfile(t *testing.T) {
+	res, err := NewGuestProvider().Authenticate(context.Background(), map[string]string{GuestIDFieldName: "some_client_generated_id"})
+	require.NoError(t, err)
+	require.Equal(t, domain.ProviderProfile{}, res.GetProfile())
+}
+
+func TestProviderGuest_Returns_ErrorOnInvalidID(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        map[string]string
+		expectedErr error
+	}{
+		{
+			name:        "missing id",
+			data:        map[string]string{},
+			expectedErr: domain.ErrMissingRequiredProviderAuthData,
+		},
+		{
+			name:        "empty id",
+			data:        map[string]string{GuestIDFieldName: ""},
+			expectedErr: domain.ErrMissingRequiredProviderAuthData,
+		},
+		{
+			name:        "oversized id",
+			data:        map[string]string{GuestIDFieldName: strings.Repeat("a", GuestIDMaxLength+1)},
+			expectedErr: domain.ErrInvalidProviderAuthData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := NewGuestProvider().Authenticate(context.Background(), tt.data)
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Nil(t, res)
+		})
+	}
+
+	res, err := NewGuestProvider().Authenticate(context.Background(), map[string]string{GuestIDFieldName: strings.Repeat("a", GuestIDMaxLength)})
+	require.NoError(t, err)
+	require.Len(t, res.GetID(), GuestIDMaxLength)
+}
+
+func TestUUIDGuestValidator(t *testing.T) {
+	require.NoError(t, UUIDGuestValidator("0b7e4a6e-3c5f-4d0a-9d8e-7f1c2b3a4d5e"))
+
+	for _, id := range []string{
+		"some_client_generated_id",
+		"0b7e4a6e3c5f4d0a9d8e7f1c2b3a4d5e",
+		"{0b7e4a6e-3c5f-4d0a-9d8e-7f1c2b3a4d5e}",
+		"urn:uuid:0b7e4a6e-3c5f-4d0a-9d8e-7f1c2b3a4d5e",
+		"0b7e4a6e-3c5f-4d0a-9d8e-7f1c2b3a4dzz",
+	} {
+		t.Run(id, func(t *testing.T) {
+			require.ErrorIs(t, UUIDGuestValidator(id), ErrInvalidGuestID)
+		})
+	}
+}
+
+func TestProviderGuest_WithIDValidator(t *testing.T) {
+	p := NewGuestProvider(WithIDValidator(UUIDGuestValidator))
+
+	res, err := p.Authenticate(context.Background(), map[string]string{GuestIDFieldName: "0b7e4a6e-3c5f-4d0a-9d8e-7f1c2b3a4d5e"})
+	require.NoError(t, err)
+	require.Equal(t, "0b7e4a6e-3c5f-4d0a-9d8e-7f1c2b3a4d5e", res.GetID())
+
+	res, err = p.Authenticate(context.Background(), map[string]string{GuestIDFieldName: "some_client_generated_id"})
+	require.ErrorIs(t, err, domain.ErrInvalidProviderAuthData)
+	require.ErrorIs(t, err, ErrInvalidGuestID)
+	require.Nil(t, res)
+}
+
+func TestProviderGuest_WithCustomIDValidator(t *testing.T) {
+	errReserved := errors.New("reserved id")
+	p := NewGuestProvider(WithIDValidator(func(id string) error {
+		if strings.HasPrefix(id, "admin") {
+			return errReserved
+		}
+		return nil
+	}))
+
+	_, err := p.Authenticate(context.Background(), map[string]string{GuestIDFieldName: "device-1"})
+	require.NoError(t, err)
+
+	_, err = p.Authenticate(context.Background(), map[string]string{GuestIDFieldName: "admin-device"})
+	require.ErrorIs(t, err, errReserved)
+	require.ErrorIs(t, err, domain.ErrInvalidProviderAuthData)
+}
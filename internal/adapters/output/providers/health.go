@@ -0,0 +1,59 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/health"
+)
+
+// jwksHealthCheckTimeout bounds the signing keys fetch of a health check
+const jwksHealthCheckTimeout = 5 * time.Second
+
+// signingKeysLoader is implemented by the providers verifying tokens with the keys published by the provider
+type signingKeysLoader interface {
+	// loadSigningKeys fetches the signing keys into the cache and returns when they expire
+	loadSigningKeys(ctx context.Context) (time.Time, error)
+}
+
+// Safeguard check to ensure the federated providers implement the signingKeysLoader interface
+var (
+	_ signingKeysLoader = (*googleProvider)(nil)
+	_ signingKeysLoader = (*appleProvider)(nil)
+	_ signingKeysLoader = (*epicProvider)(nil)
+)
+
+// JWKSHealthCheck returns a health check failing when the provider signing keys can't be loaded, so no token
+// could be verified. The keys are only fetched again once the loaded ones expire so the probes do not hammer
+// the provider. Providers that do not verify signed tokens are always healthy
+func JWKSHealthCheck(provider ports.AuthProvider) health.CheckFunc {
+	loader, ok := provider.(signingKeysLoader)
+	if !ok {
+		return func(context.Context) error {
+			return nil
+		}
+	}
+
+	var (
+		mu        sync.Mutex
+		expiresAt time.Time
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Now().Before(expiresAt) {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, jwksHealthCheckTimeout)
+		defer cancel()
+		loadedExpiresAt, err := loader.loadSigningKeys(ctx)
+		if err != nil {
+			return err
+		}
+		expiresAt = loadedExpiresAt
+		return nil
+	}
+}
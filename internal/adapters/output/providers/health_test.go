@@ -0,0 +1,116 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func newJWKSHealthCheckTestProvider(certsURL string) *appleProvider {
+	return NewAppleProvider(AppleCredentials{
+		CertsURL:                certsURL,
+		ClientID:                "apple_client_id",
+		IDTokenExpectedAudience: testExpectedAudience,
+		IDTokenExpectedIssuer:   testExpectedIssuer,
+	}).(*appleProvider)
+}
+
+func TestJWKSHealthCheck_Healthy(t *testing.T) {
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+	ts := httptest.NewServer(appleCertsURLHandler(keyGen.PublicKey))
+	defer ts.Close()
+
+	check := JWKSHealthCheck(newJWKSHealthCheckTestProvider(ts.URL + "/certs"))
+	require.NoError(t, check(context.Background()))
+}
+
+func TestJWKSHealthCheck_Unhealthy(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		closed  bool
+	}{
+		{
+			name:   "refused",
+			closed: true,
+		},
+		{
+			name: "error status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+		},
+		{
+			name: "no keys",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"keys":[]}`))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(tt.handler)
+			if tt.closed {
+				ts.Close()
+			} else {
+				defer ts.Close()
+			}
+
+			check := JWKSHealthCheck(newJWKSHealthCheckTestProvider(ts.URL + "/certs"))
+			err := check(context.Background())
+			require.Error(t, err)
+			require.ErrorIs(t, err, domain.ErrProviderUnavailable)
+		})
+	}
+}
+
+func TestJWKSHealthCheck_RespectsCache(t *testing.T) {
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+	var hits atomic.Int32
+	certsHandler := appleCertsURLHandler(keyGen.PublicKey)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		certsHandler(w, r)
+	}))
+	defer ts.Close()
+
+	check := JWKSHealthCheck(newJWKSHealthCheckTestProvider(ts.URL + "/certs"))
+	for range 5 {
+		require.NoError(t, check(context.Background()))
+	}
+	require.Equal(t, int32(1), hits.Load())
+}
+
+func TestJWKSHealthCheck_RetriesAfterFailure(t *testing.T) {
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+	var failing atomic.Bool
+	failing.Store(true)
+	certsHandler := appleCertsURLHandler(keyGen.PublicKey)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		certsHandler(w, r)
+	}))
+	defer ts.Close()
+
+	check := JWKSHealthCheck(newJWKSHealthCheckTestProvider(ts.URL + "/certs"))
+	require.Error(t, check(context.Background()))
+
+	failing.Store(false)
+	require.NoError(t, check(context.Background()))
+}
+
+func TestJWKSHealthCheck_ProviderWithoutKeys(t *testing.T) {
+	check := JWKSHealthCheck(NewGuestProvider())
+	require.NoError(t, check(context.Background()))
+}
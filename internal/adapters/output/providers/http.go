@@ -0,0 +1,102 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrUnexpectedRedirect is returned when a provider endpoint issues a redirect not allowed by the policy
+var ErrUnexpectedRedirect = errors.New("unexpected redirect from provider endpoint")
+
+// maxRedirects is the maximum number of redirects followed when they are allowed
+const maxRedirects = 10
+
+// RedirectPolicy defines how the provider HTTP client handles redirects
+type RedirectPolicy int
+
+const (
+	// RedirectPolicyDeny rejects every redirect, the default for the security sensitive provider endpoints
+	RedirectPolicyDeny RedirectPolicy = iota
+	// RedirectPolicySameHost follows redirects only when they stay on the original host
+	RedirectPolicySameHost
+)
+
+// checkRedirect returns the http.Client CheckRedirect function enforcing the policy
+func (rp RedirectPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if rp == RedirectPolicySameHost && len(via) > 0 && req.URL.Host == via[0].URL.Host {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects: %w", maxRedirects, ErrUnexpectedRedirect)
+		}
+		return nil
+	}
+	return fmt.Errorf("redirect to %s: %w", req.URL.Redacted(), ErrUnexpectedRedirect)
+}
+
+// postForm posts the form like http.Client.PostForm bound to the context
+func postForm(ctx context.Context, client *http.Client, url string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return client.Do(req)
+}
+
+// getWithContext gets the url like http.Client.Get bound to the context
+func getWithContext(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// minTLSVersion is the oldest TLS version accepted by the provider HTTP client
+const minTLSVersion = tls.VersionTLS12
+
+// newHTTPClient returns the client used to reach the provider endpoints, a copy of the given client is
+// made so the caller client is never modified
+func newHTTPClient(c *http.Client, timeout time.Duration, policy RedirectPolicy, tlsConfig *tls.Config) *http.Client {
+	if c == nil {
+		return &http.Client{
+			Timeout:       timeout,
+			CheckRedirect: policy.checkRedirect,
+			Transport:     newTLSTransport(http.DefaultTransport.(*http.Transport), tlsConfig),
+		}
+	}
+
+	client := *c
+	if client.CheckRedirect == nil {
+		client.CheckRedirect = policy.checkRedirect
+	}
+	if tlsConfig != nil {
+		switch t := client.Transport.(type) {
+		case nil:
+			client.Transport = newTLSTransport(http.DefaultTransport.(*http.Transport), tlsConfig)
+		case *http.Transport:
+			client.Transport = newTLSTransport(t, tlsConfig)
+		}
+	}
+	return &client
+}
+
+// newTLSTransport returns a copy of the transport using the TLS policy, TLS 1.2 is the minimum version when unset
+func newTLSTransport(t *http.Transport, tlsConfig *tls.Config) *http.Transport {
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	} else {
+		tlsConfig = tlsConfig.Clone()
+	}
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = minTLSVersion
+	}
+	transport := t.Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport
+}
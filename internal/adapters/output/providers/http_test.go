@@ -0,0 +1,133 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderHTTPClient_RedirectPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	// the other host serves valid certificates so only the redirect policy can fail the authentication
+	otherMux := http.NewServeMux()
+	otherMux.HandleFunc("/certs", googleCertsURLHandler(keyGen.PublicKeyStr))
+	otherHost := httptest.NewServer(otherMux)
+	defer otherHost.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authCode", googleAuthURIHandler(10, keyGen.PrivateKey))
+	mux.HandleFunc("/certs", googleCertsURLHandler(keyGen.PublicKeyStr))
+	mux.HandleFunc("/cross-host-certs", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, otherHost.URL+"/certs", http.StatusFound)
+	})
+	mux.HandleFunc("/same-host-certs", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/certs", http.StatusFound)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		certsURL string
+		policy   RedirectPolicy
+		wantErr  bool
+	}{
+		{name: "deny cross host", certsURL: ts.URL + "/cross-host-certs", policy: RedirectPolicyDeny, wantErr: true},
+		{name: "deny same host", certsURL: ts.URL + "/same-host-certs", policy: RedirectPolicyDeny, wantErr: true},
+		{name: "same host policy rejects cross host", certsURL: ts.URL + "/cross-host-certs", policy: RedirectPolicySameHost, wantErr: true},
+		{name: "same host policy follows same host", certsURL: ts.URL + "/same-host-certs", policy: RedirectPolicySameHost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credentials := GoogleCredentials{
+				AuthURI:               ts.URL + "/authCode",
+				CertsURL:              tt.certsURL,
+				IDTokenExpectedAud:    testExpectedAudience,
+				IDTokenExpectedIssuer: testExpectedIssuer,
+			}
+
+			p := NewGoogleProvider(credentials, WithRedirectPolicy(tt.policy))
+			res, err := p.Authenticate(ctx, map[string]string{GoogleAuthCodeFieldName: "auth_code"})
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrUnexpectedRedirect)
+				require.Nil(t, res)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testSubject, res.GetID())
+		})
+	}
+}
+
+func TestProviderHTTPClient_DoesNotModifyCallerClient(t *testing.T) {
+	client := &http.Client{}
+	_ = NewGoogleProvider(GoogleCredentials{}, WithHTTPClient(client))
+	require.Nil(t, client.CheckRedirect)
+}
+
+func TestProviderHTTPClient_TLSConfig(t *testing.T) {
+	p := NewGoogleProvider(GoogleCredentials{}).(*googleProvider)
+	transport, ok := p.httpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	require.Equal(t, uint16(tls.VersionTLS12), transport.TLSClientConfig.MinVersion)
+
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	}
+	p = NewGoogleProvider(GoogleCredentials{}, WithTLSConfig(tlsConfig)).(*googleProvider)
+	transport, ok = p.httpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	require.Equal(t, uint16(tls.VersionTLS13), transport.TLSClientConfig.MinVersion)
+	require.Equal(t, tlsConfig.CipherSuites, transport.TLSClientConfig.CipherSuites)
+	require.NotSame(t, http.DefaultTransport, transport)
+
+	// a caller client without a custom transport gets the policy too
+	client := &http.Client{}
+	p = NewGoogleProvider(GoogleCredentials{}, WithHTTPClient(client), WithTLSConfig(&tls.Config{})).(*googleProvider)
+	transport, ok = p.httpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	require.Equal(t, uint16(tls.VersionTLS12), transport.TLSClientConfig.MinVersion)
+	require.Nil(t, client.Transport)
+}
+
+func TestProviderHTTPClient_TLSConfigReachesProvider(t *testing.T) {
+	ctx := context.Background()
+
+	keyGen := TestKeyPairGenerator{}
+	keyGen.GenerateRSAKeys()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authCode", googleAuthURIHandler(10, keyGen.PrivateKey))
+	mux.HandleFunc("/certs", googleCertsURLHandler(keyGen.PublicKeyStr))
+	ts := httptest.NewTLSServer(mux)
+	defer ts.Close()
+
+	credentials := GoogleCredentials{
+		AuthURI:               ts.URL + "/authCode",
+		CertsURL:              ts.URL + "/certs",
+		IDTokenExpectedAud:    testExpectedAudience,
+		IDTokenExpectedIssuer: testExpectedIssuer,
+	}
+
+	// the test server certificate is only trusted through the custom TLS configuration
+	_, err := NewGoogleProvider(credentials).Authenticate(ctx, map[string]string{GoogleAuthCodeFieldName: "auth_code"})
+	require.Error(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	p := NewGoogleProvider(credentials, WithTLSConfig(&tls.Config{RootCAs: roots}))
+	res, err := p.Authenticate(ctx, map[string]string{GoogleAuthCodeFieldName: "auth_code"})
+	require.NoError(t, err)
+	require.Equal(t, testSubject, res.GetID())
+}
@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers/certs"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/pkg/tokens"
+)
+
+// jwksKeysTTL is how long the keys fetched from a JWKS endpoint are cached
+const jwksKeysTTL = 1 * time.Hour
+
+// fetchJWKSPublicKeyByID returns the key with the given id from the cache, on a miss the JWKS endpoint
+// is fetched again so rotated keys are picked up
+func fetchJWKSPublicKeyByID(ctx context.Context, client *http.Client, cache certs.CacheManager, jwksURL string, id string) (*rsa.PublicKey, error) {
+	key := cache.Get(id)
+	if key != nil {
+		return key, nil
+	}
+
+	if _, err := loadJWKSPublicKeys(ctx, client, cache, jwksURL); err != nil {
+		return nil, err
+	}
+
+	key = cache.Get(id)
+	if key == nil {
+		return nil, fmt.Errorf("public key id '%s' not found", id)
+	}
+	return key, nil
+}
+
+// loadJWKSPublicKeys fetches every key of the JWKS endpoint into the cache and returns when they expire,
+// an endpoint without keys is an error as no token could be verified
+func loadJWKSPublicKeys(ctx context.Context, client *http.Client, cache certs.CacheManager, jwksURL string) (time.Time, error) {
+	resp, err := getWithContext(ctx, client, jwksURL)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch public keys from certs url: %w: %w", domain.ErrProviderUnavailable, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("failed to fetch public keys with status code %d: %w", resp.StatusCode, domain.ErrProviderUnavailable)
+	}
+
+	var jwks tokens.JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return time.Time{}, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	if len(jwks.Keys) == 0 {
+		return time.Time{}, fmt.Errorf("no public keys found in certs url: %w", domain.ErrProviderUnavailable)
+	}
+
+	expireAt := time.Now().Add(jwksKeysTTL)
+	for _, jwk := range jwks.Keys {
+		k, err := tokens.RSAPublicKeyFromJWK(jwk)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to create public key from JWK key id %s: %w", jwk.Kid, err)
+		}
+		_ = cache.Add(jwk.Kid, k, expireAt)
+	}
+	return expireAt, nil
+}
@@ -0,0 +1,198 @@
+package providers
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/providers/certs"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// defaultClockSkew is the default leeway allowed when validating the token time based claims
+const defaultClockSkew = 30 * time.Second
+
+// providerOptions holds the optional settings shared by the federated providers
+type providerOptions struct {
+	requestTimeout time.Duration
+	cacheManager   certs.CacheManager
+	requiredScopes []string
+	hashedNonce    bool
+	clockSkew      time.Duration
+	redirectPolicy RedirectPolicy
+	httpClient     *http.Client
+	// tlsConfig is the TLS policy of the calls to the provider endpoints, nil enforces TLS 1.2 or newer
+	tlsConfig *tls.Config
+	// emailVerification compares the client supplied email with the token email claim (Apple only)
+	emailVerification bool
+	// nonceStore rejects the nonces already used, nil disables the replay protection (Apple only)
+	nonceStore ports.NonceStore
+}
+
+// ProviderOption configures a setting shared by every federated provider
+type ProviderOption func(*providerOptions)
+
+// GoogleProviderOption configures the Google provider
+type GoogleProviderOption interface {
+	applyTo(o *providerOptions)
+	google()
+}
+
+// AppleProviderOption configures the Apple provider
+type AppleProviderOption interface {
+	applyTo(o *providerOptions)
+	apple()
+}
+
+// TwitchProviderOption configures the Twitch provider
+type TwitchProviderOption interface {
+	applyTo(o *providerOptions)
+	twitch()
+}
+
+// EpicProviderOption configures the Epic Online Services provider
+type EpicProviderOption interface {
+	applyTo(o *providerOptions)
+	epic()
+}
+
+func (opt ProviderOption) applyTo(o *providerOptions) { opt(o) }
+func (ProviderOption) google()                        {}
+func (ProviderOption) apple()                         {}
+func (ProviderOption) twitch()                        {}
+func (ProviderOption) epic()                          {}
+
+// TokenVerifierOption configures the providers verifying signed ID tokens (Google, Apple and Epic),
+// Twitch does not accept it as the provider validates its opaque access tokens itself
+type TokenVerifierOption interface {
+	applyTo(o *providerOptions)
+	google()
+	apple()
+	epic()
+}
+
+// tokenVerifierOption is an option every provider but Twitch accepts
+type tokenVerifierOption func(*providerOptions)
+
+func (opt tokenVerifierOption) applyTo(o *providerOptions) { opt(o) }
+func (tokenVerifierOption) google()                        {}
+func (tokenVerifierOption) apple()                         {}
+func (tokenVerifierOption) epic()                          {}
+
+// googleOption is an option only the Google provider accepts
+type googleOption func(*providerOptions)
+
+func (opt googleOption) applyTo(o *providerOptions) { opt(o) }
+func (googleOption) google()                        {}
+
+// appleOption is an option only the Apple provider accepts
+type appleOption func(*providerOptions)
+
+func (opt appleOption) applyTo(o *providerOptions) { opt(o) }
+func (appleOption) apple()                         {}
+
+// Safeguard check to ensure the shared options are accepted by every provider
+var (
+	_ GoogleProviderOption = ProviderOption(nil)
+	_ AppleProviderOption  = ProviderOption(nil)
+	_ TwitchProviderOption = ProviderOption(nil)
+	_ EpicProviderOption   = ProviderOption(nil)
+)
+
+func newProviderOptions[T interface{ applyTo(o *providerOptions) }](opts []T) providerOptions {
+	o := providerOptions{
+		requestTimeout: defaultTimeout,
+		cacheManager:   certs.NewSimpleCacheManager(),
+		clockSkew:      defaultClockSkew,
+	}
+	for _, opt := range opts {
+		opt.applyTo(&o)
+	}
+	o.httpClient = newHTTPClient(o.httpClient, o.requestTimeout, o.redirectPolicy, o.tlsConfig)
+	return o
+}
+
+// sharedOptions converts the shared options to the option type of a provider,
+// the assertion cannot fail as ProviderOption implements every provider option interface
+func sharedOptions[T any](opts []ProviderOption) []T {
+	out := make([]T, 0, len(opts))
+	for _, opt := range opts {
+		out = append(out, any(opt).(T))
+	}
+	return out
+}
+
+// WithTimeout sets the timeout of the requests to the provider endpoints
+func WithTimeout(timeout time.Duration) ProviderOption {
+	return func(o *providerOptions) {
+		o.requestTimeout = timeout
+	}
+}
+
+// WithCertificatesCacheManager sets the cache manager used to keep the provider public keys
+func WithCertificatesCacheManager(cm certs.CacheManager) TokenVerifierOption {
+	return tokenVerifierOption(func(o *providerOptions) {
+		o.cacheManager = cm
+	})
+}
+
+// WithClockSkew sets the leeway allowed when validating the token expiry, issued at and not before claims
+func WithClockSkew(d time.Duration) TokenVerifierOption {
+	return tokenVerifierOption(func(o *providerOptions) {
+		o.clockSkew = d
+	})
+}
+
+// WithHTTPClient sets the HTTP client used to reach the provider endpoints,
+// the redirect policy is applied when the client does not define its own
+func WithHTTPClient(c *http.Client) ProviderOption {
+	return func(o *providerOptions) {
+		o.httpClient = c
+	}
+}
+
+// WithTLSConfig sets the TLS policy (e.g. minimum version and cipher suites) of the calls to the provider endpoints,
+// a zero MinVersion is raised to TLS 1.2. It applies to a client set by WithHTTPClient unless it has a custom transport
+func WithTLSConfig(c *tls.Config) ProviderOption {
+	return func(o *providerOptions) {
+		o.tlsConfig = c
+	}
+}
+
+// WithRedirectPolicy sets how redirects issued by the provider endpoints are handled
+func WithRedirectPolicy(policy RedirectPolicy) ProviderOption {
+	return func(o *providerOptions) {
+		o.redirectPolicy = policy
+	}
+}
+
+// WithEmailVerification makes the provider reject a client supplied email that does not match the token,
+// it is off by default as Apple omits the email after the first authorization
+func WithEmailVerification(enabled bool) AppleProviderOption {
+	return appleOption(func(o *providerOptions) {
+		o.emailVerification = enabled
+	})
+}
+
+// WithNonceStore makes the provider reject a token whose nonce was already used until the token expires,
+// the replay protection is off by default
+func WithNonceStore(store ports.NonceStore) AppleProviderOption {
+	return appleOption(func(o *providerOptions) {
+		o.nonceStore = store
+	})
+}
+
+// WithRequiredScopes sets the scopes that must be granted in the token response
+func WithRequiredScopes(scopes ...string) GoogleProviderOption {
+	return googleOption(func(o *providerOptions) {
+		o.requiredScopes = scopes
+	})
+}
+
+// WithHashedNonce makes the provider compare the hex encoded SHA-256 of the raw nonce
+// against the token nonce claim, as documented by Apple
+func WithHashedNonce(hashed bool) AppleProviderOption {
+	return appleOption(func(o *providerOptions) {
+		o.hashedNonce = hashed
+	})
+}
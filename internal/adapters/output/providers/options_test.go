@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderOptions_SpecificOptionsAreScoped(t *testing.T) {
+	isGoogle := func(opt any) bool { _, ok := opt.(GoogleProviderOption); return ok }
+	isApple := func(opt any) bool { _, ok := opt.(AppleProviderOption); return ok }
+	isTwitch := func(opt any) bool { _, ok := opt.(TwitchProviderOption); return ok }
+	isEpic := func(opt any) bool { _, ok := opt.(EpicProviderOption); return ok }
+
+	shared := WithTimeout(0)
+	require.True(t, isGoogle(shared) && isApple(shared) && isTwitch(shared) && isEpic(shared))
+
+	for _, opt := range []any{WithHashedNonce(true), WithEmailVerification(true), WithNonceStore(nil)} {
+		require.True(t, isApple(opt))
+		require.False(t, isGoogle(opt) || isTwitch(opt) || isEpic(opt))
+	}
+
+	for _, opt := range []any{WithClockSkew(0), WithCertificatesCacheManager(nil)} {
+		require.True(t, isGoogle(opt) && isApple(opt) && isEpic(opt))
+		require.False(t, isTwitch(opt))
+	}
+
+	scopes := WithRequiredScopes("openid")
+	require.True(t, isGoogle(scopes))
+	require.False(t, isApple(scopes) || isTwitch(scopes) || isEpic(scopes))
+}
+
+func TestSharedOptions_AppliesToEveryProvider(t *testing.T) {
+	opts := []ProviderOption{WithRedirectPolicy(RedirectPolicySameHost), WithTimeout(7)}
+
+	o := newProviderOptions(sharedOptions[TwitchProviderOption](opts))
+	require.Equal(t, RedirectPolicySameHost, o.redirectPolicy)
+	require.EqualValues(t, 7, o.requestTimeout)
+}
@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/config"
+	"github.com/posilva/simpleidentity/pkg/logger"
+)
+
+// CredentialsRefresher periodically rebuilds the configured providers so rotated credentials are picked up
+// without a restart, the secret references are resolved again through the secrets provider
+type CredentialsRefresher struct {
+	cfg      *config.Config
+	factory  ports.AuthProviderFactory
+	interval time.Duration
+	logger   logger.Logger
+	opts     []BuildOption
+}
+
+// NewCredentialsRefresher creates a refresher swapping the providers of the factory every interval,
+// the options must be the ones the factory was built with, the certificates caches function is called again
+// on every refresh so it should return the caches it already created
+func NewCredentialsRefresher(cfg *config.Config, factory ports.AuthProviderFactory, interval time.Duration, logger logger.Logger, opts ...BuildOption) *CredentialsRefresher {
+	return &CredentialsRefresher{
+		cfg:      cfg,
+		factory:  factory,
+		interval: interval,
+		logger:   logger,
+		opts:     opts,
+	}
+}
+
+// Start refreshes the credentials every interval until the context is done,
+// a failed refresh is logged and the current providers are kept
+func (r *CredentialsRefresher) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Refresh(); err != nil {
+				r.logger.Error().Err(err).Msg("Failed to refresh provider credentials")
+				continue
+			}
+			r.logger.Debug().Msg("Provider credentials refreshed")
+		}
+	}
+}
+
+// Refresh rebuilds the providers from the configuration and replaces the registered ones,
+// nothing is replaced when a provider can't be built
+func (r *CredentialsRefresher) Refresh() error {
+	rebuilt, err := BuildFactoryFromConfig(r.cfg, r.opts...)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild providers: %w", err)
+	}
+
+	var errs []error
+	for _, providerType := range rebuilt.List() {
+		provider, err := rebuilt.Get(providerType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := r.factory.Replace(providerType, provider); err != nil {
+			errs = append(errs, fmt.Errorf("failed to replace %s provider: %w", providerType, err))
+		}
+	}
+	return errors.Join(errs...)
+}
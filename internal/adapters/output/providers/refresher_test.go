@@ -0,0 +1,85 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/pkg/config"
+	"github.com/posilva/simpleidentity/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func newRefresherTestConfig(t *testing.T) *config.Config {
+	t.Setenv("SMPIDT_EPIC_CLIENT_ID", "epic_client_id")
+	t.Setenv("SMPIDT_EPIC_CLIENT_SECRET", "secret://prod/identity/epic_client_secret")
+
+	cfg, err := config.NewManager().Load()
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestCredentialsRefresher_Refresh_SwapsRotatedCredentials(t *testing.T) {
+	cfg := newRefresherTestConfig(t)
+	secrets := fakeSecretsProvider{"prod/identity/epic_client_secret": "secret_v1"}
+
+	factory, err := BuildFactoryFromConfig(cfg, WithSecretsProvider(secrets))
+	require.NoError(t, err)
+	// an in flight request holds the provider it got before the rotation
+	inFlight, err := factory.Get(domain.ProviderTypeEpic)
+	require.NoError(t, err)
+
+	secrets["prod/identity/epic_client_secret"] = "secret_v2"
+	refresher := NewCredentialsRefresher(cfg, factory, time.Hour, logger.NewWithWriter(io.Discard, "error"), WithSecretsProvider(secrets))
+	require.NoError(t, refresher.Refresh())
+
+	current, err := factory.Get(domain.ProviderTypeEpic)
+	require.NoError(t, err)
+	require.Equal(t, "secret_v2", current.(*epicProvider).credentials.ClientSecret)
+	require.Equal(t, "secret_v1", inFlight.(*epicProvider).credentials.ClientSecret)
+}
+
+func TestCredentialsRefresher_Refresh_KeepsProvidersOnFailure(t *testing.T) {
+	cfg := newRefresherTestConfig(t)
+	secrets := fakeSecretsProvider{"prod/identity/epic_client_secret": "secret_v1"}
+
+	factory, err := BuildFactoryFromConfig(cfg, WithSecretsProvider(secrets))
+	require.NoError(t, err)
+
+	delete(secrets, "prod/identity/epic_client_secret")
+	refresher := NewCredentialsRefresher(cfg, factory, time.Hour, logger.NewWithWriter(io.Discard, "error"), WithSecretsProvider(secrets))
+	require.ErrorIs(t, refresher.Refresh(), domain.ErrSecretNotFound)
+
+	current, err := factory.Get(domain.ProviderTypeEpic)
+	require.NoError(t, err)
+	require.Equal(t, "secret_v1", current.(*epicProvider).credentials.ClientSecret)
+}
+
+func TestCredentialsRefresher_Start_RefreshesUntilDone(t *testing.T) {
+	cfg := newRefresherTestConfig(t)
+	secrets := fakeSecretsProvider{"prod/identity/epic_client_secret": "secret_v1"}
+
+	factory, err := BuildFactoryFromConfig(cfg, WithSecretsProvider(secrets))
+	require.NoError(t, err)
+
+	// the secrets are swapped before starting so the refresher goroutine does not race with the test
+	rotated := fakeSecretsProvider{"prod/identity/epic_client_secret": "secret_v2"}
+	refresher := NewCredentialsRefresher(cfg, factory, 10*time.Millisecond, logger.NewWithWriter(io.Discard, "error"), WithSecretsProvider(rotated))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		refresher.Start(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		current, err := factory.Get(domain.ProviderTypeEpic)
+		return err == nil && current.(*epicProvider).credentials.ClientSecret == "secret_v2"
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviders_RequiredFields_MatchAuthenticate(t *testing.T) {
+	// every provider endpoint fails so Authenticate stops right after checking the authentication data
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		provider ports.AuthProvider
+	}{
+		{name: "guest", provider: NewGuestProvider()},
+		{name: "google", provider: NewGoogleProvider(GoogleCredentials{AuthURI: srv.URL, CertsURL: srv.URL})},
+		{name: "apple", provider: NewAppleProvider(AppleCredentials{AuthTokensURL: srv.URL, CertsURL: srv.URL})},
+		{name: "epic", provider: NewEpicProvider(EpicCredentials{TokenURL: srv.URL, JWKSURL: srv.URL})},
+		{name: "twitch", provider: NewTwitchProvider(TwitchCredentials{TokenURL: srv.URL, ValidateURL: srv.URL})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			required := tt.provider.RequiredFields()
+			require.NotEmpty(t, required)
+
+			complete := make(map[string]string, len(required))
+			for _, field := range required {
+				complete[field] = "value"
+			}
+			_, err := tt.provider.Authenticate(context.Background(), complete)
+			require.NotErrorIs(t, err, domain.ErrMissingRequiredProviderAuthData)
+
+			for _, missing := range required {
+				data := make(map[string]string, len(complete))
+				for field, value := range complete {
+					if field != missing {
+						data[field] = value
+					}
+				}
+				_, err := tt.provider.Authenticate(context.Background(), data)
+				require.ErrorIs(t, err, domain.ErrMissingRequiredProviderAuthData, missing)
+			}
+		})
+	}
+}
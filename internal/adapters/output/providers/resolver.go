@@ -1 +1,35 @@
 package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// SecretReferencePrefix marks the credential values to resolve with the secrets provider, e.g. secret://name#key
+const SecretReferencePrefix = "secret://"
+
+// secretsResolveTimeout bounds the time spent resolving the secret references at startup
+const secretsResolveTimeout = 30 * time.Second
+
+// resolveSecrets replaces in place the fields holding a secret reference with the value it points to
+func resolveSecrets(ctx context.Context, secrets ports.SecretsProvider, fields ...*string) error {
+	for _, field := range fields {
+		ref, ok := strings.CutPrefix(*field, SecretReferencePrefix)
+		if !ok {
+			continue
+		}
+		if secrets == nil {
+			return fmt.Errorf("secret reference %s requires a secrets provider", *field)
+		}
+		value, err := secrets.Get(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("failed to resolve secret reference %s: %w", *field, err)
+		}
+		*field = value
+	}
+	return nil
+}
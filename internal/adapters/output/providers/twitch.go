@@ -0,0 +1,189 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// References:
+// https://dev.twitch.tv/docs/authentication/getting-tokens-oauth/#authorization-code-grant-flow
+// https://dev.twitch.tv/docs/authentication/validate-tokens/
+
+const (
+	TwitchAuthCodeFieldName = "code"
+)
+
+// TwitchCredentials holds the Twitch application credentials and endpoints
+type TwitchCredentials struct {
+	ClientID     string
+	ClientSecret string
+	TokenURL     string
+	ValidateURL  string
+	// RedirectURI must match the redirect URI registered in the Twitch application and used to obtain the code
+	RedirectURI string
+}
+
+// Validate checks the required credentials are set and the URLs are valid
+func (c TwitchCredentials) Validate() error {
+	return errors.Join(
+		requireFields(
+			credentialField{"ClientID", c.ClientID},
+			credentialField{"ClientSecret", c.ClientSecret},
+			credentialField{"TokenURL", c.TokenURL},
+			credentialField{"ValidateURL", c.ValidateURL},
+			credentialField{"RedirectURI", c.RedirectURI},
+		),
+		requireURLs(
+			credentialField{"TokenURL", c.TokenURL},
+			credentialField{"ValidateURL", c.ValidateURL},
+			credentialField{"RedirectURI", c.RedirectURI},
+		),
+	)
+}
+
+type twitchProvider struct {
+	providerOptions
+	credentials TwitchCredentials
+}
+
+// Safeguard check to ensure twitchProvider implements the AuthProvider interface
+var _ ports.AuthProvider = (*twitchProvider)(nil)
+
+type twitchAuthResult struct {
+	ID      string
+	Profile domain.ProviderProfile
+}
+
+// Safeguard check to ensure twitchAuthResult implements the AuthResult interface
+var _ ports.AuthResult = (*twitchAuthResult)(nil)
+
+func (r *twitchAuthResult) GetID() string {
+	return r.ID
+}
+
+func (r *twitchAuthResult) GetProfile() domain.ProviderProfile {
+	return r.Profile
+}
+
+type twitchValidateResponse struct {
+	ClientID  string   `json:"client_id"`
+	Login     string   `json:"login"`
+	Scopes    []string `json:"scopes"`
+	UserID    string   `json:"user_id"`
+	ExpiresIn int64    `json:"expires_in"`
+}
+
+// NewTwitchProvider creates a new Twitch provider
+func NewTwitchProvider(credentials TwitchCredentials, opts ...TwitchProviderOption) ports.AuthProvider {
+	return &twitchProvider{
+		providerOptions: newProviderOptions(opts),
+		credentials:     credentials,
+	}
+}
+
+// RequiredFields returns the authorization code field
+func (p *twitchProvider) RequiredFields() []string {
+	return []string{TwitchAuthCodeFieldName}
+}
+
+// Authenticate exchanges the authorization code for an access token and validates it with Twitch
+func (p *twitchProvider) Authenticate(ctx context.Context, data map[string]string) (ports.AuthResult, error) {
+	code, ok := data[TwitchAuthCodeFieldName]
+	if !ok {
+		return nil, fmt.Errorf("missing required field %s: %w", TwitchAuthCodeFieldName, domain.ErrMissingRequiredProviderAuthData)
+	}
+
+	tokenResp, err := p.exchangeAuthCode(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange auth code: %w", err)
+	}
+
+	validation, err := p.validateAccessToken(ctx, tokenResp.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate access token: %w", err)
+	}
+	if validation.ClientID != p.credentials.ClientID {
+		return nil, fmt.Errorf("client_id mismatch: %w", domain.ErrTokenInvalidAudience)
+	}
+	if validation.UserID == "" {
+		return nil, fmt.Errorf("missing user_id: %w", domain.ErrTokenInvalidClaims)
+	}
+
+	return &twitchAuthResult{
+		ID:      validation.UserID,
+		Profile: domain.ProviderProfile{DisplayName: validation.Login},
+	}, nil
+}
+
+func (p *twitchProvider) exchangeAuthCode(ctx context.Context, code string) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Add("code", code)
+	form.Add("client_id", p.credentials.ClientID)
+	form.Add("client_secret", p.credentials.ClientSecret)
+	form.Add("redirect_uri", p.credentials.RedirectURI)
+	form.Add("grant_type", "authorization_code")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.credentials.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post to token endpoint: %w: %w", domain.ErrProviderUnavailable, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		var body bytes.Buffer
+		_, _ = body.ReadFrom(resp.Body)
+		return nil, fmt.Errorf("token exchange failed: %s: %w", body.String(), statusCodeError(resp.StatusCode))
+	}
+
+	var tokenResp tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	return &tokenResp, nil
+}
+
+// validateAccessToken calls the Twitch validate endpoint, an invalid or expired token is rejected with 401
+func (p *twitchProvider) validateAccessToken(ctx context.Context, accessToken string) (*twitchValidateResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.credentials.ValidateURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create validate request: %w", err)
+	}
+	req.Header.Set("Authorization", "OAuth "+accessToken)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call validate endpoint: %w: %w", domain.ErrProviderUnavailable, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		var body bytes.Buffer
+		_, _ = body.ReadFrom(resp.Body)
+		return nil, fmt.Errorf("token validation failed: %s: %w", body.String(), statusCodeError(resp.StatusCode))
+	}
+
+	var validation twitchValidateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&validation); err != nil {
+		return nil, fmt.Errorf("failed to decode validate response: %w", err)
+	}
+	return &validation, nil
+}
@@ -0,0 +1,115 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTwitchClientID    = "twitch_client_id"
+	testTwitchAccessToken = "twitch_access_token"
+	testTwitchLogin       = "streamer"
+	testTwitchRedirectURI = "https://game.example.com/auth/twitch"
+)
+
+func newTwitchTestServer(t *testing.T, validate http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("code") != "valid_code" || r.PostForm.Get("redirect_uri") != testTwitchRedirectURI {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status":400,"message":"Invalid authorization code"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(tokenResponse{AccessToken: testTwitchAccessToken, TokenType: "bearer"})
+	})
+	mux.HandleFunc("/validate", validate)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func twitchValidateHandler(clientID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "OAuth "+testTwitchAccessToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"status":401,"message":"invalid access token"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(twitchValidateResponse{
+			ClientID:  clientID,
+			Login:     testTwitchLogin,
+			UserID:    testSubject,
+			ExpiresIn: 3600,
+		})
+	}
+}
+
+func newTestTwitchProvider(ts *httptest.Server) *twitchProvider {
+	return NewTwitchProvider(TwitchCredentials{
+		ClientID:     testTwitchClientID,
+		ClientSecret: "twitch_client_secret",
+		TokenURL:     ts.URL + "/token",
+		ValidateURL:  ts.URL + "/validate",
+		RedirectURI:  testTwitchRedirectURI,
+	}).(*twitchProvider)
+}
+
+func TestProviderTwitch_Returns_TwitchAuthResult(t *testing.T) {
+	ts := newTwitchTestServer(t, twitchValidateHandler(testTwitchClientID))
+	p := newTestTwitchProvider(ts)
+
+	res, err := p.Authenticate(context.Background(), map[string]string{TwitchAuthCodeFieldName: "valid_code"})
+	require.NoError(t, err)
+	require.Equal(t, testSubject, res.GetID())
+	require.Equal(t, testTwitchLogin, res.GetProfile().DisplayName)
+}
+
+func TestProviderTwitch_Returns_ErrorOnClientIDMismatch(t *testing.T) {
+	ts := newTwitchTestServer(t, twitchValidateHandler("another_client_id"))
+	p := newTestTwitchProvider(ts)
+
+	res, err := p.Authenticate(context.Background(), map[string]string{TwitchAuthCodeFieldName: "valid_code"})
+	require.ErrorIs(t, err, domain.ErrTokenInvalidAudience)
+	require.Nil(t, res)
+}
+
+func TestProviderTwitch_Returns_ErrorOnInvalidToken(t *testing.T) {
+	ts := newTwitchTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"status":401,"message":"invalid access token"}`))
+	})
+	p := newTestTwitchProvider(ts)
+
+	res, err := p.Authenticate(context.Background(), map[string]string{TwitchAuthCodeFieldName: "valid_code"})
+	require.ErrorIs(t, err, domain.ErrTokenInvalidClaims)
+	require.Nil(t, res)
+}
+
+func TestProviderTwitch_Returns_ErrorOnRejectedCode(t *testing.T) {
+	ts := newTwitchTestServer(t, twitchValidateHandler(testTwitchClientID))
+	p := newTestTwitchProvider(ts)
+
+	_, err := p.Authenticate(context.Background(), map[string]string{TwitchAuthCodeFieldName: "expired_code"})
+	require.ErrorIs(t, err, domain.ErrTokenInvalidClaims)
+
+	_, err = p.Authenticate(context.Background(), map[string]string{})
+	require.ErrorIs(t, err, domain.ErrMissingRequiredProviderAuthData)
+}
+
+func TestProviderTwitch_Returns_ProviderUnavailable(t *testing.T) {
+	ts := newTwitchTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	p := newTestTwitchProvider(ts)
+
+	_, err := p.Authenticate(context.Background(), map[string]string{TwitchAuthCodeFieldName: "valid_code"})
+	require.ErrorIs(t, err, domain.ErrProviderUnavailable)
+}
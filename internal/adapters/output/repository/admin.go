@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// Limits of the accounts listing page size
+const (
+	DefaultListAccountsLimit = 25
+	MaxListAccountsLimit     = 100
+)
+
+// Safeguard check to ensure dynamoDBAccountsRepository implements the AccountsAdminRepository interface
+var _ ports.AccountsAdminRepository = (*dynamoDBAccountsRepository)(nil)
+
+// NewDynamoDBAccountsAdminRepository creates the admin repository over the same table as the accounts repository.
+func NewDynamoDBAccountsAdminRepository(client DynamoDBAPI, tableName string, opts ...RepositoryOption) ports.AccountsAdminRepository {
+	return newDynamoDBAccountsRepository(client, tableName, nil, opts...)
+}
+
+// ListAccounts returns a page of the accounts of the tenant in the context and the cursor of the next page,
+// an empty cursor means there are no more pages. The limit is capped to MaxListAccountsLimit and defaults to
+// DefaultListAccountsLimit when not positive.
+// NOTE: it uses a Scan over the whole table, the limit bounds the items read per call so a page
+// can hold fewer accounts than the limit (even none) while the cursor is still set. The records of the
+// last account of the page are completed with a Query so an account is never split across pages.
+func (r *dynamoDBAccountsRepository) ListAccounts(ctx context.Context, limit int, cursor string) ([]domain.Account, string, error) {
+	if limit <= 0 {
+		limit = DefaultListAccountsLimit
+	}
+	limit = min(limit, MaxListAccountsLimit)
+
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filter := expression.Name(r.table.PartitionKeyName).BeginsWith(tenantPK(ctx, r.table.AccountKeyPrefix))
+	expr, err := expression.NewBuilder().WithFilter(filter).Build()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build expression: %w", err)
+	}
+
+	result, err := r.client.Scan(ctx, &dynamodb.ScanInput{
+		TableName:                 aws.String(r.tableName),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		Limit:                     aws.Int32(int32(limit)),
+		ExclusiveStartKey:         startKey,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to scan DynamoDB: %w", err)
+	}
+
+	items := result.Items
+	nextKey := result.LastEvaluatedKey
+	if len(nextKey) > 0 && len(items) > 0 {
+		// the scan may have stopped in the middle of the last account partition
+		last := items[len(items)-1]
+		rest, err := r.queryPartition(ctx, stringAttribute(last, r.table.PartitionKeyName), r.recordKey(last))
+		if err != nil {
+			return nil, "", err
+		}
+		if len(rest) > 0 {
+			items = append(items, rest...)
+			nextKey = r.recordKey(rest[len(rest)-1])
+		}
+	}
+
+	accounts, err := r.groupAccounts(items)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next, err := encodeCursor(nextKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return accounts, next, nil
+}
+
+// groupAccounts builds the accounts from the items, the items of a partition are contiguous in the results
+func (r *dynamoDBAccountsRepository) groupAccounts(items []map[string]types.AttributeValue) ([]domain.Account, error) {
+	accounts := make([]domain.Account, 0)
+	for start := 0; start < len(items); {
+		pk := stringAttribute(items[start], r.table.PartitionKeyName)
+		end := start + 1
+		for end < len(items) && stringAttribute(items[end], r.table.PartitionKeyName) == pk {
+			end++
+		}
+		records, err := r.unmarshalRecords(items[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			// the table TTL deletes the records of an expired guest one at a time, its metadata may outlive them briefly
+			start = end
+			continue
+		}
+		account, err := accountFromRecords(records)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, *account)
+		start = end
+	}
+	return accounts, nil
+}
+
+// recordKey returns the primary key of the item
+func (r *dynamoDBAccountsRepository) recordKey(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		r.table.PartitionKeyName: item[r.table.PartitionKeyName],
+		r.table.SortKeyName:      item[r.table.SortKeyName],
+	}
+}
+
+// stringAttribute returns the value of a string attribute, empty when missing or not a string
+func stringAttribute(item map[string]types.AttributeValue, name string) string {
+	if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
+
+// encodeCursor encodes the last evaluated key as an opaque cursor, the table keys are strings
+func encodeCursor(key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	values := make(map[string]string, len(key))
+	for name, value := range key {
+		s, ok := value.(*types.AttributeValueMemberS)
+		if !ok {
+			return "", fmt.Errorf("unexpected non string key attribute %s in last evaluated key", name)
+		}
+		values[name] = s.Value
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor decodes a cursor returned by encodeCursor, an empty cursor starts from the beginning
+func decodeCursor(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidCursor, err)
+	}
+	var values map[string]string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidCursor, err)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: empty key", domain.ErrInvalidCursor)
+	}
+	key := make(map[string]types.AttributeValue, len(values))
+	for name, value := range values {
+		key[name] = &types.AttributeValueMemberS{Value: value}
+	}
+	return key, nil
+}
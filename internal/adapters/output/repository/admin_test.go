@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ovechkin-dm/mockio/v2/mock"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func accountItem(accountID string, providerType domain.ProviderType, providerID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK":           &types.AttributeValueMemberS{Value: "ACNT#" + accountID},
+		"SK":           &types.AttributeValueMemberS{Value: "PVDR#" + string(providerType) + "#" + providerID},
+		"AccountID":    &types.AttributeValueMemberS{Value: accountID},
+		"ProviderType": &types.AttributeValueMemberS{Value: string(providerType)},
+		"ProviderID":   &types.AttributeValueMemberS{Value: providerID},
+		"DateCreated":  &types.AttributeValueMemberS{Value: "2023-10-01T00:00:00Z"},
+	}
+}
+
+func itemKey(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]}
+}
+
+func TestDynamoDBAccountsRepository_ListAccounts_PagesWithCursor(t *testing.T) {
+	ctx := context.Background()
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+
+	account1 := accountItem("account_1", domain.ProviderTypeGuest, "guest_1")
+	account2Guest := accountItem("account_2", domain.ProviderTypeGuest, "guest_2")
+	account2Google := accountItem("account_2", domain.ProviderTypeGoogle, "google_2")
+	account3 := accountItem("account_3", domain.ProviderTypeGuest, "guest_3")
+
+	var scans []*dynamodb.ScanInput
+	mock.WhenDouble(clientMock.Scan(mock.Any[context.Context](), mock.Any[*dynamodb.ScanInput]())).ThenAnswer(func(args []any) (*dynamodb.ScanOutput, error) {
+		input := args[1].(*dynamodb.ScanInput)
+		scans = append(scans, input)
+		if input.ExclusiveStartKey == nil {
+			// the page stops in the middle of the second account
+			return &dynamodb.ScanOutput{
+				Items:            []map[string]types.AttributeValue{account1, account2Guest},
+				LastEvaluatedKey: itemKey(account2Guest),
+			}, nil
+		}
+		return &dynamodb.ScanOutput{
+			Items: []map[string]types.AttributeValue{account3},
+		}, nil
+	})
+	queryCaptor := mock.Captor[*dynamodb.QueryInput]()
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), queryCaptor.Capture())).ThenReturn(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{account2Google},
+	}, nil)
+
+	repo := NewDynamoDBAccountsAdminRepository(clientMock, "accounts_test")
+
+	accounts, cursor, err := repo.ListAccounts(ctx, 2, "")
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.Equal(t, domain.AccountID("account_1"), accounts[0].ID)
+	require.Equal(t, domain.AccountID("account_2"), accounts[1].ID)
+	require.Len(t, accounts[1].Providers, 2)
+	require.Equal(t, "google_2", accounts[1].Providers[1].ProviderID)
+	require.NotEmpty(t, cursor)
+	require.Equal(t, itemKey(account2Guest), queryCaptor.Last().ExclusiveStartKey)
+
+	accounts, cursor, err = repo.ListAccounts(ctx, 2, cursor)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, domain.AccountID("account_3"), accounts[0].ID)
+	require.Empty(t, cursor)
+
+	require.Len(t, scans, 2)
+	require.Equal(t, aws.Int32(2), scans[0].Limit)
+	// the next page starts after the last record of the completed account
+	require.Equal(t, itemKey(account2Google), scans[1].ExclusiveStartKey)
+}
+
+func TestDynamoDBAccountsRepository_ListAccounts_CapsLimit(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	captor := mock.Captor[*dynamodb.ScanInput]()
+	mock.WhenDouble(clientMock.Scan(mock.Any[context.Context](), captor.Capture())).ThenReturn(&dynamodb.ScanOutput{}, nil)
+
+	repo := NewDynamoDBAccountsAdminRepository(clientMock, "accounts_test")
+
+	_, _, err := repo.ListAccounts(context.Background(), 10_000, "")
+	require.NoError(t, err)
+	require.Equal(t, int32(MaxListAccountsLimit), *captor.Last().Limit)
+
+	_, _, err = repo.ListAccounts(context.Background(), 0, "")
+	require.NoError(t, err)
+	require.Equal(t, int32(DefaultListAccountsLimit), *captor.Last().Limit)
+}
+
+func TestDynamoDBAccountsRepository_ListAccounts_RejectsInvalidCursor(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	repo := NewDynamoDBAccountsAdminRepository(clientMock, "accounts_test")
+
+	for _, cursor := range []string{"not base64!", "bm90LWpzb24", "e30"} {
+		t.Run(cursor, func(t *testing.T) {
+			accounts, next, err := repo.ListAccounts(context.Background(), 10, cursor)
+			require.ErrorIs(t, err, domain.ErrInvalidCursor)
+			require.Nil(t, accounts)
+			require.Empty(t, next)
+		})
+	}
+}
@@ -5,16 +5,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/posilva/simpleidentity/internal/adapters/output/idgen"
 	"github.com/posilva/simpleidentity/internal/core/domain"
 	"github.com/posilva/simpleidentity/internal/core/ports"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // Constants for DynamoDB table and index names
@@ -22,27 +27,43 @@ const (
 	TablePKName                = "PK"
 	TableSKName                = "SK"
 	AccountIdentitySKName      = "IDENTITY"
+	AccountMetadataSKName      = "PROFILE"
 	AccountProviderPKPrefixFmt = "ACNT#%s"
 	AccountProviderSKPrefixFmt = "PVDR#%s#%s"
+	TenantPKPrefixFmt          = "TNT#%s#"
+	AccountKeyPrefix           = "ACNT#"
+	ProviderKeyPrefix          = "PVDR#"
+	// ProviderIndexName is the GSI keyed by the provider identity of the account records
+	ProviderIndexName   = "GSI1"
+	ProviderIndexPKName = "GSI1PK"
+	ProviderIndexSKName = "GSI1SK"
 )
 
 // errTransactionErrorConditionFailed is an internal error
 var errTransactionErrorConditionFailed = errors.New("transaction error ConditionalCheckFailed")
 
-// DDBAccountProviderRecordData represents the data of an account provider record in DynamoDB.
+// ddbAccountProviderRecordData represents the data of an account provider record in DynamoDB.
 // We use ISO8601 format for date strings to facilitate reading dates in DynamoDB, as this format also sorts correctly.
-type DDBAccountProviderRecordData struct {
+type ddbAccountProviderRecordData struct {
 	AccountID          string `dynamodbav:"AccountID"`
 	ProviderType       string `dynamodbav:"ProviderType"`
 	ProviderID         string `dynamodbav:"ProviderID"`
 	DateCreatedISO8601 string `dynamodbav:"DateCreated"`
+	// Profile subset captured at creation, avatar URLs are not stored as providers rotate them
+	DisplayName string `dynamodbav:"DisplayName,omitempty"`
+	Locale      string `dynamodbav:"Locale,omitempty"`
+	// ExpiresAt is the epoch in seconds used by the table TTL to delete abandoned guest accounts
+	ExpiresAt int64 `dynamodbav:"ExpiresAt,omitempty"`
 }
 
-// DDBAccountProviderRecord represents an account provider record in DynamoDB with primary key of the table and GSI
-type DDBAccountProviderRecord struct {
-	DDBAccountProviderRecordData
+// ddbAccountProviderRecord represents an account provider record in DynamoDB with primary key of the table and GSI
+type ddbAccountProviderRecord struct {
+	ddbAccountProviderRecordData
 	PK string `dynamodbav:"PK"`
 	SK string `dynamodbav:"SK"`
+	// GSI keys are only set in the account records so the identity records stay out of the index
+	GSI1PK string `dynamodbav:"GSI1PK,omitempty"`
+	GSI1SK string `dynamodbav:"GSI1SK,omitempty"`
 }
 
 // DynamoDBAPI defines the interface for DynamoDB operations to make it easy to mock in tests as suggested in the docs
@@ -51,165 +72,491 @@ type DDBAccountProviderRecord struct {
 type DynamoDBAPI interface {
 	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
 	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 }
 
 // dynamoDBAccountsRepository implements the AccountsRepository interface for DynamoDB.
 type dynamoDBAccountsRepository struct {
-	tableName   string
-	idGenerator ports.IDGenerator
-	client      DynamoDBAPI
+	tableName string
+	table     TableConfig
+	// providerIndex is the GSI used to resolve provider identities, empty reads the identity records
+	providerIndex string
+	retryPolicy   RetryPolicy
+	guestTTL      time.Duration
+	meter         metric.Meter
+	tracer        trace.Tracer
+	idGenerator   ports.IDGenerator
+	client        DynamoDBAPI
+	// consistentReads makes the base table reads strongly consistent
+	consistentReads bool
+}
+
+// RepositoryOption configures optional settings of the DynamoDB accounts repository
+type RepositoryOption func(*dynamoDBAccountsRepository)
+
+// WithTableConfig sets the key and attribute names used in the table
+func WithTableConfig(c TableConfig) RepositoryOption {
+	return func(r *dynamoDBAccountsRepository) {
+		r.table = c.withDefaults()
+	}
+}
+
+// WithProviderIndex resolves the provider identities through the given GSI of the account records instead of
+// the identity records, the index is eventually consistent so an identity created right before may not be found yet
+func WithProviderIndex(indexName string) RepositoryOption {
+	return func(r *dynamoDBAccountsRepository) {
+		r.providerIndex = indexName
+	}
+}
+
+// WithConsistentReads makes the reads of the base table strongly consistent so an account is resolved right
+// after it was created, at twice the read capacity cost. A GSI can't be read consistently, so with
+// WithProviderIndex an identity missing from the index is looked up again in the base table identity records.
+// A single call gets the same reads with a context from domain.ContextWithConsistentRead
+func WithConsistentReads(enabled bool) RepositoryOption {
+	return func(r *dynamoDBAccountsRepository) {
+		r.consistentReads = enabled
+	}
+}
+
+// WithTableKeys sets the key names and prefixes used in the table keeping the configured attribute names
+func WithTableKeys(k TableKeys) RepositoryOption {
+	return func(r *dynamoDBAccountsRepository) {
+		c := r.table
+		c.PartitionKeyName = k.PartitionKeyName
+		c.SortKeyName = k.SortKeyName
+		c.AccountKeyPrefix = k.AccountKeyPrefix
+		c.ProviderKeyPrefix = k.ProviderKeyPrefix
+		r.table = c.withDefaults()
+	}
 }
 
 // Safeguard check to ensure dynamoDBAccountsRepository implements the AccountsRepository interface
 var _ ports.AccountsRepository = (*dynamoDBAccountsRepository)(nil)
 
 // NewDynamoDBAccountsRepositoryWithIDGenerator creates a new instance of DynamoDBAccountsRepository with a custom ID generator.
-func NewDynamoDBAccountsRepositoryWithIDGenerator(client DynamoDBAPI, tableName string, idGenerator ports.IDGenerator) ports.AccountsRepository {
-	return &dynamoDBAccountsRepository{
+func NewDynamoDBAccountsRepositoryWithIDGenerator(client DynamoDBAPI, tableName string, idGenerator ports.IDGenerator, opts ...RepositoryOption) ports.AccountsRepository {
+	return newDynamoDBAccountsRepository(client, tableName, idGenerator, opts...)
+}
+
+// NewDynamoDBAccountsRepository creates a new instance of DynamoDBAccountsRepository.
+func NewDynamoDBAccountsRepository(client DynamoDBAPI, tableName string, opts ...RepositoryOption) ports.AccountsRepository {
+	return NewDynamoDBAccountsRepositoryWithIDGenerator(client, tableName, idgen.NewKSUIDGenerator(), opts...)
+}
+
+// newDynamoDBAccountsRepository applies the options and wraps the client with the instrumentation and retries
+func newDynamoDBAccountsRepository(client DynamoDBAPI, tableName string, idGenerator ports.IDGenerator, opts ...RepositoryOption) *dynamoDBAccountsRepository {
+	r := &dynamoDBAccountsRepository{
 		tableName:   tableName,
+		table:       DefaultTableConfig(),
+		retryPolicy: DefaultRetryPolicy(),
 		idGenerator: idGenerator,
 		client:      client,
 	}
-}
-
-// NewDynamoDBAccountsRepository creates a new instance of DynamoDBAccountsRepository.
-func NewDynamoDBAccountsRepository(client DynamoDBAPI, tableName string) ports.AccountsRepository {
-	return NewDynamoDBAccountsRepositoryWithIDGenerator(client, tableName, idgen.NewKSUIDGenerator())
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.meter != nil || r.tracer != nil {
+		if r.tracer == nil {
+			r.tracer = otel.Tracer(instrumentationScope)
+		}
+		r.client = newInstrumentedClient(r.client, r.meter, r.tracer)
+	} else {
+		r.tracer = noop.NewTracerProvider().Tracer(instrumentationScope)
+	}
+	r.client = newRetryingClient(r.client, r.retryPolicy)
+	return r
 }
 
 // ResolveIDByProvider resolves the account ID by provider type and provider ID.
 // If the account does not exist, it returns an error indicating that the account was not found
-func (r *dynamoDBAccountsRepository) ResolveIDByProvider(ctx context.Context, providerType domain.ProviderType, providerID string) (domain.AccountID, error) {
+func (r *dynamoDBAccountsRepository) ResolveIDByProvider(ctx context.Context, providerType domain.ProviderType, providerID string) (_ domain.AccountID, err error) {
+	ctx, span := r.startSpan(ctx, "ResolveIDByProvider", providerType)
+	defer func() { endSpan(span, err) }()
+
+	record, err := r.resolveIdentityRecord(ctx, providerType, providerID)
+	if err != nil {
+		return domain.EmptyAccountID, err
+	}
+	return domain.AccountID(record.AccountID), nil
+}
+
+// ResolveAccountByProvider resolves the account with every linked provider by provider type and provider ID,
+// it reads the identity record like ResolveIDByProvider and then the account records
+func (r *dynamoDBAccountsRepository) ResolveAccountByProvider(ctx context.Context, providerType domain.ProviderType, providerID string) (_ *domain.Account, err error) {
+	ctx, span := r.startSpan(ctx, "ResolveAccountByProvider", providerType)
+	defer func() { endSpan(span, err) }()
+
+	record, err := r.resolveIdentityRecord(ctx, providerType, providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	pk := tenantPK(ctx, r.table.accountKey(domain.AccountID(record.AccountID)))
+	items, err := r.queryPartition(ctx, pk, nil)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int(ReturnedRowsAttributeKey, len(items)))
+	records, err := r.unmarshalRecords(items)
+	if err != nil {
+		return nil, err
+	}
+	return accountFromRecords(records)
+}
+
+// queryPartition reads every item of the partition after the exclusive start key, a nil key reads from the start
+func (r *dynamoDBAccountsRepository) queryPartition(ctx context.Context, pk string, startKey map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	keyCond := expression.Key(r.table.PartitionKeyName).Equal(expression.Value(pk))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build expression: %w", err)
+	}
+
+	var items []map[string]types.AttributeValue
+	for {
+		result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(r.tableName),
+			KeyConditionExpression:    expr.KeyCondition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ExclusiveStartKey:         startKey,
+			ConsistentRead:            r.consistentRead(ctx),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query DynamoDB: %w", err)
+		}
+		items = append(items, result.Items...)
+		if len(result.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
+}
+
+// unmarshalRecords unmarshals the DynamoDB items into the record data, the metadata record of the account is skipped
+func (r *dynamoDBAccountsRepository) unmarshalRecords(items []map[string]types.AttributeValue) ([]ddbAccountProviderRecordData, error) {
+	records := make([]ddbAccountProviderRecordData, 0, len(items))
+	for _, item := range items {
+		if stringAttribute(item, r.table.SortKeyName) == AccountMetadataSKName {
+			continue
+		}
+		var record ddbAccountProviderRecordData
+		if err := r.table.unmarshalRecordData(item, &record); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal DynamoDB items: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// resolveIdentityRecord reads the identity record of the provider identity,
+// or the account record through the provider index when configured
+func (r *dynamoDBAccountsRepository) resolveIdentityRecord(ctx context.Context, providerType domain.ProviderType, providerID string) (*ddbAccountProviderRecordData, error) {
+	record, err := r.queryIdentityRecord(ctx, providerType, providerID, r.providerIndex)
+	if errors.Is(err, domain.ErrAccountNotFound) && r.providerIndex != "" && r.consistentRead(ctx) != nil {
+		// the index may not have caught up with a just created account yet
+		return r.queryIdentityRecord(ctx, providerType, providerID, "")
+	}
+	return record, err
+}
+
+// queryIdentityRecord reads the identity record of the provider identity from the base table,
+// or the account record through the given index when not empty
+func (r *dynamoDBAccountsRepository) queryIdentityRecord(ctx context.Context, providerType domain.ProviderType, providerID string, index string) (*ddbAccountProviderRecordData, error) {
 	// Resolve the account ID by provider type and provider ID using dynamoDB operations.
 	// use go sdk v2 query builder to query the DynamoDB table
 
-	pk := fmt.Sprintf(AccountProviderSKPrefixFmt, providerType, providerID)
-	sk := AccountIdentitySKName
-	pkExp := expression.Key(TablePKName).Equal(expression.Value(pk))
-	skExp := expression.Key(TableSKName).Equal(expression.Value(sk))
+	pk := tenantPK(ctx, r.table.providerKey(providerType, providerID))
+	keyCond := expression.Key(r.table.PartitionKeyName).Equal(expression.Value(pk)).
+		And(expression.Key(r.table.SortKeyName).Equal(expression.Value(AccountIdentitySKName)))
+	var indexName *string
+	consistentRead := r.consistentRead(ctx)
+	if index != "" {
+		keyCond = expression.Key(r.table.ProviderIndexPKName).Equal(expression.Value(pk))
+		indexName = aws.String(index)
+		// a GSI can't be read consistently
+		consistentRead = nil
+	}
 
-	expr, err := expression.NewBuilder().WithKeyCondition(pkExp.And(skExp)).Build()
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
 	if err != nil {
-		return "", fmt.Errorf("failed to build expression: %w", err)
+		return nil, fmt.Errorf("failed to build expression: %w", err)
 	}
 
 	input := &dynamodb.QueryInput{
 		TableName:                 aws.String(r.tableName),
+		IndexName:                 indexName,
 		KeyConditionExpression:    expr.KeyCondition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
+		ConsistentRead:            consistentRead,
 	}
 
 	result, err := r.client.Query(ctx, input)
 	if err != nil {
-		return domain.EmptyAccountID, fmt.Errorf("failed to query DynamoDB: %w", err)
+		return nil, fmt.Errorf("failed to query DynamoDB: %w", err)
 	}
+	// the callers run in their own repository span
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int(ReturnedRowsAttributeKey, len(result.Items)))
 	if len(result.Items) == 0 {
-		return domain.EmptyAccountID, domain.ErrAccountNotFound
+		return nil, domain.ErrAccountNotFound
 	}
 
 	if len(result.Items) > 1 {
 		// in the future we may consider to just pick the first one, but for now we will return an error
 		// as we cannot ensure the order of the items in the result this could lead to unexpected behavior
 		// hard to debug
-		return domain.EmptyAccountID, fmt.Errorf("unexpected multiple accounts found for provider type %s and provider ID %s", providerType, providerID)
+		return nil, fmt.Errorf("unexpected multiple accounts found for provider type %s and provider ID %s", providerType, providerID)
 	}
 
-	record := &DDBAccountProviderRecordData{}
-	if err := attributevalue.UnmarshalMap(result.Items[0], record); err != nil {
-		return domain.EmptyAccountID, fmt.Errorf("failed to unmarshal DynamoDB items: %w", err)
+	record := &ddbAccountProviderRecordData{}
+	if err := r.table.unmarshalRecordData(result.Items[0], record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal DynamoDB items: %w", err)
 	}
+	return record, nil
+}
 
-	return domain.AccountID(record.AccountID), nil
+// consistentRead returns the ConsistentRead setting of the base table reads, nil keeps the DynamoDB default.
+// The reads are consistent when the repository is configured so or the context asks for it
+func (r *dynamoDBAccountsRepository) consistentRead(ctx context.Context) *bool {
+	if !r.consistentReads && !domain.ConsistentReadFromContext(ctx) {
+		return nil
+	}
+	return aws.Bool(true)
 }
 
 // Create creates a new account in DynamoDB using the provider type and provider ID.
 // It returns the newly created account ID or an error if the creation fails.
 func (r *dynamoDBAccountsRepository) Create(ctx context.Context, providerType domain.ProviderType, providerID string) (domain.AccountID, error) {
-	accountID := r.idGenerator.GenerateID()
+	return r.CreateWithProfile(ctx, providerType, providerID, domain.ProviderProfile{})
+}
+
+// CreateWithProfile creates a new account like Create storing a subset of the provider profile along with it.
+func (r *dynamoDBAccountsRepository) CreateWithProfile(ctx context.Context, providerType domain.ProviderType, providerID string, profile domain.ProviderProfile) (_ domain.AccountID, err error) {
+	ctx, span := r.startSpan(ctx, "Create", providerType)
+	defer func() { endSpan(span, err) }()
+
+	accountID := domain.AccountID(r.idGenerator.GenerateID())
+
+	items, err := r.providerRecordsWriteItems(ctx, accountID, domain.ProviderIdentity{ProviderType: providerType, ProviderID: providerID}, profile, r.expiresAt(providerType))
+	if err != nil {
+		return domain.EmptyAccountID, err
+	}
+	span.SetAttributes(attribute.Int(BatchSizeAttributeKey, len(items)))
+
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		tErr := enrichErrorWithOperationContext(err, []string{"PUT Provider Identity data", "PUT Account data"})
+		if errors.Is(tErr, errTransactionErrorConditionFailed) {
+			tErr = domain.ErrProviderIDOrAccountAlreadyExists
+		}
+		return domain.EmptyAccountID, fmt.Errorf("failed to execute transaction when creating account: %w", tErr)
+	}
 
+	return accountID, nil
+}
+
+// LinkProvider links a provider identity to an existing account.
+// It returns ErrProviderIDOrAccountAlreadyExists if the identity is already linked to any account
+// and ErrAccountNotFound if the account does not exist
+func (r *dynamoDBAccountsRepository) LinkProvider(ctx context.Context, accountID domain.AccountID, identity domain.ProviderIdentity) error {
+	accountItem, err := r.findAccountRecord(ctx, tenantPK(ctx, r.table.accountKey(accountID)))
+	if err != nil {
+		return fmt.Errorf("failed to link provider: %w", err)
+	}
+
+	items, err := r.providerRecordsWriteItems(ctx, accountID, identity, domain.ProviderProfile{}, 0)
+	if err != nil {
+		return err
+	}
+	operations := []string{"PUT Provider Identity data", "PUT Account data"}
+
+	// a linked guest must no longer expire
+	var clearItems []types.TransactWriteItem
+	if r.guestTTL > 0 {
+		var clearOperations []string
+		clearItems, clearOperations, err = r.clearExpiryWriteItems(ctx, accountID)
+		if err != nil {
+			return err
+		}
+		operations = append(operations, clearOperations...)
+	}
+	// the expiry updates are conditioned on the records existence, a transaction can't check the same record twice
+	if !updatesKey(clearItems, r.recordKey(accountItem)) {
+		checkItem, err := r.accountExistsCheckItem(accountItem)
+		if err != nil {
+			return err
+		}
+		items = append(items, checkItem)
+		operations = append(operations[:2], append([]string{"CHECK Account data"}, operations[2:]...)...)
+	}
+	items = append(items, clearItems...)
+
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		tErr := enrichErrorWithOperationContext(err, operations)
+		if errors.Is(tErr, errTransactionErrorConditionFailed) {
+			tErr = domain.ErrProviderIDOrAccountAlreadyExists
+			// only the puts of the new records fail when the identity exists, the other items fail when the account is gone
+			if cancelledItemIndex(err) >= 2 {
+				tErr = domain.ErrAccountNotFound
+			}
+		}
+		return fmt.Errorf("failed to execute transaction when linking provider: %w", tErr)
+	}
+
+	return nil
+}
+
+// accountExistsCheckItem builds the transaction item checking the account record still exists
+func (r *dynamoDBAccountsRepository) accountExistsCheckItem(accountItem map[string]types.AttributeValue) (types.TransactWriteItem, error) {
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeExists(expression.Name(r.table.PartitionKeyName))).
+		Build()
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("failed to build account expression: %w", err)
+	}
+	return types.TransactWriteItem{
+		ConditionCheck: &types.ConditionCheck{
+			TableName:                aws.String(r.tableName),
+			Key:                      r.recordKey(accountItem),
+			ConditionExpression:      expr.Condition(),
+			ExpressionAttributeNames: expr.Names(),
+		},
+	}, nil
+}
+
+// updatesKey reports whether one of the items updates the record with the key
+func updatesKey(items []types.TransactWriteItem, key map[string]types.AttributeValue) bool {
+	for _, item := range items {
+		if item.Update != nil && reflect.DeepEqual(item.Update.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelledItemIndex returns the index of the item that cancelled the transaction, -1 when unknown
+func cancelledItemIndex(err error) int {
+	var transactionCancelledErr *types.TransactionCanceledException
+	if !errors.As(err, &transactionCancelledErr) {
+		return -1
+	}
+	for i, reason := range transactionCancelledErr.CancellationReasons {
+		if reason.Code != nil && *reason.Code != "None" {
+			return i
+		}
+	}
+	return -1
+}
+
+// LinkBatch links each provider identity to an existing account and reports the outcome per identity,
+// a failed link does not stop the remaining ones. It only returns an error if the context is done,
+// in which case the results contain the identities processed so far
+func (r *dynamoDBAccountsRepository) LinkBatch(ctx context.Context, accountID domain.AccountID, identities []domain.ProviderIdentity) ([]domain.LinkResult, error) {
+	results := make([]domain.LinkResult, 0, len(identities))
+	for _, identity := range identities {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("failed to link providers batch: %w", err)
+		}
+		results = append(results, domain.LinkResult{
+			Identity: identity,
+			Err:      r.LinkProvider(ctx, accountID, identity),
+		})
+	}
+	return results, nil
+}
+
+// providerRecordsWriteItems builds the transaction items that store a provider identity record and
+// the account provider record, both conditioned to not exist yet, a non zero expiresAt is stored in both records
+func (r *dynamoDBAccountsRepository) providerRecordsWriteItems(ctx context.Context, accountID domain.AccountID, identity domain.ProviderIdentity, profile domain.ProviderProfile, expiresAt int64) ([]types.TransactWriteItem, error) {
 	identityCond := expression.And(
-		expression.AttributeNotExists(expression.Name(TablePKName)),
-		expression.AttributeNotExists(expression.Name(TableSKName)),
+		expression.AttributeNotExists(expression.Name(r.table.PartitionKeyName)),
+		expression.AttributeNotExists(expression.Name(r.table.SortKeyName)),
 	)
 
-	data := DDBAccountProviderRecordData{
-		AccountID:          accountID,
-		ProviderType:       string(providerType),
-		ProviderID:         providerID,
+	data := ddbAccountProviderRecordData{
+		AccountID:          string(accountID),
+		ProviderType:       string(identity.ProviderType),
+		ProviderID:         identity.ProviderID,
 		DateCreatedISO8601: time.Now().UTC().Format(time.RFC3339),
+		DisplayName:        profile.DisplayName,
+		Locale:             profile.Locale,
+		ExpiresAt:          expiresAt,
 	}
 
-	identityRecord := DDBAccountProviderRecord{
-		PK:                           fmt.Sprintf(AccountProviderSKPrefixFmt, providerType, providerID),
+	identityRecord := ddbAccountProviderRecord{
+		PK:                           tenantPK(ctx, r.table.providerKey(identity.ProviderType, identity.ProviderID)),
 		SK:                           AccountIdentitySKName,
-		DDBAccountProviderRecordData: data,
+		ddbAccountProviderRecordData: data,
 	}
 	identityExpr, err := expression.NewBuilder().
 		WithCondition(identityCond).
 		Build()
 	if err != nil {
-		return domain.EmptyAccountID, fmt.Errorf("failed to build identity expression: %w", err)
+		return nil, fmt.Errorf("failed to build identity expression: %w", err)
 	}
 
-	identityItem, err := attributevalue.MarshalMap(identityRecord)
+	identityItem, err := r.table.marshalRecord(identityRecord)
 	if err != nil {
-		return domain.EmptyAccountID, fmt.Errorf("failed to marshal identity record: %w", err)
+		return nil, fmt.Errorf("failed to marshal identity record: %w", err)
 	}
 
 	accountCond := expression.And(
-		expression.AttributeNotExists(expression.Name(TablePKName)),
-		expression.AttributeNotExists(expression.Name(TableSKName)),
+		expression.AttributeNotExists(expression.Name(r.table.PartitionKeyName)),
+		expression.AttributeNotExists(expression.Name(r.table.SortKeyName)),
 	)
 
 	accountExpr, err := expression.NewBuilder().WithCondition(accountCond).Build()
 	if err != nil {
-		return domain.EmptyAccountID, fmt.Errorf("failed to build account expression: %w", err)
+		return nil, fmt.Errorf("failed to build account expression: %w", err)
 	}
 
-	accountRecord := DDBAccountProviderRecord{
-		PK:                           fmt.Sprintf(AccountProviderPKPrefixFmt, accountID),
-		SK:                           fmt.Sprintf(AccountProviderSKPrefixFmt, providerType, providerID),
-		DDBAccountProviderRecordData: data,
+	accountRecord := ddbAccountProviderRecord{
+		PK:                           tenantPK(ctx, r.table.accountKey(accountID)),
+		SK:                           r.table.providerKey(identity.ProviderType, identity.ProviderID),
+		GSI1PK:                       tenantPK(ctx, r.table.providerKey(identity.ProviderType, identity.ProviderID)),
+		GSI1SK:                       r.table.accountKey(accountID),
+		ddbAccountProviderRecordData: data,
 	}
 
-	accountItem, err := attributevalue.MarshalMap(accountRecord)
+	accountItem, err := r.table.marshalRecord(accountRecord)
 	if err != nil {
-		return domain.EmptyAccountID, fmt.Errorf("failed to marshal account record: %w", err)
+		return nil, fmt.Errorf("failed to marshal account record: %w", err)
 	}
-	input := &dynamodb.TransactWriteItemsInput{
-		TransactItems: []types.TransactWriteItem{
-			{
-				Put: &types.Put{
-					TableName:                 aws.String(r.tableName),
-					Item:                      identityItem,
-					ConditionExpression:       identityExpr.Condition(),
-					ExpressionAttributeNames:  identityExpr.Names(),
-					ExpressionAttributeValues: identityExpr.Values(),
-				},
+
+	return []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName:                 aws.String(r.tableName),
+				Item:                      identityItem,
+				ConditionExpression:       identityExpr.Condition(),
+				ExpressionAttributeNames:  identityExpr.Names(),
+				ExpressionAttributeValues: identityExpr.Values(),
 			},
-			{
-				Put: &types.Put{
-					TableName:                 aws.String(r.tableName),
-					Item:                      accountItem,
-					ConditionExpression:       accountExpr.Condition(),
-					ExpressionAttributeNames:  accountExpr.Names(),
-					ExpressionAttributeValues: accountExpr.Values(),
-				},
+		},
+		{
+			Put: &types.Put{
+				TableName:                 aws.String(r.tableName),
+				Item:                      accountItem,
+				ConditionExpression:       accountExpr.Condition(),
+				ExpressionAttributeNames:  accountExpr.Names(),
+				ExpressionAttributeValues: accountExpr.Values(),
 			},
 		},
-	}
+	}, nil
+}
 
-	_, err = r.client.TransactWriteItems(ctx, input)
-	if err != nil {
-		tErr := enrichErrorWithOperationContext(err, []string{"PUT Provider Identity data", "PUT Account data"})
-		if errors.Is(tErr, errTransactionErrorConditionFailed) {
-			tErr = domain.ErrProviderIDOrAccountAlreadyExists
-		}
-		return domain.EmptyAccountID, fmt.Errorf("failed to execute transaction when creating account: %w", tErr)
+// tenantPK prefixes the partition key with the tenant carried by the context,
+// the default tenant keeps the key unchanged
+func tenantPK(ctx context.Context, pk string) string {
+	tenantID := domain.TenantIDFromContext(ctx)
+	if tenantID == domain.DefaultTenantID {
+		return pk
 	}
-
-	return domain.AccountID(accountID), nil
+	return fmt.Sprintf(TenantPKPrefixFmt, tenantID) + pk
 }
 
 // enrichErrorWithOperationContext extracts transaction related error from the SDK error
@@ -230,10 +577,7 @@ func enrichErrorWithOperationContext(err error, operations []string) error {
 					}
 
 					// custom sentinel errors to allow to bubble up the error with a specific semantic
-					err = fmt.Errorf("transaction error %s", *reason.Code)
-					if *reason.Code == "ConditionalCheckFailed" {
-						err = errTransactionErrorConditionFailed
-					}
+					err = cancellationReasonError(*reason.Code)
 					return fmt.Errorf("operation: %s, index: %d, reason: %s: %w",
 						operationName, i, reasonStr, err)
 				}
@@ -244,6 +588,22 @@ func enrichErrorWithOperationContext(err error, operations []string) error {
 	return err
 }
 
+// cancellationReasonError maps a transaction cancellation reason code to a sentinel error
+func cancellationReasonError(code string) error {
+	switch code {
+	case "ConditionalCheckFailed":
+		return errTransactionErrorConditionFailed
+	case "ThrottlingError", "ProvisionedThroughputExceeded", "RequestLimitExceeded":
+		return fmt.Errorf("transaction error %s: %w", code, domain.ErrThrottled)
+	case "TransactionConflict":
+		return fmt.Errorf("transaction error %s: %w", code, domain.ErrTransactionConflict)
+	case "ValidationError":
+		return fmt.Errorf("transaction error %s: %w", code, domain.ErrValidation)
+	default:
+		return fmt.Errorf("transaction error %s", code)
+	}
+}
+
 func ListWrappedErrors(err error) []error {
 	var chain []error
 	for err != nil {
@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/ovechkin-dm/mockio/v2/mock"
@@ -45,6 +47,171 @@ func TestDynamoDBAccountsRepository_ResolveIDByProvider_ReturnsAccountID(t *test
 	require.NotEqual(t, accountID, domain.EmptyAccountID)
 }
 
+func TestDynamoDBAccountsRepository_WithProviderIndex_QueriesIndex(t *testing.T) {
+	ctx := domain.ContextWithTenantID(context.Background(), "game-1")
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	captor := mock.Captor[*dynamodb.QueryInput]()
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), captor.Capture())).ThenReturn(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{
+			{
+				"AccountID":    &types.AttributeValueMemberS{Value: "account_id"},
+				"ProviderType": &types.AttributeValueMemberS{Value: string(domain.ProviderTypeGuest)},
+				"ProviderID":   &types.AttributeValueMemberS{Value: "guest_id"},
+				"DateCreated":  &types.AttributeValueMemberS{Value: "2023-10-01T00:00:00Z"},
+			},
+		},
+	}, nil)
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test", WithProviderIndex(ProviderIndexName))
+	accountID, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, "guest_id")
+	require.NoError(t, err)
+	require.Equal(t, domain.AccountID("account_id"), accountID)
+
+	input := captor.Last()
+	require.Equal(t, ProviderIndexName, aws.ToString(input.IndexName))
+	require.Equal(t, map[string]string{"#0": ProviderIndexPKName}, input.ExpressionAttributeNames)
+	require.Equal(t, map[string]types.AttributeValue{
+		":0": &types.AttributeValueMemberS{Value: "TNT#game-1#PVDR#guest#guest_id"},
+	}, input.ExpressionAttributeValues)
+}
+
+func TestDynamoDBAccountsRepository_WithConsistentReads(t *testing.T) {
+	ctx := context.Background()
+	identity := map[string]types.AttributeValue{
+		"AccountID":    &types.AttributeValueMemberS{Value: "account_id"},
+		"ProviderType": &types.AttributeValueMemberS{Value: string(domain.ProviderTypeGuest)},
+		"ProviderID":   &types.AttributeValueMemberS{Value: "guest_id"},
+		"DateCreated":  &types.AttributeValueMemberS{Value: "2023-10-01T00:00:00Z"},
+	}
+
+	t.Run("base table reads are consistent", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		clientMock := mock.Mock[DynamoDBAPI](ctrl)
+		captor := mock.Captor[*dynamodb.QueryInput]()
+		mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), captor.Capture())).ThenReturn(&dynamodb.QueryOutput{
+			Items: []map[string]types.AttributeValue{identity},
+		}, nil)
+
+		repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test", WithConsistentReads(true))
+		accountID, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, "guest_id")
+		require.NoError(t, err)
+		require.Equal(t, domain.AccountID("account_id"), accountID)
+		require.True(t, aws.ToBool(captor.Last().ConsistentRead))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		clientMock := mock.Mock[DynamoDBAPI](ctrl)
+		captor := mock.Captor[*dynamodb.QueryInput]()
+		mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), captor.Capture())).ThenReturn(&dynamodb.QueryOutput{
+			Items: []map[string]types.AttributeValue{identity},
+		}, nil)
+
+		repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+		_, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, "guest_id")
+		require.NoError(t, err)
+		require.Nil(t, captor.Last().ConsistentRead)
+	})
+
+	t.Run("index miss falls back to the base table", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		clientMock := mock.Mock[DynamoDBAPI](ctrl)
+		captor := mock.Captor[*dynamodb.QueryInput]()
+		mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), captor.Capture())).
+			ThenReturn(&dynamodb.QueryOutput{}, nil).
+			ThenReturn(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{identity}}, nil)
+
+		repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test", WithProviderIndex(ProviderIndexName), WithConsistentReads(true))
+		accountID, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, "guest_id")
+		require.NoError(t, err)
+		require.Equal(t, domain.AccountID("account_id"), accountID)
+
+		inputs := captor.Values()
+		require.Len(t, inputs, 2)
+		require.Equal(t, ProviderIndexName, aws.ToString(inputs[0].IndexName))
+		require.Nil(t, inputs[0].ConsistentRead)
+		require.Nil(t, inputs[1].IndexName)
+		require.True(t, aws.ToBool(inputs[1].ConsistentRead))
+	})
+}
+
+func TestDynamoDBAccountsRepository_Create_SetsProviderIndexKeysOnAccountRecord(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	idGeneratorMock := mock.Mock[ports.IDGenerator](ctrl)
+	mock.WhenSingle(idGeneratorMock.GenerateID()).ThenReturn("account_id")
+	captor := mock.Captor[*dynamodb.TransactWriteItemsInput]()
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), captor.Capture())).ThenReturn(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepositoryWithIDGenerator(clientMock, "accounts_test", idGeneratorMock)
+	_, err := repo.Create(context.Background(), domain.ProviderTypeGuest, "guest_id")
+	require.NoError(t, err)
+
+	items := captor.Last().TransactItems
+	identityItem, accountItem := items[0].Put.Item, items[1].Put.Item
+	require.NotContains(t, identityItem, ProviderIndexPKName)
+	require.NotContains(t, identityItem, ProviderIndexSKName)
+	require.Equal(t, &types.AttributeValueMemberS{Value: "PVDR#guest#guest_id"}, accountItem[ProviderIndexPKName])
+	require.Equal(t, &types.AttributeValueMemberS{Value: "ACNT#account_id"}, accountItem[ProviderIndexSKName])
+}
+
+func TestDynamoDBAccountsRepository_ResolveAccountByProvider_ReturnsAccount(t *testing.T) {
+	ctx := context.Background()
+	aid := idgen.NewKSUIDGenerator().GenerateID()
+	record := func(pk string, sk string, providerType domain.ProviderType, providerID string, dateCreated string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"PK":           &types.AttributeValueMemberS{Value: pk},
+			"SK":           &types.AttributeValueMemberS{Value: sk},
+			"AccountID":    &types.AttributeValueMemberS{Value: aid},
+			"ProviderType": &types.AttributeValueMemberS{Value: string(providerType)},
+			"ProviderID":   &types.AttributeValueMemberS{Value: providerID},
+			"DateCreated":  &types.AttributeValueMemberS{Value: dateCreated},
+		}
+	}
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenAnswer(func(args []any) (*dynamodb.QueryOutput, error) {
+		input := args[1].(*dynamodb.QueryInput)
+		// the identity record lookup has a sort key condition while the account records query has not
+		if _, ok := input.ExpressionAttributeValues[":1"]; ok {
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{
+				record("PVDR#google#google_id", AccountIdentitySKName, domain.ProviderTypeGoogle, "google_id", "2023-10-02T00:00:00Z"),
+			}}, nil
+		}
+		require.Equal(t, &types.AttributeValueMemberS{Value: "ACNT#" + aid}, input.ExpressionAttributeValues[":0"])
+		return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{
+			record("ACNT#"+aid, "PVDR#google#google_id", domain.ProviderTypeGoogle, "google_id", "2023-10-02T00:00:00Z"),
+			record("ACNT#"+aid, "PVDR#guest#guest_id", domain.ProviderTypeGuest, "guest_id", "2023-10-01T00:00:00Z"),
+		}}, nil
+	})
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+	account, err := repo.ResolveAccountByProvider(ctx, domain.ProviderTypeGoogle, "google_id")
+	require.NoError(t, err)
+	require.Equal(t, &domain.Account{
+		ID: domain.AccountID(aid),
+		Providers: []domain.LinkedProvider{
+			{Type: domain.ProviderTypeGoogle, ProviderID: "google_id", LinkedAt: time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC)},
+			{Type: domain.ProviderTypeGuest, ProviderID: "guest_id", LinkedAt: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		CreatedAt: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+	}, account)
+}
+
+func TestDynamoDBAccountsRepository_ResolveAccountByProvider_ReturnsErrAccountNotFound(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenReturn(&dynamodb.QueryOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+	account, err := repo.ResolveAccountByProvider(context.Background(), domain.ProviderTypeGoogle, "google_id")
+	require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	require.Nil(t, account)
+}
+
 func TestDynamoDBAccountsRepository_CreateIdentity_ReturnsAccountID(t *testing.T) {
 	ctx := context.Background()
 	providerType := domain.ProviderTypeGuest
@@ -65,3 +232,412 @@ func TestDynamoDBAccountsRepository_CreateIdentity_ReturnsAccountID(t *testing.T
 	require.NotEqual(t, accountID, domain.EmptyAccountID)
 	require.NoError(t, err)
 }
+
+func TestDynamoDBAccountsRepository_LinkBatch_ReportsPerItemResults(t *testing.T) {
+	ctx := context.Background()
+	accountID := domain.AccountID(idgen.NewKSUIDGenerator().GenerateID())
+	tableName := "accounts_test"
+
+	identities := []domain.ProviderIdentity{
+		{ProviderType: domain.ProviderTypeGoogle, ProviderID: "google_id"},
+		{ProviderType: domain.ProviderTypeApple, ProviderID: "already_linked_apple_id"},
+		{ProviderType: domain.ProviderTypeFacebook, ProviderID: "facebook_id"},
+	}
+	conflictingPK := "PVDR#apple#already_linked_apple_id"
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenReturn(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{linkTestAccountItem(accountID)},
+	}, nil)
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())).ThenAnswer(func(args []any) (*dynamodb.TransactWriteItemsOutput, error) {
+		input := args[1].(*dynamodb.TransactWriteItemsInput)
+		pk := input.TransactItems[0].Put.Item[TablePKName].(*types.AttributeValueMemberS).Value
+		if pk == conflictingPK {
+			return nil, &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("ConditionalCheckFailed")},
+					{Code: aws.String("None")},
+				},
+			}
+		}
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	})
+
+	repo := NewDynamoDBAccountsRepository(clientMock, tableName)
+	results, err := repo.LinkBatch(ctx, accountID, identities)
+
+	require.NoError(t, err)
+	require.Len(t, results, len(identities))
+	for i, result := range results {
+		require.Equal(t, identities[i], result.Identity)
+	}
+	require.NoError(t, results[0].Err)
+	require.ErrorIs(t, results[1].Err, domain.ErrProviderIDOrAccountAlreadyExists)
+	require.NoError(t, results[2].Err)
+}
+
+// linkTestAccountItem returns the account record of an account linked to a Google identity
+func linkTestAccountItem(accountID domain.AccountID) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK":           &types.AttributeValueMemberS{Value: "ACNT#" + string(accountID)},
+		"SK":           &types.AttributeValueMemberS{Value: "PVDR#google#existing_google_id"},
+		"AccountID":    &types.AttributeValueMemberS{Value: string(accountID)},
+		"ProviderType": &types.AttributeValueMemberS{Value: string(domain.ProviderTypeGoogle)},
+		"ProviderID":   &types.AttributeValueMemberS{Value: "existing_google_id"},
+		"DateCreated":  &types.AttributeValueMemberS{Value: "2023-10-01T00:00:00Z"},
+	}
+}
+
+func TestDynamoDBAccountsRepository_LinkProvider_ChecksAccountExists(t *testing.T) {
+	ctx := context.Background()
+	accountID := domain.AccountID("account_id")
+	identity := domain.ProviderIdentity{ProviderType: domain.ProviderTypeApple, ProviderID: "apple_id"}
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	queryCaptor := mock.Captor[*dynamodb.QueryInput]()
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), queryCaptor.Capture())).ThenReturn(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{linkTestAccountItem(accountID)},
+	}, nil)
+	captor := mock.Captor[*dynamodb.TransactWriteItemsInput]()
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), captor.Capture())).ThenReturn(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+	require.NoError(t, repo.LinkProvider(ctx, accountID, identity))
+
+	require.True(t, aws.ToBool(queryCaptor.Last().ConsistentRead))
+	items := captor.Last().TransactItems
+	require.Len(t, items, 3)
+	check := items[2].ConditionCheck
+	require.NotNil(t, check)
+	require.Equal(t, map[string]types.AttributeValue{
+		TablePKName: &types.AttributeValueMemberS{Value: "ACNT#account_id"},
+		TableSKName: &types.AttributeValueMemberS{Value: "PVDR#google#existing_google_id"},
+	}, check.Key)
+	require.Contains(t, aws.ToString(check.ConditionExpression), "attribute_exists")
+}
+
+func TestDynamoDBAccountsRepository_LinkProvider_ReturnsAccountNotFound(t *testing.T) {
+	ctx := context.Background()
+	identity := domain.ProviderIdentity{ProviderType: domain.ProviderTypeApple, ProviderID: "apple_id"}
+
+	t.Run("no account record", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		clientMock := mock.Mock[DynamoDBAPI](ctrl)
+		mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenReturn(&dynamodb.QueryOutput{}, nil)
+
+		repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+		err := repo.LinkProvider(ctx, "unknown_id", identity)
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+		mock.Verify(clientMock, mock.Never()).TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())
+	})
+
+	t.Run("account deleted meanwhile", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		clientMock := mock.Mock[DynamoDBAPI](ctrl)
+		mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenReturn(&dynamodb.QueryOutput{
+			Items: []map[string]types.AttributeValue{linkTestAccountItem("account_id")},
+		}, nil)
+		mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())).ThenReturn(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+			},
+		})
+
+		repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+		err := repo.LinkProvider(ctx, "account_id", identity)
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+		require.NotErrorIs(t, err, domain.ErrProviderIDOrAccountAlreadyExists)
+	})
+}
+
+func TestDynamoDBAccountsRepository_LinkBatch_StopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+	results, err := repo.LinkBatch(ctx, domain.AccountID("account_id"), []domain.ProviderIdentity{
+		{ProviderType: domain.ProviderTypeGoogle, ProviderID: "google_id"},
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Empty(t, results)
+	mock.VerifyNoMoreInteractions(clientMock)
+}
+
+func TestDynamoDBAccountsRepository_CreateWithProfile_StoresProfileSubset(t *testing.T) {
+	ctx := context.Background()
+	aid := idgen.NewKSUIDGenerator().GenerateID()
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	idGeneratorMock := mock.Mock[ports.IDGenerator](ctrl)
+	mock.WhenSingle(idGeneratorMock.GenerateID()).ThenReturn(aid)
+
+	captor := mock.Captor[*dynamodb.TransactWriteItemsInput]()
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), captor.Capture())).ThenReturn(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepositoryWithIDGenerator(clientMock, "accounts_test", idGeneratorMock)
+	accountID, err := repo.CreateWithProfile(ctx, domain.ProviderTypeGoogle, "google_id", domain.ProviderProfile{
+		DisplayName: "Player One",
+		AvatarURL:   "https://example.com/player01.png",
+		Locale:      "pt-PT",
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.AccountID(aid), accountID)
+
+	for _, item := range captor.Last().TransactItems {
+		require.Equal(t, &types.AttributeValueMemberS{Value: "Player One"}, item.Put.Item["DisplayName"])
+		require.Equal(t, &types.AttributeValueMemberS{Value: "pt-PT"}, item.Put.Item["Locale"])
+		require.NotContains(t, item.Put.Item, "AvatarURL")
+	}
+}
+
+func TestDynamoDBAccountsRepository_WithTableConfig_UsesCustomNames(t *testing.T) {
+	ctx := context.Background()
+	aid := idgen.NewKSUIDGenerator().GenerateID()
+	tableConfig := TableConfig{
+		PartitionKeyName:       "pk",
+		SortKeyName:            "sk",
+		AccountIDAttributeName: "account_id",
+	}
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	idGeneratorMock := mock.Mock[ports.IDGenerator](ctrl)
+	mock.WhenSingle(idGeneratorMock.GenerateID()).ThenReturn(aid)
+
+	queryCaptor := mock.Captor[*dynamodb.QueryInput]()
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), queryCaptor.Capture())).ThenReturn(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{
+			{
+				"pk":           &types.AttributeValueMemberS{Value: "PVDR#guest#guest_id"},
+				"sk":           &types.AttributeValueMemberS{Value: AccountIdentitySKName},
+				"account_id":   &types.AttributeValueMemberS{Value: aid},
+				"ProviderType": &types.AttributeValueMemberS{Value: "guest"},
+				"ProviderID":   &types.AttributeValueMemberS{Value: "guest_id"},
+			},
+		},
+	}, nil)
+	transactCaptor := mock.Captor[*dynamodb.TransactWriteItemsInput]()
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), transactCaptor.Capture())).ThenReturn(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepositoryWithIDGenerator(clientMock, "accounts_test", idGeneratorMock, WithTableConfig(tableConfig))
+
+	accountID, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, "guest_id")
+	require.NoError(t, err)
+	require.Equal(t, domain.AccountID(aid), accountID)
+	require.ElementsMatch(t, []string{"pk", "sk"}, mapValues(queryCaptor.Last().ExpressionAttributeNames))
+
+	_, err = repo.Create(ctx, domain.ProviderTypeGuest, "guest_id")
+	require.NoError(t, err)
+	for _, item := range transactCaptor.Last().TransactItems {
+		require.Contains(t, item.Put.Item, "pk")
+		require.Contains(t, item.Put.Item, "sk")
+		require.Contains(t, item.Put.Item, "account_id")
+		require.Contains(t, item.Put.Item, ProviderTypeAttributeName)
+		require.NotContains(t, item.Put.Item, TablePKName)
+		require.NotContains(t, item.Put.Item, AccountIDAttributeName)
+		require.ElementsMatch(t, []string{"pk", "sk"}, mapValues(item.Put.ExpressionAttributeNames))
+	}
+}
+
+func TestDynamoDBAccountsRepository_WithTableKeys_UsesCustomKeys(t *testing.T) {
+	ctx := context.Background()
+	keys := TableKeys{
+		PartitionKeyName:  "pk",
+		SortKeyName:       "sk",
+		AccountKeyPrefix:  "USER#",
+		ProviderKeyPrefix: "IDP#",
+	}
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	idGeneratorMock := mock.Mock[ports.IDGenerator](ctrl)
+	mock.WhenSingle(idGeneratorMock.GenerateID()).ThenReturn("account_id")
+
+	queryCaptor := mock.Captor[*dynamodb.QueryInput]()
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), queryCaptor.Capture())).ThenReturn(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{
+			{
+				"pk":        &types.AttributeValueMemberS{Value: "IDP#guest#guest_id"},
+				"sk":        &types.AttributeValueMemberS{Value: AccountIdentitySKName},
+				"AccountID": &types.AttributeValueMemberS{Value: "account_id"},
+			},
+		},
+	}, nil)
+	transactCaptor := mock.Captor[*dynamodb.TransactWriteItemsInput]()
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), transactCaptor.Capture())).ThenReturn(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepositoryWithIDGenerator(clientMock, "accounts_test", idGeneratorMock, WithTableKeys(keys))
+
+	_, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, "guest_id")
+	require.NoError(t, err)
+	query := queryCaptor.Last()
+	require.ElementsMatch(t, []string{"pk", "sk"}, mapValues(query.ExpressionAttributeNames))
+	require.Equal(t, &types.AttributeValueMemberS{Value: "IDP#guest#guest_id"}, query.ExpressionAttributeValues[":0"])
+
+	_, err = repo.Create(ctx, domain.ProviderTypeGuest, "guest_id")
+	require.NoError(t, err)
+	items := transactCaptor.Last().TransactItems
+	require.Equal(t, &types.AttributeValueMemberS{Value: "IDP#guest#guest_id"}, items[0].Put.Item["pk"])
+	require.Equal(t, &types.AttributeValueMemberS{Value: "USER#account_id"}, items[1].Put.Item["pk"])
+	require.Equal(t, &types.AttributeValueMemberS{Value: "IDP#guest#guest_id"}, items[1].Put.Item["sk"])
+	for _, item := range items {
+		require.NotContains(t, item.Put.Item, TablePKName)
+		require.Contains(t, item.Put.Item, AccountIDAttributeName)
+		require.ElementsMatch(t, []string{"pk", "sk"}, mapValues(item.Put.ExpressionAttributeNames))
+	}
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+func TestEnrichErrorWithOperationContext_MapsCancellationReasons(t *testing.T) {
+	operations := []string{"PUT Provider Identity data", "PUT Account data"}
+	tests := []struct {
+		code    string
+		wantErr error
+	}{
+		{code: "ConditionalCheckFailed", wantErr: errTransactionErrorConditionFailed},
+		{code: "ThrottlingError", wantErr: domain.ErrThrottled},
+		{code: "ProvisionedThroughputExceeded", wantErr: domain.ErrThrottled},
+		{code: "TransactionConflict", wantErr: domain.ErrTransactionConflict},
+		{code: "ValidationError", wantErr: domain.ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			err := enrichErrorWithOperationContext(&types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("None")},
+					{Code: aws.String(tt.code), Message: aws.String("reason message")},
+				},
+			}, operations)
+
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Contains(t, err.Error(), "operation: PUT Account data, index: 1")
+			require.Contains(t, err.Error(), tt.code+": reason message")
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		err := enrichErrorWithOperationContext(&types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ItemCollectionSizeLimitExceeded")}},
+		}, operations)
+
+		for _, sentinel := range []error{errTransactionErrorConditionFailed, domain.ErrThrottled, domain.ErrTransactionConflict, domain.ErrValidation} {
+			require.NotErrorIs(t, err, sentinel)
+		}
+		require.Contains(t, err.Error(), "operation: PUT Provider Identity data, index: 0")
+	})
+}
+
+func TestDynamoDBAccountsRepository_Tenants_DoNotCollide(t *testing.T) {
+	providerType := domain.ProviderTypeGuest
+	providerID := "same_provider_id"
+	ctxA := domain.ContextWithTenantID(context.Background(), "game-a")
+	ctxB := domain.ContextWithTenantID(context.Background(), "game-b")
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+
+	// identities keeps the identity records written by the transactions indexed by partition key
+	identities := map[string]map[string]types.AttributeValue{}
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())).ThenAnswer(func(args []any) (*dynamodb.TransactWriteItemsOutput, error) {
+		item := args[1].(*dynamodb.TransactWriteItemsInput).TransactItems[0].Put.Item
+		identities[item[TablePKName].(*types.AttributeValueMemberS).Value] = item
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	})
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenAnswer(func(args []any) (*dynamodb.QueryOutput, error) {
+		output := &dynamodb.QueryOutput{}
+		for _, v := range args[1].(*dynamodb.QueryInput).ExpressionAttributeValues {
+			if item, ok := identities[v.(*types.AttributeValueMemberS).Value]; ok {
+				output.Items = append(output.Items, item)
+			}
+		}
+		return output, nil
+	})
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+
+	accountA, err := repo.Create(ctxA, providerType, providerID)
+	require.NoError(t, err)
+	require.Contains(t, identities, "TNT#game-a#PVDR#guest#same_provider_id")
+
+	_, err = repo.ResolveIDByProvider(ctxB, providerType, providerID)
+	require.ErrorIs(t, err, domain.ErrAccountNotFound)
+
+	accountB, err := repo.Create(ctxB, providerType, providerID)
+	require.NoError(t, err)
+	require.NotEqual(t, accountA, accountB)
+
+	resolvedA, err := repo.ResolveIDByProvider(ctxA, providerType, providerID)
+	require.NoError(t, err)
+	require.Equal(t, accountA, resolvedA)
+
+	resolvedB, err := repo.ResolveIDByProvider(ctxB, providerType, providerID)
+	require.NoError(t, err)
+	require.Equal(t, accountB, resolvedB)
+
+	_, err = repo.ResolveIDByProvider(context.Background(), providerType, providerID)
+	require.ErrorIs(t, err, domain.ErrAccountNotFound)
+}
+
+func TestDynamoDBAccountsRepository_ResolveIDByProvider_ConsistentReadFromContext(t *testing.T) {
+	identity := map[string]types.AttributeValue{
+		"AccountID":    &types.AttributeValueMemberS{Value: "account_id"},
+		"ProviderType": &types.AttributeValueMemberS{Value: string(domain.ProviderTypeGuest)},
+		"ProviderID":   &types.AttributeValueMemberS{Value: "guest_id"},
+		"DateCreated":  &types.AttributeValueMemberS{Value: "2023-10-01T00:00:00Z"},
+	}
+	// the eventually consistent reads have not seen the account created right before yet
+	query := func(args []any) (*dynamodb.QueryOutput, error) {
+		if !aws.ToBool(args[1].(*dynamodb.QueryInput).ConsistentRead) {
+			return &dynamodb.QueryOutput{}, nil
+		}
+		return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{identity}}, nil
+	}
+
+	t.Run("base table", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		clientMock := mock.Mock[DynamoDBAPI](ctrl)
+		mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenAnswer(query)
+
+		repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+		_, err := repo.ResolveIDByProvider(context.Background(), domain.ProviderTypeGuest, "guest_id")
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+
+		accountID, err := repo.ResolveIDByProvider(domain.ContextWithConsistentRead(context.Background()), domain.ProviderTypeGuest, "guest_id")
+		require.NoError(t, err)
+		require.Equal(t, domain.AccountID("account_id"), accountID)
+	})
+
+	t.Run("index miss falls back to the base table", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		clientMock := mock.Mock[DynamoDBAPI](ctrl)
+		captor := mock.Captor[*dynamodb.QueryInput]()
+		mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), captor.Capture())).ThenAnswer(query)
+
+		repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test", WithProviderIndex(ProviderIndexName))
+		accountID, err := repo.ResolveIDByProvider(domain.ContextWithConsistentRead(context.Background()), domain.ProviderTypeGuest, "guest_id")
+		require.NoError(t, err)
+		require.Equal(t, domain.AccountID("account_id"), accountID)
+
+		inputs := captor.Values()
+		require.Len(t, inputs, 2)
+		require.Equal(t, ProviderIndexName, aws.ToString(inputs[0].IndexName))
+		require.Nil(t, inputs[1].IndexName)
+	})
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/posilva/simpleidentity/pkg/health"
+)
+
+// tableHealthCheckTimeout bounds the table description of a health check
+const tableHealthCheckTimeout = 5 * time.Second
+
+// TableDescriber defines the DynamoDB operation used by the table health check
+type TableDescriber interface {
+	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+}
+
+// DynamoDBHealthCheck returns a health check failing when the accounts table can't be described or can't serve
+// reads and writes, an updating table still serves them
+func DynamoDBHealthCheck(client TableDescriber, tableName string) health.CheckFunc {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, tableHealthCheckTimeout)
+		defer cancel()
+
+		out, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+		if err != nil {
+			return fmt.Errorf("failed to describe table %s: %w", tableName, err)
+		}
+		if out.Table == nil {
+			return fmt.Errorf("table %s has no description", tableName)
+		}
+		switch out.Table.TableStatus {
+		case types.TableStatusActive, types.TableStatusUpdating:
+			return nil
+		default:
+			return fmt.Errorf("table %s is %s", tableName, out.Table.TableStatus)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTableDescriber describes the table with the configured status or fails with err
+type fakeTableDescriber struct {
+	status types.TableStatus
+	err    error
+	table  string
+}
+
+func (f *fakeTableDescriber) DescribeTable(_ context.Context, params *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	f.table = aws.ToString(params.TableName)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{TableStatus: f.status}}, nil
+}
+
+func TestDynamoDBHealthCheck(t *testing.T) {
+	describeErr := errors.New("connection refused")
+	tests := []struct {
+		name    string
+		client  *fakeTableDescriber
+		wantErr string
+	}{
+		{name: "active", client: &fakeTableDescriber{status: types.TableStatusActive}},
+		{name: "updating", client: &fakeTableDescriber{status: types.TableStatusUpdating}},
+		{name: "creating", client: &fakeTableDescriber{status: types.TableStatusCreating}, wantErr: "table accounts is CREATING"},
+		{name: "unreachable", client: &fakeTableDescriber{err: describeErr}, wantErr: "failed to describe table accounts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DynamoDBHealthCheck(tt.client, "accounts")(context.Background())
+			require.Equal(t, "accounts", tt.client.table)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Metric names recorded around every DynamoDB call
+const (
+	OperationDurationMetricName = "db.client.operation.duration"
+	OperationCountMetricName    = "db.client.operations"
+	OperationErrorsMetricName   = "db.client.operation.errors"
+
+	instrumentationScope = "github.com/posilva/simpleidentity/internal/adapters/output/repository"
+)
+
+// Span attribute keys set by the repository operations
+const (
+	ReturnedRowsAttributeKey = "db.response.returned_rows"
+	BatchSizeAttributeKey    = "db.operation.batch.size"
+)
+
+// WithInstrumentation records the duration, count and errors of every DynamoDB call tagged by
+// operation name and starts a span around it, retried calls are recorded once per attempt.
+// The Create and Resolve operations get a parent span of the calls they make.
+// A nil meter or tracer uses the global otel providers
+func WithInstrumentation(meter metric.Meter, tracer trace.Tracer) RepositoryOption {
+	return func(r *dynamoDBAccountsRepository) {
+		r.meter = meter
+		r.tracer = tracer
+	}
+}
+
+// instrumentedClient decorates a DynamoDBAPI with metrics and spans
+type instrumentedClient struct {
+	client   DynamoDBAPI
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	count    metric.Int64Counter
+	errors   metric.Int64Counter
+}
+
+// Safeguard check to ensure instrumentedClient implements the DynamoDBAPI interface
+var _ DynamoDBAPI = (*instrumentedClient)(nil)
+
+// newInstrumentedClient creates the decorator, a nil meter or tracer falls back to the global providers
+func newInstrumentedClient(client DynamoDBAPI, meter metric.Meter, tracer trace.Tracer) DynamoDBAPI {
+	if meter == nil {
+		meter = otel.Meter(instrumentationScope)
+	}
+	if tracer == nil {
+		tracer = otel.Tracer(instrumentationScope)
+	}
+
+	// instruments are always usable even when creating them fails, so errors are only reported
+	duration, err := meter.Float64Histogram(OperationDurationMetricName,
+		metric.WithDescription("Duration of DynamoDB operations"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	count, err := meter.Int64Counter(OperationCountMetricName,
+		metric.WithDescription("Number of DynamoDB operations"),
+		metric.WithUnit("{operation}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	errs, err := meter.Int64Counter(OperationErrorsMetricName,
+		metric.WithDescription("Number of failed DynamoDB operations"),
+		metric.WithUnit("{operation}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return &instrumentedClient{
+		client:   client,
+		tracer:   tracer,
+		duration: duration,
+		count:    count,
+		errors:   errs,
+	}
+}
+
+func (c *instrumentedClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	ctx, done := c.start(ctx, "Query")
+	out, err := c.client.Query(ctx, params, optFns...)
+	done(err)
+	return out, err
+}
+
+func (c *instrumentedClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	ctx, done := c.start(ctx, "Scan")
+	out, err := c.client.Scan(ctx, params, optFns...)
+	done(err)
+	return out, err
+}
+
+func (c *instrumentedClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	ctx, done := c.start(ctx, "GetItem")
+	out, err := c.client.GetItem(ctx, params, optFns...)
+	done(err)
+	return out, err
+}
+
+func (c *instrumentedClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	ctx, done := c.start(ctx, "TransactWriteItems")
+	out, err := c.client.TransactWriteItems(ctx, params, optFns...)
+	done(err)
+	return out, err
+}
+
+// startSpan starts the span of a repository operation, the spans of the DynamoDB calls it makes are its children
+func (r *dynamoDBAccountsRepository) startSpan(ctx context.Context, operation string, providerType domain.ProviderType) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, "AccountsRepository."+operation,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "dynamodb"),
+			attribute.String("db.collection.name", r.tableName),
+			attribute.String("db.operation.name", operation),
+			attribute.String("provider.type", string(providerType)),
+		),
+	)
+}
+
+// endSpan ends the span of a repository operation, a missing account is an expected outcome and not an error
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, domain.ErrAccountNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// start starts the span of the operation and returns the function that ends it and records the metrics
+func (c *instrumentedClient) start(ctx context.Context, operation string) (context.Context, func(error)) {
+	kv := []attribute.KeyValue{
+		attribute.String("db.system.name", "dynamodb"),
+		attribute.String("db.operation.name", operation),
+	}
+	attrs := metric.WithAttributes(kv...)
+	ctx, span := c.tracer.Start(ctx, "DynamoDB."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(kv...),
+	)
+	start := time.Now()
+
+	return ctx, func(err error) {
+		c.duration.Record(ctx, time.Since(start).Seconds(), attrs)
+		c.count.Add(ctx, 1, attrs)
+		if err != nil {
+			c.errors.Add(ctx, 1, attrs)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/ovechkin-dm/mockio/v2/mock"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// counterValue returns the counter value for the given operation or zero if it was not recorded
+func counterValue(t *testing.T, rm metricdata.ResourceMetrics, name string, operation string) int64 {
+	t.Helper()
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			require.True(t, ok)
+			for _, dp := range sum.DataPoints {
+				if v, ok := dp.Attributes.Value(attribute.Key("db.operation.name")); ok && v.AsString() == operation {
+					return dp.Value
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestDynamoDBAccountsRepository_WithInstrumentation_RecordsMetrics(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(ctx)
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenReturn(&dynamodb.QueryOutput{}, nil)
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())).ThenReturn(nil, errors.New("connection reset"))
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test",
+		WithInstrumentation(provider.Meter("test"), noop.NewTracerProvider().Tracer("test")),
+	)
+
+	_, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, "guest_id")
+	require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	_, err = repo.Create(ctx, domain.ProviderTypeGuest, "guest_id")
+	require.Error(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+
+	require.Equal(t, int64(1), counterValue(t, rm, OperationCountMetricName, "Query"))
+	require.Equal(t, int64(0), counterValue(t, rm, OperationErrorsMetricName, "Query"))
+	require.Equal(t, int64(1), counterValue(t, rm, OperationCountMetricName, "TransactWriteItems"))
+	require.Equal(t, int64(1), counterValue(t, rm, OperationErrorsMetricName, "TransactWriteItems"))
+
+	var durations int
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == OperationDurationMetricName {
+				durations = len(m.Data.(metricdata.Histogram[float64]).DataPoints)
+			}
+		}
+	}
+	require.Equal(t, 2, durations)
+}
+
+// spanAttributes returns the attributes of the span as a map
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestDynamoDBAccountsRepository_WithInstrumentation_RecordsOperationSpans(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(ctx)
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenReturn(&dynamodb.QueryOutput{}, nil)
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())).ThenReturn(nil, errors.New("connection reset"))
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test",
+		WithInstrumentation(noopmetric.NewMeterProvider().Meter("test"), provider.Tracer("test")),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+	)
+
+	_, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, "guest_id")
+	require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	_, err = repo.Create(ctx, domain.ProviderTypeGuest, "guest_id")
+	require.Error(t, err)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	require.Len(t, spans, 4)
+
+	resolve := spans["AccountsRepository.ResolveIDByProvider"]
+	require.NotNil(t, resolve)
+	attrs := spanAttributes(resolve)
+	require.Equal(t, "accounts_test", attrs["db.collection.name"].AsString())
+	require.Equal(t, "ResolveIDByProvider", attrs["db.operation.name"].AsString())
+	require.Equal(t, int64(0), attrs[ReturnedRowsAttributeKey].AsInt64())
+	// a missing account is not an error
+	require.Equal(t, codes.Unset, resolve.Status().Code)
+	require.Equal(t, resolve.SpanContext().SpanID(), spans["DynamoDB.Query"].Parent().SpanID())
+
+	create := spans["AccountsRepository.Create"]
+	require.NotNil(t, create)
+	attrs = spanAttributes(create)
+	require.Equal(t, "Create", attrs["db.operation.name"].AsString())
+	require.Equal(t, int64(2), attrs[BatchSizeAttributeKey].AsInt64())
+	require.Equal(t, codes.Error, create.Status().Code)
+	require.Len(t, create.Events(), 1)
+	require.Equal(t, create.SpanContext().SpanID(), spans["DynamoDB.TransactWriteItems"].Parent().SpanID())
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// toLinkedProvider converts the record data into the domain linked provider
+func (d ddbAccountProviderRecordData) toLinkedProvider() (domain.LinkedProvider, error) {
+	linkedAt, err := time.Parse(time.RFC3339, d.DateCreatedISO8601)
+	if err != nil {
+		return domain.LinkedProvider{}, fmt.Errorf("failed to parse provider link date: %w", err)
+	}
+	return domain.LinkedProvider{
+		Type:        domain.ProviderType(d.ProviderType),
+		ProviderID:  d.ProviderID,
+		LinkedAt:    linkedAt,
+		DisplayName: d.DisplayName,
+		Locale:      d.Locale,
+	}, nil
+}
+
+// accountFromRecords builds the account aggregate from the records of a single account,
+// the account creation date is the date of the first linked provider
+func accountFromRecords(records []ddbAccountProviderRecordData) (*domain.Account, error) {
+	if len(records) == 0 {
+		return nil, domain.ErrAccountNotFound
+	}
+
+	account := &domain.Account{
+		ID:        domain.AccountID(records[0].AccountID),
+		Providers: make([]domain.LinkedProvider, 0, len(records)),
+	}
+	for _, record := range records {
+		if domain.AccountID(record.AccountID) != account.ID {
+			return nil, fmt.Errorf("unexpected records of accounts %s and %s mapped together", account.ID, record.AccountID)
+		}
+		provider, err := record.toLinkedProvider()
+		if err != nil {
+			return nil, err
+		}
+		if account.CreatedAt.IsZero() || provider.LinkedAt.Before(account.CreatedAt) {
+			account.CreatedAt = provider.LinkedAt
+		}
+		account.Providers = append(account.Providers, provider)
+	}
+	return account, nil
+}
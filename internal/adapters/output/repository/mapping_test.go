@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountFromRecords(t *testing.T) {
+	records := []ddbAccountProviderRecordData{
+		{AccountID: "account_id", ProviderType: "google", ProviderID: "google_id", DateCreatedISO8601: "2023-10-02T00:00:00Z", DisplayName: "Player One", Locale: "pt-PT"},
+		{AccountID: "account_id", ProviderType: "guest", ProviderID: "guest_id", DateCreatedISO8601: "2023-10-01T00:00:00Z"},
+	}
+
+	account, err := accountFromRecords(records)
+	require.NoError(t, err)
+	require.Equal(t, &domain.Account{
+		ID: "account_id",
+		Providers: []domain.LinkedProvider{
+			{
+				Type:        domain.ProviderTypeGoogle,
+				ProviderID:  "google_id",
+				LinkedAt:    time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+				DisplayName: "Player One",
+				Locale:      "pt-PT",
+			},
+			{Type: domain.ProviderTypeGuest, ProviderID: "guest_id", LinkedAt: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		CreatedAt: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+	}, account)
+}
+
+func TestAccountFromRecords_Errors(t *testing.T) {
+	_, err := accountFromRecords(nil)
+	require.ErrorIs(t, err, domain.ErrAccountNotFound)
+
+	_, err = accountFromRecords([]ddbAccountProviderRecordData{
+		{AccountID: "account_id", ProviderType: "guest", ProviderID: "guest_id", DateCreatedISO8601: "not a date"},
+	})
+	require.ErrorContains(t, err, "failed to parse provider link date")
+
+	_, err = accountFromRecords([]ddbAccountProviderRecordData{
+		{AccountID: "account_1", ProviderType: "guest", ProviderID: "guest_1", DateCreatedISO8601: "2023-10-01T00:00:00Z"},
+		{AccountID: "account_2", ProviderType: "guest", ProviderID: "guest_2", DateCreatedISO8601: "2023-10-01T00:00:00Z"},
+	})
+	require.ErrorContains(t, err, "unexpected records of accounts")
+}
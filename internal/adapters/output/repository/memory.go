@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/idgen"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// inMemoryAccount holds the data stored for a provider identity
+type inMemoryAccount struct {
+	accountID   domain.AccountID
+	identity    domain.ProviderIdentity
+	profile     domain.ProviderProfile
+	dateCreated time.Time
+}
+
+// inMemoryAccountsRepository implements the AccountsRepository interface keeping the accounts in memory,
+// it follows the same uniqueness semantics as the DynamoDB repository
+type inMemoryAccountsRepository struct {
+	mu          sync.RWMutex
+	idGenerator ports.IDGenerator
+	identities  map[string]inMemoryAccount
+	metadata    map[domain.AccountID]domain.AccountMetadata
+}
+
+// Safeguard check to ensure inMemoryAccountsRepository implements the AccountsRepository interface
+var _ ports.AccountsRepository = (*inMemoryAccountsRepository)(nil)
+
+// NewInMemoryAccountsRepository creates a new in-memory accounts repository safe for concurrent use,
+// useful as a drop-in replacement for DynamoDB in tests
+func NewInMemoryAccountsRepository() ports.AccountsRepository {
+	return &inMemoryAccountsRepository{
+		idGenerator: idgen.NewKSUIDGenerator(),
+		identities:  make(map[string]inMemoryAccount),
+		metadata:    make(map[domain.AccountID]domain.AccountMetadata),
+	}
+}
+
+// ResolveIDByProvider resolves the account ID by provider type and provider ID.
+func (r *inMemoryAccountsRepository) ResolveIDByProvider(ctx context.Context, providerType domain.ProviderType, providerID string) (domain.AccountID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	account, ok := r.identities[identityKey(ctx, domain.ProviderIdentity{ProviderType: providerType, ProviderID: providerID})]
+	if !ok {
+		return domain.EmptyAccountID, domain.ErrAccountNotFound
+	}
+	return account.accountID, nil
+}
+
+// ResolveAccountByProvider resolves the account with every linked provider by provider type and provider ID.
+func (r *inMemoryAccountsRepository) ResolveAccountByProvider(ctx context.Context, providerType domain.ProviderType, providerID string) (*domain.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resolved, ok := r.identities[identityKey(ctx, domain.ProviderIdentity{ProviderType: providerType, ProviderID: providerID})]
+	if !ok {
+		return nil, domain.ErrAccountNotFound
+	}
+
+	account := &domain.Account{ID: resolved.accountID}
+	// account IDs are unique across tenants so matching them is enough
+	for _, linked := range r.identities {
+		if linked.accountID != resolved.accountID {
+			continue
+		}
+		if account.CreatedAt.IsZero() || linked.dateCreated.Before(account.CreatedAt) {
+			account.CreatedAt = linked.dateCreated
+		}
+		account.Providers = append(account.Providers, domain.LinkedProvider{
+			Type:        linked.identity.ProviderType,
+			ProviderID:  linked.identity.ProviderID,
+			LinkedAt:    linked.dateCreated,
+			DisplayName: linked.profile.DisplayName,
+			Locale:      linked.profile.Locale,
+		})
+	}
+	// keep the same order as the DynamoDB account records
+	slices.SortFunc(account.Providers, func(a, b domain.LinkedProvider) int {
+		return strings.Compare(fmt.Sprintf(AccountProviderSKPrefixFmt, a.Type, a.ProviderID), fmt.Sprintf(AccountProviderSKPrefixFmt, b.Type, b.ProviderID))
+	})
+	return account, nil
+}
+
+// Create creates a new account using the provider type and provider ID.
+func (r *inMemoryAccountsRepository) Create(ctx context.Context, providerType domain.ProviderType, providerID string) (domain.AccountID, error) {
+	return r.CreateWithProfile(ctx, providerType, providerID, domain.ProviderProfile{})
+}
+
+// CreateWithProfile creates a new account like Create storing the provider profile along with it.
+func (r *inMemoryAccountsRepository) CreateWithProfile(ctx context.Context, providerType domain.ProviderType, providerID string, profile domain.ProviderProfile) (domain.AccountID, error) {
+	accountID := domain.AccountID(r.idGenerator.GenerateID())
+	if err := r.link(ctx, accountID, domain.ProviderIdentity{ProviderType: providerType, ProviderID: providerID}, profile, false); err != nil {
+		return domain.EmptyAccountID, fmt.Errorf("failed to create account: %w", err)
+	}
+	return accountID, nil
+}
+
+// LinkProvider links a provider identity to an existing account.
+func (r *inMemoryAccountsRepository) LinkProvider(ctx context.Context, accountID domain.AccountID, identity domain.ProviderIdentity) error {
+	if err := r.link(ctx, accountID, identity, domain.ProviderProfile{}, true); err != nil {
+		return fmt.Errorf("failed to link provider: %w", err)
+	}
+	return nil
+}
+
+// LinkBatch links each provider identity to an existing account and reports the outcome per identity.
+func (r *inMemoryAccountsRepository) LinkBatch(ctx context.Context, accountID domain.AccountID, identities []domain.ProviderIdentity) ([]domain.LinkResult, error) {
+	results := make([]domain.LinkResult, 0, len(identities))
+	for _, identity := range identities {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("failed to link providers batch: %w", err)
+		}
+		results = append(results, domain.LinkResult{
+			Identity: identity,
+			Err:      r.LinkProvider(ctx, accountID, identity),
+		})
+	}
+	return results, nil
+}
+
+// UpdateAccountMetadata replaces the metadata of the account.
+// It returns ErrAccountNotFound if the account does not exist
+func (r *inMemoryAccountsRepository) UpdateAccountMetadata(_ context.Context, accountID domain.AccountID, metadata domain.AccountMetadata) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.accountExists(accountID) {
+		return domain.ErrAccountNotFound
+	}
+	// account IDs are unique across tenants so they key the metadata alone
+	r.metadata[accountID] = metadata
+	return nil
+}
+
+// GetAccountMetadata returns the metadata of the account, the zero metadata when none was stored.
+func (r *inMemoryAccountsRepository) GetAccountMetadata(_ context.Context, accountID domain.AccountID) (domain.AccountMetadata, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.metadata[accountID], nil
+}
+
+// accountExists reports whether any identity is linked to the account, the lock must be held
+func (r *inMemoryAccountsRepository) accountExists(accountID domain.AccountID) bool {
+	// account IDs are unique across tenants so matching them is enough
+	for _, linked := range r.identities {
+		if linked.accountID == accountID {
+			return true
+		}
+	}
+	return false
+}
+
+// link stores the identity unless it already exists, the account must exist when linking to an existing one
+func (r *inMemoryAccountsRepository) link(ctx context.Context, accountID domain.AccountID, identity domain.ProviderIdentity, profile domain.ProviderProfile, existing bool) error {
+	key := identityKey(ctx, identity)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing && !r.accountExists(accountID) {
+		return domain.ErrAccountNotFound
+	}
+	if _, exists := r.identities[key]; exists {
+		return domain.ErrProviderIDOrAccountAlreadyExists
+	}
+	r.identities[key] = inMemoryAccount{
+		accountID:   accountID,
+		identity:    identity,
+		profile:     profile,
+		dateCreated: time.Now().UTC().Truncate(time.Second),
+	}
+	return nil
+}
+
+// identityKey returns the key of the identity using the same scheme as the DynamoDB identity records
+func identityKey(ctx context.Context, identity domain.ProviderIdentity) string {
+	return tenantPK(ctx, fmt.Sprintf(AccountProviderSKPrefixFmt, identity.ProviderType, identity.ProviderID))
+}
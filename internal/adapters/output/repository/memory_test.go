@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/posilva/simpleidentity/internal/adapters/output/idgen"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryAccountsRepository(t *testing.T) {
+	repo := NewInMemoryAccountsRepository()
+	ctx := context.Background()
+
+	t.Run("ResolveIDByProvider returns ErrAccountNotFound", func(t *testing.T) {
+		accountID, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, "test_provider_id")
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+		require.Equal(t, domain.EmptyAccountID, accountID)
+	})
+
+	t.Run("ResolveIDByProvider returns accountID", func(t *testing.T) {
+		providerID := idgen.NewKSUIDGenerator().GenerateID()
+		accountID, err := repo.Create(ctx, domain.ProviderTypeGuest, providerID)
+		require.NoError(t, err)
+		require.NotEmpty(t, accountID)
+
+		resolvedAccountID, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, providerID)
+		require.NoError(t, err)
+		require.Equal(t, accountID, resolvedAccountID)
+	})
+
+	t.Run("LinkProvider rejects unknown accounts", func(t *testing.T) {
+		unknownID := domain.AccountID(idgen.NewKSUIDGenerator().GenerateID())
+		providerID := idgen.NewKSUIDGenerator().GenerateID()
+		err := repo.LinkProvider(ctx, unknownID, domain.ProviderIdentity{ProviderType: domain.ProviderTypeApple, ProviderID: providerID})
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+
+		_, err = repo.ResolveIDByProvider(ctx, domain.ProviderTypeApple, providerID)
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	})
+
+	t.Run("ResolveAccountByProvider returns every linked provider", func(t *testing.T) {
+		providerID := idgen.NewKSUIDGenerator().GenerateID()
+		accountID, err := repo.CreateWithProfile(ctx, domain.ProviderTypeGoogle, providerID, domain.ProviderProfile{DisplayName: "Player One", Locale: "pt-PT"})
+		require.NoError(t, err)
+		require.NoError(t, repo.LinkProvider(ctx, accountID, domain.ProviderIdentity{ProviderType: domain.ProviderTypeApple, ProviderID: providerID}))
+
+		account, err := repo.ResolveAccountByProvider(ctx, domain.ProviderTypeGoogle, providerID)
+		require.NoError(t, err)
+		require.Equal(t, accountID, account.ID)
+		require.False(t, account.CreatedAt.IsZero())
+		require.Len(t, account.Providers, 2)
+		require.Equal(t, domain.ProviderTypeApple, account.Providers[0].Type)
+		require.Equal(t, domain.ProviderTypeGoogle, account.Providers[1].Type)
+		require.Equal(t, providerID, account.Providers[1].ProviderID)
+		require.Equal(t, "Player One", account.Providers[1].DisplayName)
+		require.Equal(t, "pt-PT", account.Providers[1].Locale)
+
+		_, err = repo.ResolveAccountByProvider(ctx, domain.ProviderTypeGoogle, "unknown_provider_id")
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	})
+
+	t.Run("UpdateAccountMetadata upserts the metadata", func(t *testing.T) {
+		accountID, err := repo.Create(ctx, domain.ProviderTypeGoogle, idgen.NewKSUIDGenerator().GenerateID())
+		require.NoError(t, err)
+
+		metadata, err := repo.GetAccountMetadata(ctx, accountID)
+		require.NoError(t, err)
+		require.Zero(t, metadata)
+
+		require.NoError(t, repo.UpdateAccountMetadata(ctx, accountID, domain.AccountMetadata{DisplayName: "Player One", Email: "player01@example.com", EmailVerified: true}))
+		require.NoError(t, repo.UpdateAccountMetadata(ctx, accountID, domain.AccountMetadata{DisplayName: "Player Two", AvatarURL: "https://example.com/player02.png"}))
+
+		metadata, err = repo.GetAccountMetadata(ctx, accountID)
+		require.NoError(t, err)
+		require.Equal(t, domain.AccountMetadata{DisplayName: "Player Two", AvatarURL: "https://example.com/player02.png"}, metadata)
+
+		err = repo.UpdateAccountMetadata(ctx, "unknown_id", domain.AccountMetadata{DisplayName: "Player One"})
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	})
+
+	t.Run("Create account returns Provider ID already exists", func(t *testing.T) {
+		providerID := idgen.NewKSUIDGenerator().GenerateID()
+		accountID, err := repo.Create(ctx, domain.ProviderTypeGuest, providerID)
+		require.NoError(t, err)
+		require.NotEmpty(t, accountID)
+
+		empty, err := repo.Create(ctx, domain.ProviderTypeGuest, providerID)
+		require.ErrorIs(t, err, domain.ErrProviderIDOrAccountAlreadyExists)
+		require.Equal(t, domain.EmptyAccountID, empty)
+	})
+
+	t.Run("LinkBatch reports conflicts per identity", func(t *testing.T) {
+		accountID, err := repo.Create(ctx, domain.ProviderTypeGoogle, "linked_google_id")
+		require.NoError(t, err)
+
+		results, err := repo.LinkBatch(ctx, accountID, []domain.ProviderIdentity{
+			{ProviderType: domain.ProviderTypeApple, ProviderID: "apple_id"},
+			{ProviderType: domain.ProviderTypeGoogle, ProviderID: "linked_google_id"},
+		})
+		require.NoError(t, err)
+		require.NoError(t, results[0].Err)
+		require.ErrorIs(t, results[1].Err, domain.ErrProviderIDOrAccountAlreadyExists)
+
+		resolvedAccountID, err := repo.ResolveIDByProvider(ctx, domain.ProviderTypeApple, "apple_id")
+		require.NoError(t, err)
+		require.Equal(t, accountID, resolvedAccountID)
+	})
+
+	t.Run("Tenants do not collide", func(t *testing.T) {
+		tenantCtx := domain.ContextWithTenantID(ctx, "game-1")
+		providerID := idgen.NewKSUIDGenerator().GenerateID()
+		accountID, err := repo.Create(ctx, domain.ProviderTypeGuest, providerID)
+		require.NoError(t, err)
+
+		tenantAccountID, err := repo.Create(tenantCtx, domain.ProviderTypeGuest, providerID)
+		require.NoError(t, err)
+		require.NotEqual(t, accountID, tenantAccountID)
+	})
+}
+
+func TestInMemoryAccountsRepository_ConcurrentCreate_OnlyOneSucceeds(t *testing.T) {
+	repo := NewInMemoryAccountsRepository()
+	ctx := context.Background()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.Create(ctx, domain.ProviderTypeGuest, "same_provider_id"); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+			_, _ = repo.Create(ctx, domain.ProviderTypeGuest, fmt.Sprintf("provider_id_%d", i))
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, succeeded)
+}
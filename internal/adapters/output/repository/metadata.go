@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// ddbAccountMetadataRecord represents the metadata record stored in the account partition
+type ddbAccountMetadataRecord struct {
+	PK                 string `dynamodbav:"PK"`
+	SK                 string `dynamodbav:"SK"`
+	AccountID          string `dynamodbav:"AccountID"`
+	DisplayName        string `dynamodbav:"DisplayName,omitempty"`
+	AvatarURL          string `dynamodbav:"AvatarURL,omitempty"`
+	Email              string `dynamodbav:"Email,omitempty"`
+	EmailVerified      bool   `dynamodbav:"EmailVerified,omitempty"`
+	DateUpdatedISO8601 string `dynamodbav:"DateUpdated"`
+	// ExpiresAt is copied from the account records so the metadata of a guest expires along with it
+	ExpiresAt int64 `dynamodbav:"ExpiresAt,omitempty"`
+}
+
+// UpdateAccountMetadata replaces the metadata record of the account.
+// It returns ErrAccountNotFound if the account does not exist
+func (r *dynamoDBAccountsRepository) UpdateAccountMetadata(ctx context.Context, accountID domain.AccountID, metadata domain.AccountMetadata) error {
+	pk := tenantPK(ctx, r.table.accountKey(accountID))
+	accountItem, err := r.findAccountRecord(ctx, pk)
+	if err != nil {
+		return err
+	}
+	var account ddbAccountProviderRecordData
+	if err := r.table.unmarshalRecordData(accountItem, &account); err != nil {
+		return fmt.Errorf("failed to unmarshal DynamoDB items: %w", err)
+	}
+
+	item, err := r.table.marshalItem(ddbAccountMetadataRecord{
+		PK:                 pk,
+		SK:                 AccountMetadataSKName,
+		AccountID:          string(accountID),
+		DisplayName:        metadata.DisplayName,
+		AvatarURL:          metadata.AvatarURL,
+		Email:              metadata.Email,
+		EmailVerified:      metadata.EmailVerified,
+		DateUpdatedISO8601: time.Now().UTC().Format(time.RFC3339),
+		ExpiresAt:          account.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal account metadata record: %w", err)
+	}
+
+	// the account record must still exist when the metadata is written
+	checkItem, err := r.accountExistsCheckItem(accountItem)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			checkItem,
+			{
+				Put: &types.Put{
+					TableName: aws.String(r.tableName),
+					Item:      item,
+				},
+			},
+		},
+	})
+	if err != nil {
+		tErr := enrichErrorWithOperationContext(err, []string{"CHECK Account data", "PUT Account metadata"})
+		if errors.Is(tErr, errTransactionErrorConditionFailed) {
+			tErr = domain.ErrAccountNotFound
+		}
+		return fmt.Errorf("failed to execute transaction when updating account metadata: %w", tErr)
+	}
+	return nil
+}
+
+// findAccountRecord reads one account provider record of the account partition, the read is strongly consistent
+// as it guards a write. It returns ErrAccountNotFound if the partition holds no account record
+func (r *dynamoDBAccountsRepository) findAccountRecord(ctx context.Context, pk string) (map[string]types.AttributeValue, error) {
+	keyCond := expression.KeyAnd(
+		expression.Key(r.table.PartitionKeyName).Equal(expression.Value(pk)),
+		expression.Key(r.table.SortKeyName).BeginsWith(r.table.ProviderKeyPrefix),
+	)
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build expression: %w", err)
+	}
+
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.tableName),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ConsistentRead:            aws.Bool(true),
+		Limit:                     aws.Int32(1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DynamoDB: %w", err)
+	}
+	if len(result.Items) == 0 {
+		return nil, domain.ErrAccountNotFound
+	}
+	return result.Items[0], nil
+}
+
+// GetAccountMetadata returns the metadata of the account, the zero metadata when none was stored.
+func (r *dynamoDBAccountsRepository) GetAccountMetadata(ctx context.Context, accountID domain.AccountID) (domain.AccountMetadata, error) {
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			r.table.PartitionKeyName: &types.AttributeValueMemberS{Value: tenantPK(ctx, r.table.accountKey(accountID))},
+			r.table.SortKeyName:      &types.AttributeValueMemberS{Value: AccountMetadataSKName},
+		},
+		ConsistentRead: r.consistentRead(ctx),
+	})
+	if err != nil {
+		return domain.AccountMetadata{}, fmt.Errorf("failed to get account metadata: %w", err)
+	}
+	if len(result.Item) == 0 {
+		return domain.AccountMetadata{}, nil
+	}
+
+	var record ddbAccountMetadataRecord
+	if err := r.table.unmarshalItem(result.Item, &record); err != nil {
+		return domain.AccountMetadata{}, fmt.Errorf("failed to unmarshal account metadata record: %w", err)
+	}
+	return domain.AccountMetadata{
+		DisplayName:   record.DisplayName,
+		AvatarURL:     record.AvatarURL,
+		Email:         record.Email,
+		EmailVerified: record.EmailVerified,
+	}, nil
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ovechkin-dm/mockio/v2/mock"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// metadataTestAccountItem returns an account provider record of the account partition
+func metadataTestAccountItem(pk string, expiresAt string) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"PK":           &types.AttributeValueMemberS{Value: pk},
+		"SK":           &types.AttributeValueMemberS{Value: "PVDR#guest#guest_id"},
+		"AccountID":    &types.AttributeValueMemberS{Value: "account_id"},
+		"ProviderType": &types.AttributeValueMemberS{Value: string(domain.ProviderTypeGuest)},
+		"ProviderID":   &types.AttributeValueMemberS{Value: "guest_id"},
+		"DateCreated":  &types.AttributeValueMemberS{Value: "2023-10-01T00:00:00Z"},
+	}
+	if expiresAt != "" {
+		item["ExpiresAt"] = &types.AttributeValueMemberN{Value: expiresAt}
+	}
+	return item
+}
+
+func TestDynamoDBAccountsRepository_AccountMetadata_ReadsBackTheUpsert(t *testing.T) {
+	ctx := domain.ContextWithTenantID(context.Background(), "game-1")
+	metadata := domain.AccountMetadata{
+		DisplayName:   "Player One",
+		AvatarURL:     "https://example.com/player01.png",
+		Email:         "player01@example.com",
+		EmailVerified: true,
+	}
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	queryCaptor := mock.Captor[*dynamodb.QueryInput]()
+	writeCaptor := mock.Captor[*dynamodb.TransactWriteItemsInput]()
+	getCaptor := mock.Captor[*dynamodb.GetItemInput]()
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), queryCaptor.Capture())).ThenReturn(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{metadataTestAccountItem("TNT#game-1#ACNT#account_id", "")},
+	}, nil)
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), writeCaptor.Capture())).ThenReturn(&dynamodb.TransactWriteItemsOutput{}, nil)
+	mock.WhenDouble(clientMock.GetItem(mock.Any[context.Context](), getCaptor.Capture())).ThenAnswer(func(args []any) (*dynamodb.GetItemOutput, error) {
+		return &dynamodb.GetItemOutput{Item: writeCaptor.Last().TransactItems[1].Put.Item}, nil
+	})
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+	require.NoError(t, repo.UpdateAccountMetadata(ctx, "account_id", metadata))
+
+	query := queryCaptor.Last()
+	require.True(t, aws.ToBool(query.ConsistentRead))
+	require.Equal(t, &types.AttributeValueMemberS{Value: "TNT#game-1#ACNT#account_id"}, query.ExpressionAttributeValues[":0"])
+
+	items := writeCaptor.Last().TransactItems
+	require.Len(t, items, 2)
+	check := items[0].ConditionCheck
+	require.NotNil(t, check)
+	require.Equal(t, "accounts_test", aws.ToString(check.TableName))
+	require.Equal(t, map[string]types.AttributeValue{
+		TablePKName: &types.AttributeValueMemberS{Value: "TNT#game-1#ACNT#account_id"},
+		TableSKName: &types.AttributeValueMemberS{Value: "PVDR#guest#guest_id"},
+	}, check.Key)
+	require.Contains(t, aws.ToString(check.ConditionExpression), "attribute_exists")
+
+	put := items[1].Put
+	require.Equal(t, "accounts_test", aws.ToString(put.TableName))
+	require.Equal(t, &types.AttributeValueMemberS{Value: "TNT#game-1#ACNT#account_id"}, put.Item[TablePKName])
+	require.Equal(t, &types.AttributeValueMemberS{Value: AccountMetadataSKName}, put.Item[TableSKName])
+	require.NotContains(t, put.Item, ExpiresAtAttributeName)
+
+	got, err := repo.GetAccountMetadata(ctx, "account_id")
+	require.NoError(t, err)
+	require.Equal(t, metadata, got)
+	require.Equal(t, map[string]types.AttributeValue{
+		TablePKName: &types.AttributeValueMemberS{Value: "TNT#game-1#ACNT#account_id"},
+		TableSKName: &types.AttributeValueMemberS{Value: AccountMetadataSKName},
+	}, getCaptor.Last().Key)
+}
+
+func TestDynamoDBAccountsRepository_UpdateAccountMetadata_ExpiresWithGuest(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	writeCaptor := mock.Captor[*dynamodb.TransactWriteItemsInput]()
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenReturn(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{metadataTestAccountItem("ACNT#account_id", "1700000000")},
+	}, nil)
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), writeCaptor.Capture())).ThenReturn(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test", WithGuestTTL(24*time.Hour))
+	require.NoError(t, repo.UpdateAccountMetadata(context.Background(), "account_id", domain.AccountMetadata{DisplayName: "Guest"}))
+
+	put := writeCaptor.Last().TransactItems[1].Put
+	require.Equal(t, &types.AttributeValueMemberN{Value: "1700000000"}, put.Item[ExpiresAtAttributeName])
+}
+
+func TestDynamoDBAccountsRepository_UpdateAccountMetadata_ReturnsAccountNotFound(t *testing.T) {
+	t.Run("no account record", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		clientMock := mock.Mock[DynamoDBAPI](ctrl)
+		mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenReturn(&dynamodb.QueryOutput{}, nil)
+
+		repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+		err := repo.UpdateAccountMetadata(context.Background(), "unknown_id", domain.AccountMetadata{DisplayName: "Player One"})
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+		mock.Verify(clientMock, mock.Never()).TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())
+	})
+
+	t.Run("account deleted meanwhile", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		clientMock := mock.Mock[DynamoDBAPI](ctrl)
+		mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenReturn(&dynamodb.QueryOutput{
+			Items: []map[string]types.AttributeValue{metadataTestAccountItem("ACNT#account_id", "")},
+		}, nil)
+		mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())).ThenReturn(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}, {Code: aws.String("None")}},
+		})
+
+		repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+		err := repo.UpdateAccountMetadata(context.Background(), "account_id", domain.AccountMetadata{DisplayName: "Player One"})
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	})
+}
+
+func TestDynamoDBAccountsRepository_GetAccountMetadata_ReturnsZeroWhenNotStored(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	mock.WhenDouble(clientMock.GetItem(mock.Any[context.Context](), mock.Any[*dynamodb.GetItemInput]())).ThenReturn(&dynamodb.GetItemOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+	metadata, err := repo.GetAccountMetadata(context.Background(), "account_id")
+	require.NoError(t, err)
+	require.Zero(t, metadata)
+}
+
+func TestDynamoDBAccountsRepository_ResolveAccountByProvider_SkipsMetadataRecord(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	identity := map[string]types.AttributeValue{
+		"PK":           &types.AttributeValueMemberS{Value: "PVDR#google#google_id"},
+		"SK":           &types.AttributeValueMemberS{Value: AccountIdentitySKName},
+		"AccountID":    &types.AttributeValueMemberS{Value: "account_id"},
+		"ProviderType": &types.AttributeValueMemberS{Value: string(domain.ProviderTypeGoogle)},
+		"ProviderID":   &types.AttributeValueMemberS{Value: "google_id"},
+		"DateCreated":  &types.AttributeValueMemberS{Value: "2023-10-01T00:00:00Z"},
+	}
+	account := map[string]types.AttributeValue{
+		"PK":           &types.AttributeValueMemberS{Value: "ACNT#account_id"},
+		"SK":           &types.AttributeValueMemberS{Value: "PVDR#google#google_id"},
+		"AccountID":    &types.AttributeValueMemberS{Value: "account_id"},
+		"ProviderType": &types.AttributeValueMemberS{Value: string(domain.ProviderTypeGoogle)},
+		"ProviderID":   &types.AttributeValueMemberS{Value: "google_id"},
+		"DateCreated":  &types.AttributeValueMemberS{Value: "2023-10-01T00:00:00Z"},
+	}
+	metadata := map[string]types.AttributeValue{
+		"PK":          &types.AttributeValueMemberS{Value: "ACNT#account_id"},
+		"SK":          &types.AttributeValueMemberS{Value: AccountMetadataSKName},
+		"AccountID":   &types.AttributeValueMemberS{Value: "account_id"},
+		"Email":       &types.AttributeValueMemberS{Value: "player01@example.com"},
+		"DateUpdated": &types.AttributeValueMemberS{Value: "2023-10-02T00:00:00Z"},
+	}
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).
+		ThenReturn(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{identity}}, nil).
+		ThenReturn(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{metadata, account}}, nil)
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+	resolved, err := repo.ResolveAccountByProvider(context.Background(), domain.ProviderTypeGoogle, "google_id")
+	require.NoError(t, err)
+	require.Len(t, resolved.Providers, 1)
+	require.Equal(t, domain.ProviderTypeGoogle, resolved.Providers[0].Type)
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TableAPI defines the DynamoDB operations needed to create the accounts table
+type TableAPI interface {
+	CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	TableDescriber
+}
+
+// Safeguard check to ensure the dynamodb client implements the TableAPI interface
+var _ TableAPI = (*dynamodb.Client)(nil)
+
+// TableOptions configures the billing of the accounts table,
+// the capacity units are only used with provisioned billing
+type TableOptions struct {
+	BillingMode   types.BillingMode
+	ReadCapacity  int64
+	WriteCapacity int64
+}
+
+// CreateTable creates the accounts table and waits until it is active,
+// it returns false without error when the table already exists
+func CreateTable(ctx context.Context, client TableAPI, tableName string, opts TableOptions, timeout time.Duration) (bool, error) {
+	created := true
+	_, err := client.CreateTable(ctx, createTableInput(tableName, opts))
+	if err != nil {
+		var inUse *types.ResourceInUseException
+		if !errors.As(err, &inUse) {
+			return false, fmt.Errorf("failed to create table %s: %w", tableName, err)
+		}
+		created = false
+	}
+
+	waiter := dynamodb.NewTableExistsWaiter(client)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)}, timeout); err != nil {
+		return false, fmt.Errorf("failed waiting for table %s to be active: %w", tableName, err)
+	}
+	return created, nil
+}
+
+// TableSchema returns the accounts table definition with on-demand billing,
+// including the provider index used by WithProviderIndex
+func TableSchema(tableName string) *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String(TablePKName), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String(TableSKName), KeyType: types.KeyTypeRange},
+		},
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String(TablePKName), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String(TableSKName), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String(ProviderIndexPKName), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String(ProviderIndexSKName), AttributeType: types.ScalarAttributeTypeS},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String(ProviderIndexName),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String(ProviderIndexPKName), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String(ProviderIndexSKName), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	}
+}
+
+// createTableInput applies the billing options to the table schema
+func createTableInput(tableName string, opts TableOptions) *dynamodb.CreateTableInput {
+	input := TableSchema(tableName)
+	if opts.BillingMode == types.BillingModeProvisioned {
+		input.BillingMode = types.BillingModeProvisioned
+		input.ProvisionedThroughput = &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(opts.ReadCapacity),
+			WriteCapacityUnits: aws.Int64(opts.WriteCapacity),
+		}
+		// the indexes are provisioned with the same capacity as the table
+		for i := range input.GlobalSecondaryIndexes {
+			input.GlobalSecondaryIndexes[i].ProvisionedThroughput = input.ProvisionedThroughput
+		}
+	}
+	return input
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTableAPI records the created table and reports it active,
+// the table waiter passes options that the mocks can't match
+type fakeTableAPI struct {
+	createErr error
+	created   *dynamodb.CreateTableInput
+}
+
+func (f *fakeTableAPI) CreateTable(_ context.Context, params *dynamodb.CreateTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	f.created = params
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (f *fakeTableAPI) DescribeTable(_ context.Context, params *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{
+		Table: &types.TableDescription{TableName: params.TableName, TableStatus: types.TableStatusActive},
+	}, nil
+}
+
+func TestCreateTable_CreatesProvisionedTable(t *testing.T) {
+	client := &fakeTableAPI{}
+
+	created, err := CreateTable(context.Background(), client, "accounts_test", TableOptions{
+		BillingMode:   types.BillingModeProvisioned,
+		ReadCapacity:  5,
+		WriteCapacity: 10,
+	}, time.Second)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	input := client.created
+	require.Equal(t, "accounts_test", aws.ToString(input.TableName))
+	require.Equal(t, TablePKName, aws.ToString(input.KeySchema[0].AttributeName))
+	require.Equal(t, TableSKName, aws.ToString(input.KeySchema[1].AttributeName))
+	require.Equal(t, types.BillingModeProvisioned, input.BillingMode)
+	require.Equal(t, int64(5), aws.ToInt64(input.ProvisionedThroughput.ReadCapacityUnits))
+	require.Equal(t, int64(10), aws.ToInt64(input.ProvisionedThroughput.WriteCapacityUnits))
+	for _, index := range input.GlobalSecondaryIndexes {
+		require.Equal(t, input.ProvisionedThroughput, index.ProvisionedThroughput)
+	}
+}
+
+func TestCreateTable_DefaultsToOnDemand(t *testing.T) {
+	client := &fakeTableAPI{}
+
+	_, err := CreateTable(context.Background(), client, "accounts_test", TableOptions{}, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, types.BillingModePayPerRequest, client.created.BillingMode)
+	require.Nil(t, client.created.ProvisionedThroughput)
+}
+
+func TestCreateTable_ExistingTableIsNoop(t *testing.T) {
+	client := &fakeTableAPI{createErr: &types.ResourceInUseException{}}
+
+	created, err := CreateTable(context.Background(), client, "accounts_test", TableOptions{}, time.Second)
+	require.NoError(t, err)
+	require.False(t, created)
+}
+
+func TestCreateTable_ReturnsCreateError(t *testing.T) {
+	client := &fakeTableAPI{createErr: &types.LimitExceededException{}}
+
+	_, err := CreateTable(context.Background(), client, "accounts_test", TableOptions{}, time.Second)
+	require.ErrorContains(t, err, "failed to create table accounts_test")
+}
+
+func TestTableSchema(t *testing.T) {
+	schema := TableSchema("accounts_test")
+
+	require.Equal(t, "accounts_test", aws.ToString(schema.TableName))
+	require.Equal(t, []types.KeySchemaElement{
+		{AttributeName: aws.String(TablePKName), KeyType: types.KeyTypeHash},
+		{AttributeName: aws.String(TableSKName), KeyType: types.KeyTypeRange},
+	}, schema.KeySchema)
+	require.Equal(t, []types.AttributeDefinition{
+		{AttributeName: aws.String(TablePKName), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String(TableSKName), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String(ProviderIndexPKName), AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: aws.String(ProviderIndexSKName), AttributeType: types.ScalarAttributeTypeS},
+	}, schema.AttributeDefinitions)
+	require.Len(t, schema.GlobalSecondaryIndexes, 1)
+	index := schema.GlobalSecondaryIndexes[0]
+	require.Equal(t, ProviderIndexName, aws.ToString(index.IndexName))
+	require.Equal(t, []types.KeySchemaElement{
+		{AttributeName: aws.String(ProviderIndexPKName), KeyType: types.KeyTypeHash},
+		{AttributeName: aws.String(ProviderIndexSKName), KeyType: types.KeyTypeRange},
+	}, index.KeySchema)
+	require.Equal(t, types.BillingModePayPerRequest, schema.BillingMode)
+}
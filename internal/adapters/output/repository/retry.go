@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// RetryPolicy defines how DynamoDB calls are retried on throttling and transaction conflicts,
+// MaxAttempts includes the first attempt so a value of 1 disables retries.
+// The SDK retryer of the client retries the throttled calls too, so the client should make single attempts
+// (RetryMaxAttempts of 1) or each attempt of the policy runs the SDK attempts again
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    time.Second,
+	}
+}
+
+// WithRetryPolicy sets the retry policy used for DynamoDB calls
+func WithRetryPolicy(p RetryPolicy) RepositoryOption {
+	return func(r *dynamoDBAccountsRepository) {
+		r.retryPolicy = p
+	}
+}
+
+// retryingClient decorates a DynamoDBAPI retrying the calls that failed with a retryable error
+type retryingClient struct {
+	client DynamoDBAPI
+	policy RetryPolicy
+}
+
+// Safeguard check to ensure retryingClient implements the DynamoDBAPI interface
+var _ DynamoDBAPI = (*retryingClient)(nil)
+
+func newRetryingClient(client DynamoDBAPI, policy RetryPolicy) DynamoDBAPI {
+	if policy.MaxAttempts <= 1 {
+		return client
+	}
+	return &retryingClient{client: client, policy: policy}
+}
+
+func (c *retryingClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	return withRetry(ctx, c.policy, func() (*dynamodb.QueryOutput, error) {
+		return c.client.Query(ctx, params, optFns...)
+	})
+}
+
+func (c *retryingClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	return withRetry(ctx, c.policy, func() (*dynamodb.ScanOutput, error) {
+		return c.client.Scan(ctx, params, optFns...)
+	})
+}
+
+func (c *retryingClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return withRetry(ctx, c.policy, func() (*dynamodb.GetItemOutput, error) {
+		return c.client.GetItem(ctx, params, optFns...)
+	})
+}
+
+func (c *retryingClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	return withRetry(ctx, c.policy, func() (*dynamodb.TransactWriteItemsOutput, error) {
+		return c.client.TransactWriteItems(ctx, params, optFns...)
+	})
+}
+
+// withRetry calls fn until it succeeds, fails with a non retryable error or the attempts are exhausted
+func withRetry[T any](ctx context.Context, policy RetryPolicy, fn func() (T, error)) (T, error) {
+	var (
+		out T
+		err error
+	)
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(backoff(policy, attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return out, errors.Join(err, ctx.Err())
+			case <-timer.C:
+			}
+		}
+		out, err = fn()
+		if err == nil || !isRetryable(err) {
+			return out, err
+		}
+	}
+	return out, err
+}
+
+// backoff returns the exponential backoff delay with full jitter for the given attempt
+func backoff(policy RetryPolicy, attempt int) time.Duration {
+	delay := policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || (policy.MaxDelay > 0 && delay > policy.MaxDelay) {
+		delay = policy.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return rand.N(delay) + 1
+}
+
+// isRetryable reports whether the error is a throttling or transaction conflict error,
+// conditional check failures are never retried as they are deterministic
+func isRetryable(err error) bool {
+	err = classifyError(err)
+	return errors.Is(err, domain.ErrThrottled) || errors.Is(err, domain.ErrTransactionConflict)
+}
+
+// classifyError maps the DynamoDB SDK errors to the domain typed errors
+func classifyError(err error) error {
+	var (
+		throughputErr *types.ProvisionedThroughputExceededException
+		limitErr      *types.RequestLimitExceeded
+		conflictErr   *types.TransactionConflictException
+		apiErr        smithy.APIError
+	)
+	switch {
+	case errors.As(err, &throughputErr), errors.As(err, &limitErr):
+		return errors.Join(err, domain.ErrThrottled)
+	case errors.As(err, &conflictErr):
+		return errors.Join(err, domain.ErrTransactionConflict)
+	case errors.As(err, &apiErr) && apiErr.ErrorCode() == "ThrottlingException":
+		return errors.Join(err, domain.ErrThrottled)
+	}
+	return enrichErrorWithOperationContext(err, nil)
+}
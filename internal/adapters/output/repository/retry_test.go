@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ovechkin-dm/mockio/v2/mock"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func throttlingCancellation() error {
+	return &types.TransactionCanceledException{
+		CancellationReasons: []types.CancellationReason{{Code: aws.String("ThrottlingError")}},
+	}
+}
+
+func TestDynamoDBAccountsRepository_Create_RetriesWhenThrottled(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+
+	calls := 0
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())).ThenAnswer(func(args []any) (*dynamodb.TransactWriteItemsOutput, error) {
+		calls++
+		if calls <= 2 {
+			return nil, throttlingCancellation()
+		}
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	})
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test", WithRetryPolicy(testRetryPolicy))
+	accountID, err := repo.Create(context.Background(), domain.ProviderTypeGuest, "guest_id")
+
+	require.NoError(t, err)
+	require.NotEqual(t, domain.EmptyAccountID, accountID)
+	require.Equal(t, 3, calls)
+}
+
+func TestDynamoDBAccountsRepository_ResolveIDByProvider_RetriesWhenThrottled(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+
+	calls := 0
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), mock.Any[*dynamodb.QueryInput]())).ThenAnswer(func(args []any) (*dynamodb.QueryOutput, error) {
+		calls++
+		if calls <= 2 {
+			return nil, &types.ProvisionedThroughputExceededException{Message: aws.String("slow down")}
+		}
+		return &dynamodb.QueryOutput{}, nil
+	})
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test", WithRetryPolicy(testRetryPolicy))
+	_, err := repo.ResolveIDByProvider(context.Background(), domain.ProviderTypeGuest, "guest_id")
+
+	require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	require.Equal(t, 3, calls)
+}
+
+func TestDynamoDBAccountsRepository_Create_StopsRetryingAfterMaxAttempts(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+
+	calls := 0
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())).ThenAnswer(func(args []any) (*dynamodb.TransactWriteItemsOutput, error) {
+		calls++
+		return nil, throttlingCancellation()
+	})
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test", WithRetryPolicy(testRetryPolicy))
+	_, err := repo.Create(context.Background(), domain.ProviderTypeGuest, "guest_id")
+
+	require.ErrorIs(t, err, domain.ErrThrottled)
+	require.Equal(t, testRetryPolicy.MaxAttempts, calls)
+}
+
+func TestDynamoDBAccountsRepository_Create_NeverRetriesConditionalCheckFailed(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+
+	calls := 0
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), mock.Any[*dynamodb.TransactWriteItemsInput]())).ThenAnswer(func(args []any) (*dynamodb.TransactWriteItemsOutput, error) {
+		calls++
+		return nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}},
+		}
+	})
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test", WithRetryPolicy(testRetryPolicy))
+	_, err := repo.Create(context.Background(), domain.ProviderTypeGuest, "guest_id")
+
+	require.ErrorIs(t, err, domain.ErrProviderIDOrAccountAlreadyExists)
+	require.Equal(t, 1, calls)
+}
+
+func TestBackoff_IsBoundedByMaxDelay(t *testing.T) {
+	for attempt := 1; attempt < 70; attempt++ {
+		delay := backoff(testRetryPolicy, attempt)
+		require.Positive(t, delay)
+		require.LessOrEqual(t, delay, testRetryPolicy.MaxDelay)
+	}
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// Default attribute names, they match the dynamodbav tags of the records
+const (
+	AccountIDAttributeName    = "AccountID"
+	ProviderTypeAttributeName = "ProviderType"
+	ProviderIDAttributeName   = "ProviderID"
+	DateCreatedAttributeName  = "DateCreated"
+	DisplayNameAttributeName  = "DisplayName"
+	LocaleAttributeName       = "Locale"
+	ExpiresAtAttributeName    = "ExpiresAt"
+)
+
+// TableConfig defines the key names, key prefixes and attribute names used in the DynamoDB table,
+// empty values fall back to the defaults
+type TableConfig struct {
+	PartitionKeyName          string
+	SortKeyName               string
+	ProviderIndexPKName       string
+	ProviderIndexSKName       string
+	AccountKeyPrefix          string
+	ProviderKeyPrefix         string
+	AccountIDAttributeName    string
+	ProviderTypeAttributeName string
+	ProviderIDAttributeName   string
+	DateCreatedAttributeName  string
+	DisplayNameAttributeName  string
+	LocaleAttributeName       string
+	ExpiresAtAttributeName    string
+}
+
+// DefaultTableConfig returns the table configuration with the default key and attribute names
+func DefaultTableConfig() TableConfig {
+	return TableConfig{
+		PartitionKeyName:          TablePKName,
+		SortKeyName:               TableSKName,
+		ProviderIndexPKName:       ProviderIndexPKName,
+		ProviderIndexSKName:       ProviderIndexSKName,
+		AccountKeyPrefix:          AccountKeyPrefix,
+		ProviderKeyPrefix:         ProviderKeyPrefix,
+		AccountIDAttributeName:    AccountIDAttributeName,
+		ProviderTypeAttributeName: ProviderTypeAttributeName,
+		ProviderIDAttributeName:   ProviderIDAttributeName,
+		DateCreatedAttributeName:  DateCreatedAttributeName,
+		DisplayNameAttributeName:  DisplayNameAttributeName,
+		LocaleAttributeName:       LocaleAttributeName,
+		ExpiresAtAttributeName:    ExpiresAtAttributeName,
+	}
+}
+
+// withDefaults fills the empty names with the defaults
+func (c TableConfig) withDefaults() TableConfig {
+	d := DefaultTableConfig()
+	fill := func(v *string, def string) {
+		if *v == "" {
+			*v = def
+		}
+	}
+	fill(&c.PartitionKeyName, d.PartitionKeyName)
+	fill(&c.SortKeyName, d.SortKeyName)
+	fill(&c.ProviderIndexPKName, d.ProviderIndexPKName)
+	fill(&c.ProviderIndexSKName, d.ProviderIndexSKName)
+	fill(&c.AccountKeyPrefix, d.AccountKeyPrefix)
+	fill(&c.ProviderKeyPrefix, d.ProviderKeyPrefix)
+	fill(&c.AccountIDAttributeName, d.AccountIDAttributeName)
+	fill(&c.ProviderTypeAttributeName, d.ProviderTypeAttributeName)
+	fill(&c.ProviderIDAttributeName, d.ProviderIDAttributeName)
+	fill(&c.DateCreatedAttributeName, d.DateCreatedAttributeName)
+	fill(&c.DisplayNameAttributeName, d.DisplayNameAttributeName)
+	fill(&c.LocaleAttributeName, d.LocaleAttributeName)
+	fill(&c.ExpiresAtAttributeName, d.ExpiresAtAttributeName)
+	return c
+}
+
+// TableKeys defines the key names and the key prefixes used in the table,
+// empty values fall back to the defaults
+type TableKeys struct {
+	PartitionKeyName  string
+	SortKeyName       string
+	AccountKeyPrefix  string
+	ProviderKeyPrefix string
+}
+
+// accountKey returns the key of the account records
+func (c TableConfig) accountKey(accountID domain.AccountID) string {
+	return c.AccountKeyPrefix + string(accountID)
+}
+
+// providerKey returns the key of the provider identity
+func (c TableConfig) providerKey(providerType domain.ProviderType, providerID string) string {
+	return c.ProviderKeyPrefix + string(providerType) + "#" + providerID
+}
+
+// attributeNames maps the default names to the configured ones
+func (c TableConfig) attributeNames() map[string]string {
+	return map[string]string{
+		TablePKName:               c.PartitionKeyName,
+		TableSKName:               c.SortKeyName,
+		ProviderIndexPKName:       c.ProviderIndexPKName,
+		ProviderIndexSKName:       c.ProviderIndexSKName,
+		AccountIDAttributeName:    c.AccountIDAttributeName,
+		ProviderTypeAttributeName: c.ProviderTypeAttributeName,
+		ProviderIDAttributeName:   c.ProviderIDAttributeName,
+		DateCreatedAttributeName:  c.DateCreatedAttributeName,
+		DisplayNameAttributeName:  c.DisplayNameAttributeName,
+		LocaleAttributeName:       c.LocaleAttributeName,
+		ExpiresAtAttributeName:    c.ExpiresAtAttributeName,
+	}
+}
+
+// marshalRecord marshals the record into a DynamoDB item using the configured names
+func (c TableConfig) marshalRecord(record ddbAccountProviderRecord) (map[string]types.AttributeValue, error) {
+	return c.marshalItem(record)
+}
+
+// unmarshalRecordData unmarshals a DynamoDB item using the configured names into the record data
+func (c TableConfig) unmarshalRecordData(item map[string]types.AttributeValue, data *ddbAccountProviderRecordData) error {
+	return c.unmarshalItem(item, data)
+}
+
+// marshalItem marshals any record into a DynamoDB item using the configured names
+func (c TableConfig) marshalItem(record any) (map[string]types.AttributeValue, error) {
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return nil, err
+	}
+	return renameAttributes(item, c.attributeNames()), nil
+}
+
+// unmarshalItem unmarshals a DynamoDB item using the configured names into any record
+func (c TableConfig) unmarshalItem(item map[string]types.AttributeValue, record any) error {
+	names := make(map[string]string)
+	for def, configured := range c.attributeNames() {
+		names[configured] = def
+	}
+	return attributevalue.UnmarshalMap(renameAttributes(item, names), record)
+}
+
+// renameAttributes returns a copy of the item with the attributes renamed, unknown attributes are kept
+func renameAttributes(item map[string]types.AttributeValue, names map[string]string) map[string]types.AttributeValue {
+	renamed := make(map[string]types.AttributeValue, len(item))
+	for k, v := range item {
+		if name, ok := names[k]; ok {
+			k = name
+		}
+		renamed[k] = v
+	}
+	return renamed
+}
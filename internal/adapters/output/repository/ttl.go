@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// WithGuestTTL sets the expiry of the accounts created with a guest identity, the table TTL must be enabled
+// on the ExpiresAt attribute for DynamoDB to delete them. When set, linking a provider to an account removes
+// the expiry so the account is kept.
+func WithGuestTTL(d time.Duration) RepositoryOption {
+	return func(r *dynamoDBAccountsRepository) {
+		r.guestTTL = d
+	}
+}
+
+// expiresAt returns the expiry epoch of a new account, only guest accounts expire
+func (r *dynamoDBAccountsRepository) expiresAt(providerType domain.ProviderType) int64 {
+	if providerType != domain.ProviderTypeGuest || r.guestTTL <= 0 {
+		return 0
+	}
+	return time.Now().Add(r.guestTTL).Unix()
+}
+
+// clearExpiryWriteItems builds the transaction items that remove the expiry from the records of the account
+// and their identity records, it returns the operation names of the items for error reporting
+func (r *dynamoDBAccountsRepository) clearExpiryWriteItems(ctx context.Context, accountID domain.AccountID) ([]types.TransactWriteItem, []string, error) {
+	pk := tenantPK(ctx, r.table.accountKey(accountID))
+	keyCond := expression.Key(r.table.PartitionKeyName).Equal(expression.Value(pk))
+	expr, err := expression.NewBuilder().
+		WithKeyCondition(keyCond).
+		WithFilter(expression.AttributeExists(expression.Name(r.table.ExpiresAtAttributeName))).
+		Build()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build expression: %w", err)
+	}
+
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.tableName),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query DynamoDB: %w", err)
+	}
+
+	var (
+		items      []types.TransactWriteItem
+		operations []string
+	)
+	for _, item := range result.Items {
+		if stringAttribute(item, r.table.SortKeyName) == AccountMetadataSKName {
+			metadataItem, err := r.removeExpiryItem(pk, AccountMetadataSKName)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, metadataItem)
+			operations = append(operations, "REMOVE Account metadata expiry")
+			continue
+		}
+		record := &ddbAccountProviderRecordData{}
+		if err := r.table.unmarshalRecordData(item, record); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal DynamoDB items: %w", err)
+		}
+		sk := r.table.providerKey(domain.ProviderType(record.ProviderType), record.ProviderID)
+
+		accountItem, err := r.removeExpiryItem(pk, sk)
+		if err != nil {
+			return nil, nil, err
+		}
+		identityItem, err := r.removeExpiryItem(tenantPK(ctx, sk), AccountIdentitySKName)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, accountItem, identityItem)
+		operations = append(operations, "REMOVE Account expiry", "REMOVE Provider Identity expiry")
+	}
+	return items, operations, nil
+}
+
+// removeExpiryItem builds the update that removes the expiry of an existing record
+func (r *dynamoDBAccountsRepository) removeExpiryItem(pk string, sk string) (types.TransactWriteItem, error) {
+	expr, err := expression.NewBuilder().
+		WithUpdate(expression.Remove(expression.Name(r.table.ExpiresAtAttributeName))).
+		// the update must not create the record when it was deleted meanwhile
+		WithCondition(expression.AttributeExists(expression.Name(r.table.PartitionKeyName))).
+		Build()
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("failed to build expiry update expression: %w", err)
+	}
+	return types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(r.tableName),
+			Key: map[string]types.AttributeValue{
+				r.table.PartitionKeyName: &types.AttributeValueMemberS{Value: pk},
+				r.table.SortKeyName:      &types.AttributeValueMemberS{Value: sk},
+			},
+			UpdateExpression:          expr.Update(),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		},
+	}, nil
+}
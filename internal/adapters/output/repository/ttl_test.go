@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/ovechkin-dm/mockio/v2/mock"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBAccountsRepository_WithGuestTTL_SetsExpiryOnGuests(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	idGeneratorMock := mock.Mock[ports.IDGenerator](ctrl)
+	mock.WhenSingle(idGeneratorMock.GenerateID()).ThenReturn("account_id")
+	captor := mock.Captor[*dynamodb.TransactWriteItemsInput]()
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), captor.Capture())).ThenReturn(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepositoryWithIDGenerator(clientMock, "accounts_test", idGeneratorMock, WithGuestTTL(24*time.Hour))
+
+	_, err := repo.Create(context.Background(), domain.ProviderTypeGuest, "guest_id")
+	require.NoError(t, err)
+	for _, item := range captor.Last().TransactItems {
+		attr, ok := item.Put.Item[ExpiresAtAttributeName].(*types.AttributeValueMemberN)
+		require.True(t, ok)
+		expiresAt, err := strconv.ParseInt(attr.Value, 10, 64)
+		require.NoError(t, err)
+		require.InDelta(t, time.Now().Add(24*time.Hour).Unix(), expiresAt, 5)
+	}
+
+	_, err = repo.Create(context.Background(), domain.ProviderTypeGoogle, "google_id")
+	require.NoError(t, err)
+	for _, item := range captor.Last().TransactItems {
+		require.NotContains(t, item.Put.Item, ExpiresAtAttributeName)
+	}
+}
+
+func TestDynamoDBAccountsRepository_WithoutGuestTTL_GuestsDoNotExpire(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	captor := mock.Captor[*dynamodb.TransactWriteItemsInput]()
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), captor.Capture())).ThenReturn(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test")
+	_, err := repo.Create(context.Background(), domain.ProviderTypeGuest, "guest_id")
+	require.NoError(t, err)
+	for _, item := range captor.Last().TransactItems {
+		require.NotContains(t, item.Put.Item, ExpiresAtAttributeName)
+	}
+}
+
+func TestDynamoDBAccountsRepository_LinkProvider_ClearsGuestExpiry(t *testing.T) {
+	ctx := domain.ContextWithTenantID(context.Background(), "game-1")
+	accountID := domain.AccountID("account_id")
+
+	ctrl := mock.NewMockController(t)
+	clientMock := mock.Mock[DynamoDBAPI](ctrl)
+	guestRecord := map[string]types.AttributeValue{
+		"PK":           &types.AttributeValueMemberS{Value: "TNT#game-1#ACNT#account_id"},
+		"SK":           &types.AttributeValueMemberS{Value: "PVDR#guest#guest_id"},
+		"AccountID":    &types.AttributeValueMemberS{Value: string(accountID)},
+		"ProviderType": &types.AttributeValueMemberS{Value: string(domain.ProviderTypeGuest)},
+		"ProviderID":   &types.AttributeValueMemberS{Value: "guest_id"},
+		"DateCreated":  &types.AttributeValueMemberS{Value: "2023-10-01T00:00:00Z"},
+		"ExpiresAt":    &types.AttributeValueMemberN{Value: "1700000000"},
+	}
+	queryCaptor := mock.Captor[*dynamodb.QueryInput]()
+	mock.WhenDouble(clientMock.Query(mock.Any[context.Context](), queryCaptor.Capture())).ThenReturn(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{guestRecord},
+	}, nil).ThenReturn(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{
+			{
+				"PK":          &types.AttributeValueMemberS{Value: "TNT#game-1#ACNT#account_id"},
+				"SK":          &types.AttributeValueMemberS{Value: AccountMetadataSKName},
+				"AccountID":   &types.AttributeValueMemberS{Value: string(accountID)},
+				"DateUpdated": &types.AttributeValueMemberS{Value: "2023-10-01T00:00:00Z"},
+				"ExpiresAt":   &types.AttributeValueMemberN{Value: "1700000000"},
+			},
+			guestRecord,
+		},
+	}, nil)
+	captor := mock.Captor[*dynamodb.TransactWriteItemsInput]()
+	mock.WhenDouble(clientMock.TransactWriteItems(mock.Any[context.Context](), captor.Capture())).ThenReturn(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	repo := NewDynamoDBAccountsRepository(clientMock, "accounts_test", WithGuestTTL(24*time.Hour))
+	err := repo.LinkProvider(ctx, accountID, domain.ProviderIdentity{ProviderType: domain.ProviderTypeGoogle, ProviderID: "google_id"})
+	require.NoError(t, err)
+
+	require.Equal(t, &types.AttributeValueMemberS{Value: "TNT#game-1#ACNT#account_id"}, queryCaptor.Last().ExpressionAttributeValues[":0"])
+
+	// the conditional expiry update of the account record checks the account exists
+	items := captor.Last().TransactItems
+	require.Len(t, items, 5)
+	for _, item := range items[:2] {
+		require.NotContains(t, item.Put.Item, ExpiresAtAttributeName)
+	}
+	var keys [][2]string
+	for _, item := range items[2:] {
+		require.NotNil(t, item.Update)
+		require.Contains(t, *item.Update.UpdateExpression, "REMOVE")
+		require.Contains(t, slices.Collect(maps.Values(item.Update.ExpressionAttributeNames)), ExpiresAtAttributeName)
+		keys = append(keys, [2]string{
+			item.Update.Key["PK"].(*types.AttributeValueMemberS).Value,
+			item.Update.Key["SK"].(*types.AttributeValueMemberS).Value,
+		})
+	}
+	require.Equal(t, [][2]string{
+		{"TNT#game-1#ACNT#account_id", AccountMetadataSKName},
+		{"TNT#game-1#ACNT#account_id", "PVDR#guest#guest_id"},
+		{"TNT#game-1#PVDR#guest#guest_id", AccountIdentitySKName},
+	}, keys)
+}
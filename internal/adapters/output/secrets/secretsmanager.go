@@ -0,0 +1,139 @@
+// Package secrets provides adapters to resolve secret references from external secret stores.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// DefaultCacheTTL is the default time a secret is kept before it is fetched again
+const DefaultCacheTTL = 5 * time.Minute
+
+// SecretsManagerAPI defines the AWS Secrets Manager operations used by the provider to make it easy to mock in tests
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// keySeparator splits a reference into the secret name and the JSON key,
+// Secrets Manager names cannot contain it while they often contain slashes
+const keySeparator = "#"
+
+// cachedSecret is a secret string fetched from AWS Secrets Manager
+type cachedSecret struct {
+	value     string
+	expiresAt time.Time
+}
+
+// secretsManagerProvider resolves the references against AWS Secrets Manager
+type secretsManagerProvider struct {
+	client SecretsManagerAPI
+	ttl    time.Duration
+	now    func() time.Time
+
+	mutex sync.Mutex
+	cache map[string]cachedSecret
+}
+
+// Safeguard check to ensure secretsManagerProvider implements the SecretsProvider interface
+var _ ports.SecretsProvider = (*secretsManagerProvider)(nil)
+
+// SecretsManagerOption configures the AWS Secrets Manager provider
+type SecretsManagerOption func(*secretsManagerProvider)
+
+// WithCacheTTL sets the time a secret is kept before it is fetched again, a non positive value disables the cache
+func WithCacheTTL(ttl time.Duration) SecretsManagerOption {
+	return func(p *secretsManagerProvider) {
+		p.ttl = ttl
+	}
+}
+
+// NewAWSSecretsManagerProvider creates a secrets provider backed by AWS Secrets Manager.
+// A reference is either the secret name, resolving to the whole secret string, or name#key,
+// resolving to the key of a secret stored as a JSON object. The secrets are cached by name.
+func NewAWSSecretsManagerProvider(client SecretsManagerAPI, opts ...SecretsManagerOption) ports.SecretsProvider {
+	p := &secretsManagerProvider{
+		client: client,
+		ttl:    DefaultCacheTTL,
+		now:    time.Now,
+		cache:  make(map[string]cachedSecret),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Get returns the value the reference points to
+func (p *secretsManagerProvider) Get(ctx context.Context, ref string) (string, error) {
+	name, key, hasKey := strings.Cut(ref, keySeparator)
+	if !hasKey {
+		return p.secretString(ctx, ref)
+	}
+
+	value, err := p.secretString(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("failed to decode secret %s: %w", name, err)
+	}
+	field, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s has no string key %s", domain.ErrSecretNotFound, name, key)
+	}
+	return field, nil
+}
+
+// secretString returns the secret string from the cache, on a miss or expiry it is fetched again,
+// the lock is not held while fetching so a slow call does not block the cached secrets
+func (p *secretsManagerProvider) secretString(ctx context.Context, name string) (string, error) {
+	if value, ok := p.cached(name); ok {
+		return value, nil
+	}
+
+	out, err := p.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return "", fmt.Errorf("%w: %s", domain.ErrSecretNotFound, name)
+		}
+		return "", fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("%w: %s has no secret string", domain.ErrSecretNotFound, name)
+	}
+
+	if p.ttl > 0 {
+		p.mutex.Lock()
+		p.cache[name] = cachedSecret{value: *out.SecretString, expiresAt: p.now().Add(p.ttl)}
+		p.mutex.Unlock()
+	}
+	return *out.SecretString, nil
+}
+
+// cached returns the secret string of name when it is cached and not expired
+func (p *secretsManagerProvider) cached(name string) (string, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	cached, ok := p.cache[name]
+	if !ok || !p.now().Before(cached.expiresAt) {
+		return "", false
+	}
+	return cached.value, true
+}
@@ -0,0 +1,168 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSecretsManager serves the secret strings from a map and counts the calls
+type fakeSecretsManager struct {
+	secrets map[string]string
+	calls   int
+}
+
+func (f *fakeSecretsManager) GetSecretValue(_ context.Context, params *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	f.calls++
+	value, ok := f.secrets[aws.ToString(params.SecretId)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(value)}, nil
+}
+
+func TestSecretsManagerProvider_Get(t *testing.T) {
+	client := &fakeSecretsManager{secrets: map[string]string{
+		"prod/identity": `{"google_client_secret":"g-secret","epic_client_secret":"e-secret","port":8080}`,
+		"plain":         "plain-value",
+	}}
+	p := NewAWSSecretsManagerProvider(client)
+	ctx := context.Background()
+
+	value, err := p.Get(ctx, "prod/identity#google_client_secret")
+	require.NoError(t, err)
+	require.Equal(t, "g-secret", value)
+
+	value, err = p.Get(ctx, "prod/identity#epic_client_secret")
+	require.NoError(t, err)
+	require.Equal(t, "e-secret", value)
+	// both keys come from the same cached secret
+	require.Equal(t, 1, client.calls)
+
+	value, err = p.Get(ctx, "plain")
+	require.NoError(t, err)
+	require.Equal(t, "plain-value", value)
+
+	// the slashes belong to the secret name
+	value, err = p.Get(ctx, "prod/identity")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"google_client_secret":"g-secret","epic_client_secret":"e-secret","port":8080}`, value)
+}
+
+func TestSecretsManagerProvider_Get_Missing(t *testing.T) {
+	p := NewAWSSecretsManagerProvider(&fakeSecretsManager{secrets: map[string]string{
+		"prod/identity": `{"port":8080}`,
+	}})
+	ctx := context.Background()
+
+	for _, ref := range []string{"unknown", "prod/identity#missing", "prod/identity#port"} {
+		_, err := p.Get(ctx, ref)
+		require.ErrorIs(t, err, domain.ErrSecretNotFound, ref)
+	}
+}
+
+func TestSecretsManagerProvider_Get_NotJSON(t *testing.T) {
+	p := NewAWSSecretsManagerProvider(&fakeSecretsManager{secrets: map[string]string{"prod": "plain-value"}})
+
+	_, err := p.Get(context.Background(), "prod#key")
+	require.ErrorContains(t, err, "failed to decode secret prod")
+}
+
+func TestSecretsManagerProvider_Get_RefreshesAfterTTL(t *testing.T) {
+	client := &fakeSecretsManager{secrets: map[string]string{"plain": "v1"}}
+	p := NewAWSSecretsManagerProvider(client, WithCacheTTL(time.Minute)).(*secretsManagerProvider)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	value, err := p.Get(ctx, "plain")
+	require.NoError(t, err)
+	require.Equal(t, "v1", value)
+
+	client.secrets["plain"] = "v2"
+	value, err = p.Get(ctx, "plain")
+	require.NoError(t, err)
+	require.Equal(t, "v1", value)
+
+	now = now.Add(time.Minute)
+	value, err = p.Get(ctx, "plain")
+	require.NoError(t, err)
+	require.Equal(t, "v2", value)
+	require.Equal(t, 2, client.calls)
+}
+
+func TestSecretsManagerProvider_Get_WrapsClientErrors(t *testing.T) {
+	clientErr := errors.New("access denied")
+	p := NewAWSSecretsManagerProvider(erroringSecretsManager{err: clientErr})
+
+	_, err := p.Get(context.Background(), "plain")
+	require.ErrorIs(t, err, clientErr)
+	require.NotErrorIs(t, err, domain.ErrSecretNotFound)
+}
+
+// erroringSecretsManager fails every call with err
+type erroringSecretsManager struct {
+	err error
+}
+
+func (f erroringSecretsManager) GetSecretValue(context.Context, *secretsmanager.GetSecretValueInput, ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	return nil, f.err
+}
+
+func TestSecretsManagerProvider_Get_DoesNotBlockCachedSecretsWhileFetching(t *testing.T) {
+	release := make(chan struct{})
+	client := &blockingSecretsManager{
+		fakeSecretsManager: fakeSecretsManager{secrets: map[string]string{"plain": "plain-value", "slow": "slow-value"}},
+		block:              map[string]chan struct{}{"slow": release},
+		started:            make(chan struct{}),
+	}
+	p := NewAWSSecretsManagerProvider(client)
+	ctx := context.Background()
+
+	_, err := p.Get(ctx, "plain")
+	require.NoError(t, err)
+
+	slow := make(chan error, 1)
+	go func() {
+		_, err := p.Get(ctx, "slow")
+		slow <- err
+	}()
+	<-client.started
+
+	cached := make(chan string, 1)
+	go func() {
+		value, _ := p.Get(ctx, "plain")
+		cached <- value
+	}()
+	select {
+	case value := <-cached:
+		require.Equal(t, "plain-value", value)
+	case <-time.After(time.Second):
+		t.Fatal("cached secret blocked by the in flight fetch")
+	}
+
+	close(release)
+	require.NoError(t, <-slow)
+}
+
+// blockingSecretsManager holds the calls of the names in block until their channel is closed
+type blockingSecretsManager struct {
+	fakeSecretsManager
+	block   map[string]chan struct{}
+	started chan struct{}
+}
+
+func (f *blockingSecretsManager) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	if release, ok := f.block[aws.ToString(params.SecretId)]; ok {
+		close(f.started)
+		<-release
+	}
+	return f.fakeSecretsManager.GetSecretValue(ctx, params, optFns...)
+}
@@ -1,5 +1,62 @@
 package domain
 
+import (
+	"context"
+	"time"
+)
+
 const EmptyAccountID = AccountID("")
 
 type AccountID string
+
+type accountIDContextKey struct{}
+
+// ContextWithAccount returns a copy of the context carrying the authenticated account ID
+func ContextWithAccount(ctx context.Context, accountID AccountID) context.Context {
+	return context.WithValue(ctx, accountIDContextKey{}, accountID)
+}
+
+// AccountFromContext returns the authenticated account ID carried by the context, if any
+func AccountFromContext(ctx context.Context) (AccountID, bool) {
+	accountID, ok := ctx.Value(accountIDContextKey{}).(AccountID)
+	return accountID, ok && accountID != EmptyAccountID
+}
+
+// Account is the account aggregate with every provider identity linked to it
+type Account struct {
+	ID        AccountID
+	Providers []LinkedProvider
+	CreatedAt time.Time
+}
+
+// AccountMetadata holds the profile information a game keeps for an account, empty fields are not set
+type AccountMetadata struct {
+	DisplayName string
+	AvatarURL   string
+	Email       string
+	// EmailVerified reports whether the provider that shared the email verified its ownership
+	EmailVerified bool
+}
+
+// LinkedProvider is a provider identity linked to an account
+type LinkedProvider struct {
+	Type       ProviderType
+	ProviderID string
+	LinkedAt   time.Time
+	// DisplayName and Locale are the provider profile subset captured at creation, empty when not shared
+	DisplayName string
+	Locale      string
+}
+
+// ProviderIdentity identifies an account in an external provider
+type ProviderIdentity struct {
+	ProviderType ProviderType
+	ProviderID   string
+}
+
+// LinkResult represents the outcome of linking a single provider identity to an account,
+// Err is nil when the identity was linked
+type LinkResult struct {
+	Identity ProviderIdentity
+	Err      error
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountFromContext(t *testing.T) {
+	_, ok := AccountFromContext(context.Background())
+	require.False(t, ok)
+
+	_, ok = AccountFromContext(ContextWithAccount(context.Background(), EmptyAccountID))
+	require.False(t, ok)
+
+	accountID, ok := AccountFromContext(ContextWithAccount(context.Background(), "account-1"))
+	require.True(t, ok)
+	require.Equal(t, AccountID("account-1"), accountID)
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// AuditAction identifies the action recorded by an audit event
+type AuditAction string
+
+// AuditResult identifies the outcome recorded by an audit event
+type AuditResult string
+
+const (
+	AuditActionAuthenticate AuditAction = "authenticate"
+
+	AuditResultSuccess AuditResult = "success"
+	AuditResultFailure AuditResult = "failure"
+)
+
+// AuditEvent represents a security relevant event to be shipped to a SIEM
+type AuditEvent struct {
+	// Actor is the account ID that performed the action, empty when it could not be resolved
+	Actor     AccountID
+	Action    AuditAction
+	Result    AuditResult
+	SourceIP  string
+	Provider  ProviderType
+	Timestamp time.Time
+	// Reason describes why the action failed, empty on success
+	Reason string
+}
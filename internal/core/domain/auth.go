@@ -1,9 +1,70 @@
 package domain
 
+import (
+	"fmt"
+	"strings"
+)
+
+// AttestationAuthDataKey is the authentication data field carrying the device attestation token
+const AttestationAuthDataKey = "attestation"
+
 // AuthenticateInput represents the input for the authentication process.
 type AuthenticateInput struct {
 	ProviderType ProviderType
 	AuthData     map[string]string
+	// TenantID is the game or tenant the account belongs to, empty uses the default tenant
+	TenantID TenantID
+	// SourceIP is the address of the client that originated the request, used for auditing
+	SourceIP string
+}
+
+// Validate checks the provider type is known, the authentication data is present and the tenant ID is valid,
+// it returns a *ValidationError listing every invalid field
+func (i AuthenticateInput) Validate() error {
+	var fields []FieldError
+	if _, err := ParseProviderType(string(i.ProviderType)); err != nil {
+		fields = append(fields, FieldError{Field: "provider_type", Err: err})
+	}
+	if i.AuthData == nil {
+		fields = append(fields, FieldError{Field: "auth_data", Err: ErrMissingRequiredProviderAuthData})
+	}
+	if err := i.TenantID.Validate(); err != nil {
+		fields = append(fields, FieldError{Field: "tenant_id", Err: err})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// FieldError describes why an input field is invalid
+type FieldError struct {
+	// Field is the name of the invalid field as exposed by the API
+	Field string
+	// Err is the reason the field is invalid
+	Err error
+}
+
+// ValidationError reports the invalid fields of an input, it matches ErrInvalidInput and the error of every field
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	details := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		details = append(details, f.Field+": "+f.Err.Error())
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidInput, strings.Join(details, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Fields)+1)
+	errs = append(errs, ErrInvalidInput)
+	for _, f := range e.Fields {
+		errs = append(errs, f.Err)
+	}
+	return errs
 }
 
 // AuthenticateOutput represents the output of the authentication process.
@@ -13,3 +74,11 @@ type AuthenticateOutput struct {
 	// IsNew indicates if the account was newly created during authentication
 	IsNew bool
 }
+
+// AuthenticateResult represents the outcome of one input of a batch authentication.
+type AuthenticateResult struct {
+	// Output is the authentication output, nil when the authentication failed
+	Output *AuthenticateOutput
+	// Err is the reason the authentication failed
+	Err error
+}
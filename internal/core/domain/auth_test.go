@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticateInput_Validate(t *testing.T) {
+	t.Run("valid input", func(t *testing.T) {
+		input := AuthenticateInput{ProviderType: ProviderTypeGuest, AuthData: map[string]string{}, TenantID: "game-1"}
+		require.NoError(t, input.Validate())
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		err := AuthenticateInput{ProviderType: "myspace", AuthData: map[string]string{}}.Validate()
+
+		require.ErrorIs(t, err, ErrInvalidInput)
+		require.ErrorIs(t, err, ErrProviderNotFound)
+		require.EqualError(t, err, `invalid input: provider_type: provider not found: "myspace"`)
+	})
+
+	t.Run("nil auth data", func(t *testing.T) {
+		err := AuthenticateInput{ProviderType: ProviderTypeGuest}.Validate()
+
+		require.ErrorIs(t, err, ErrInvalidInput)
+		require.ErrorIs(t, err, ErrMissingRequiredProviderAuthData)
+	})
+
+	t.Run("every invalid field is reported", func(t *testing.T) {
+		err := AuthenticateInput{ProviderType: "", TenantID: "game#1"}.Validate()
+
+		var validationErr *ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		fields := make([]string, 0, len(validationErr.Fields))
+		for _, f := range validationErr.Fields {
+			fields = append(fields, f.Field)
+		}
+		require.Equal(t, []string{"provider_type", "auth_data", "tenant_id"}, fields)
+		require.ErrorIs(t, err, ErrInvalidTenantID)
+	})
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrorCode is the stable machine readable code of an error, clients branch on it instead of the message
+type ErrorCode string
+
+const (
+	ErrorCodeInternal                = ErrorCode("internal")
+	ErrorCodeProviderNotFound        = ErrorCode("provider_not_found")
+	ErrorCodeAccountNotFound         = ErrorCode("account_not_found")
+	ErrorCodeAccountAlreadyExists    = ErrorCode("account_already_exists")
+	ErrorCodeMissingProviderAuthData = ErrorCode("missing_provider_auth_data")
+	ErrorCodeInvalidProviderAuthData = ErrorCode("invalid_provider_auth_data")
+	ErrorCodeInsufficientScope       = ErrorCode("insufficient_scope")
+	ErrorCodeInvalidTenantID         = ErrorCode("invalid_tenant_id")
+	ErrorCodeInvalidCursor           = ErrorCode("invalid_cursor")
+	ErrorCodeAttestationFailed       = ErrorCode("attestation_failed")
+	ErrorCodeSecretNotFound          = ErrorCode("secret_not_found")
+	ErrorCodeInvalidInput            = ErrorCode("invalid_input")
+	ErrorCodeProviderUnavailable     = ErrorCode("provider_unavailable")
+	ErrorCodeTokenMalformed          = ErrorCode("token_malformed")
+	ErrorCodeTokenExpired            = ErrorCode("token_expired")
+	ErrorCodeTokenNotYetValid        = ErrorCode("token_not_yet_valid")
+	ErrorCodeTokenInvalidSignature   = ErrorCode("token_invalid_signature")
+	ErrorCodeTokenInvalidIssuer      = ErrorCode("token_invalid_issuer")
+	ErrorCodeTokenInvalidAudience    = ErrorCode("token_invalid_audience")
+	ErrorCodeTokenInvalidNonce       = ErrorCode("token_invalid_nonce")
+	ErrorCodeNonceReused             = ErrorCode("nonce_reused")
+	ErrorCodeTokenInvalidClaims      = ErrorCode("token_invalid_claims")
+	ErrorCodeThrottled               = ErrorCode("throttled")
+	ErrorCodeTransactionConflict     = ErrorCode("transaction_conflict")
+	ErrorCodeValidation              = ErrorCode("validation_failed")
+	ErrorCodeTimeout                 = ErrorCode("timeout")
+	ErrorCodeUnauthenticated         = ErrorCode("unauthenticated")
+	ErrorCodeRequestTooLarge         = ErrorCode("request_too_large")
+)
+
+// errorCodes maps the sentinel errors to their codes, it is ordered so the domain errors win over the
+// causes they wrap
+var errorCodes = []struct {
+	err  error
+	code ErrorCode
+}{
+	{ErrProviderNotFound, ErrorCodeProviderNotFound},
+	{ErrAccountNotFound, ErrorCodeAccountNotFound},
+	{ErrProviderIDOrAccountAlreadyExists, ErrorCodeAccountAlreadyExists},
+	{ErrMissingRequiredProviderAuthData, ErrorCodeMissingProviderAuthData},
+	{ErrInvalidProviderAuthData, ErrorCodeInvalidProviderAuthData},
+	{ErrInsufficientScope, ErrorCodeInsufficientScope},
+	{ErrInvalidTenantID, ErrorCodeInvalidTenantID},
+	{ErrInvalidCursor, ErrorCodeInvalidCursor},
+	{ErrAttestationFailed, ErrorCodeAttestationFailed},
+	{ErrSecretNotFound, ErrorCodeSecretNotFound},
+	{ErrInvalidInput, ErrorCodeInvalidInput},
+	{ErrProviderUnavailable, ErrorCodeProviderUnavailable},
+	{ErrTokenMalformed, ErrorCodeTokenMalformed},
+	{ErrTokenExpired, ErrorCodeTokenExpired},
+	{ErrTokenNotYetValid, ErrorCodeTokenNotYetValid},
+	{ErrTokenInvalidSignature, ErrorCodeTokenInvalidSignature},
+	{ErrTokenInvalidIssuer, ErrorCodeTokenInvalidIssuer},
+	{ErrTokenInvalidAudience, ErrorCodeTokenInvalidAudience},
+	{ErrTokenInvalidNonce, ErrorCodeTokenInvalidNonce},
+	{ErrNonceReused, ErrorCodeNonceReused},
+	{ErrTokenInvalidClaims, ErrorCodeTokenInvalidClaims},
+	{ErrThrottled, ErrorCodeThrottled},
+	{ErrTransactionConflict, ErrorCodeTransactionConflict},
+	{ErrValidation, ErrorCodeValidation},
+	{context.DeadlineExceeded, ErrorCodeTimeout},
+}
+
+// CodeFor returns the code of the first sentinel error the error wraps, unknown errors are internal
+func CodeFor(err error) ErrorCode {
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+	return ErrorCodeInternal
+}
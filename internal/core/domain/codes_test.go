@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeFor(t *testing.T) {
+	for _, ec := range errorCodes {
+		t.Run(string(ec.code), func(t *testing.T) {
+			require.Equal(t, ec.code, CodeFor(ec.err))
+			require.Equal(t, ec.code, CodeFor(fmt.Errorf("wrapped: %w", ec.err)))
+		})
+	}
+}
+
+func TestCodeFor_SentinelsHaveUniqueCodes(t *testing.T) {
+	seen := map[ErrorCode]bool{}
+	for _, ec := range errorCodes {
+		require.False(t, seen[ec.code], "duplicated code %s", ec.code)
+		require.NotEqual(t, ErrorCodeInternal, ec.code)
+		seen[ec.code] = true
+	}
+}
+
+func TestCodeFor_DomainErrorWinsOverCause(t *testing.T) {
+	err := fmt.Errorf("failed to fetch public keys: %w: %w", ErrProviderUnavailable, context.DeadlineExceeded)
+	require.Equal(t, ErrorCodeProviderUnavailable, CodeFor(err))
+}
+
+func TestCodeFor_Unknown(t *testing.T) {
+	require.Equal(t, ErrorCodeInternal, CodeFor(errors.New("boom")))
+	require.Equal(t, ErrorCodeInternal, CodeFor(nil))
+}
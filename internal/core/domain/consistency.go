@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+type consistentReadContextKey struct{}
+
+// ContextWithConsistentRead returns a copy of the context asking the repository reads to see every write completed
+// before them, e.g. to resolve an account a concurrent request just created
+func ContextWithConsistentRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consistentReadContextKey{}, true)
+}
+
+// ConsistentReadFromContext reports whether the context asks for consistent reads
+func ConsistentReadFromContext(ctx context.Context) bool {
+	consistent, _ := ctx.Value(consistentReadContextKey{}).(bool)
+	return consistent
+}
@@ -0,0 +1,13 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsistentReadFromContext(t *testing.T) {
+	require.False(t, ConsistentReadFromContext(context.Background()))
+	require.True(t, ConsistentReadFromContext(ContextWithConsistentRead(context.Background())))
+}
@@ -7,4 +7,32 @@ var (
 	ErrAccountNotFound                  = errors.New("account not found")
 	ErrProviderIDOrAccountAlreadyExists = errors.New("provider ID or account already exists")
 	ErrMissingRequiredProviderAuthData  = errors.New("missing required provider authentication data")
+	ErrInvalidProviderAuthData          = errors.New("invalid provider authentication data")
+	ErrInsufficientScope                = errors.New("insufficient scope granted by provider")
+	ErrInvalidTenantID                  = errors.New("invalid tenant ID")
+	ErrInvalidCursor                    = errors.New("invalid pagination cursor")
+	ErrAttestationFailed                = errors.New("device attestation failed")
+	ErrSecretNotFound                   = errors.New("secret not found")
+	ErrInvalidInput                     = errors.New("invalid input")
+)
+
+// Provider token verification errors, providers wrap them so callers can tell the failures apart with errors.Is
+var (
+	ErrProviderUnavailable   = errors.New("provider unavailable")
+	ErrTokenMalformed        = errors.New("token malformed")
+	ErrTokenExpired          = errors.New("token expired")
+	ErrTokenNotYetValid      = errors.New("token not yet valid")
+	ErrTokenInvalidSignature = errors.New("token signature invalid")
+	ErrTokenInvalidIssuer    = errors.New("token issuer invalid")
+	ErrTokenInvalidAudience  = errors.New("token audience invalid")
+	ErrTokenInvalidNonce     = errors.New("token nonce invalid")
+	ErrNonceReused           = errors.New("nonce reused")
+	ErrTokenInvalidClaims    = errors.New("token claims invalid")
+)
+
+// Persistence errors, repositories wrap them so callers can decide whether to retry
+var (
+	ErrThrottled           = errors.New("request throttled")
+	ErrTransactionConflict = errors.New("transaction conflict")
+	ErrValidation          = errors.New("request validation failed")
 )
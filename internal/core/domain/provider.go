@@ -1,9 +1,47 @@
 package domain
 
+import "fmt"
+
 type ProviderType string
 
 const (
-	ProviderTypeGuest  ProviderType = "guest"
-	ProviderTypeGoogle ProviderType = "google"
-	ProviderTypeApple  ProviderType = "apple"
+	ProviderTypeGuest    ProviderType = "guest"
+	ProviderTypeGoogle   ProviderType = "google"
+	ProviderTypeApple    ProviderType = "apple"
+	ProviderTypeFacebook ProviderType = "facebook"
+	ProviderTypeTwitch   ProviderType = "twitch"
+	ProviderTypeEpic     ProviderType = "epic"
 )
+
+// providerTypes is the set of known provider types
+var providerTypes = map[ProviderType]struct{}{
+	ProviderTypeGuest:    {},
+	ProviderTypeGoogle:   {},
+	ProviderTypeApple:    {},
+	ProviderTypeFacebook: {},
+	ProviderTypeTwitch:   {},
+	ProviderTypeEpic:     {},
+}
+
+// IsValid reports whether the provider type is a known provider type
+func (p ProviderType) IsValid() bool {
+	_, ok := providerTypes[p]
+	return ok
+}
+
+// ParseProviderType converts a string into a known provider type, returns ErrProviderNotFound for unknown values
+func ParseProviderType(s string) (ProviderType, error) {
+	p := ProviderType(s)
+	if !p.IsValid() {
+		return "", fmt.Errorf("%w: %q", ErrProviderNotFound, s)
+	}
+	return p, nil
+}
+
+// ProviderProfile holds the optional profile information exposed by a provider, empty fields mean
+// the provider did not share them
+type ProviderProfile struct {
+	DisplayName string
+	AvatarURL   string
+	Locale      string
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProviderType(t *testing.T) {
+	for _, s := range []string{"guest", "google", "apple", "facebook", "twitch", "epic"} {
+		t.Run(s, func(t *testing.T) {
+			p, err := ParseProviderType(s)
+			require.NoError(t, err)
+			require.Equal(t, ProviderType(s), p)
+			require.True(t, p.IsValid())
+		})
+	}
+}
+
+func TestParseProviderType_Unknown(t *testing.T) {
+	for _, s := range []string{"", "twitter", "Google", " apple"} {
+		t.Run(s, func(t *testing.T) {
+			p, err := ParseProviderType(s)
+			require.ErrorIs(t, err, ErrProviderNotFound)
+			require.Empty(t, p)
+			require.False(t, ProviderType(s).IsValid())
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// DefaultTenantID is the tenant used when none is provided, it keeps the keys without a tenant prefix
+const DefaultTenantID = TenantID("")
+
+// TenantID identifies the game or tenant that owns an account, accounts of different tenants never collide
+type TenantID string
+
+var tenantIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Validate checks the tenant ID is either the default tenant or made of up to 64 letters, digits, '-' or '_'
+func (t TenantID) Validate() error {
+	if t == DefaultTenantID || tenantIDPattern.MatchString(string(t)) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidTenantID, string(t))
+}
+
+type tenantIDContextKey struct{}
+
+// ContextWithTenantID returns a copy of the context carrying the tenant ID
+func ContextWithTenantID(ctx context.Context, tenantID TenantID) context.Context {
+	return context.WithValue(ctx, tenantIDContextKey{}, tenantID)
+}
+
+// TenantIDFromContext returns the tenant ID carried by the context or the default tenant
+func TenantIDFromContext(ctx context.Context) TenantID {
+	if tenantID, ok := ctx.Value(tenantIDContextKey{}).(TenantID); ok {
+		return tenantID
+	}
+	return DefaultTenantID
+}
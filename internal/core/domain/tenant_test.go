@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantID_Validate(t *testing.T) {
+	for _, tenantID := range []TenantID{DefaultTenantID, "game-1", "Game_2", TenantID(strings.Repeat("a", 64))} {
+		require.NoError(t, tenantID.Validate(), tenantID)
+	}
+	for _, tenantID := range []TenantID{"game#1", "game 1", "game/1", TenantID(strings.Repeat("a", 65))} {
+		require.ErrorIs(t, tenantID.Validate(), ErrInvalidTenantID, tenantID)
+	}
+}
+
+func TestTenantIDFromContext(t *testing.T) {
+	require.Equal(t, DefaultTenantID, TenantIDFromContext(context.Background()))
+
+	ctx := ContextWithTenantID(context.Background(), "game-1")
+	require.Equal(t, TenantID("game-1"), TenantIDFromContext(ctx))
+}
@@ -3,23 +3,37 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/pkg/tokens"
 )
 
 // AuthService defines the interface for authentication services.
 type AuthService interface {
 	Authenticate(context.Context, domain.AuthenticateInput) (*domain.AuthenticateOutput, error)
+	AuthenticateBatch(context.Context, []domain.AuthenticateInput) ([]domain.AuthenticateResult, error)
 }
 
 // AuthResult defines the interface for providers authentication results.
 type AuthResult interface {
 	GetID() string
+	GetProfile() domain.ProviderProfile
+}
+
+// AuthResultWithEmail is optionally implemented by the results of providers that share the account email.
+type AuthResultWithEmail interface {
+	AuthResult
+	GetEmail() string
+	// GetEmailVerified reports whether the provider verified the ownership of the email
+	GetEmailVerified() bool
 }
 
 // AuthProvider defines the interface for authentication providers.
 type AuthProvider interface {
 	Authenticate(context.Context, map[string]string) (AuthResult, error)
+	// RequiredFields returns the authentication data fields Authenticate can't do without
+	RequiredFields() []string
 }
 
 // AuthProviderFactory defines the interface for creating authentication providers.
@@ -27,12 +41,63 @@ type AuthProviderFactory interface {
 	Get(providerType domain.ProviderType) (AuthProvider, error)
 	Add(providerType domain.ProviderType, provider AuthProvider) error
 	Remove(providerType domain.ProviderType) error
+	Replace(providerType domain.ProviderType, provider AuthProvider) error
+	List() []domain.ProviderType
 }
 
 // AccountsRepository defines the interface for account repository operations.
 type AccountsRepository interface {
 	ResolveIDByProvider(context.Context, domain.ProviderType, string) (domain.AccountID, error)
+	ResolveAccountByProvider(context.Context, domain.ProviderType, string) (*domain.Account, error)
 	Create(context.Context, domain.ProviderType, string) (domain.AccountID, error)
+	CreateWithProfile(context.Context, domain.ProviderType, string, domain.ProviderProfile) (domain.AccountID, error)
+	LinkProvider(context.Context, domain.AccountID, domain.ProviderIdentity) error
+	LinkBatch(context.Context, domain.AccountID, []domain.ProviderIdentity) ([]domain.LinkResult, error)
+	// UpdateAccountMetadata replaces the metadata of the account, it returns ErrAccountNotFound if the account does not exist
+	UpdateAccountMetadata(context.Context, domain.AccountID, domain.AccountMetadata) error
+	// GetAccountMetadata returns the metadata of the account, the zero metadata when none was stored
+	GetAccountMetadata(context.Context, domain.AccountID) (domain.AccountMetadata, error)
+}
+
+// AccountsAdminRepository defines the interface for the operator oriented account operations,
+// they can be expensive and must not be used in the authentication hot path.
+type AccountsAdminRepository interface {
+	ListAccounts(ctx context.Context, limit int, cursor string) ([]domain.Account, string, error)
+}
+
+// AuditLogger defines the interface for emitting security audit events.
+type AuditLogger interface {
+	Record(context.Context, domain.AuditEvent) error
+}
+
+// AccountLifecycleListener defines the interface for reacting to account lifecycle events (e.g. analytics,
+// welcome emails or starter rewards), a failing listener never fails the operation that raised the event
+type AccountLifecycleListener interface {
+	OnAccountCreated(ctx context.Context, account domain.Account) error
+}
+
+// AttestationVerifier defines the interface for verifying the device attestation sent along the authentication data,
+// it returns an error wrapping domain.ErrAttestationFailed when the attestation is rejected
+type AttestationVerifier interface {
+	Verify(ctx context.Context, providerType domain.ProviderType, attestation string) error
+}
+
+// NonceStore defines the interface for remembering the nonces already used so a token can't be replayed,
+// ConsumeOnce returns false when the nonce was consumed within its ttl
+type NonceStore interface {
+	ConsumeOnce(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// SecretsProvider defines the interface for resolving secret references from an external secret store,
+// it returns an error wrapping domain.ErrSecretNotFound when the reference does not exist
+type SecretsProvider interface {
+	Get(ctx context.Context, ref string) (string, error)
+}
+
+// SessionVerifier defines the interface for verifying the session tokens presented to the protected endpoints
+// and methods, *tokens.Verifier implements it
+type SessionVerifier interface {
+	Verify(ctx context.Context, token string) (*tokens.Claims, error)
 }
 
 // IDGenerator defines the interface for generating unique account IDs.
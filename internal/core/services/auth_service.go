@@ -4,37 +4,210 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
 
 	"github.com/posilva/simpleidentity/internal/core/domain"
 	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/logger"
 )
 
+// DefaultBatchConcurrency is the default number of inputs of a batch authenticated concurrently
+const DefaultBatchConcurrency = 8
+
 // AuthService is the implementation of the AuthService interface.
 type authService struct {
 	providerFactory ports.AuthProviderFactory
 	repository      ports.AccountsRepository
+	auditLogger     ports.AuditLogger
+	attestation     ports.AttestationVerifier
+	listeners       []ports.AccountLifecycleListener
+	logger          logger.Logger
+	meter           metric.Meter
+	metrics         authMetrics
+	// batchConcurrency bounds the number of inputs of a batch authenticated concurrently
+	batchConcurrency int
+	// defaultTimeout bounds the authentications whose context has no deadline
+	defaultTimeout time.Duration
+}
+
+// AuthServiceOption configures optional dependencies of the auth service
+type AuthServiceOption func(*authService)
+
+// WithAuditLogger sets the audit logger used to record every authentication attempt
+func WithAuditLogger(l ports.AuditLogger) AuthServiceOption {
+	return func(s *authService) {
+		s.auditLogger = l
+	}
+}
+
+// WithAttestationVerifier sets the verifier of the device attestation carried by the authentication data
+func WithAttestationVerifier(v ports.AttestationVerifier) AuthServiceOption {
+	return func(s *authService) {
+		s.attestation = v
+	}
+}
+
+// WithAccountLifecycleListeners adds listeners notified when an account is created, they are called in order
+func WithAccountLifecycleListeners(listeners ...ports.AccountLifecycleListener) AuthServiceOption {
+	return func(s *authService) {
+		s.listeners = append(s.listeners, listeners...)
+	}
+}
+
+// WithLogger sets the logger recording the authentication outcomes, nothing is logged by default
+func WithLogger(l logger.Logger) AuthServiceOption {
+	return func(s *authService) {
+		s.logger = l
+	}
+}
+
+// WithMeter sets the meter recording the provider validations and call durations,
+// the global otel provider is used by default
+func WithMeter(m metric.Meter) AuthServiceOption {
+	return func(s *authService) {
+		s.meter = m
+	}
+}
+
+// WithBatchConcurrency sets the number of inputs of a batch authenticated concurrently,
+// a non positive value keeps the default
+func WithBatchConcurrency(n int) AuthServiceOption {
+	return func(s *authService) {
+		if n > 0 {
+			s.batchConcurrency = n
+		}
+	}
+}
+
+// WithDefaultTimeout bounds the whole authentication flow when the caller context has no deadline,
+// a non positive value leaves such authentications unbounded
+func WithDefaultTimeout(d time.Duration) AuthServiceOption {
+	return func(s *authService) {
+		s.defaultTimeout = d
+	}
 }
 
 // Safegard check to ensure authService implements the AuthService interface
 var _ ports.AuthService = (*authService)(nil)
 
 // NewAuthService creates a new instance of AuthService with the given provider factory.
-func NewAuthService(providerFactory ports.AuthProviderFactory, r ports.AccountsRepository) *authService {
-
-	return &authService{
-		providerFactory: providerFactory,
-		repository:      r,
+func NewAuthService(providerFactory ports.AuthProviderFactory, r ports.AccountsRepository, opts ...AuthServiceOption) *authService {
+	svc := &authService{
+		providerFactory:  providerFactory,
+		repository:       r,
+		auditLogger:      noopAuditLogger{},
+		attestation:      noopAttestationVerifier{},
+		logger:           logger.NewWithWriter(io.Discard, "disabled"),
+		batchConcurrency: DefaultBatchConcurrency,
+	}
+	for _, opt := range opts {
+		opt(svc)
 	}
+	svc.metrics = newAuthMetrics(svc.meter)
+	return svc
 }
 
 // Authenticate authenticates a user using the specified authentication provider.
 func (s *authService) Authenticate(ctx context.Context, input domain.AuthenticateInput) (*domain.AuthenticateOutput, error) {
+	authCtx, cancel := s.withDefaultTimeout(ctx)
+	defer cancel()
+
+	output, err := s.authenticate(authCtx, input)
+	s.logOutcome(input, output, err)
+	// the attempt is audited with the caller context so the record survives the authentication deadline
+	s.audit(ctx, input, output, err)
+	return output, err
+}
+
+// withDefaultTimeout bounds the context with the default timeout when it has no deadline of its own
+func (s *authService) withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.defaultTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.defaultTimeout)
+}
+
+// AuthenticateBatch authenticates the inputs concurrently, the results are in the order of the inputs and
+// a failing input does not fail the others. When the context is done the inputs not yet started fail with
+// the context error, which is also returned.
+func (s *authService) AuthenticateBatch(ctx context.Context, inputs []domain.AuthenticateInput) ([]domain.AuthenticateResult, error) {
+	results := make([]domain.AuthenticateResult, len(inputs))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(s.batchConcurrency, len(inputs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := ctx.Err(); err != nil {
+					results[i] = domain.AuthenticateResult{Err: err}
+					continue
+				}
+				output, err := s.Authenticate(ctx, inputs[i])
+				results[i] = domain.AuthenticateResult{Output: output, Err: err}
+			}
+		}()
+	}
+
+dispatch:
+	for i := range inputs {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			for j := i; j < len(inputs); j++ {
+				results[j] = domain.AuthenticateResult{Err: ctx.Err()}
+			}
+			break dispatch
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+func (s *authService) authenticate(ctx context.Context, input domain.AuthenticateInput) (*domain.AuthenticateOutput, error) {
+	// the input is validated before reaching any provider so malformed requests fail consistently
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if input.TenantID != domain.DefaultTenantID {
+		// the repository scopes the account keys to the tenant carried by the context
+		ctx = domain.ContextWithTenantID(ctx, input.TenantID)
+	}
+
 	provider, err := s.providerFactory.Get(input.ProviderType)
 	if err != nil {
 		return nil, err
 	}
+	s.logger.Debug().
+		Str("provider", string(input.ProviderType)).
+		Str("tenant_id", string(input.TenantID)).
+		Msg("Authentication provider selected")
 
+	// the attestation is checked before reaching the provider so automated clients are rejected early
+	if attestation, ok := input.AuthData[domain.AttestationAuthDataKey]; ok {
+		if err := s.attestation.Verify(ctx, input.ProviderType, attestation); err != nil {
+			return nil, fmt.Errorf("failed to verify attestation: %w", err)
+		}
+	}
+
+	if err := requireAuthData(input.AuthData, provider.RequiredFields()); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	result, err := provider.Authenticate(ctx, input.AuthData)
+	s.metrics.recordProviderCall(ctx, input.ProviderType, time.Since(start), err)
+	s.metrics.recordValidation(ctx, input.ProviderType, err)
 	if err != nil {
 		return nil, err
 	}
@@ -43,10 +216,28 @@ func (s *authService) Authenticate(ctx context.Context, input domain.Authenticat
 	if err != nil {
 		if errors.Is(err, domain.ErrAccountNotFound) {
 			// this means that the account does not exist, so we need to create it
-			accountID, err := s.repository.Create(ctx, input.ProviderType, result.GetID())
+			accountID, err := s.repository.CreateWithProfile(ctx, input.ProviderType, result.GetID(), result.GetProfile())
+			if errors.Is(err, domain.ErrProviderIDOrAccountAlreadyExists) {
+				// a concurrent request (e.g. a client retry) created the account first, so return it
+				return s.resolveConcurrentlyCreated(ctx, input.ProviderType, result.GetID(), err)
+			}
 			if err != nil {
 				return nil, fmt.Errorf("failed to create account: %w", err)
 			}
+			// the account is committed already, failing here would lose the account created event for good
+			if err := s.storeAccountMetadata(ctx, accountID, result); err != nil {
+				s.logger.Error().
+					Err(err).
+					Str("account_id", string(accountID)).
+					Str("provider", string(input.ProviderType)).
+					Msg("Account metadata not stored")
+			}
+			s.logger.Info().
+				Str("account_id", string(accountID)).
+				Str("provider", string(input.ProviderType)).
+				Str("provider_id", maskID(result.GetID())).
+				Msg("Account created")
+			s.notifyAccountCreated(ctx, accountID, input.ProviderType, result)
 
 			return &domain.AuthenticateOutput{
 				AccountID: accountID,
@@ -62,3 +253,146 @@ func (s *authService) Authenticate(ctx context.Context, input domain.Authenticat
 		AccountID: accountID,
 	}, nil
 }
+
+// storeAccountMetadata stores the metadata shared by the provider on the first login of the account,
+// nothing is stored when the provider shared none. It is best effort, the caller only logs the failures
+func (s *authService) storeAccountMetadata(ctx context.Context, accountID domain.AccountID, result ports.AuthResult) error {
+	profile := result.GetProfile()
+	metadata := domain.AccountMetadata{
+		DisplayName: profile.DisplayName,
+		AvatarURL:   profile.AvatarURL,
+	}
+	if withEmail, ok := result.(ports.AuthResultWithEmail); ok {
+		metadata.Email = withEmail.GetEmail()
+		metadata.EmailVerified = withEmail.GetEmailVerified()
+	}
+	if metadata == (domain.AccountMetadata{}) {
+		return nil
+	}
+	if err := s.repository.UpdateAccountMetadata(ctx, accountID, metadata); err != nil {
+		return fmt.Errorf("failed to store account metadata: %w", err)
+	}
+	return nil
+}
+
+// notifyAccountCreated calls every lifecycle listener with the new account, the listener failures are only logged
+func (s *authService) notifyAccountCreated(ctx context.Context, accountID domain.AccountID, providerType domain.ProviderType, result ports.AuthResult) {
+	if len(s.listeners) == 0 {
+		return
+	}
+	profile := result.GetProfile()
+	now := time.Now().UTC()
+	account := domain.Account{
+		ID: accountID,
+		Providers: []domain.LinkedProvider{{
+			Type:        providerType,
+			ProviderID:  result.GetID(),
+			LinkedAt:    now,
+			DisplayName: profile.DisplayName,
+			Locale:      profile.Locale,
+		}},
+		CreatedAt: now,
+	}
+	for _, listener := range s.listeners {
+		if err := listener.OnAccountCreated(ctx, account); err != nil {
+			s.logger.Error().
+				Err(err).
+				Str("account_id", string(accountID)).
+				Str("provider", string(providerType)).
+				Msg("Account created listener failed")
+		}
+	}
+}
+
+// logOutcome logs the outcome of an authentication, the failures caused by the client are warnings and the
+// others errors
+func (s *authService) logOutcome(input domain.AuthenticateInput, output *domain.AuthenticateOutput, err error) {
+	if err == nil {
+		s.logger.Info().
+			Str("account_id", string(output.AccountID)).
+			Str("provider", string(input.ProviderType)).
+			Bool("is_new", output.IsNew).
+			Msg("Authentication succeeded")
+		return
+	}
+	code := domain.CodeFor(err)
+	event := s.logger.Warn()
+	if code == domain.ErrorCodeInternal {
+		event = s.logger.Error()
+	}
+	event.
+		Err(err).
+		Str("provider", string(input.ProviderType)).
+		Str("code", string(code)).
+		Msg("Authentication failed")
+}
+
+// maskID keeps the first characters of an identifier so it can be correlated in the logs without being disclosed
+func maskID(id string) string {
+	const visible = 4
+	if len(id) <= visible*2 {
+		return "***"
+	}
+	return id[:visible] + "***"
+}
+
+// requireAuthData checks every required field is present in the authentication data,
+// the missing ones are all reported so the client can fix the request at once
+func requireAuthData(authData map[string]string, required []string) error {
+	var fields []domain.FieldError
+	for _, name := range required {
+		if _, ok := authData[name]; !ok {
+			fields = append(fields, domain.FieldError{Field: "auth_data." + name, Err: domain.ErrMissingRequiredProviderAuthData})
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &domain.ValidationError{Fields: fields}
+}
+
+// resolveConcurrentlyCreated resolves the account created by a concurrent request after the create
+// conflicted, the create error is returned when the account cannot be resolved. The read is consistent
+// as the account was only just created and an eventually consistent read could miss it
+func (s *authService) resolveConcurrentlyCreated(ctx context.Context, providerType domain.ProviderType, providerID string, createErr error) (*domain.AuthenticateOutput, error) {
+	accountID, err := s.repository.ResolveIDByProvider(domain.ContextWithConsistentRead(ctx), providerType, providerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create account: %w", errors.Join(createErr, err))
+	}
+	return &domain.AuthenticateOutput{
+		AccountID: accountID,
+	}, nil
+}
+
+// audit records the outcome of an authentication attempt, failing to audit never fails the authentication
+func (s *authService) audit(ctx context.Context, input domain.AuthenticateInput, output *domain.AuthenticateOutput, err error) {
+	event := domain.AuditEvent{
+		Action:    domain.AuditActionAuthenticate,
+		Result:    domain.AuditResultSuccess,
+		SourceIP:  input.SourceIP,
+		Provider:  input.ProviderType,
+		Timestamp: time.Now().UTC(),
+	}
+	if output != nil {
+		event.Actor = output.AccountID
+	}
+	if err != nil {
+		event.Result = domain.AuditResultFailure
+		event.Reason = err.Error()
+	}
+	_ = s.auditLogger.Record(ctx, event)
+}
+
+// noopAuditLogger is used when no audit logger is configured
+type noopAuditLogger struct{}
+
+func (noopAuditLogger) Record(context.Context, domain.AuditEvent) error {
+	return nil
+}
+
+// noopAttestationVerifier is used when no attestation verifier is configured, it accepts every attestation
+type noopAttestationVerifier struct{}
+
+func (noopAttestationVerifier) Verify(context.Context, domain.ProviderType, string) error {
+	return nil
+}
@@ -1,12 +1,18 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ovechkin-dm/mockio/v2/mock"
 	"github.com/posilva/simpleidentity/internal/core/domain"
 	"github.com/posilva/simpleidentity/internal/core/ports"
+	"github.com/posilva/simpleidentity/pkg/logger"
 	"github.com/segmentio/ksuid"
 	"github.com/stretchr/testify/require"
 )
@@ -89,7 +95,8 @@ func TestAuthService_AuthenticateGuest_ReturnAccountIsNew(t *testing.T) {
 	mock.WhenDouble(providerMock.Authenticate(ctx, authData)).ThenReturn(authResultMock, nil)
 	mock.WhenDouble(factoryMock.Get(providerType)).ThenReturn(providerMock, nil)
 	mock.WhenDouble(repoMock.ResolveIDByProvider(ctx, providerType, uid)).ThenReturn(domain.AccountID(""), domain.ErrAccountNotFound)
-	mock.WhenDouble(repoMock.Create(ctx, providerType, uid)).ThenReturn(domain.AccountID(uid), nil)
+	mock.WhenSingle(authResultMock.GetProfile()).ThenReturn(domain.ProviderProfile{})
+	mock.WhenDouble(repoMock.CreateWithProfile(ctx, providerType, uid, domain.ProviderProfile{})).ThenReturn(domain.AccountID(uid), nil)
 	// create the AuthService instance
 	authService := NewAuthService(factoryMock, repoMock)
 	output, err := authService.Authenticate(ctx, domain.AuthenticateInput{
@@ -102,3 +109,769 @@ func TestAuthService_AuthenticateGuest_ReturnAccountIsNew(t *testing.T) {
 	require.Equal(t, domain.AccountID(uid), output.AccountID)
 	require.True(t, output.IsNew)
 }
+
+func TestAuthService_Authenticate_StoresMetadataOfNewAccount(t *testing.T) {
+	authData := map[string]string{"token": "auth_code"}
+	uid := ksuid.New().String()
+	providerType := domain.ProviderTypeGoogle
+	profile := domain.ProviderProfile{DisplayName: "Player One", AvatarURL: "https://example.com/player01.png", Locale: "pt-PT"}
+
+	ctrl := mock.NewMockController(t)
+	factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+	repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+	providerMock := mock.Mock[ports.AuthProvider](ctrl)
+	authResultMock := mock.Mock[ports.AuthResultWithEmail](ctrl)
+	ctx := context.Background()
+
+	mock.WhenSingle(authResultMock.GetID()).ThenReturn(uid)
+	mock.WhenSingle(authResultMock.GetProfile()).ThenReturn(profile)
+	mock.WhenSingle(authResultMock.GetEmail()).ThenReturn("player01@example.com")
+	mock.WhenSingle(authResultMock.GetEmailVerified()).ThenReturn(true)
+	mock.WhenDouble(providerMock.Authenticate(ctx, authData)).ThenReturn(authResultMock, nil)
+	mock.WhenDouble(factoryMock.Get(providerType)).ThenReturn(providerMock, nil)
+	mock.WhenDouble(repoMock.ResolveIDByProvider(ctx, providerType, uid)).ThenReturn(domain.EmptyAccountID, domain.ErrAccountNotFound)
+	mock.WhenDouble(repoMock.CreateWithProfile(ctx, providerType, uid, profile)).ThenReturn(domain.AccountID(uid), nil)
+	mock.WhenSingle(repoMock.UpdateAccountMetadata(mock.Any[context.Context](), mock.Any[domain.AccountID](), mock.Any[domain.AccountMetadata]())).ThenReturn(nil)
+
+	authService := NewAuthService(factoryMock, repoMock)
+	output, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+		ProviderType: providerType,
+		AuthData:     authData,
+	})
+	require.NoError(t, err)
+	require.True(t, output.IsNew)
+
+	mock.Verify(repoMock, mock.Once()).UpdateAccountMetadata(ctx, domain.AccountID(uid), domain.AccountMetadata{
+		DisplayName:   "Player One",
+		AvatarURL:     "https://example.com/player01.png",
+		Email:         "player01@example.com",
+		EmailVerified: true,
+	})
+}
+
+func TestAuthService_Authenticate_SucceedsWhenMetadataIsNotStored(t *testing.T) {
+	authData := map[string]string{"token": "auth_code"}
+	uid := ksuid.New().String()
+	providerType := domain.ProviderTypeGoogle
+	profile := domain.ProviderProfile{DisplayName: "Player One"}
+
+	ctrl := mock.NewMockController(t)
+	factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+	repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+	providerMock := mock.Mock[ports.AuthProvider](ctrl)
+	authResultMock := mock.Mock[ports.AuthResult](ctrl)
+	listenerMock := mock.Mock[ports.AccountLifecycleListener](ctrl)
+	ctx := context.Background()
+
+	mock.WhenSingle(authResultMock.GetID()).ThenReturn(uid)
+	mock.WhenSingle(authResultMock.GetProfile()).ThenReturn(profile)
+	mock.WhenDouble(providerMock.Authenticate(ctx, authData)).ThenReturn(authResultMock, nil)
+	mock.WhenDouble(factoryMock.Get(providerType)).ThenReturn(providerMock, nil)
+	mock.WhenDouble(repoMock.ResolveIDByProvider(ctx, providerType, uid)).ThenReturn(domain.EmptyAccountID, domain.ErrAccountNotFound)
+	mock.WhenDouble(repoMock.CreateWithProfile(ctx, providerType, uid, profile)).ThenReturn(domain.AccountID(uid), nil)
+	mock.WhenSingle(repoMock.UpdateAccountMetadata(ctx, domain.AccountID(uid), domain.AccountMetadata{DisplayName: "Player One"})).ThenReturn(domain.ErrThrottled)
+	mock.WhenSingle(listenerMock.OnAccountCreated(mock.Any[context.Context](), mock.Any[domain.Account]())).ThenReturn(nil)
+
+	var logs strings.Builder
+	authService := NewAuthService(factoryMock, repoMock,
+		WithAccountLifecycleListeners(listenerMock),
+		WithLogger(logger.NewWithWriter(&logs, "error")),
+	)
+	output, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+		ProviderType: providerType,
+		AuthData:     authData,
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.AccountID(uid), output.AccountID)
+	require.True(t, output.IsNew)
+	mock.Verify(listenerMock, mock.Once()).OnAccountCreated(mock.Any[context.Context](), mock.Any[domain.Account]())
+	require.Contains(t, logs.String(), "Account metadata not stored")
+}
+
+func TestAuthService_Authenticate_NotifiesAccountCreated(t *testing.T) {
+	authData := map[string]string{"id": "some_client_generated_id"}
+	uid := ksuid.New().String()
+	providerType := domain.ProviderTypeGuest
+
+	tests := []struct {
+		name        string
+		existing    bool
+		listenerErr error
+	}{
+		{name: "new account"},
+		{name: "existing account", existing: true},
+		{name: "failing listener", listenerErr: errors.New("rewards unavailable")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := mock.NewMockController(t)
+			factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+			repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+			providerMock := mock.Mock[ports.AuthProvider](ctrl)
+			authResultMock := mock.Mock[ports.AuthResult](ctrl)
+			firstMock := mock.Mock[ports.AccountLifecycleListener](ctrl)
+			secondMock := mock.Mock[ports.AccountLifecycleListener](ctrl)
+			ctx := context.Background()
+
+			mock.WhenSingle(authResultMock.GetID()).ThenReturn(uid)
+			mock.WhenSingle(authResultMock.GetProfile()).ThenReturn(domain.ProviderProfile{})
+			mock.WhenDouble(providerMock.Authenticate(ctx, authData)).ThenReturn(authResultMock, nil)
+			mock.WhenDouble(factoryMock.Get(providerType)).ThenReturn(providerMock, nil)
+			if tt.existing {
+				mock.WhenDouble(repoMock.ResolveIDByProvider(ctx, providerType, uid)).ThenReturn(domain.AccountID(uid), nil)
+			} else {
+				mock.WhenDouble(repoMock.ResolveIDByProvider(ctx, providerType, uid)).ThenReturn(domain.EmptyAccountID, domain.ErrAccountNotFound)
+			}
+			mock.WhenDouble(repoMock.CreateWithProfile(ctx, providerType, uid, domain.ProviderProfile{})).ThenReturn(domain.AccountID(uid), nil)
+			captor := mock.Captor[domain.Account]()
+			mock.WhenSingle(firstMock.OnAccountCreated(mock.Any[context.Context](), captor.Capture())).ThenReturn(tt.listenerErr)
+			mock.WhenSingle(secondMock.OnAccountCreated(mock.Any[context.Context](), mock.Any[domain.Account]())).ThenReturn(nil)
+
+			var logs strings.Builder
+			authService := NewAuthService(factoryMock, repoMock,
+				WithAccountLifecycleListeners(firstMock, secondMock),
+				WithLogger(logger.NewWithWriter(&logs, "error")),
+			)
+			output, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+				ProviderType: providerType,
+				AuthData:     authData,
+			})
+			require.NoError(t, err)
+			require.Equal(t, domain.AccountID(uid), output.AccountID)
+			require.Equal(t, !tt.existing, output.IsNew)
+
+			if tt.existing {
+				mock.Verify(firstMock, mock.Never()).OnAccountCreated(mock.Any[context.Context](), mock.Any[domain.Account]())
+				mock.Verify(secondMock, mock.Never()).OnAccountCreated(mock.Any[context.Context](), mock.Any[domain.Account]())
+				return
+			}
+			// a failing listener does not stop the next ones
+			mock.Verify(secondMock, mock.Once()).OnAccountCreated(mock.Any[context.Context](), mock.Any[domain.Account]())
+
+			account := captor.Last()
+			require.Equal(t, domain.AccountID(uid), account.ID)
+			require.Len(t, account.Providers, 1)
+			require.Equal(t, providerType, account.Providers[0].Type)
+			require.Equal(t, uid, account.Providers[0].ProviderID)
+			require.False(t, account.CreatedAt.IsZero())
+
+			if tt.listenerErr != nil {
+				require.Contains(t, logs.String(), "rewards unavailable")
+			} else {
+				require.Empty(t, logs.String())
+			}
+		})
+	}
+}
+
+func TestAuthService_Authenticate_ReturnsAccountCreatedConcurrently(t *testing.T) {
+	authData := map[string]string{"id": "some_client_generated_id"}
+	uid := ksuid.New().String()
+	providerType := domain.ProviderTypeGuest
+	ctx := context.Background()
+
+	ctrl := mock.NewMockController(t)
+	factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+	repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+	providerMock := mock.Mock[ports.AuthProvider](ctrl)
+	authResultMock := mock.Mock[ports.AuthResult](ctrl)
+	mock.WhenSingle(authResultMock.GetID()).ThenReturn(uid)
+	mock.WhenSingle(authResultMock.GetProfile()).ThenReturn(domain.ProviderProfile{})
+	mock.WhenDouble(providerMock.Authenticate(ctx, authData)).ThenReturn(authResultMock, nil)
+	mock.WhenDouble(factoryMock.Get(providerType)).ThenReturn(providerMock, nil)
+	// the create conflicts with the retried request, whose account only a consistent read sees yet
+	var consistentReads []bool
+	mock.WhenDouble(repoMock.ResolveIDByProvider(mock.AnyContext(), mock.Equal(providerType), mock.Equal(uid))).ThenAnswer(func(args []any) (domain.AccountID, error) {
+		consistent := domain.ConsistentReadFromContext(args[0].(context.Context))
+		consistentReads = append(consistentReads, consistent)
+		if !consistent {
+			return domain.EmptyAccountID, domain.ErrAccountNotFound
+		}
+		return domain.AccountID(uid), nil
+	})
+	mock.WhenDouble(repoMock.CreateWithProfile(ctx, providerType, uid, domain.ProviderProfile{})).ThenReturn(domain.EmptyAccountID, domain.ErrProviderIDOrAccountAlreadyExists)
+
+	authService := NewAuthService(factoryMock, repoMock)
+	output, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+		ProviderType: providerType,
+		AuthData:     authData,
+	})
+	require.NoError(t, err)
+	require.Equal(t, domain.AccountID(uid), output.AccountID)
+	require.False(t, output.IsNew)
+	require.Equal(t, []bool{false, true}, consistentReads)
+}
+
+func TestAuthService_Authenticate_ReturnsCreateConflictWhenNotResolvable(t *testing.T) {
+	authData := map[string]string{"id": "some_client_generated_id"}
+	uid := ksuid.New().String()
+	providerType := domain.ProviderTypeGuest
+	ctx := context.Background()
+
+	ctrl := mock.NewMockController(t)
+	factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+	repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+	providerMock := mock.Mock[ports.AuthProvider](ctrl)
+	authResultMock := mock.Mock[ports.AuthResult](ctrl)
+	mock.WhenSingle(authResultMock.GetID()).ThenReturn(uid)
+	mock.WhenSingle(authResultMock.GetProfile()).ThenReturn(domain.ProviderProfile{})
+	mock.WhenDouble(providerMock.Authenticate(ctx, authData)).ThenReturn(authResultMock, nil)
+	mock.WhenDouble(factoryMock.Get(providerType)).ThenReturn(providerMock, nil)
+	mock.WhenDouble(repoMock.ResolveIDByProvider(mock.AnyContext(), mock.Equal(providerType), mock.Equal(uid))).ThenReturn(domain.EmptyAccountID, domain.ErrAccountNotFound)
+	mock.WhenDouble(repoMock.CreateWithProfile(ctx, providerType, uid, domain.ProviderProfile{})).ThenReturn(domain.EmptyAccountID, domain.ErrProviderIDOrAccountAlreadyExists)
+
+	authService := NewAuthService(factoryMock, repoMock)
+	output, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+		ProviderType: providerType,
+		AuthData:     authData,
+	})
+	require.ErrorIs(t, err, domain.ErrProviderIDOrAccountAlreadyExists)
+	require.Nil(t, output)
+}
+
+// recordingAuditLogger keeps the audit events in memory to assert on them
+type recordingAuditLogger struct {
+	events []domain.AuditEvent
+}
+
+func (l *recordingAuditLogger) Record(_ context.Context, event domain.AuditEvent) error {
+	l.events = append(l.events, event)
+	return nil
+}
+
+func TestAuthService_Authenticate_RecordsAuditEvents(t *testing.T) {
+	authData := map[string]string{"id": "some_client_generated_id"}
+	uid := ksuid.New().String()
+	providerType := domain.ProviderTypeGuest
+	sourceIP := "10.0.0.1"
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+		repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+		providerMock := mock.Mock[ports.AuthProvider](ctrl)
+		authResultMock := mock.Mock[ports.AuthResult](ctrl)
+		mock.WhenSingle(authResultMock.GetID()).ThenReturn(uid)
+		mock.WhenDouble(providerMock.Authenticate(ctx, authData)).ThenReturn(authResultMock, nil)
+		mock.WhenDouble(factoryMock.Get(providerType)).ThenReturn(providerMock, nil)
+		mock.WhenDouble(repoMock.ResolveIDByProvider(ctx, providerType, uid)).ThenReturn(domain.AccountID(uid), nil)
+
+		auditLogger := &recordingAuditLogger{}
+		authService := NewAuthService(factoryMock, repoMock, WithAuditLogger(auditLogger))
+		_, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+			ProviderType: providerType,
+			AuthData:     authData,
+			SourceIP:     sourceIP,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, auditLogger.events, 1)
+		event := auditLogger.events[0]
+		require.Equal(t, domain.AccountID(uid), event.Actor)
+		require.Equal(t, domain.AuditActionAuthenticate, event.Action)
+		require.Equal(t, domain.AuditResultSuccess, event.Result)
+		require.Equal(t, sourceIP, event.SourceIP)
+		require.Equal(t, providerType, event.Provider)
+		require.False(t, event.Timestamp.IsZero())
+		require.Empty(t, event.Reason)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		ctrl := mock.NewMockController(t)
+		factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+		repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+		mock.WhenDouble(factoryMock.Get(providerType)).ThenReturn(nil, domain.ErrProviderNotFound)
+
+		auditLogger := &recordingAuditLogger{}
+		authService := NewAuthService(factoryMock, repoMock, WithAuditLogger(auditLogger))
+		_, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+			ProviderType: providerType,
+			AuthData:     authData,
+			SourceIP:     sourceIP,
+		})
+		require.Error(t, err)
+
+		require.Len(t, auditLogger.events, 1)
+		event := auditLogger.events[0]
+		require.Equal(t, domain.EmptyAccountID, event.Actor)
+		require.Equal(t, domain.AuditResultFailure, event.Result)
+		require.Equal(t, sourceIP, event.SourceIP)
+		require.Equal(t, providerType, event.Provider)
+		require.Equal(t, domain.ErrProviderNotFound.Error(), event.Reason)
+	})
+}
+
+func TestAuthService_Authenticate_ScopesRepositoryToTenant(t *testing.T) {
+	authData := map[string]string{"id": "some_client_generated_id"}
+	uid := ksuid.New().String()
+	providerType := domain.ProviderTypeGuest
+	ctx := context.Background()
+
+	ctrl := mock.NewMockController(t)
+	factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+	repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+	providerMock := mock.Mock[ports.AuthProvider](ctrl)
+	authResultMock := mock.Mock[ports.AuthResult](ctrl)
+	ctxCaptor := mock.Captor[context.Context]()
+	mock.WhenSingle(authResultMock.GetID()).ThenReturn(uid)
+	mock.WhenDouble(providerMock.Authenticate(mock.Any[context.Context](), mock.Equal(authData))).ThenReturn(authResultMock, nil)
+	mock.WhenDouble(factoryMock.Get(providerType)).ThenReturn(providerMock, nil)
+	mock.WhenDouble(repoMock.ResolveIDByProvider(ctxCaptor.Capture(), mock.Equal(providerType), mock.Equal(uid))).ThenReturn(domain.AccountID(uid), nil)
+
+	authService := NewAuthService(factoryMock, repoMock)
+	_, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+		ProviderType: providerType,
+		AuthData:     authData,
+		TenantID:     "game-1",
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, domain.TenantID("game-1"), domain.TenantIDFromContext(ctxCaptor.Last()))
+}
+
+func TestAuthService_Authenticate_RejectsInvalidTenant(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+	repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+
+	authService := NewAuthService(factoryMock, repoMock)
+	output, err := authService.Authenticate(context.Background(), domain.AuthenticateInput{
+		ProviderType: domain.ProviderTypeGuest,
+		TenantID:     "game#1",
+	})
+
+	require.ErrorIs(t, err, domain.ErrInvalidTenantID)
+	require.Nil(t, output)
+	mock.VerifyNoMoreInteractions(factoryMock)
+	mock.VerifyNoMoreInteractions(repoMock)
+}
+
+func TestAuthService_Authenticate_VerifiesAttestation(t *testing.T) {
+	uid := ksuid.New().String()
+	providerType := domain.ProviderTypeGoogle
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		authData  map[string]string
+		verifyErr error
+		wantErr   error
+	}{
+		{
+			name:     "valid attestation",
+			authData: map[string]string{"token": "id_token", domain.AttestationAuthDataKey: "integrity-token"},
+		},
+		{
+			name:      "rejected attestation",
+			authData:  map[string]string{"token": "id_token", domain.AttestationAuthDataKey: "integrity-token"},
+			verifyErr: domain.ErrAttestationFailed,
+			wantErr:   domain.ErrAttestationFailed,
+		},
+		{
+			name:      "absent attestation",
+			authData:  map[string]string{"token": "id_token"},
+			verifyErr: domain.ErrAttestationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := mock.NewMockController(t)
+			factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+			repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+			providerMock := mock.Mock[ports.AuthProvider](ctrl)
+			authResultMock := mock.Mock[ports.AuthResult](ctrl)
+			verifierMock := mock.Mock[ports.AttestationVerifier](ctrl)
+			mock.WhenSingle(authResultMock.GetID()).ThenReturn(uid)
+			mock.WhenDouble(providerMock.Authenticate(ctx, tt.authData)).ThenReturn(authResultMock, nil)
+			mock.WhenDouble(factoryMock.Get(providerType)).ThenReturn(providerMock, nil)
+			mock.WhenDouble(repoMock.ResolveIDByProvider(ctx, providerType, uid)).ThenReturn(domain.AccountID(uid), nil)
+			mock.WhenSingle(verifierMock.Verify(ctx, providerType, "integrity-token")).ThenReturn(tt.verifyErr)
+
+			authService := NewAuthService(factoryMock, repoMock, WithAttestationVerifier(verifierMock))
+			output, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+				ProviderType: providerType,
+				AuthData:     tt.authData,
+			})
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, output)
+				// the provider is not reached when the attestation is rejected
+				mock.VerifyNoMoreInteractions(providerMock)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, domain.AccountID(uid), output.AccountID)
+			if _, ok := tt.authData[domain.AttestationAuthDataKey]; !ok {
+				mock.VerifyNoMoreInteractions(verifierMock)
+			}
+		})
+	}
+}
+
+// batchAuthResult is an authentication result whose id is the string itself
+type batchAuthResult string
+
+func (r batchAuthResult) GetID() string                      { return string(r) }
+func (r batchAuthResult) GetProfile() domain.ProviderProfile { return domain.ProviderProfile{} }
+
+// the batch tests use hand written fakes because the mocks are not safe for concurrent calls
+
+// batchProvider fails the ids prefixed with bad and succeeds the others
+type batchProvider struct{}
+
+func (batchProvider) Authenticate(_ context.Context, authData map[string]string) (ports.AuthResult, error) {
+	if strings.HasPrefix(authData["id"], "bad") {
+		return nil, errInvalidBatchToken
+	}
+	return batchAuthResult(authData["id"]), nil
+}
+
+func (batchProvider) RequiredFields() []string {
+	return []string{"id"}
+}
+
+var errInvalidBatchToken = errors.New("invalid token")
+
+// batchFactory only knows the guest provider
+type batchFactory struct {
+	ports.AuthProviderFactory
+}
+
+func (batchFactory) Get(providerType domain.ProviderType) (ports.AuthProvider, error) {
+	if providerType != domain.ProviderTypeGuest {
+		return nil, domain.ErrProviderNotFound
+	}
+	return batchProvider{}, nil
+}
+
+// batchRepository resolves every provider id to an existing account
+type batchRepository struct {
+	ports.AccountsRepository
+}
+
+func (batchRepository) ResolveIDByProvider(_ context.Context, _ domain.ProviderType, providerID string) (domain.AccountID, error) {
+	return domain.AccountID("account-" + providerID), nil
+}
+
+func TestAuthService_AuthenticateBatch_ReturnsPartialResultsInOrder(t *testing.T) {
+	inputs := []domain.AuthenticateInput{
+		{ProviderType: domain.ProviderTypeGuest, AuthData: map[string]string{"id": "one"}},
+		{ProviderType: domain.ProviderTypeGuest, AuthData: map[string]string{"id": "bad-two"}},
+		{ProviderType: domain.ProviderTypeApple, AuthData: map[string]string{"id": "three"}},
+		{ProviderType: domain.ProviderTypeGuest, AuthData: map[string]string{"id": "four"}},
+		{ProviderType: domain.ProviderTypeGuest, AuthData: map[string]string{"id": "five"}},
+	}
+
+	authService := NewAuthService(batchFactory{}, batchRepository{}, WithBatchConcurrency(2))
+	results, err := authService.AuthenticateBatch(context.Background(), inputs)
+
+	require.NoError(t, err)
+	require.Len(t, results, len(inputs))
+	require.NoError(t, results[0].Err)
+	require.Equal(t, domain.AccountID("account-one"), results[0].Output.AccountID)
+	require.ErrorIs(t, results[1].Err, errInvalidBatchToken)
+	require.Nil(t, results[1].Output)
+	require.ErrorIs(t, results[2].Err, domain.ErrProviderNotFound)
+	require.Nil(t, results[2].Output)
+	require.Equal(t, domain.AccountID("account-four"), results[3].Output.AccountID)
+	require.Equal(t, domain.AccountID("account-five"), results[4].Output.AccountID)
+}
+
+func TestAuthService_AuthenticateBatch_StopsOnContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	inputs := []domain.AuthenticateInput{
+		{ProviderType: domain.ProviderTypeGuest, AuthData: map[string]string{"id": "one"}},
+		{ProviderType: domain.ProviderTypeGuest, AuthData: map[string]string{"id": "two"}},
+	}
+
+	authService := NewAuthService(batchFactory{}, batchRepository{}, WithBatchConcurrency(1))
+	results, err := authService.AuthenticateBatch(ctx, inputs)
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Len(t, results, len(inputs))
+	for _, result := range results {
+		require.ErrorIs(t, result.Err, context.Canceled)
+		require.Nil(t, result.Output)
+	}
+}
+
+func TestAuthService_AuthenticateBatch_EmptyInputs(t *testing.T) {
+	authService := NewAuthService(batchFactory{}, batchRepository{})
+
+	results, err := authService.AuthenticateBatch(context.Background(), nil)
+
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
+func TestAuthService_Authenticate_RejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   domain.AuthenticateInput
+		wantErr error
+	}{
+		{
+			name:    "unknown provider",
+			input:   domain.AuthenticateInput{ProviderType: "myspace", AuthData: map[string]string{"token": "token"}},
+			wantErr: domain.ErrProviderNotFound,
+		},
+		{
+			name:    "nil auth data",
+			input:   domain.AuthenticateInput{ProviderType: domain.ProviderTypeGuest},
+			wantErr: domain.ErrMissingRequiredProviderAuthData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := mock.NewMockController(t)
+			factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+			repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+
+			authService := NewAuthService(factoryMock, repoMock)
+			output, err := authService.Authenticate(context.Background(), tt.input)
+
+			require.ErrorIs(t, err, domain.ErrInvalidInput)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Nil(t, output)
+			// no provider is reached for an invalid input
+			mock.VerifyNoMoreInteractions(factoryMock)
+		})
+	}
+}
+
+func TestAuthService_Authenticate_ListsMissingRequiredFields(t *testing.T) {
+	ctrl := mock.NewMockController(t)
+	factoryMock := mock.Mock[ports.AuthProviderFactory](ctrl)
+	repoMock := mock.Mock[ports.AccountsRepository](ctrl)
+	providerMock := mock.Mock[ports.AuthProvider](ctrl)
+	mock.WhenDouble(factoryMock.Get(domain.ProviderTypeApple)).ThenReturn(providerMock, nil)
+	mock.WhenSingle(providerMock.RequiredFields()).ThenReturn([]string{"identityToken", "userID", "nonce"})
+
+	authService := NewAuthService(factoryMock, repoMock)
+	output, err := authService.Authenticate(context.Background(), domain.AuthenticateInput{
+		ProviderType: domain.ProviderTypeApple,
+		AuthData:     map[string]string{"userID": "user"},
+	})
+
+	require.Nil(t, output)
+	require.ErrorIs(t, err, domain.ErrInvalidInput)
+	require.ErrorIs(t, err, domain.ErrMissingRequiredProviderAuthData)
+	require.EqualError(t, err, "invalid input: auth_data.identityToken: missing required provider authentication data; "+
+		"auth_data.nonce: missing required provider authentication data")
+	// the provider is not reached when fields are missing
+	mock.Verify(providerMock, mock.Never()).Authenticate(mock.AnyContext(), mock.Any[map[string]string]())
+}
+
+// slowProvider blocks until the context is done and records whether it had a deadline
+type slowProvider struct {
+	deadlines chan time.Time
+}
+
+func (p slowProvider) Authenticate(ctx context.Context, _ map[string]string) (ports.AuthResult, error) {
+	deadline, _ := ctx.Deadline()
+	p.deadlines <- deadline
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (slowProvider) RequiredFields() []string {
+	return []string{"id"}
+}
+
+// slowFactory returns the slow provider for every provider type
+type slowFactory struct {
+	ports.AuthProviderFactory
+	provider slowProvider
+}
+
+func (f slowFactory) Get(domain.ProviderType) (ports.AuthProvider, error) {
+	return f.provider, nil
+}
+
+func TestAuthService_Authenticate_AbortsAtDefaultTimeout(t *testing.T) {
+	provider := slowProvider{deadlines: make(chan time.Time, 1)}
+	auditLogger := &recordingAuditLogger{}
+	timeout := 50 * time.Millisecond
+	authService := NewAuthService(slowFactory{provider: provider}, batchRepository{},
+		WithDefaultTimeout(timeout), WithAuditLogger(auditLogger))
+
+	start := time.Now()
+	output, err := authService.Authenticate(context.Background(), domain.AuthenticateInput{
+		ProviderType: domain.ProviderTypeGuest,
+		AuthData:     map[string]string{"id": "one"},
+	})
+	elapsed := time.Since(start)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Nil(t, output)
+	require.False(t, (<-provider.deadlines).IsZero())
+	require.GreaterOrEqual(t, elapsed, timeout)
+	require.Less(t, elapsed, time.Second)
+
+	require.Len(t, auditLogger.events, 1)
+	require.Equal(t, domain.AuditResultFailure, auditLogger.events[0].Result)
+	require.Equal(t, context.DeadlineExceeded.Error(), auditLogger.events[0].Reason)
+}
+
+func TestAuthService_Authenticate_KeepsCallerDeadline(t *testing.T) {
+	provider := slowProvider{deadlines: make(chan time.Time, 1)}
+	authService := NewAuthService(slowFactory{provider: provider}, batchRepository{}, WithDefaultTimeout(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	callerDeadline, _ := ctx.Deadline()
+
+	_, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+		ProviderType: domain.ProviderTypeGuest,
+		AuthData:     map[string]string{"id": "one"},
+	})
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, callerDeadline, <-provider.deadlines)
+}
+
+func TestAuthService_Authenticate_NoDefaultTimeout(t *testing.T) {
+	provider := slowProvider{deadlines: make(chan time.Time, 1)}
+	authService := NewAuthService(slowFactory{provider: provider}, batchRepository{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	deadline := make(chan time.Time, 1)
+	go func() {
+		deadline <- <-provider.deadlines
+		cancel()
+	}()
+
+	_, err := authService.Authenticate(ctx, domain.AuthenticateInput{
+		ProviderType: domain.ProviderTypeGuest,
+		AuthData:     map[string]string{"id": "one"},
+	})
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.True(t, (<-deadline).IsZero())
+}
+
+// creatingRepository creates an account for every provider id
+type creatingRepository struct {
+	ports.AccountsRepository
+}
+
+func (creatingRepository) ResolveIDByProvider(context.Context, domain.ProviderType, string) (domain.AccountID, error) {
+	return domain.EmptyAccountID, domain.ErrAccountNotFound
+}
+
+func (creatingRepository) CreateWithProfile(_ context.Context, _ domain.ProviderType, providerID string, _ domain.ProviderProfile) (domain.AccountID, error) {
+	return domain.AccountID("account-" + providerID), nil
+}
+
+// failingRepository fails every lookup with an unexpected error
+type failingRepository struct {
+	ports.AccountsRepository
+}
+
+func (failingRepository) ResolveIDByProvider(context.Context, domain.ProviderType, string) (domain.AccountID, error) {
+	return domain.EmptyAccountID, errors.New("connection reset")
+}
+
+// decodeLogLines decodes the JSON log lines written to the buffer
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]any
+		require.NoError(t, dec.Decode(&line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestAuthService_Authenticate_Logs(t *testing.T) {
+	tests := []struct {
+		name         string
+		providerType domain.ProviderType
+		repository   ports.AccountsRepository
+		id           string
+		want         []map[string]any
+	}{
+		{
+			name:       "existing account",
+			repository: batchRepository{},
+			id:         "player-one",
+			want: []map[string]any{
+				{"level": "debug", "message": "Authentication provider selected", "provider": "guest"},
+				{"level": "info", "message": "Authentication succeeded", "provider": "guest", "account_id": "account-player-one", "is_new": false},
+			},
+		},
+		{
+			name:       "new account",
+			repository: creatingRepository{},
+			id:         "player-one",
+			want: []map[string]any{
+				{"level": "debug", "message": "Authentication provider selected", "provider": "guest"},
+				{"level": "info", "message": "Account created", "provider": "guest", "account_id": "account-player-one", "provider_id": "play***"},
+				{"level": "info", "message": "Authentication succeeded", "provider": "guest", "account_id": "account-player-one", "is_new": true},
+			},
+		},
+		{
+			name:         "unknown provider",
+			providerType: domain.ProviderTypeApple,
+			repository:   batchRepository{},
+			id:           "player-one",
+			want: []map[string]any{
+				{"level": "warn", "message": "Authentication failed", "provider": "apple", "code": "provider_not_found", "error": "provider not found"},
+			},
+		},
+		{
+			name:       "repository failure",
+			repository: failingRepository{},
+			id:         "player-one",
+			want: []map[string]any{
+				{"level": "debug", "message": "Authentication provider selected", "provider": "guest"},
+				{"level": "error", "message": "Authentication failed", "provider": "guest", "code": "internal", "error": "failed to resolve account ID: connection reset"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			authService := NewAuthService(batchFactory{}, tt.repository, WithLogger(logger.NewWithWriter(&buf, "debug")))
+
+			providerType := tt.providerType
+			if providerType == "" {
+				providerType = domain.ProviderTypeGuest
+			}
+			_, _ = authService.Authenticate(context.Background(), domain.AuthenticateInput{
+				ProviderType: providerType,
+				AuthData:     map[string]string{"id": tt.id},
+			})
+
+			lines := decodeLogLines(t, &buf)
+			require.Len(t, lines, len(tt.want))
+			for i, want := range tt.want {
+				for k, v := range want {
+					require.Equal(t, v, lines[i][k], "line %d key %s", i, k)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthService_Authenticate_LogsNothingByDefault(t *testing.T) {
+	authService := NewAuthService(batchFactory{}, batchRepository{})
+	_, err := authService.Authenticate(context.Background(), domain.AuthenticateInput{
+		ProviderType: domain.ProviderTypeGuest,
+		AuthData:     map[string]string{"id": "player-one"},
+	})
+	require.NoError(t, err)
+}
+
+func TestMaskID(t *testing.T) {
+	require.Equal(t, "***", maskID(""))
+	require.Equal(t, "***", maskID("12345678"))
+	require.Equal(t, "1234***", maskID("123456789"))
+}
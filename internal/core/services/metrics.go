@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+)
+
+// Metric names recorded by the auth service
+const (
+	TokensValidatedMetricName      = "auth.tokens.validated"
+	ProviderCallDurationMetricName = "auth.provider.call.duration"
+
+	instrumentationScope = "github.com/posilva/simpleidentity/internal/core/services"
+)
+
+// Attribute keys and outcomes of the auth service metrics
+const (
+	ProviderAttributeKey = "auth.provider"
+	OutcomeAttributeKey  = "auth.outcome"
+
+	OutcomeValid       = "valid"
+	OutcomeInvalid     = "invalid"
+	OutcomeUnavailable = "unavailable"
+	OutcomeTimeout     = "timeout"
+	OutcomeError       = "error"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// invalidCredentialsErrors are the provider errors caused by the credentials the client sent
+var invalidCredentialsErrors = []error{
+	domain.ErrTokenMalformed,
+	domain.ErrTokenExpired,
+	domain.ErrTokenNotYetValid,
+	domain.ErrTokenInvalidSignature,
+	domain.ErrTokenInvalidIssuer,
+	domain.ErrTokenInvalidAudience,
+	domain.ErrTokenInvalidNonce,
+	domain.ErrTokenInvalidClaims,
+	domain.ErrNonceReused,
+	domain.ErrInvalidProviderAuthData,
+	domain.ErrInsufficientScope,
+}
+
+// authMetrics holds the instruments recorded around the provider calls
+type authMetrics struct {
+	tokensValidated      metric.Int64Counter
+	providerCallDuration metric.Float64Histogram
+}
+
+// newAuthMetrics creates the instruments, a nil meter falls back to the global provider
+func newAuthMetrics(meter metric.Meter) authMetrics {
+	if meter == nil {
+		meter = otel.Meter(instrumentationScope)
+	}
+
+	// instruments are always usable even when creating them fails, so errors are only reported
+	tokensValidated, err := meter.Int64Counter(TokensValidatedMetricName,
+		metric.WithDescription("Number of provider credentials validated, tagged by provider and outcome"),
+		metric.WithUnit("{token}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	providerCallDuration, err := meter.Float64Histogram(ProviderCallDurationMetricName,
+		metric.WithDescription("Duration of the provider calls alone, tagged by provider and outcome"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return authMetrics{
+		tokensValidated:      tokensValidated,
+		providerCallDuration: providerCallDuration,
+	}
+}
+
+// recordProviderCall records the duration of a provider call, the repository lookups that follow are not included
+func (m authMetrics) recordProviderCall(ctx context.Context, providerType domain.ProviderType, elapsed time.Duration, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeFailure
+	}
+	m.providerCallDuration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(
+		attribute.String(ProviderAttributeKey, string(providerType)),
+		attribute.String(OutcomeAttributeKey, outcome),
+	))
+}
+
+// recordValidation counts the credentials validated by a provider tagged by the outcome of validationOutcome
+func (m authMetrics) recordValidation(ctx context.Context, providerType domain.ProviderType, err error) {
+	m.tokensValidated.Add(ctx, 1, metric.WithAttributes(
+		attribute.String(ProviderAttributeKey, string(providerType)),
+		attribute.String(OutcomeAttributeKey, validationOutcome(err)),
+	))
+}
+
+// validationOutcome tells the credentials the client sent were invalid apart from the provider failing to
+// check them, so an upstream outage does not show as a spike of invalid tokens
+func validationOutcome(err error) string {
+	if err == nil {
+		return OutcomeValid
+	}
+	for _, target := range invalidCredentialsErrors {
+		if errors.Is(err, target) {
+			return OutcomeInvalid
+		}
+	}
+	// the deadline is checked first as a timed out call to the provider is also reported unavailable
+	if errors.Is(err, context.DeadlineExceeded) {
+		return OutcomeTimeout
+	}
+	if errors.Is(err, domain.ErrProviderUnavailable) {
+		return OutcomeUnavailable
+	}
+	return OutcomeError
+}
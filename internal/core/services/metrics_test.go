@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/posilva/simpleidentity/internal/core/domain"
+	"github.com/posilva/simpleidentity/internal/core/ports"
+)
+
+// collectMetric returns the metric with the given name collected by the reader
+func collectMetric(t *testing.T, reader sdkmetric.Reader, name string) (metricdata.Metrics, bool) {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+	return metricdata.Metrics{}, false
+}
+
+// validationCounts returns the validated tokens counted by provider and outcome
+func validationCounts(t *testing.T, reader sdkmetric.Reader) map[[2]string]int64 {
+	t.Helper()
+	m, ok := collectMetric(t, reader, TokensValidatedMetricName)
+	require.True(t, ok)
+	sum, ok := m.Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+
+	counts := make(map[[2]string]int64)
+	for _, dp := range sum.DataPoints {
+		provider, _ := dp.Attributes.Value(attribute.Key(ProviderAttributeKey))
+		outcome, _ := dp.Attributes.Value(attribute.Key(OutcomeAttributeKey))
+		counts[[2]string{provider.AsString(), outcome.AsString()}] += dp.Value
+	}
+	return counts
+}
+
+func TestAuthService_Authenticate_CountsTokenValidations(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	authService := NewAuthService(batchFactory{}, batchRepository{}, WithMeter(meter))
+
+	for _, id := range []string{"one", "two", "bad-three"} {
+		_, _ = authService.Authenticate(context.Background(), domain.AuthenticateInput{
+			ProviderType: domain.ProviderTypeGuest,
+			AuthData:     map[string]string{"id": id},
+		})
+	}
+	// the provider is never reached for an unknown provider type so nothing is validated
+	_, err := authService.Authenticate(context.Background(), domain.AuthenticateInput{
+		ProviderType: domain.ProviderTypeApple,
+		AuthData:     map[string]string{"id": "four"},
+	})
+	require.ErrorIs(t, err, domain.ErrProviderNotFound)
+
+	require.Equal(t, map[[2]string]int64{
+		{string(domain.ProviderTypeGuest), OutcomeValid}: 2,
+		{string(domain.ProviderTypeGuest), OutcomeError}: 1,
+	}, validationCounts(t, reader))
+}
+
+// failingProvider fails every authentication with the error
+type failingProvider struct {
+	err error
+}
+
+func (p failingProvider) Authenticate(context.Context, map[string]string) (ports.AuthResult, error) {
+	return nil, p.err
+}
+
+func (failingProvider) RequiredFields() []string {
+	return []string{"id"}
+}
+
+func TestAuthService_Authenticate_CountsTokenValidationsByOutcome(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "expired", err: fmt.Errorf("failed to parse token: %w", domain.ErrTokenExpired), want: OutcomeInvalid},
+		{name: "invalid signature", err: fmt.Errorf("failed to parse token: %w", domain.ErrTokenInvalidSignature), want: OutcomeInvalid},
+		{name: "nonce reused", err: domain.ErrNonceReused, want: OutcomeInvalid},
+		{name: "invalid auth data", err: fmt.Errorf("invalid code: %w", domain.ErrInvalidProviderAuthData), want: OutcomeInvalid},
+		{name: "keys not fetched", err: fmt.Errorf("failed to fetch public keys: %w", domain.ErrProviderUnavailable), want: OutcomeUnavailable},
+		{name: "unexpected", err: errors.New("failed to unmarshal JSON"), want: OutcomeError},
+		{name: "timed out", err: fmt.Errorf("failed to fetch public keys: %w: %w", domain.ErrProviderUnavailable, context.DeadlineExceeded), want: OutcomeTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+			authService := NewAuthService(fixedFactory{provider: failingProvider{err: tt.err}}, batchRepository{}, WithMeter(meter))
+
+			_, err := authService.Authenticate(context.Background(), domain.AuthenticateInput{
+				ProviderType: domain.ProviderTypeGoogle,
+				AuthData:     map[string]string{"id": "one"},
+			})
+			require.ErrorIs(t, err, tt.err)
+			require.Equal(t, map[[2]string]int64{
+				{string(domain.ProviderTypeGoogle), tt.want}: 1,
+			}, validationCounts(t, reader))
+		})
+	}
+}
+
+func TestAuthService_Authenticate_RecordsNothingWithoutValidation(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	authService := NewAuthService(batchFactory{}, batchRepository{}, WithMeter(meter))
+
+	_, err := authService.Authenticate(context.Background(), domain.AuthenticateInput{ProviderType: domain.ProviderTypeGuest})
+	require.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	_, ok := collectMetric(t, reader, TokensValidatedMetricName)
+	require.False(t, ok)
+}
+
+// delayedProvider takes the delay before authenticating, the ids prefixed with bad- are rejected
+type delayedProvider struct {
+	delay time.Duration
+}
+
+func (p delayedProvider) Authenticate(ctx context.Context, authData map[string]string) (ports.AuthResult, error) {
+	time.Sleep(p.delay)
+	return batchProvider{}.Authenticate(ctx, authData)
+}
+
+func (delayedProvider) RequiredFields() []string {
+	return []string{"id"}
+}
+
+// fixedFactory returns the provider for every provider type
+type fixedFactory struct {
+	ports.AuthProviderFactory
+	provider ports.AuthProvider
+}
+
+func (f fixedFactory) Get(domain.ProviderType) (ports.AuthProvider, error) {
+	return f.provider, nil
+}
+
+// delayedRepository takes the delay before resolving every provider id to an existing account
+type delayedRepository struct {
+	batchRepository
+	delay time.Duration
+}
+
+func (r delayedRepository) ResolveIDByProvider(ctx context.Context, providerType domain.ProviderType, providerID string) (domain.AccountID, error) {
+	time.Sleep(r.delay)
+	return r.batchRepository.ResolveIDByProvider(ctx, providerType, providerID)
+}
+
+func TestAuthService_Authenticate_RecordsProviderCallDuration(t *testing.T) {
+	const providerDelay = 50 * time.Millisecond
+	const repositoryDelay = 300 * time.Millisecond
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	authService := NewAuthService(fixedFactory{provider: delayedProvider{delay: providerDelay}},
+		delayedRepository{delay: repositoryDelay}, WithMeter(meter))
+
+	for _, id := range []string{"one", "bad-two"} {
+		_, _ = authService.Authenticate(context.Background(), domain.AuthenticateInput{
+			ProviderType: domain.ProviderTypeGuest,
+			AuthData:     map[string]string{"id": id},
+		})
+	}
+
+	m, ok := collectMetric(t, reader, ProviderCallDurationMetricName)
+	require.True(t, ok)
+	require.Equal(t, "s", m.Unit)
+	histogram, ok := m.Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, histogram.DataPoints, 2)
+
+	outcomes := make(map[string]float64)
+	for _, dp := range histogram.DataPoints {
+		provider, _ := dp.Attributes.Value(attribute.Key(ProviderAttributeKey))
+		require.Equal(t, string(domain.ProviderTypeGuest), provider.AsString())
+		outcome, _ := dp.Attributes.Value(attribute.Key(OutcomeAttributeKey))
+		require.Equal(t, uint64(1), dp.Count)
+		outcomes[outcome.AsString()] = dp.Sum
+	}
+	require.Len(t, outcomes, 2)
+	// only the provider phase is measured, the slower repository lookup of the success is left out
+	for _, outcome := range []string{OutcomeSuccess, OutcomeFailure} {
+		require.GreaterOrEqual(t, outcomes[outcome], providerDelay.Seconds())
+		require.Less(t, outcomes[outcome], repositoryDelay.Seconds())
+	}
+}
+
+func TestAuthService_Authenticate_CountsDefaultTimeout(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	provider := slowProvider{deadlines: make(chan time.Time, 1)}
+	authService := NewAuthService(slowFactory{provider: provider}, batchRepository{},
+		WithDefaultTimeout(20*time.Millisecond), WithMeter(meter))
+
+	_, err := authService.Authenticate(context.Background(), domain.AuthenticateInput{
+		ProviderType: domain.ProviderTypeGuest,
+		AuthData:     map[string]string{"id": "one"},
+	})
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, map[[2]string]int64{
+		{string(domain.ProviderTypeGuest), OutcomeTimeout}: 1,
+	}, validationCounts(t, reader))
+}
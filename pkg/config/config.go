@@ -2,9 +2,12 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/posilva/simpleidentity/pkg/telemetry"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -19,6 +22,113 @@ type Config struct {
 	HttpAddr        string        `mapstructure:"http-addr"`
 	ShutdownTimeout time.Duration `mapstructure:"shutdown-timeout"`
 	Version         string        `mapstructure:"version"`
+	// LogCaller adds the file and line of the log call to every line, disabling it saves CPU on hot paths
+	LogCaller bool `mapstructure:"log-caller"`
+	// GoroutineSoftCap is the number of goroutines above which a warning is logged
+	GoroutineSoftCap int `mapstructure:"goroutine-soft-cap"`
+	// PprofBearerToken protects the pprof server when set
+	PprofBearerToken string `mapstructure:"pprof-bearer-token"`
+	// HttpMaxBodyBytes is the maximum size of an HTTP API request body
+	HttpMaxBodyBytes int64 `mapstructure:"http-max-body-bytes"`
+	// HttpRequestTimeout is the maximum time an HTTP API request is allowed to take
+	HttpRequestTimeout time.Duration `mapstructure:"http-request-timeout"`
+	// HttpCompressionMinBytes is the size from which an HTTP API response is gzip compressed
+	HttpCompressionMinBytes int `mapstructure:"http-compression-min-bytes"`
+	// SecretsProvider resolves the secret:// references of the provider credentials, empty disables the resolution
+	SecretsProvider string `mapstructure:"secrets-provider"`
+	// SecretsCacheTTL is the time a resolved secret is kept before it is fetched again
+	SecretsCacheTTL time.Duration `mapstructure:"secrets-cache-ttl"`
+	// CredentialsRefreshInterval is the period the provider credentials are resolved again, zero disables the refresh
+	CredentialsRefreshInterval time.Duration `mapstructure:"credentials-refresh-interval"`
+	// CORS is the cross origin policy of the HTTP API
+	CORS CORSConfig `mapstructure:",squash"`
+	// DynamoDB holds the DynamoDB client settings
+	DynamoDB DynamoDBConfig `mapstructure:",squash"`
+	// CertsCache bounds the public keys cache of each provider
+	CertsCache CertsCacheConfig `mapstructure:",squash"`
+	// MetricsLatencyBuckets are the bucket boundaries in seconds of the latency histograms
+	MetricsLatencyBuckets []float64 `mapstructure:"metrics-latency-buckets"`
+	// NonceStoreMaxEntries bounds the number of consumed nonces kept to reject the replayed tokens
+	NonceStoreMaxEntries int `mapstructure:"nonce-store-max-entries"`
+
+	// Providers configuration
+	GuestEnabled bool                 `mapstructure:"guest-enabled"`
+	Google       GoogleProviderConfig `mapstructure:",squash"`
+	Apple        AppleProviderConfig  `mapstructure:",squash"`
+	Epic         EpicProviderConfig   `mapstructure:",squash"`
+	Twitch       TwitchProviderConfig `mapstructure:",squash"`
+}
+
+// SecretsProviderAWSSecretsManager resolves the secret references against AWS Secrets Manager
+const SecretsProviderAWSSecretsManager = "aws-secrets-manager"
+
+// CORSConfig holds the cross origin policy of the HTTP API, no allowed origins disables CORS
+type CORSConfig struct {
+	AllowedOrigins   []string `mapstructure:"http-cors-allowed-origins"`
+	AllowedMethods   []string `mapstructure:"http-cors-allowed-methods"`
+	AllowedHeaders   []string `mapstructure:"http-cors-allowed-headers"`
+	AllowCredentials bool     `mapstructure:"http-cors-allow-credentials"`
+	MaxAge           int      `mapstructure:"http-cors-max-age"`
+}
+
+// DynamoDBConfig holds the DynamoDB client settings, empty endpoint and region use the AWS configuration
+type DynamoDBConfig struct {
+	// Endpoint overrides the DynamoDB endpoint, e.g. DynamoDB Local or a VPC endpoint
+	Endpoint string `mapstructure:"dynamodb-endpoint"`
+	Region   string `mapstructure:"dynamodb-region"`
+	Table    string `mapstructure:"dynamodb-table"`
+}
+
+// CertsCacheConfig bounds the public keys cache, the least recently used key is evicted at the max entries
+type CertsCacheConfig struct {
+	MaxEntries int `mapstructure:"certs-cache-max-entries"`
+	// MaxTTL caps the expiry advertised by the providers
+	MaxTTL time.Duration `mapstructure:"certs-cache-max-ttl"`
+}
+
+// GoogleProviderConfig holds the Google provider credentials, the provider is disabled when the client ID or secret are empty
+type GoogleProviderConfig struct {
+	ClientID          string   `mapstructure:"google-client-id"`
+	ClientSecret      string   `mapstructure:"google-client-secret"`
+	PrivateKey        string   `mapstructure:"google-private-key"`
+	AuthURI           string   `mapstructure:"google-auth-uri"`
+	CertsURL          string   `mapstructure:"google-certs-url"`
+	ExpectedIssuer    string   `mapstructure:"google-expected-issuer"`
+	ExpectedAudiences []string `mapstructure:"google-expected-audiences"`
+}
+
+// AppleProviderConfig holds the Apple provider credentials, the provider is disabled when the client ID or secret are empty
+type AppleProviderConfig struct {
+	ClientID         string `mapstructure:"apple-client-id"`
+	ClientSecret     string `mapstructure:"apple-client-secret"`
+	TeamID           string `mapstructure:"apple-team-id"`
+	KeyID            string `mapstructure:"apple-key-id"`
+	CertsURL         string `mapstructure:"apple-certs-url"`
+	AuthTokensURL    string `mapstructure:"apple-auth-tokens-url"`
+	ExpectedIssuer   string `mapstructure:"apple-expected-issuer"`
+	ExpectedAudience string `mapstructure:"apple-expected-audience"`
+	// EmailVerification rejects a client supplied email not matching the token, Apple omits it after the first authorization
+	EmailVerification bool `mapstructure:"apple-email-verification"`
+}
+
+// EpicProviderConfig holds the Epic Online Services provider credentials, the provider is disabled when the client ID or secret are empty
+type EpicProviderConfig struct {
+	ClientID         string `mapstructure:"epic-client-id"`
+	ClientSecret     string `mapstructure:"epic-client-secret"`
+	TokenURL         string `mapstructure:"epic-token-url"`
+	JWKSURL          string `mapstructure:"epic-jwks-url"`
+	ExpectedIssuer   string `mapstructure:"epic-expected-issuer"`
+	ExpectedAudience string `mapstructure:"epic-expected-audience"`
+}
+
+// TwitchProviderConfig holds the Twitch provider credentials, the provider is disabled when the client ID or secret are empty
+type TwitchProviderConfig struct {
+	ClientID     string `mapstructure:"twitch-client-id"`
+	ClientSecret string `mapstructure:"twitch-client-secret"`
+	TokenURL     string `mapstructure:"twitch-token-url"`
+	ValidateURL  string `mapstructure:"twitch-validate-url"`
+	// RedirectURI is the redirect URI registered in the Twitch application, the clients obtain the code with it
+	RedirectURI string `mapstructure:"twitch-redirect-uri"`
 }
 
 // Manager handles configuration loading and management
@@ -43,12 +153,63 @@ func (m *Manager) SetDefaults() {
 	// Server defaults
 	m.viper.SetDefault("log-level", "info")
 	m.viper.SetDefault("log-pretty", false)
+	m.viper.SetDefault("log-caller", true)
 	m.viper.SetDefault("health-addr", ":8080")
-	m.viper.SetDefault("pprof-addr", ":6060")
+	m.viper.SetDefault("pprof-addr", "localhost:6060")
+	m.viper.SetDefault("pprof-bearer-token", "")
 	m.viper.SetDefault("grpc-addr", ":9090")
 	m.viper.SetDefault("http-addr", ":8090")
+	m.viper.SetDefault("http-max-body-bytes", 1<<20)
+	m.viper.SetDefault("http-request-timeout", 30*time.Second)
+	m.viper.SetDefault("http-compression-min-bytes", 1024)
+	m.viper.SetDefault("secrets-provider", "")
+	m.viper.SetDefault("secrets-cache-ttl", 5*time.Minute)
+	m.viper.SetDefault("credentials-refresh-interval", 0)
+	m.viper.SetDefault("http-cors-allowed-origins", []string{})
+	m.viper.SetDefault("http-cors-allowed-methods", []string{"GET", "POST"})
+	m.viper.SetDefault("http-cors-allowed-headers", []string{"Content-Type", "Authorization", "X-Request-ID"})
+	m.viper.SetDefault("http-cors-allow-credentials", false)
+	m.viper.SetDefault("http-cors-max-age", 0)
 	m.viper.SetDefault("shutdown-timeout", 30*time.Second)
+	m.viper.SetDefault("dynamodb-endpoint", "")
+	m.viper.SetDefault("dynamodb-region", "")
+	m.viper.SetDefault("dynamodb-table", "accounts")
+	m.viper.SetDefault("certs-cache-max-entries", 100)
+	m.viper.SetDefault("certs-cache-max-ttl", 24*time.Hour)
+	m.viper.SetDefault("nonce-store-max-entries", 100000)
+	m.viper.SetDefault("metrics-latency-buckets", telemetry.DefaultLatencyBoundaries)
 	m.viper.SetDefault("version", "dev")
+	m.viper.SetDefault("goroutine-soft-cap", 10000)
+
+	// Providers defaults, credentials are left empty so providers are disabled unless configured
+	m.viper.SetDefault("guest-enabled", false)
+	m.viper.SetDefault("google-client-id", "")
+	m.viper.SetDefault("google-client-secret", "")
+	m.viper.SetDefault("google-private-key", "")
+	m.viper.SetDefault("google-auth-uri", "https://oauth2.googleapis.com/token")
+	m.viper.SetDefault("google-certs-url", "https://www.googleapis.com/oauth2/v1/certs")
+	m.viper.SetDefault("google-expected-issuer", "https://accounts.google.com")
+	m.viper.SetDefault("google-expected-audiences", []string{})
+	m.viper.SetDefault("apple-client-id", "")
+	m.viper.SetDefault("apple-client-secret", "")
+	m.viper.SetDefault("apple-team-id", "")
+	m.viper.SetDefault("apple-key-id", "")
+	m.viper.SetDefault("apple-certs-url", "https://appleid.apple.com/auth/keys")
+	m.viper.SetDefault("apple-auth-tokens-url", "https://appleid.apple.com/auth/token")
+	m.viper.SetDefault("apple-expected-issuer", "https://appleid.apple.com")
+	m.viper.SetDefault("apple-expected-audience", "")
+	m.viper.SetDefault("apple-email-verification", false)
+	m.viper.SetDefault("epic-client-id", "")
+	m.viper.SetDefault("epic-client-secret", "")
+	m.viper.SetDefault("epic-token-url", "https://api.epicgames.dev/epic/oauth/v2/token")
+	m.viper.SetDefault("epic-jwks-url", "https://api.epicgames.dev/epic/oauth/v2/.well-known/jwks.json")
+	m.viper.SetDefault("epic-expected-issuer", "https://api.epicgames.dev/epic/oauth/v2")
+	m.viper.SetDefault("epic-expected-audience", "")
+	m.viper.SetDefault("twitch-client-id", "")
+	m.viper.SetDefault("twitch-client-secret", "")
+	m.viper.SetDefault("twitch-token-url", "https://id.twitch.tv/oauth2/token")
+	m.viper.SetDefault("twitch-validate-url", "https://id.twitch.tv/oauth2/validate")
+	m.viper.SetDefault("twitch-redirect-uri", "")
 }
 
 // Load loads configuration from environment variables and defaults
@@ -68,14 +229,33 @@ func (m *Manager) Load() (*Config, error) {
 	return &config, nil
 }
 
-// BindFlags binds command line flags to the configuration
-func (m *Manager) BindFlags(flags interface{}) error {
-	// This will be used by cobra to bind flags
-	// The interface{} parameter would typically be *pflag.FlagSet
-	if flagSet, ok := flags.(interface{ VisitAll(func(interface{})) }); ok {
-		// In a real implementation, you'd iterate through flags and bind them
-		// For now, we'll use viper's automatic binding
-		_ = flagSet
+// supportedConfigFileExtensions lists the config file formats accepted by LoadFile
+var supportedConfigFileExtensions = []string{".yaml", ".yml", ".json"}
+
+// LoadFile reads the configuration file at path, its values override the defaults and are
+// overridden by environment variables. The merged configuration is validated
+func (m *Manager) LoadFile(path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	if !contains(supportedConfigFileExtensions, ext) {
+		return fmt.Errorf("unsupported config file extension: %q, must be one of: %v", ext, supportedConfigFileExtensions)
+	}
+
+	m.viper.SetConfigFile(path)
+	if err := m.viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	if _, err := m.Load(); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// BindFlags binds the command line flags to the configuration, a flag set explicitly
+// takes precedence over environment variables, config files and defaults
+func (m *Manager) BindFlags(flagSet *pflag.FlagSet) error {
+	if err := m.viper.BindPFlags(flagSet); err != nil {
+		return fmt.Errorf("failed to bind flags: %w", err)
 	}
 	return nil
 }
@@ -93,6 +273,52 @@ func (m *Manager) validate(config *Config) error {
 		return fmt.Errorf("shutdown timeout must be positive, got: %v", config.ShutdownTimeout)
 	}
 
+	if config.DynamoDB.Table == "" {
+		return fmt.Errorf("dynamodb table must not be empty")
+	}
+
+	if config.CertsCache.MaxEntries <= 0 {
+		return fmt.Errorf("certs cache max entries must be positive, got: %d", config.CertsCache.MaxEntries)
+	}
+
+	if config.CertsCache.MaxTTL <= 0 {
+		return fmt.Errorf("certs cache max ttl must be positive, got: %v", config.CertsCache.MaxTTL)
+	}
+
+	if config.NonceStoreMaxEntries <= 0 {
+		return fmt.Errorf("nonce store max entries must be positive, got: %d", config.NonceStoreMaxEntries)
+	}
+
+	if len(config.MetricsLatencyBuckets) == 0 {
+		return fmt.Errorf("metrics latency buckets must not be empty")
+	}
+	for i, bound := range config.MetricsLatencyBuckets {
+		if bound <= 0 || (i > 0 && bound <= config.MetricsLatencyBuckets[i-1]) {
+			return fmt.Errorf("metrics latency buckets must be positive and increasing, got: %v", config.MetricsLatencyBuckets)
+		}
+	}
+
+	if config.HttpMaxBodyBytes <= 0 {
+		return fmt.Errorf("http max body bytes must be positive, got: %d", config.HttpMaxBodyBytes)
+	}
+
+	if config.HttpRequestTimeout <= 0 {
+		return fmt.Errorf("http request timeout must be positive, got: %v", config.HttpRequestTimeout)
+	}
+
+	if config.HttpCompressionMinBytes < 0 {
+		return fmt.Errorf("http compression min bytes must not be negative, got: %d", config.HttpCompressionMinBytes)
+	}
+
+	if config.CredentialsRefreshInterval < 0 {
+		return fmt.Errorf("credentials refresh interval must not be negative, got: %v", config.CredentialsRefreshInterval)
+	}
+
+	validSecretsProviders := []string{"", SecretsProviderAWSSecretsManager}
+	if !contains(validSecretsProviders, config.SecretsProvider) {
+		return fmt.Errorf("invalid secrets provider: %s, must be one of: %q", config.SecretsProvider, validSecretsProviders)
+	}
+
 	return nil
 }
 
@@ -147,14 +373,39 @@ func (m *Manager) PrintConfig(config *Config) map[string]interface{} {
 
 	// Server settings
 	settings["server"] = map[string]interface{}{
-		"log_level":        config.LogLevel,
-		"log_pretty":       config.LogPretty,
-		"health_addr":      config.HealthAddr,
-		"pprof_addr":       config.PprofAddr,
-		"grpc_addr":        config.GrpcAddr,
-		"http_addr":        config.HttpAddr,
-		"shutdown_timeout": config.ShutdownTimeout,
-		"version":          config.Version,
+		"log_level":                  config.LogLevel,
+		"log_pretty":                 config.LogPretty,
+		"log_caller":                 config.LogCaller,
+		"health_addr":                config.HealthAddr,
+		"pprof_addr":                 config.PprofAddr,
+		"pprof_auth":                 config.PprofBearerToken != "",
+		"grpc_addr":                  config.GrpcAddr,
+		"http_addr":                  config.HttpAddr,
+		"http_max_body_bytes":        config.HttpMaxBodyBytes,
+		"http_request_timeout":       config.HttpRequestTimeout,
+		"http_compression_min_bytes": config.HttpCompressionMinBytes,
+		"http_cors_allowed_origins":  config.CORS.AllowedOrigins,
+		"shutdown_timeout":           config.ShutdownTimeout,
+		"version":                    config.Version,
+		"goroutine_soft_cap":         config.GoroutineSoftCap,
+		"secrets_provider":           config.SecretsProvider,
+		"credentials_refresh":        config.CredentialsRefreshInterval,
+		"dynamodb_endpoint":          config.DynamoDB.Endpoint,
+		"dynamodb_region":            config.DynamoDB.Region,
+		"dynamodb_table":             config.DynamoDB.Table,
+		"certs_cache_max_entries":    config.CertsCache.MaxEntries,
+		"certs_cache_max_ttl":        config.CertsCache.MaxTTL,
+		"nonce_store_max_entries":    config.NonceStoreMaxEntries,
+		"metrics_latency_buckets":    config.MetricsLatencyBuckets,
+	}
+
+	// Providers settings, secrets are never printed
+	settings["providers"] = map[string]interface{}{
+		"guest_enabled":    config.GuestEnabled,
+		"google_client_id": config.Google.ClientID,
+		"apple_client_id":  config.Apple.ClientID,
+		"epic_client_id":   config.Epic.ClientID,
+		"twitch_client_id": config.Twitch.ClientID,
 	}
 	return settings
 }
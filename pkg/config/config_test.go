@@ -0,0 +1,269 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/posilva/simpleidentity/pkg/telemetry"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestManager_LoadFile_YAML(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+log-level: debug
+http-addr: ":9999"
+shutdown-timeout: 10s
+google-client-id: file_google_client_id
+google-expected-audiences:
+  - android_client_id
+  - ios_client_id
+`)
+
+	m := NewManager()
+	require.NoError(t, m.LoadFile(path))
+
+	cfg, err := m.Load()
+	require.NoError(t, err)
+	require.Equal(t, "debug", cfg.LogLevel)
+	require.Equal(t, ":9999", cfg.HttpAddr)
+	require.Equal(t, 10*time.Second, cfg.ShutdownTimeout)
+	require.Equal(t, "file_google_client_id", cfg.Google.ClientID)
+	require.Equal(t, []string{"android_client_id", "ios_client_id"}, cfg.Google.ExpectedAudiences)
+	// defaults are kept for the values missing in the file
+	require.Equal(t, ":8080", cfg.HealthAddr)
+}
+
+func TestManager_LoadFile_JSON(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{"log-level": "warn", "guest-enabled": true}`)
+
+	m := NewManager()
+	require.NoError(t, m.LoadFile(path))
+
+	cfg, err := m.Load()
+	require.NoError(t, err)
+	require.Equal(t, "warn", cfg.LogLevel)
+	require.True(t, cfg.GuestEnabled)
+}
+
+func TestManager_Load_CORS(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.Empty(t, cfg.CORS.AllowedOrigins)
+	require.Equal(t, []string{"GET", "POST"}, cfg.CORS.AllowedMethods)
+
+	t.Setenv("SMPIDT_HTTP_CORS_ALLOWED_ORIGINS", "https://play.example.com,https://beta.example.com")
+	cfg, err = NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://play.example.com", "https://beta.example.com"}, cfg.CORS.AllowedOrigins)
+}
+
+func TestManager_Load_HttpMaxBodyBytes(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, int64(1<<20), cfg.HttpMaxBodyBytes)
+
+	t.Setenv("SMPIDT_HTTP_MAX_BODY_BYTES", "0")
+	_, err = NewManager().Load()
+	require.ErrorContains(t, err, "http max body bytes must be positive")
+}
+
+func TestManager_Load_HttpRequestTimeout(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, cfg.HttpRequestTimeout)
+
+	t.Setenv("SMPIDT_HTTP_REQUEST_TIMEOUT", "5s")
+	cfg, err = NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, cfg.HttpRequestTimeout)
+
+	t.Setenv("SMPIDT_HTTP_REQUEST_TIMEOUT", "0s")
+	_, err = NewManager().Load()
+	require.ErrorContains(t, err, "http request timeout must be positive")
+}
+
+func TestManager_Load_HttpCompressionMinBytes(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, 1024, cfg.HttpCompressionMinBytes)
+
+	t.Setenv("SMPIDT_HTTP_COMPRESSION_MIN_BYTES", "-1")
+	_, err = NewManager().Load()
+	require.ErrorContains(t, err, "http compression min bytes must not be negative")
+}
+
+func TestManager_Load_SecretsProvider(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.Empty(t, cfg.SecretsProvider)
+	require.Equal(t, 5*time.Minute, cfg.SecretsCacheTTL)
+
+	t.Setenv("SMPIDT_SECRETS_PROVIDER", SecretsProviderAWSSecretsManager)
+	cfg, err = NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, SecretsProviderAWSSecretsManager, cfg.SecretsProvider)
+
+	t.Setenv("SMPIDT_SECRETS_PROVIDER", "vault")
+	_, err = NewManager().Load()
+	require.ErrorContains(t, err, "invalid secrets provider: vault")
+}
+
+func TestManager_Load_CredentialsRefreshInterval(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.Zero(t, cfg.CredentialsRefreshInterval)
+
+	t.Setenv("SMPIDT_CREDENTIALS_REFRESH_INTERVAL", "-1m")
+	_, err = NewManager().Load()
+	require.ErrorContains(t, err, "credentials refresh interval must not be negative")
+}
+
+func TestManager_Load_LogCaller(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.True(t, cfg.LogCaller)
+
+	t.Setenv("SMPIDT_LOG_CALLER", "false")
+	cfg, err = NewManager().Load()
+	require.NoError(t, err)
+	require.False(t, cfg.LogCaller)
+}
+
+func TestManager_Load_DynamoDB(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.Empty(t, cfg.DynamoDB.Endpoint)
+	require.Empty(t, cfg.DynamoDB.Region)
+	require.Equal(t, "accounts", cfg.DynamoDB.Table)
+
+	t.Setenv("SMPIDT_DYNAMODB_ENDPOINT", "http://localhost:8000")
+	t.Setenv("SMPIDT_DYNAMODB_REGION", "eu-west-1")
+	t.Setenv("SMPIDT_DYNAMODB_TABLE", "accounts-test")
+	cfg, err = NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, DynamoDBConfig{Endpoint: "http://localhost:8000", Region: "eu-west-1", Table: "accounts-test"}, cfg.DynamoDB)
+}
+
+func TestManager_Load_CertsCache(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, CertsCacheConfig{MaxEntries: 100, MaxTTL: 24 * time.Hour}, cfg.CertsCache)
+
+	t.Setenv("SMPIDT_CERTS_CACHE_MAX_ENTRIES", "10")
+	t.Setenv("SMPIDT_CERTS_CACHE_MAX_TTL", "1h")
+	cfg, err = NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, CertsCacheConfig{MaxEntries: 10, MaxTTL: time.Hour}, cfg.CertsCache)
+
+	t.Setenv("SMPIDT_CERTS_CACHE_MAX_ENTRIES", "0")
+	_, err = NewManager().Load()
+	require.EqualError(t, err, "configuration validation failed: certs cache max entries must be positive, got: 0")
+}
+
+func TestManager_Load_NonceStoreMaxEntries(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, 100000, cfg.NonceStoreMaxEntries)
+
+	t.Setenv("SMPIDT_NONCE_STORE_MAX_ENTRIES", "10")
+	cfg, err = NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, 10, cfg.NonceStoreMaxEntries)
+
+	t.Setenv("SMPIDT_NONCE_STORE_MAX_ENTRIES", "0")
+	_, err = NewManager().Load()
+	require.EqualError(t, err, "configuration validation failed: nonce store max entries must be positive, got: 0")
+}
+
+func TestManager_Load_MetricsLatencyBuckets(t *testing.T) {
+	cfg, err := NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, telemetry.DefaultLatencyBoundaries, cfg.MetricsLatencyBuckets)
+
+	t.Setenv("SMPIDT_METRICS_LATENCY_BUCKETS", "0.01,0.1,1")
+	cfg, err = NewManager().Load()
+	require.NoError(t, err)
+	require.Equal(t, []float64{0.01, 0.1, 1}, cfg.MetricsLatencyBuckets)
+
+	t.Setenv("SMPIDT_METRICS_LATENCY_BUCKETS", "0.1,0.01")
+	_, err = NewManager().Load()
+	require.EqualError(t, err, "configuration validation failed: metrics latency buckets must be positive and increasing, got: [0.1 0.01]")
+}
+
+func TestManager_LoadFile_EnvOverridesFile(t *testing.T) {
+	t.Setenv("SMPIDT_HTTP_ADDR", ":7777")
+	path := writeConfigFile(t, "config.yml", `
+http-addr: ":9999"
+log-level: error
+`)
+
+	m := NewManager()
+	require.NoError(t, m.LoadFile(path))
+
+	cfg, err := m.Load()
+	require.NoError(t, err)
+	require.Equal(t, ":7777", cfg.HttpAddr)
+	require.Equal(t, "error", cfg.LogLevel)
+}
+
+func TestManager_LoadFile_Errors(t *testing.T) {
+	t.Run("unsupported extension", func(t *testing.T) {
+		path := writeConfigFile(t, "config.toml", `log-level = "debug"`)
+		require.ErrorContains(t, NewManager().LoadFile(path), "unsupported config file extension")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		require.Error(t, NewManager().LoadFile(filepath.Join(t.TempDir(), "missing.yaml")))
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", `log-level: verbose`)
+		require.ErrorContains(t, NewManager().LoadFile(path), "invalid log level")
+	})
+}
+
+func TestManager_BindFlags(t *testing.T) {
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.String("http-addr", ":8090", "HTTP server address")
+
+	m := NewManager()
+	require.NoError(t, m.BindFlags(flagSet))
+
+	cfg, err := m.Load()
+	require.NoError(t, err)
+	require.Equal(t, ":8090", cfg.HttpAddr)
+
+	require.NoError(t, flagSet.Set("http-addr", ":9191"))
+	cfg, err = m.Load()
+	require.NoError(t, err)
+	require.Equal(t, ":9191", cfg.HttpAddr)
+	require.Equal(t, ":9191", m.GetString("http-addr"))
+}
+
+func TestManager_BindFlags_FlagOverridesEnv(t *testing.T) {
+	t.Setenv("SMPIDT_LOG_LEVEL", "warn")
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.String("log-level", "info", "Log level")
+
+	m := NewManager()
+	require.NoError(t, m.BindFlags(flagSet))
+
+	cfg, err := m.Load()
+	require.NoError(t, err)
+	require.Equal(t, "warn", cfg.LogLevel)
+
+	require.NoError(t, flagSet.Set("log-level", "debug"))
+	cfg, err = m.Load()
+	require.NoError(t, err)
+	require.Equal(t, "debug", cfg.LogLevel)
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedValue replaces the value of sensitive settings
+const RedactedValue = "***"
+
+// Supported dump formats
+const (
+	DumpFormatJSON = "json"
+	DumpFormatYAML = "yaml"
+)
+
+// sensitiveKeyMarkers lists the key fragments that identify sensitive settings
+var sensitiveKeyMarkers = []string{"secret", "private-key", "password", "headers", "bearer-token"}
+
+// IsSensitiveKey reports whether the setting key holds a secret
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range sensitiveKeyMarkers {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Settings returns the effective settings, non empty sensitive values are redacted unless showSecrets is set
+func (m *Manager) Settings(showSecrets bool) map[string]interface{} {
+	return redactSettings(m.viper.AllSettings(), showSecrets)
+}
+
+// Dump writes the effective settings in the given format, see Settings for the redaction rules
+func (m *Manager) Dump(w io.Writer, format string, showSecrets bool) error {
+	settings := m.Settings(showSecrets)
+
+	switch format {
+	case DumpFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(settings)
+	case DumpFormatYAML:
+		enc := yaml.NewEncoder(w)
+		defer enc.Close()
+		return enc.Encode(settings)
+	default:
+		return fmt.Errorf("unsupported dump format: %q, must be one of: %v", format, []string{DumpFormatJSON, DumpFormatYAML})
+	}
+}
+
+// redactSettings returns a copy of the settings with the sensitive values redacted and the durations
+// formatted as strings so they read the same as in config files
+func redactSettings(settings map[string]interface{}, showSecrets bool) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = redactSettings(v, showSecrets)
+		case time.Duration:
+			value = v.String()
+		}
+		if !showSecrets && IsSensitiveKey(key) && value != "" && value != nil {
+			value = RedactedValue
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestManager_Dump_RedactsSecretsByDefault(t *testing.T) {
+	t.Setenv("SMPIDT_GOOGLE_CLIENT_ID", "google_client_id")
+	t.Setenv("SMPIDT_GOOGLE_CLIENT_SECRET", "google_client_secret")
+	t.Setenv("SMPIDT_GOOGLE_PRIVATE_KEY", "google_private_key")
+	t.Setenv("SMPIDT_APPLE_CLIENT_SECRET", "apple_client_secret")
+
+	m := NewManager()
+	_, err := m.Load()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, m.Dump(&buf, DumpFormatJSON, false))
+	require.NotContains(t, buf.String(), "_secret")
+	require.NotContains(t, buf.String(), "google_private_key")
+
+	var settings map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &settings))
+	require.Equal(t, RedactedValue, settings["google-client-secret"])
+	require.Equal(t, RedactedValue, settings["google-private-key"])
+	require.Equal(t, RedactedValue, settings["apple-client-secret"])
+	require.Equal(t, "google_client_id", settings["google-client-id"])
+	require.Equal(t, "https://appleid.apple.com/auth/token", settings["apple-auth-tokens-url"])
+	require.Equal(t, "30s", settings["shutdown-timeout"])
+	// unset secrets are kept empty so operators can tell them apart from configured ones
+	require.Equal(t, "", settings["apple-team-id"])
+}
+
+func TestManager_Dump_ShowsSecretsWhenRequested(t *testing.T) {
+	t.Setenv("SMPIDT_GOOGLE_CLIENT_SECRET", "google_client_secret")
+
+	m := NewManager()
+	_, err := m.Load()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, m.Dump(&buf, DumpFormatYAML, true))
+
+	var settings map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &settings))
+	require.Equal(t, "google_client_secret", settings["google-client-secret"])
+}
+
+func TestManager_Dump_UnsupportedFormat(t *testing.T) {
+	require.ErrorContains(t, NewManager().Dump(&bytes.Buffer{}, "toml", false), "unsupported dump format")
+}
+
+func TestIsSensitiveKey(t *testing.T) {
+	for _, key := range []string{"google-client-secret", "google-private-key", "otlp-headers", "DB-PASSWORD", "pprof-bearer-token"} {
+		require.True(t, IsSensitiveKey(key), key)
+	}
+	for _, key := range []string{"google-client-id", "apple-auth-tokens-url", "log-level"} {
+		require.False(t, IsSensitiveKey(key), key)
+	}
+}
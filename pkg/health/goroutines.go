@@ -0,0 +1,44 @@
+package health
+
+import (
+	"context"
+	"runtime"
+
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/posilva/simpleidentity/pkg/logger"
+)
+
+// GoroutinesGaugeName is the name of the gauge reporting the number of goroutines
+const GoroutinesGaugeName = "runtime.goroutines"
+
+// RegisterGoroutineGauge registers an observable gauge reporting the current number of goroutines
+func RegisterGoroutineGauge(meter metric.Meter) (metric.Registration, error) {
+	gauge, err := meter.Int64ObservableGauge(GoroutinesGaugeName,
+		metric.WithDescription("Number of goroutines that currently exist"),
+		metric.WithUnit("{goroutine}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(gauge, int64(runtime.NumGoroutine()))
+		return nil
+	}, gauge)
+}
+
+// GoroutineCheck creates a goroutine soft cap check, it logs a warning when the number of goroutines
+// exceeds softCap but never reports unhealthy, a non positive softCap disables the warning
+func GoroutineCheck(softCap int, log logger.Logger) CheckFunc {
+	return func(ctx context.Context) error {
+		count := runtime.NumGoroutine()
+		if softCap > 0 && count > softCap {
+			log.Warn().
+				Int("goroutines", count).
+				Int("soft_cap", softCap).
+				Msg("Number of goroutines exceeds the soft cap")
+		}
+		return nil
+	}
+}
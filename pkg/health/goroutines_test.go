@@ -0,0 +1,46 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/posilva/simpleidentity/pkg/logger"
+)
+
+func TestRegisterGoroutineGauge_ReportsGoroutines(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	_, err := RegisterGoroutineGauge(provider.Meter("test"))
+	require.NoError(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+
+	m := rm.ScopeMetrics[0].Metrics[0]
+	require.Equal(t, GoroutinesGaugeName, m.Name)
+	gauge, ok := m.Data.(metricdata.Gauge[int64])
+	require.True(t, ok)
+	require.Len(t, gauge.DataPoints, 1)
+	require.GreaterOrEqual(t, gauge.DataPoints[0].Value, int64(1))
+}
+
+func TestGoroutineCheck_WarnsWhenSoftCapExceeded(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewWithWriter(&buf, "warn")
+
+	require.NoError(t, GoroutineCheck(1_000_000, log)(context.Background()))
+	require.Empty(t, buf.String())
+
+	require.NoError(t, GoroutineCheck(1, log)(context.Background()))
+	require.Contains(t, buf.String(), "Number of goroutines exceeds the soft cap")
+	require.Contains(t, buf.String(), `"soft_cap":1`)
+}
@@ -18,6 +18,7 @@ const (
 	StatusHealthy   Status = "healthy"
 	StatusUnhealthy Status = "unhealthy"
 	StatusUnknown   Status = "unknown"
+	StatusNotReady  Status = "not_ready"
 )
 
 // Check represents a health check
@@ -134,10 +135,30 @@ type Server struct {
 	server  *http.Server
 	checker *Checker
 	logger  logger.Logger
+	gate    *ReadinessGate
+	// metrics serves the metrics scrape endpoint, nil leaves /metrics unregistered
+	metrics http.Handler
+}
+
+// ServerOption configures optional settings of the health check server
+type ServerOption func(*Server)
+
+// WithReadinessGate makes the readiness probe fail until the gate is ready, the checks are not run before
+func WithReadinessGate(gate *ReadinessGate) ServerOption {
+	return func(s *Server) {
+		s.gate = gate
+	}
+}
+
+// WithMetricsHandler serves the metrics scrape endpoint at /metrics next to the probes
+func WithMetricsHandler(h http.Handler) ServerOption {
+	return func(s *Server) {
+		s.metrics = h
+	}
 }
 
 // NewServer creates a new health check server
-func NewServer(addr string, checker *Checker, logger logger.Logger) *Server {
+func NewServer(addr string, checker *Checker, logger logger.Logger, opts ...ServerOption) *Server {
 	mux := http.NewServeMux()
 
 	s := &Server{
@@ -148,11 +169,17 @@ func NewServer(addr string, checker *Checker, logger logger.Logger) *Server {
 		checker: checker,
 		logger:  logger,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
 
 	// Health check endpoints
 	mux.HandleFunc("/health", s.healthHandler)
 	mux.HandleFunc("/health/live", s.livenessHandler)
 	mux.HandleFunc("/health/ready", s.readinessHandler)
+	if s.metrics != nil {
+		mux.Handle("GET /metrics", s.metrics)
+	}
 
 	return s
 }
@@ -218,13 +245,25 @@ func (s *Server) livenessHandler(w http.ResponseWriter, r *http.Request) {
 
 // readinessHandler handles readiness probe (dependencies check)
 func (s *Server) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.gate != nil && !s.gate.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		notReadyResponse := map[string]interface{}{
+			"status":    StatusNotReady,
+			"timestamp": time.Now().UTC(),
+		}
+		if err := json.NewEncoder(w).Encode(notReadyResponse); err != nil {
+			s.logger.Error().Err(err).Msg("Error encoding readiness response")
+		}
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
 	response := s.checker.Check(ctx)
 
-	w.Header().Set("Content-Type", "application/json")
-
 	if response.Status == StatusUnhealthy {
 		w.WriteHeader(http.StatusServiceUnavailable)
 	} else {
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// readinessCheckTimeout bounds every run of the checks, as the readiness probe does
+const readinessCheckTimeout = 5 * time.Second
+
+// ReadinessGate reports whether the service finished its startup and can receive traffic,
+// it starts not ready and the readiness probe fails until it is flipped
+type ReadinessGate struct {
+	ready atomic.Bool
+}
+
+// NewReadinessGate creates a new readiness gate in the not ready state
+func NewReadinessGate() *ReadinessGate {
+	return &ReadinessGate{}
+}
+
+// SetReady flips the gate to the given state
+func (g *ReadinessGate) SetReady(ready bool) {
+	g.ready.Store(ready)
+}
+
+// Ready returns true when the gate was flipped to ready
+func (g *ReadinessGate) Ready() bool {
+	return g.ready.Load()
+}
+
+// SetReadyWhenHealthy runs the checks every interval until they all pass once and then flips the gate to ready,
+// it returns the context error when the context is done first
+func (g *ReadinessGate) SetReadyWhenHealthy(ctx context.Context, checker *Checker, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+		response := checker.Check(checkCtx)
+		cancel()
+		if response.Status == StatusHealthy {
+			g.SetReady(true)
+			return nil
+		}
+		checker.logger.Warn().
+			Any("checks", unhealthyChecks(response)).
+			Msg("Readiness checks failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// unhealthyChecks returns the messages of the failed checks by name
+func unhealthyChecks(response Response) map[string]string {
+	failed := make(map[string]string)
+	for name, check := range response.Checks {
+		if check.Status == StatusUnhealthy {
+			failed[name] = check.Message
+		}
+	}
+	return failed
+}
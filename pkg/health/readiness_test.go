@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/posilva/simpleidentity/pkg/logger"
+)
+
+func readinessStatus(t *testing.T, s *Server) (int, Status) {
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	var body struct {
+		Status Status `json:"status"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	return rec.Code, body.Status
+}
+
+func TestReadinessGate_StartsNotReady(t *testing.T) {
+	gate := NewReadinessGate()
+	require.False(t, gate.Ready())
+
+	gate.SetReady(true)
+	require.True(t, gate.Ready())
+
+	gate.SetReady(false)
+	require.False(t, gate.Ready())
+}
+
+func TestServer_Readiness_FollowsGate(t *testing.T) {
+	log := logger.NewWithWriter(io.Discard, "error")
+	gate := NewReadinessGate()
+	s := NewServer(":0", NewChecker(log, "test"), log, WithReadinessGate(gate))
+
+	code, status := readinessStatus(t, s)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, StatusNotReady, status)
+
+	gate.SetReady(true)
+	code, status = readinessStatus(t, s)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, StatusHealthy, status)
+
+	gate.SetReady(false)
+	code, _ = readinessStatus(t, s)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestServer_Readiness_WithoutGate(t *testing.T) {
+	log := logger.NewWithWriter(io.Discard, "error")
+	code, status := readinessStatus(t, NewServer(":0", NewChecker(log, "test"), log))
+
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, StatusHealthy, status)
+}
+
+func TestReadinessGate_SetReadyWhenHealthy(t *testing.T) {
+	log := logger.NewWithWriter(io.Discard, "error")
+	checker := NewChecker(log, "test")
+	var attempts atomic.Int32
+	checker.AddCheck("dynamodb", func(ctx context.Context) error {
+		if attempts.Add(1) < 3 {
+			return errors.New("table not active")
+		}
+		return nil
+	})
+	gate := NewReadinessGate()
+
+	require.NoError(t, gate.SetReadyWhenHealthy(context.Background(), checker, time.Millisecond))
+	require.True(t, gate.Ready())
+	require.Equal(t, int32(3), attempts.Load())
+}
+
+func TestReadinessGate_SetReadyWhenHealthy_StaysNotReadyUntilCancelled(t *testing.T) {
+	log := logger.NewWithWriter(io.Discard, "error")
+	checker := NewChecker(log, "test")
+	checker.AddCheck("dynamodb", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	gate := NewReadinessGate()
+	s := NewServer(":0", checker, log, WithReadinessGate(gate))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, gate.SetReadyWhenHealthy(ctx, checker, time.Millisecond), context.DeadlineExceeded)
+	require.False(t, gate.Ready())
+
+	code, status := readinessStatus(t, s)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, StatusNotReady, status)
+}
+
+func TestServer_Metrics(t *testing.T) {
+	log := logger.NewWithWriter(io.Discard, "error")
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("runtime_goroutines 12\n"))
+	})
+
+	rec := httptest.NewRecorder()
+	NewServer(":0", NewChecker(log, "test"), log, WithMetricsHandler(metrics)).server.Handler.
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "runtime_goroutines 12\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	NewServer(":0", NewChecker(log, "test"), log).server.Handler.
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
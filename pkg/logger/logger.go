@@ -8,6 +8,10 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/posilva/simpleidentity/pkg/reqid"
 )
 
 // Logger interface abstracts the logging functionality
@@ -59,43 +63,65 @@ type Context interface {
 
 // zerologLogger wraps zerolog.Logger to implement our Logger interface
 type zerologLogger struct {
-	logger zerolog.Logger
+	logger   zerolog.Logger
+	redactor *redactor
 }
 
 // zerologEvent wraps zerolog.Event to implement our Event interface
 type zerologEvent struct {
-	event *zerolog.Event
+	event    *zerolog.Event
+	redactor *redactor
 }
 
 // zerologContext wraps zerolog.Context to implement our Context interface
 type zerologContext struct {
-	context zerolog.Context
+	context  zerolog.Context
+	redactor *redactor
 }
 
-// New creates a new logger instance
-func New(level string, pretty bool) Logger {
-	var output io.Writer = os.Stdout
+// Option configures optional settings shared by every logger constructor
+type Option func(*options)
 
-	if pretty {
-		output = zerolog.ConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: time.RFC3339,
-		}
+type options struct {
+	caller bool
+	// timeFormat formats the timestamp of the JSON lines, empty uses the zerolog global format
+	timeFormat string
+}
+
+// WithCaller sets whether the file and line of the log call are added to every line, it is enabled by default.
+// Capturing the caller walks the stack on every line so high volume deployments can disable it to save CPU
+func WithCaller(enabled bool) Option {
+	return func(o *options) {
+		o.caller = enabled
 	}
+}
 
-	// Parse log level
-	logLevel, err := zerolog.ParseLevel(level)
-	if err != nil {
-		logLevel = zerolog.InfoLevel
+// withTimeFormat sets the layout of the JSON lines timestamp
+func withTimeFormat(layout string) Option {
+	return func(o *options) {
+		o.timeFormat = layout
 	}
+}
 
-	// Create logger with global configuration
-	logger := zerolog.New(output).
-		Level(logLevel).
-		With().
-		Timestamp().
-		Caller().
-		Logger()
+// Log line formats
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Options defines the format and destination of a logger, the zero value writes JSON lines to stdout
+type Options struct {
+	// Format is FormatJSON or FormatConsole, an unknown format falls back to JSON
+	Format string
+	// TimeFormat is the layout of the line timestamp, empty uses RFC3339
+	TimeFormat string
+	// Writer is the destination of the lines, nil writes to stdout. The console format is only colored on stdout
+	Writer io.Writer
+}
+
+// New creates a new logger instance writing to stdout, formatted for humans when pretty is set
+func New(level string, pretty bool, opts ...Option) Logger {
+	logger := newFormattedZerolog(level, stdoutOptions(pretty), opts...)
 
 	// Set global logger
 	log.Logger = logger
@@ -103,108 +129,240 @@ func New(level string, pretty bool) Logger {
 	return &zerologLogger{logger: logger}
 }
 
+// NewWithOptions creates a logger with full control of the format, time format and destination,
+// unlike New it does not replace the global logger so a process can log to several destinations
+// (e.g. JSON to a file and console to stdout) with one logger each
+func NewWithOptions(level string, o Options, opts ...Option) Logger {
+	return &zerologLogger{logger: newFormattedZerolog(level, o, opts...)}
+}
+
 // NewWithWriter creates a logger with a specific writer
-func NewWithWriter(writer io.Writer, level string) Logger {
+func NewWithWriter(writer io.Writer, level string, opts ...Option) Logger {
+	return &zerologLogger{logger: newZerolog(writer, level, opts...)}
+}
+
+// NewWithRedaction creates a logger that masks the values logged under keys with RedactedValue when
+// logged via Str, Any or Interface, keys match case-insensitively and nested in maps
+func NewWithRedaction(level string, pretty bool, keys []string, opts ...Option) Logger {
+	return newWithRedaction(level, stdoutOptions(pretty), keys, opts...)
+}
+
+// newWithRedaction creates the redacting logger in the format and destination of the options
+func newWithRedaction(level string, o Options, keys []string, opts ...Option) Logger {
+	logger := newFormattedZerolog(level, o, opts...)
+
+	// Set global logger
+	log.Logger = logger
+
+	return &zerologLogger{logger: logger, redactor: newRedactor(keys)}
+}
+
+// NewWithRotation creates a logger writing to the file at path, the file is rotated when it reaches maxSizeMB
+// keeping up to maxBackups rotated files for maxAgeDays, zero values keep every backup
+func NewWithRotation(path string, level string, maxSizeMB, maxBackups, maxAgeDays int, opts ...Option) Logger {
+	return NewWithWriter(&lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSizeMB,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAgeDays,
+	}, level, opts...)
+}
+
+// NewSampled creates a logger that samples the trace, debug, info and warn events with sampler,
+// error, fatal and panic events are never sampled out
+func NewSampled(level string, pretty bool, sampler zerolog.Sampler, opts ...Option) Logger {
+	return newSampled(level, stdoutOptions(pretty), sampler, opts...)
+}
+
+// newSampled creates the sampling logger in the format and destination of the options
+func newSampled(level string, o Options, sampler zerolog.Sampler, opts ...Option) Logger {
+	logger := withSampling(newFormattedZerolog(level, o, opts...), sampler)
+
+	// Set global logger
+	log.Logger = logger
+
+	return &zerologLogger{logger: logger}
+}
+
+// NewBurstSampler creates a sampler that lets burst events pass per period and drops the rest
+func NewBurstSampler(burst uint32, period time.Duration) zerolog.Sampler {
+	return &zerolog.BurstSampler{Burst: burst, Period: period}
+}
+
+// withSampling applies the sampler to every level below error
+func withSampling(logger zerolog.Logger, sampler zerolog.Sampler) zerolog.Logger {
+	return logger.Sample(zerolog.LevelSampler{
+		TraceSampler: sampler,
+		DebugSampler: sampler,
+		InfoSampler:  sampler,
+		WarnSampler:  sampler,
+	})
+}
+
+// stdoutOptions returns the options writing to stdout, formatted for humans when pretty is set
+func stdoutOptions(pretty bool) Options {
+	if pretty {
+		return Options{Format: FormatConsole}
+	}
+	return Options{Format: FormatJSON}
+}
+
+// newFormattedZerolog creates the zerolog logger writing in the format, time format and destination of the options
+func newFormattedZerolog(level string, o Options, opts ...Option) zerolog.Logger {
+	writer := o.Writer
+	if writer == nil {
+		writer = os.Stdout
+	}
+	colored := writer == os.Stdout
+	if o.Format == FormatConsole {
+		timeFormat := o.TimeFormat
+		if timeFormat == "" {
+			timeFormat = time.RFC3339
+		}
+		// the console writer formats the time itself from the JSON timestamp
+		return newZerolog(zerolog.ConsoleWriter{Out: writer, TimeFormat: timeFormat, NoColor: !colored}, level, opts...)
+	}
+	if o.TimeFormat != "" {
+		opts = append(opts, withTimeFormat(o.TimeFormat))
+	}
+	return newZerolog(writer, level, opts...)
+}
+
+// timestampHook adds the timestamp with a layout of its own, the zerolog timestamp layout is global
+type timestampHook string
+
+func (h timestampHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	e.Str(zerolog.TimestampFieldName, time.Now().Format(string(h)))
+}
+
+// newZerolog creates the zerolog logger with the level, timestamp and caller setup shared by every constructor
+func newZerolog(output io.Writer, level string, opts ...Option) zerolog.Logger {
+	o := options{caller: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// Parse log level
 	logLevel, err := zerolog.ParseLevel(level)
 	if err != nil {
 		logLevel = zerolog.InfoLevel
 	}
 
-	logger := zerolog.New(writer).
+	ctx := zerolog.New(output).
 		Level(logLevel).
-		With().
-		Timestamp().
-		Caller().
-		Logger()
-
-	return &zerologLogger{logger: logger}
+		With()
+	if o.timeFormat == "" {
+		ctx = ctx.Timestamp()
+	}
+	if o.caller {
+		ctx = ctx.Caller()
+	}
+	logger := ctx.Logger()
+	if o.timeFormat != "" {
+		logger = logger.Hook(timestampHook(o.timeFormat))
+	}
+	return logger
 }
 
 // Implementation of Logger interface
 func (l *zerologLogger) Debug() Event {
-	return &zerologEvent{event: l.logger.Debug()}
+	return &zerologEvent{event: l.logger.Debug(), redactor: l.redactor}
 }
 
 func (l *zerologLogger) Info() Event {
-	return &zerologEvent{event: l.logger.Info()}
+	return &zerologEvent{event: l.logger.Info(), redactor: l.redactor}
 }
 
 func (l *zerologLogger) Warn() Event {
-	return &zerologEvent{event: l.logger.Warn()}
+	return &zerologEvent{event: l.logger.Warn(), redactor: l.redactor}
 }
 
 func (l *zerologLogger) Error() Event {
-	return &zerologEvent{event: l.logger.Error()}
+	return &zerologEvent{event: l.logger.Error(), redactor: l.redactor}
 }
 
 func (l *zerologLogger) Fatal() Event {
-	return &zerologEvent{event: l.logger.Fatal()}
+	return &zerologEvent{event: l.logger.Fatal(), redactor: l.redactor}
 }
 
 func (l *zerologLogger) Panic() Event {
-	return &zerologEvent{event: l.logger.Panic()}
+	return &zerologEvent{event: l.logger.Panic(), redactor: l.redactor}
 }
 
 func (l *zerologLogger) With() Context {
-	return &zerologContext{context: l.logger.With()}
+	return &zerologContext{context: l.logger.With(), redactor: l.redactor}
 }
 
 func (l *zerologLogger) WithContext(ctx context.Context) Logger {
-	contextLogger := zerolog.Ctx(ctx)
-	if contextLogger.GetLevel() == zerolog.Disabled {
-		// If no logger in context, return current logger
-		return l
+	result := l
+	if contextLogger := zerolog.Ctx(ctx); contextLogger.GetLevel() != zerolog.Disabled {
+		result = &zerologLogger{logger: *contextLogger, redactor: l.redactor}
+	}
+
+	// Correlate the logs with the active span, if any
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		result = &zerologLogger{logger: result.logger.With().
+			Str("trace_id", spanContext.TraceID().String()).
+			Str("span_id", spanContext.SpanID().String()).
+			Logger(), redactor: result.redactor}
+	}
+
+	// Correlate the logs of the request even when tracing is disabled
+	if requestID, ok := reqid.FromContext(ctx); ok {
+		result = &zerologLogger{logger: result.logger.With().
+			Str("request_id", requestID).
+			Logger(), redactor: result.redactor}
 	}
-	return &zerologLogger{logger: *contextLogger}
+	return result
 }
 
 func (l *zerologLogger) Level(level zerolog.Level) Logger {
-	return &zerologLogger{logger: l.logger.Level(level)}
+	return &zerologLogger{logger: l.logger.Level(level), redactor: l.redactor}
 }
 
 // Implementation of Event interface
 func (e *zerologEvent) Str(key, val string) Event {
-	return &zerologEvent{event: e.event.Str(key, val)}
+	return &zerologEvent{event: e.event.Str(key, e.redactor.redactString(key, val)), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Int(key string, i int) Event {
-	return &zerologEvent{event: e.event.Int(key, i)}
+	return &zerologEvent{event: e.event.Int(key, i), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Int64(key string, i int64) Event {
-	return &zerologEvent{event: e.event.Int64(key, i)}
+	return &zerologEvent{event: e.event.Int64(key, i), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Float64(key string, f float64) Event {
-	return &zerologEvent{event: e.event.Float64(key, f)}
+	return &zerologEvent{event: e.event.Float64(key, f), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Bool(key string, b bool) Event {
-	return &zerologEvent{event: e.event.Bool(key, b)}
+	return &zerologEvent{event: e.event.Bool(key, b), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Err(err error) Event {
-	return &zerologEvent{event: e.event.Err(err)}
+	return &zerologEvent{event: e.event.Err(err), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Dict(key string, dict *zerolog.Event) Event {
-	return &zerologEvent{event: e.event.Dict(key, dict)}
+	return &zerologEvent{event: e.event.Dict(key, dict), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Dur(key string, d time.Duration) Event {
-	return &zerologEvent{event: e.event.Dur(key, d)}
+	return &zerologEvent{event: e.event.Dur(key, d), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Time(key string, t time.Time) Event {
-	return &zerologEvent{event: e.event.Time(key, t)}
+	return &zerologEvent{event: e.event.Time(key, t), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Any(key string, i interface{}) Event {
-	return &zerologEvent{event: e.event.Interface(key, i)}
+	return &zerologEvent{event: e.event.Interface(key, e.redactor.redactValue(key, i)), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Interface(key string, i interface{}) Event {
-	return &zerologEvent{event: e.event.Interface(key, i)}
+	return &zerologEvent{event: e.event.Interface(key, e.redactor.redactValue(key, i)), redactor: e.redactor}
 }
 
 func (e *zerologEvent) Msg(msg string) {
@@ -221,47 +379,47 @@ func (e *zerologEvent) Send() {
 
 // Implementation of Context interface
 func (c *zerologContext) Str(key, val string) Context {
-	return &zerologContext{context: c.context.Str(key, val)}
+	return &zerologContext{context: c.context.Str(key, c.redactor.redactString(key, val)), redactor: c.redactor}
 }
 
 func (c *zerologContext) Int(key string, i int) Context {
-	return &zerologContext{context: c.context.Int(key, i)}
+	return &zerologContext{context: c.context.Int(key, i), redactor: c.redactor}
 }
 
 func (c *zerologContext) Int64(key string, i int64) Context {
-	return &zerologContext{context: c.context.Int64(key, i)}
+	return &zerologContext{context: c.context.Int64(key, i), redactor: c.redactor}
 }
 
 func (c *zerologContext) Float64(key string, f float64) Context {
-	return &zerologContext{context: c.context.Float64(key, f)}
+	return &zerologContext{context: c.context.Float64(key, f), redactor: c.redactor}
 }
 
 func (c *zerologContext) Bool(key string, b bool) Context {
-	return &zerologContext{context: c.context.Bool(key, b)}
+	return &zerologContext{context: c.context.Bool(key, b), redactor: c.redactor}
 }
 
 func (c *zerologContext) Err(err error) Context {
-	return &zerologContext{context: c.context.Err(err)}
+	return &zerologContext{context: c.context.Err(err), redactor: c.redactor}
 }
 
 func (c *zerologContext) Dict(key string, dict *zerolog.Event) Context {
-	return &zerologContext{context: c.context.Dict(key, dict)}
+	return &zerologContext{context: c.context.Dict(key, dict), redactor: c.redactor}
 }
 
 func (c *zerologContext) Dur(key string, d time.Duration) Context {
-	return &zerologContext{context: c.context.Dur(key, d)}
+	return &zerologContext{context: c.context.Dur(key, d), redactor: c.redactor}
 }
 
 func (c *zerologContext) Time(key string, t time.Time) Context {
-	return &zerologContext{context: c.context.Time(key, t)}
+	return &zerologContext{context: c.context.Time(key, t), redactor: c.redactor}
 }
 
 func (c *zerologContext) Any(key string, i interface{}) Context {
-	return &zerologContext{context: c.context.Interface(key, i)}
+	return &zerologContext{context: c.context.Interface(key, c.redactor.redactValue(key, i)), redactor: c.redactor}
 }
 
 func (c *zerologContext) Logger() Logger {
-	return &zerologLogger{logger: c.context.Logger()}
+	return &zerologLogger{logger: c.context.Logger(), redactor: c.redactor}
 }
 
 // Global logger functions for convenience
@@ -0,0 +1,225 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	zlog "github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/posilva/simpleidentity/pkg/reqid"
+)
+
+func TestLogger_WithContext_AddsTraceContext(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithWriter(&buf, "info")
+
+	provider := sdktrace.NewTracerProvider()
+	defer provider.Shutdown(context.Background())
+	ctx, span := provider.Tracer("test").Start(context.Background(), "operation")
+	defer span.End()
+
+	log.WithContext(ctx).Info().Msg("with span")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, span.SpanContext().TraceID().String(), line["trace_id"])
+	require.Equal(t, span.SpanContext().SpanID().String(), line["span_id"])
+}
+
+func TestLogger_WithContext_WithoutSpan(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithWriter(&buf, "info")
+
+	ctxLogger := log.WithContext(context.Background())
+	require.Same(t, log, ctxLogger)
+
+	ctxLogger.Info().Msg("without span")
+	require.NotContains(t, buf.String(), "trace_id")
+	require.NotContains(t, buf.String(), "span_id")
+}
+
+func TestLogger_WithContext_AddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithWriter(&buf, "info")
+
+	ctx := reqid.WithID(context.Background(), "request-1")
+	log.WithContext(ctx).Info().Msg("with request id")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "request-1", line["request_id"])
+	require.NotContains(t, line, "trace_id")
+}
+
+func TestLogger_WithCaller(t *testing.T) {
+	var buf bytes.Buffer
+	NewWithWriter(&buf, "info").Info().Msg("with caller")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.NotEmpty(t, line["caller"])
+
+	buf.Reset()
+	NewWithWriter(&buf, "info", WithCaller(false)).Info().Msg("without caller")
+
+	line = nil
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.NotContains(t, line, "caller")
+	require.Equal(t, "without caller", line["message"])
+}
+
+func TestLogger_NewWithOptions_Formats(t *testing.T) {
+	var buf bytes.Buffer
+	NewWithOptions("info", Options{Writer: &buf}).Info().Str("provider", "guest").Msg("json line")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "json line", line["message"])
+	require.Equal(t, "guest", line["provider"])
+
+	buf.Reset()
+	NewWithOptions("info", Options{Format: FormatConsole, Writer: &buf}).Info().Str("provider", "guest").Msg("console line")
+
+	require.Error(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Contains(t, buf.String(), "INF")
+	require.Contains(t, buf.String(), "console line")
+	require.Contains(t, buf.String(), "provider=guest")
+}
+
+func TestLogger_NewWithOptions_TimeFormat(t *testing.T) {
+	const layout = "2006-01-02 15:04:05.000"
+
+	var buf bytes.Buffer
+	NewWithOptions("info", Options{TimeFormat: layout, Writer: &buf}).Info().Msg("json line")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	ts, ok := line["time"].(string)
+	require.True(t, ok)
+	parsed, err := time.ParseInLocation(layout, ts, time.Local)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), parsed, time.Minute)
+
+	buf.Reset()
+	NewWithOptions("info", Options{Format: FormatConsole, TimeFormat: "15:04", Writer: &buf}).Info().Msg("console line")
+	require.True(t, strings.HasPrefix(buf.String(), time.Now().Format("15:04")) ||
+		strings.HasPrefix(buf.String(), time.Now().Add(-time.Minute).Format("15:04")), buf.String())
+}
+
+func BenchmarkLogger_Caller(b *testing.B) {
+	for _, caller := range []bool{true, false} {
+		name := "without_caller"
+		if caller {
+			name = "with_caller"
+		}
+		b.Run(name, func(b *testing.B) {
+			log := NewWithWriter(io.Discard, "info", WithCaller(caller))
+			b.ReportAllocs()
+			for b.Loop() {
+				log.Info().Str("provider", "guest").Msg("authenticated")
+			}
+		})
+	}
+}
+
+func TestLogger_Sampling_BoundsInfoEvents(t *testing.T) {
+	var buf bytes.Buffer
+	keepGlobalLogger(t)
+	log := newSampled("info", Options{Writer: &buf}, NewBurstSampler(5, time.Hour))
+
+	for i := 0; i < 100; i++ {
+		log.Info().Int("i", i).Msg("authenticated")
+	}
+	require.Equal(t, 5, countLines(buf.String()))
+}
+
+func TestLogger_Sampling_NeverDropsErrors(t *testing.T) {
+	var buf bytes.Buffer
+	keepGlobalLogger(t)
+	log := newSampled("info", Options{Writer: &buf}, NewBurstSampler(1, time.Hour))
+
+	for i := 0; i < 100; i++ {
+		log.Error().Int("i", i).Msg("failed")
+	}
+	require.Equal(t, 100, countLines(buf.String()))
+}
+
+// keepGlobalLogger restores the global logger replaced by the constructors under test
+func keepGlobalLogger(t *testing.T) {
+	t.Helper()
+	global := zlog.Logger
+	t.Cleanup(func() { zlog.Logger = global })
+}
+
+func countLines(s string) int {
+	return strings.Count(s, "\n")
+}
+
+func TestLogger_NewWithRotation_RotatesFiles(t *testing.T) {
+	dir := t.TempDir()
+	log := NewWithRotation(filepath.Join(dir, "simpleidentity.log"), "info", 1, 3, 1)
+
+	// write a bit more than 1MB to trigger a rotation
+	payload := strings.Repeat("x", 1024)
+	for i := 0; i < 1100; i++ {
+		log.Info().Str("payload", payload).Msg("filling the log file")
+	}
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	var backups int
+	for _, f := range files {
+		if f.Name() != "simpleidentity.log" {
+			backups++
+			require.True(t, strings.HasPrefix(f.Name(), "simpleidentity-"), f.Name())
+		}
+	}
+	require.Equal(t, 1, backups)
+}
+
+func TestLogger_Redaction_MasksConfiguredKeys(t *testing.T) {
+	var buf bytes.Buffer
+	keepGlobalLogger(t)
+	log := newWithRedaction("info", Options{Writer: &buf}, DefaultRedactedKeys)
+
+	log.With().Str("client_secret", "context_secret").Logger().Info().
+		Str("token", "raw_token").
+		Str("provider", "apple").
+		Any("auth_data", map[string]string{
+			"identityToken": "raw_identity_token",
+			"userID":        "user_id",
+		}).
+		Interface("Password", "raw_password").
+		Msg("authenticating")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, RedactedValue, line["client_secret"])
+	require.Equal(t, RedactedValue, line["token"])
+	require.Equal(t, RedactedValue, line["Password"])
+	require.Equal(t, "apple", line["provider"])
+	require.Equal(t, map[string]interface{}{
+		"identityToken": RedactedValue,
+		"userID":        "user_id",
+	}, line["auth_data"])
+	require.NotContains(t, buf.String(), "raw_")
+}
+
+func TestLogger_WithoutRedaction_PassesValuesThrough(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithWriter(&buf, "info")
+
+	log.Info().Str("token", "raw_token").Msg("authenticating")
+	require.Contains(t, buf.String(), `"token":"raw_token"`)
+}
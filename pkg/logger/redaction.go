@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"strings"
+)
+
+// RedactedValue replaces the values of the redacted keys
+const RedactedValue = "***"
+
+// DefaultRedactedKeys lists the keys commonly holding secrets in the authentication flows
+var DefaultRedactedKeys = []string{"password", "token", "identityToken", "authorizationCode", "client_secret", "clientSecret"}
+
+// redactor masks the values logged under a set of keys, a nil redactor masks nothing
+type redactor struct {
+	keys map[string]struct{}
+}
+
+// newRedactor creates a redactor matching the keys case-insensitively
+func newRedactor(keys []string) *redactor {
+	r := &redactor{keys: make(map[string]struct{}, len(keys))}
+	for _, k := range keys {
+		r.keys[strings.ToLower(k)] = struct{}{}
+	}
+	return r
+}
+
+func (r *redactor) redacts(key string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.keys[strings.ToLower(key)]
+	return ok
+}
+
+// redactString masks the value if the key is redacted
+func (r *redactor) redactString(key, val string) string {
+	if r.redacts(key) {
+		return RedactedValue
+	}
+	return val
+}
+
+// redactValue masks the value if the key is redacted, maps are copied masking their redacted keys
+func (r *redactor) redactValue(key string, val interface{}) interface{} {
+	if r == nil {
+		return val
+	}
+	if r.redacts(key) {
+		return RedactedValue
+	}
+
+	switch v := val.(type) {
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for k, item := range v {
+			redacted[k] = r.redactString(k, item)
+		}
+		return redacted
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			redacted[k] = r.redactValue(k, item)
+		}
+		return redacted
+	}
+	return val
+}
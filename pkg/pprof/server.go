@@ -2,9 +2,10 @@ package pprof
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"net/http"
-	_ "net/http/pprof" // Import pprof handlers
+	"net/http/pprof"
 	"time"
 
 	"github.com/posilva/simpleidentity/pkg/logger"
@@ -16,15 +17,28 @@ type Server struct {
 	logger logger.Logger
 }
 
+// runtimeProfiles are the runtime/pprof profiles served by name
+var runtimeProfiles = []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"}
+
 // NewServer creates a new pprof server
 func NewServer(addr string, logger logger.Logger) *Server {
-	// Use default pprof mux
-	mux := http.DefaultServeMux
+	return newServer(addr, newMux(), logger)
+}
+
+// NewServerWithAuth creates a new pprof server that rejects the requests without the bearer token with 401,
+// an empty token disables the check like NewServer
+func NewServerWithAuth(addr string, token string, logger logger.Logger) *Server {
+	if token == "" {
+		return NewServer(addr, logger)
+	}
+	return newServer(addr, requireBearerToken(token, newMux()), logger)
+}
 
+func newServer(addr string, handler http.Handler, logger logger.Logger) *Server {
 	return &Server{
 		server: &http.Server{
 			Addr:    addr,
-			Handler: mux,
+			Handler: handler,
 			// Security: Add timeouts for the debug server
 			ReadTimeout:  5 * time.Second,
 			WriteTimeout: 10 * time.Second,
@@ -34,6 +48,34 @@ func NewServer(addr string, logger logger.Logger) *Server {
 	}
 }
 
+// newMux registers the pprof handlers on a dedicated mux, the net/http/pprof import also registers them
+// on http.DefaultServeMux but handlers other packages add there are never exposed on the debug port
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	for _, name := range runtimeProfiles {
+		mux.Handle("/debug/pprof/"+name, pprof.Handler(name))
+	}
+	return mux
+}
+
+// requireBearerToken rejects the requests whose Authorization header does not carry the token
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // Start starts the pprof server
 func (s *Server) Start(ctx context.Context) error {
 	s.logger.Info().
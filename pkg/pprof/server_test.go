@@ -0,0 +1,72 @@
+package pprof
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/posilva/simpleidentity/pkg/logger"
+)
+
+func TestServer_ServesPprofOnDedicatedMux(t *testing.T) {
+	// a handler registered on the default mux must not be exposed on the debug port
+	http.HandleFunc("/pprof-test-default-mux", func(w http.ResponseWriter, r *http.Request) {})
+
+	s := NewServer("localhost:0", logger.New("error", false))
+	srv := httptest.NewServer(s.server.Handler)
+	defer srv.Close()
+
+	require.NotSame(t, http.DefaultServeMux, s.server.Handler)
+
+	for path, want := range map[string]int{
+		"/debug/pprof/":                  http.StatusOK,
+		"/debug/pprof/cmdline":           http.StatusOK,
+		"/debug/pprof/heap":              http.StatusOK,
+		"/debug/pprof/goroutine?debug=1": http.StatusOK,
+		"/pprof-test-default-mux":        http.StatusNotFound,
+	} {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, want, resp.StatusCode, path)
+	}
+}
+
+func TestNewServerWithAuth_RequiresBearerToken(t *testing.T) {
+	s := NewServerWithAuth("localhost:0", "debug-token", logger.New("error", false))
+	srv := httptest.NewServer(s.server.Handler)
+	defer srv.Close()
+
+	for authorization, want := range map[string]int{
+		"":                       http.StatusUnauthorized,
+		"Bearer wrong-token":     http.StatusUnauthorized,
+		"Basic ZGVidWctdG9rZW4=": http.StatusUnauthorized,
+		"Bearer debug-token":     http.StatusOK,
+	} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/debug/pprof/", nil)
+		require.NoError(t, err)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, want, resp.StatusCode, authorization)
+		if want == http.StatusUnauthorized {
+			require.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestNewServerWithAuth_EmptyTokenDisablesAuth(t *testing.T) {
+	s := NewServerWithAuth("localhost:0", "", logger.New("error", false))
+	srv := httptest.NewServer(s.server.Handler)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/debug/pprof/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,65 @@
+// Package reqid provides request ids carried by the context to correlate the logs of a request
+// even when tracing is disabled.
+package reqid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/segmentio/ksuid"
+)
+
+// HeaderName is the HTTP header carrying the request id
+const HeaderName = "X-Request-ID"
+
+// MaxLength is the maximum length of a request id accepted from a client
+const MaxLength = 128
+
+type contextKey struct{}
+
+// New generates a new request id
+func New() string {
+	return ksuid.New().String()
+}
+
+// NewContext returns a copy of ctx carrying a newly generated request id
+func NewContext(ctx context.Context) context.Context {
+	return WithID(ctx, New())
+}
+
+// WithID returns a copy of ctx carrying the given request id
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id carried by ctx, if any
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Middleware reads the request id from the X-Request-ID header or generates one when it is missing or
+// invalid, stores it in the request context and echoes it back in the response header
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderName)
+		if !valid(id) {
+			id = New()
+		}
+		w.Header().Set(HeaderName, id)
+		next.ServeHTTP(w, r.WithContext(WithID(r.Context(), id)))
+	})
+}
+
+// valid reports whether a client supplied id is safe to log, only printable ASCII is accepted
+func valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
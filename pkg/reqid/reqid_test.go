@@ -0,0 +1,60 @@
+package reqid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewContext_GeneratesID(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	require.False(t, ok)
+
+	ctx := NewContext(context.Background())
+	id, ok := FromContext(ctx)
+	require.True(t, ok)
+	require.NotEmpty(t, id)
+
+	other, _ := FromContext(NewContext(context.Background()))
+	require.NotEqual(t, id, other)
+}
+
+func TestMiddleware_PropagatesID(t *testing.T) {
+	var seen string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = FromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "client id is kept", header: "client-request-1", expected: "client-request-1"},
+		{name: "missing id is generated"},
+		{name: "oversized id is replaced", header: strings.Repeat("a", MaxLength+1)},
+		{name: "id with control characters is replaced", header: "id\nforged=log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderName, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.NotEmpty(t, seen)
+			require.Equal(t, seen, rec.Header().Get(HeaderName))
+			if tt.expected != "" {
+				require.Equal(t, tt.expected, seen)
+			} else {
+				require.NotEqual(t, tt.header, seen)
+			}
+		})
+	}
+}
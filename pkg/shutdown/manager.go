@@ -2,6 +2,8 @@ package shutdown
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"sync"
@@ -11,24 +13,59 @@ import (
 	"github.com/posilva/simpleidentity/pkg/logger"
 )
 
-// Hook represents a shutdown hook function
-type Hook func(ctx context.Context) error
+// Hook represents a named shutdown hook, the name identifies the component in logs and errors
+type Hook interface {
+	Name() string
+	Shutdown(ctx context.Context) error
+}
+
+// namedHook implements Hook with a function
+type namedHook struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// Name returns the component name of the hook
+func (h namedHook) Name() string {
+	return h.name
+}
+
+// Shutdown runs the hook function
+func (h namedHook) Shutdown(ctx context.Context) error {
+	return h.fn(ctx)
+}
 
 // Manager manages graceful shutdown
 type Manager struct {
-	hooks   []Hook
-	timeout time.Duration
-	logger  logger.Logger
-	mutex   sync.Mutex
+	hooks      []Hook
+	timeout    time.Duration
+	logger     logger.Logger
+	mutex      sync.Mutex
+	concurrent bool
+}
+
+// Option configures optional settings of the shutdown manager
+type Option func(*Manager)
+
+// WithConcurrentHooks runs all hooks at once instead of sequentially in reverse registration order,
+// use it only when the hooks close independent resources
+func WithConcurrentHooks() Option {
+	return func(m *Manager) {
+		m.concurrent = true
+	}
 }
 
-// NewManager creates a new shutdown manager
-func NewManager(timeout time.Duration, logger logger.Logger) *Manager {
-	return &Manager{
+// NewManager creates a new shutdown manager, hooks run sequentially in reverse registration order (LIFO)
+func NewManager(timeout time.Duration, logger logger.Logger, opts ...Option) *Manager {
+	m := &Manager{
 		hooks:   make([]Hook, 0),
 		timeout: timeout,
 		logger:  logger,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // AddHook adds a shutdown hook
@@ -38,8 +75,9 @@ func (m *Manager) AddHook(hook Hook) {
 	m.hooks = append(m.hooks, hook)
 }
 
-// Wait waits for shutdown signals and executes hooks
-func (m *Manager) Wait(ctx context.Context) {
+// Wait waits for shutdown signals or the context cancellation and executes hooks,
+// it returns the hooks errors so the caller decides how to exit
+func (m *Manager) Wait(ctx context.Context) error {
 	// Create a channel to receive OS signals
 	sigChan := make(chan os.Signal, 1)
 
@@ -49,124 +87,155 @@ func (m *Manager) Wait(ctx context.Context) {
 		syscall.SIGTERM, // Termination signal
 		syscall.SIGQUIT, // Quit signal
 	)
+	defer signal.Stop(sigChan)
 
 	select {
 	case sig := <-sigChan:
 		m.logger.Info().
 			Str("signal", sig.String()).
 			Msg("Received shutdown signal")
-		m.shutdown()
 
 	case <-ctx.Done():
 		m.logger.Info().Msg("Context cancelled, initiating shutdown")
-		m.shutdown()
 	}
+
+	// the shutdown must run even though ctx may already be cancelled
+	return m.Shutdown(context.WithoutCancel(ctx))
 }
 
-// Shutdown executes all shutdown hooks
-func (m *Manager) shutdown() {
+// Shutdown executes all shutdown hooks bounded by the manager timeout,
+// it returns the joined hooks errors or an error if the timeout is reached
+func (m *Manager) Shutdown(ctx context.Context) error {
 	m.logger.Info().
 		Dur("timeout", m.timeout).
 		Msg("Starting graceful shutdown")
 
 	// Create a context with timeout for shutdown operations
-	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
 	defer cancel()
 
-	// Execute hooks in reverse order (LIFO)
 	m.mutex.Lock()
 	hooks := make([]Hook, len(m.hooks))
 	copy(hooks, m.hooks)
 	m.mutex.Unlock()
 
-	var wg sync.WaitGroup
-	errors := make(chan error, len(hooks))
+	var shutdownErrors []error
+	if m.concurrent {
+		shutdownErrors = m.runConcurrently(ctx, hooks)
+	} else {
+		shutdownErrors = m.runSequentially(ctx, hooks)
+	}
+
+	if len(shutdownErrors) > 0 {
+		m.logger.Error().
+			Int("error_count", len(shutdownErrors)).
+			Msg("Some shutdown hooks failed")
+		return errors.Join(shutdownErrors...)
+	}
+
+	m.logger.Info().Msg("Graceful shutdown completed")
+	return nil
+}
+
+// runSequentially executes the hooks in reverse order (LIFO), each one completes or times out before
+// the next starts, the remaining hooks are skipped once the shutdown timeout is reached
+func (m *Manager) runSequentially(ctx context.Context, hooks []Hook) []error {
+	var shutdownErrors []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if ctx.Err() != nil {
+			m.logger.Warn().
+				Int("skipped_hooks", i+1).
+				Msg("Shutdown timeout reached, skipping remaining hooks")
+			shutdownErrors = append(shutdownErrors, fmt.Errorf("shutdown timeout reached, %d hooks skipped: %w", i+1, ctx.Err()))
+			break
+		}
+		if err := m.runHook(ctx, hooks[i]); err != nil {
+			shutdownErrors = append(shutdownErrors, err)
+		}
+	}
+	return shutdownErrors
+}
 
-	// Execute all hooks concurrently
+// runConcurrently executes all hooks at once and waits for them to complete or time out
+func (m *Manager) runConcurrently(ctx context.Context, hooks []Hook) []error {
+	var (
+		wg             sync.WaitGroup
+		errorsMutex    sync.Mutex
+		shutdownErrors []error
+	)
 	for i := len(hooks) - 1; i >= 0; i-- {
 		wg.Add(1)
-		go func(hook Hook, index int) {
+		go func(hook Hook) {
 			defer wg.Done()
-
-			hookCtx, hookCancel := context.WithTimeout(ctx, m.timeout/2)
-			defer hookCancel()
-
-			m.logger.Debug().
-				Int("hook_index", index).
-				Msg("Executing shutdown hook")
-
-			if err := hook(hookCtx); err != nil {
-				m.logger.Error().
-					Err(err).
-					Int("hook_index", index).
-					Msg("Shutdown hook failed")
-				errors <- err
-			} else {
-				m.logger.Debug().
-					Int("hook_index", index).
-					Msg("Shutdown hook completed successfully")
+			if err := m.runHook(ctx, hook); err != nil {
+				errorsMutex.Lock()
+				shutdownErrors = append(shutdownErrors, err)
+				errorsMutex.Unlock()
 			}
-		}(hooks[i], i)
+		}(hooks[i])
 	}
+	wg.Wait()
+	return shutdownErrors
+}
+
+// runHook executes the hook bounded by the per hook timeout, a hook that ignores its context is
+// abandoned once the timeout is reached
+func (m *Manager) runHook(ctx context.Context, hook Hook) error {
+	hookCtx, hookCancel := context.WithTimeout(ctx, m.timeout/2)
+	defer hookCancel()
+
+	m.logger.Debug().
+		Str("hook", hook.Name()).
+		Msg("Executing shutdown hook")
 
-	// Wait for all hooks to complete or timeout
-	done := make(chan bool, 1)
+	result := make(chan error, 1)
 	go func() {
-		wg.Wait()
-		done <- true
+		result <- hook.Shutdown(hookCtx)
 	}()
 
+	var err error
 	select {
-	case <-done:
-		m.logger.Info().Msg("All shutdown hooks completed")
-	case <-ctx.Done():
-		m.logger.Warn().Msg("Shutdown timeout reached, forcing exit")
-	}
-
-	// Collect any errors
-	close(errors)
-	var shutdownErrors []error
-	for err := range errors {
-		shutdownErrors = append(shutdownErrors, err)
+	case err = <-result:
+	case <-hookCtx.Done():
+		err = fmt.Errorf("timed out: %w", hookCtx.Err())
 	}
 
-	if len(shutdownErrors) > 0 {
+	if err != nil {
+		err = fmt.Errorf("shutdown hook %s failed: %w", hook.Name(), err)
 		m.logger.Error().
-			Int("error_count", len(shutdownErrors)).
-			Msg("Some shutdown hooks failed")
-		for _, err := range shutdownErrors {
-			m.logger.Error().Err(err).Msg("Shutdown error")
-		}
-		os.Exit(1)
+			Err(err).
+			Str("hook", hook.Name()).
+			Msg("Shutdown hook failed")
+		return err
 	}
 
-	m.logger.Info().Msg("Graceful shutdown completed")
-	os.Exit(0)
+	m.logger.Debug().
+		Str("hook", hook.Name()).
+		Msg("Shutdown hook completed successfully")
+	return nil
 }
 
 // ServerShutdownHook creates a shutdown hook for HTTP servers
 func ServerShutdownHook(server interface{ Shutdown(context.Context) error }, name string) Hook {
-	return func(ctx context.Context) error {
-		return server.Shutdown(ctx)
-	}
+	return namedHook{name: name, fn: server.Shutdown}
 }
 
 // ContextCancelHook creates a shutdown hook that cancels a context
 func ContextCancelHook(cancel context.CancelFunc, name string) Hook {
-	return func(ctx context.Context) error {
+	return namedHook{name: name, fn: func(ctx context.Context) error {
 		cancel()
 		return nil
-	}
+	}}
 }
 
 // DatabaseCloseHook creates a shutdown hook for database connections
 func DatabaseCloseHook(closer interface{ Close() error }, name string) Hook {
-	return func(ctx context.Context) error {
+	return namedHook{name: name, fn: func(ctx context.Context) error {
 		return closer.Close()
-	}
+	}}
 }
 
 // CustomHook creates a custom shutdown hook
 func CustomHook(name string, fn func(context.Context) error) Hook {
-	return fn
+	return namedHook{name: name, fn: fn}
 }
@@ -0,0 +1,173 @@
+package shutdown
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/posilva/simpleidentity/pkg/logger"
+)
+
+func newTestManager(timeout time.Duration) *Manager {
+	return NewManager(timeout, logger.NewWithWriter(io.Discard, "error"))
+}
+
+func TestManager_Shutdown_ReturnsHookErrors(t *testing.T) {
+	m := newTestManager(time.Second)
+	errHook := errors.New("database close failed")
+
+	var succeeded atomic.Int32
+	m.AddHook(CustomHook("cache", func(ctx context.Context) error {
+		succeeded.Add(1)
+		return nil
+	}))
+	m.AddHook(CustomHook("database", func(ctx context.Context) error {
+		return errHook
+	}))
+	m.AddHook(CustomHook("server", func(ctx context.Context) error {
+		succeeded.Add(1)
+		return nil
+	}))
+
+	err := m.Shutdown(context.Background())
+	require.ErrorIs(t, err, errHook)
+	require.Equal(t, int32(2), succeeded.Load())
+}
+
+func TestManager_Shutdown_ReturnsNilWhenHooksSucceed(t *testing.T) {
+	m := newTestManager(time.Second)
+	m.AddHook(CustomHook("server", func(ctx context.Context) error { return nil }))
+
+	require.NoError(t, m.Shutdown(context.Background()))
+}
+
+func TestManager_Shutdown_ReturnsErrorOnTimeout(t *testing.T) {
+	m := newTestManager(20 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	m.AddHook(CustomHook("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+
+	err := m.Shutdown(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestManager_Wait_ShutsDownWhenContextIsCancelled(t *testing.T) {
+	m := newTestManager(time.Second)
+	hookErr := make(chan error, 1)
+	m.AddHook(CustomHook("server", func(ctx context.Context) error {
+		hookErr <- ctx.Err()
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.NoError(t, m.Wait(ctx))
+	// hooks get a live context even though the waited one is cancelled
+	require.NoError(t, <-hookErr)
+}
+
+func TestManager_Shutdown_RunsHooksInReverseOrderSequentially(t *testing.T) {
+	m := newTestManager(time.Second)
+
+	var (
+		mutex      sync.Mutex
+		order      []int
+		running    atomic.Int32
+		overlapped atomic.Bool
+	)
+	for i := 0; i < 5; i++ {
+		m.AddHook(CustomHook(fmt.Sprintf("hook-%d", i), func(ctx context.Context) error {
+			if running.Add(1) != 1 {
+				overlapped.Store(true)
+			}
+			defer running.Add(-1)
+			time.Sleep(time.Millisecond)
+			mutex.Lock()
+			order = append(order, i)
+			mutex.Unlock()
+			return nil
+		}))
+	}
+
+	require.NoError(t, m.Shutdown(context.Background()))
+	// a sequential run never has two hooks running at once
+	require.False(t, overlapped.Load())
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Equal(t, []int{4, 3, 2, 1, 0}, order)
+}
+
+func TestManager_Shutdown_SkipsRemainingHooksAfterTimeout(t *testing.T) {
+	m := newTestManager(40 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+
+	var firstCalled atomic.Bool
+	m.AddHook(CustomHook("first", func(ctx context.Context) error {
+		firstCalled.Store(true)
+		return nil
+	}))
+	// each of these hooks times out after half of the shutdown timeout
+	m.AddHook(CustomHook("stuck-1", func(ctx context.Context) error { <-release; return nil }))
+	m.AddHook(CustomHook("stuck-2", func(ctx context.Context) error { <-release; return nil }))
+
+	err := m.Shutdown(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "1 hooks skipped")
+	require.False(t, firstCalled.Load())
+}
+
+func TestManager_Shutdown_WithConcurrentHooks(t *testing.T) {
+	m := NewManager(time.Second, logger.NewWithWriter(io.Discard, "error"), WithConcurrentHooks())
+
+	// the hooks wait for each other so they only complete when run concurrently
+	started := make(chan struct{})
+	var count atomic.Int32
+	for i := 0; i < 2; i++ {
+		m.AddHook(CustomHook(fmt.Sprintf("hook-%d", i), func(ctx context.Context) error {
+			if count.Add(1) == 2 {
+				close(started)
+			}
+			select {
+			case <-started:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}))
+	}
+
+	require.NoError(t, m.Shutdown(context.Background()))
+}
+
+func TestManager_Shutdown_ReportsFailingHookName(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewManager(time.Second, logger.NewWithWriter(&buf, "error"))
+	errHook := errors.New("connection reset")
+	m.AddHook(CustomHook("server", func(ctx context.Context) error { return nil }))
+	m.AddHook(DatabaseCloseHook(closerFunc(func() error { return errHook }), "dynamodb"))
+
+	err := m.Shutdown(context.Background())
+	require.ErrorIs(t, err, errHook)
+	require.ErrorContains(t, err, "shutdown hook dynamodb failed")
+	require.NotContains(t, err.Error(), "server")
+	require.Contains(t, buf.String(), `"hook":"dynamodb"`)
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
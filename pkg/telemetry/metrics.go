@@ -0,0 +1,85 @@
+// Package telemetry provides the OpenTelemetry providers installed by the server.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// MeterName is the name of the meter the server instruments are created with
+const MeterName = "github.com/posilva/simpleidentity"
+
+// DefaultLatencyBoundaries are the bucket boundaries in seconds of the latency histograms,
+// from the sub millisecond cached lookups to the multi second provider calls
+var DefaultLatencyBoundaries = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// options holds the settings of the meter provider
+type options struct {
+	latencyBoundaries []float64
+}
+
+// Option configures the meter provider
+type Option func(*options)
+
+// WithLatencyBoundaries sets the bucket boundaries in seconds of the latency histograms
+func WithLatencyBoundaries(boundaries []float64) Option {
+	return func(o *options) {
+		o.latencyBoundaries = boundaries
+	}
+}
+
+// latencyView applies the boundaries to every histogram recorded in seconds
+func latencyView(boundaries []float64) sdkmetric.View {
+	return sdkmetric.NewView(
+		sdkmetric.Instrument{Kind: sdkmetric.InstrumentKindHistogram, Unit: "s"},
+		sdkmetric.Stream{Aggregation: sdkmetric.AggregationExplicitBucketHistogram{Boundaries: boundaries}},
+	)
+}
+
+// NewPrometheusMeterProvider creates a meter provider whose metrics are scraped in the Prometheus format
+// from the returned handler, the metrics are kept in a registry of their own so only the provider ones are served
+func NewPrometheusMeterProvider(serviceName string, version string, opts ...Option) (*sdkmetric.MeterProvider, http.Handler, error) {
+	o := options{latencyBoundaries: DefaultLatencyBoundaries}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	registry := prometheus.NewRegistry()
+	exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+	}
+
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(exporter),
+		sdkmetric.WithView(latencyView(o.latencyBoundaries)),
+		sdkmetric.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(version),
+		)),
+	)
+	return provider, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}), nil
+}
+
+// flushShutdowner is a provider whose buffered telemetry can be pushed before it is shut down
+type flushShutdowner interface {
+	ForceFlush(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+// FlushAndShutdown returns a shutdown function pushing the buffered telemetry of the provider before shutting it
+// down, the provider is shut down even when the flush fails
+func FlushAndShutdown(provider flushShutdowner) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return errors.Join(provider.ForceFlush(ctx), provider.Shutdown(ctx))
+	}
+}
@@ -0,0 +1,109 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestNewPrometheusMeterProvider_ServesMetrics(t *testing.T) {
+	provider, handler, err := NewPrometheusMeterProvider("simpleidentity", "test")
+	require.NoError(t, err)
+	defer provider.Shutdown(context.Background())
+
+	counter, err := provider.Meter(MeterName).Int64Counter("auth.attempts")
+	require.NoError(t, err)
+	counter.Add(context.Background(), 3)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "auth_attempts_total{")
+	require.Contains(t, string(body), `service_name="simpleidentity"`)
+	require.Contains(t, string(body), `service_version="test"`)
+}
+
+func TestLatencyView_AppliesBoundariesToSecondsHistograms(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithView(latencyView([]float64{0.001, 0.01, 0.1})))
+	meter := provider.Meter(MeterName)
+
+	latency, err := meter.Float64Histogram("auth.provider.call.duration", metric.WithUnit("s"))
+	require.NoError(t, err)
+	latency.Record(ctx, 0.005)
+	size, err := meter.Float64Histogram("http.request.body.size", metric.WithUnit("By"))
+	require.NoError(t, err)
+	size.Record(ctx, 512)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	bounds := make(map[string][]float64)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		histogram, ok := m.Data.(metricdata.Histogram[float64])
+		require.True(t, ok)
+		require.Len(t, histogram.DataPoints, 1)
+		bounds[m.Name] = histogram.DataPoints[0].Bounds
+	}
+	require.Equal(t, []float64{0.001, 0.01, 0.1}, bounds["auth.provider.call.duration"])
+	require.NotEqual(t, []float64{0.001, 0.01, 0.1}, bounds["http.request.body.size"])
+}
+
+func TestNewPrometheusMeterProvider_LatencyBoundaries(t *testing.T) {
+	provider, handler, err := NewPrometheusMeterProvider("simpleidentity", "test", WithLatencyBoundaries([]float64{0.25, 2}))
+	require.NoError(t, err)
+	defer provider.Shutdown(context.Background())
+
+	latency, err := provider.Meter(MeterName).Float64Histogram("auth.provider.call.duration", metric.WithUnit("s"))
+	require.NoError(t, err)
+	latency.Record(context.Background(), 0.1)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `le="0.25"`)
+	require.Contains(t, string(body), `le="2"`)
+	require.NotContains(t, string(body), `le="0.005"`)
+}
+
+// recordingProvider records the calls made to flush and shut it down
+type recordingProvider struct {
+	calls    []string
+	flushErr error
+}
+
+func (p *recordingProvider) ForceFlush(context.Context) error {
+	p.calls = append(p.calls, "flush")
+	return p.flushErr
+}
+
+func (p *recordingProvider) Shutdown(context.Context) error {
+	p.calls = append(p.calls, "shutdown")
+	return nil
+}
+
+func TestFlushAndShutdown_FlushesFirst(t *testing.T) {
+	p := &recordingProvider{}
+	require.NoError(t, FlushAndShutdown(p)(context.Background()))
+	require.Equal(t, []string{"flush", "shutdown"}, p.calls)
+}
+
+func TestFlushAndShutdown_ShutsDownWhenFlushFails(t *testing.T) {
+	flushErr := errors.New("export failed")
+	p := &recordingProvider{flushErr: flushErr}
+	require.ErrorIs(t, FlushAndShutdown(p)(context.Background()), flushErr)
+	require.Equal(t, []string{"flush", "shutdown"}, p.calls)
+}
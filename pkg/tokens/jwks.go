@@ -0,0 +1,77 @@
+package tokens
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JWK is a JSON Web Key as published in a JWKS document
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set document
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySet maps key ids to the public keys used to verify tokens
+type KeySet map[string]*rsa.PublicKey
+
+// RSAPublicKeyFromJWK returns the RSA public key described by the JWK
+func RSAPublicKeyFromJWK(jwk JWK) (*rsa.PublicKey, error) {
+	if jwk.Kty != "RSA" {
+		return nil, fmt.Errorf("expected RSA key type, got: %s", jwk.Kty)
+	}
+
+	nBytes, err := base64URLDecode(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode modulus: %w", err)
+	}
+
+	eBytes, err := base64URLDecode(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode exponent: %w", err)
+	}
+
+	n := new(big.Int).SetBytes(nBytes)
+	e := new(big.Int).SetBytes(eBytes)
+
+	return &rsa.PublicKey{
+		N: n,
+		E: int(e.Int64()),
+	}, nil
+}
+
+// KeySetFromJWKS converts a JWKS document into a key set
+func KeySetFromJWKS(jwks JWKS) (KeySet, error) {
+	keys := make(KeySet, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		k, err := RSAPublicKeyFromJWK(jwk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create public key from JWK key id %s: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = k
+	}
+	return keys, nil
+}
+
+func base64URLDecode(data string) ([]byte, error) {
+	// Go's base64.URLEncoding handles the URL-safe characters automatically
+	// but we need to add padding if it's missing
+	switch len(data) % 4 {
+	case 2:
+		data += "=="
+	case 3:
+		data += "="
+	}
+
+	return base64.URLEncoding.DecodeString(data)
+}
@@ -0,0 +1,187 @@
+// Package tokens provides a lightweight verifier for RS256 signed tokens backed by a JWKS,
+// it can be imported by services that need to validate tokens offline.
+package tokens
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultCacheTTL        = 1 * time.Hour
+	defaultMinRefreshDelay = 1 * time.Minute
+	defaultLeeway          = 30 * time.Second
+	defaultHTTPTimeout     = 5 * time.Second
+)
+
+var (
+	// ErrUnknownKeyID is returned when the token key id is not present in the key set
+	ErrUnknownKeyID = errors.New("unknown key id")
+	// ErrInvalidToken is returned when the token fails verification
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Claims are the verified claims of a token
+type Claims struct {
+	jwt.RegisteredClaims
+}
+
+// Option configures the verifier
+type Option func(*Verifier)
+
+// WithIssuer sets the expected token issuer
+func WithIssuer(issuer string) Option {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience sets the expected token audience
+func WithAudience(audience string) Option {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway sets the leeway allowed when validating the time based claims
+func WithLeeway(d time.Duration) Option {
+	return func(v *Verifier) {
+		v.leeway = d
+	}
+}
+
+// WithCacheTTL sets how long the fetched JWKS is kept before being refreshed
+func WithCacheTTL(d time.Duration) Option {
+	return func(v *Verifier) {
+		v.cacheTTL = d
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to fetch the JWKS
+func WithHTTPClient(c *http.Client) Option {
+	return func(v *Verifier) {
+		v.httpClient = c
+	}
+}
+
+// Verifier verifies RS256 tokens against a static key set or a remote JWKS
+type Verifier struct {
+	jwksURL    string
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	cacheTTL   time.Duration
+	httpClient *http.Client
+
+	mutex     sync.Mutex
+	keys      KeySet
+	fetchedAt time.Time
+}
+
+// NewVerifier creates a verifier that fetches and caches the JWKS published at jwksURL
+func NewVerifier(jwksURL string, opts ...Option) *Verifier {
+	v := &Verifier{
+		jwksURL:    jwksURL,
+		leeway:     defaultLeeway,
+		cacheTTL:   defaultCacheTTL,
+		httpClient: &http.Client{Timeout: defaultHTTPTimeout},
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// NewVerifierWithKeySet creates a verifier for a static key set, no network calls are made
+func NewVerifierWithKeySet(keys KeySet, opts ...Option) *Verifier {
+	v := NewVerifier("", opts...)
+	v.keys = keys
+	return v
+}
+
+// Verify validates the token signature and claims and returns the claims
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if v.issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(v.audience))
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, ok := t.Header["kid"].(string)
+		if !ok {
+			return nil, fmt.Errorf("no kid found in token header: %w", ErrUnknownKeyID)
+		}
+		return v.key(ctx, kid)
+	}, parserOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// key returns the public key for the key id, the JWKS is refreshed when expired or when the
+// key id is unknown (key rotation) but never more often than the minimum refresh delay
+func (v *Verifier) key(ctx context.Context, kid string) (any, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if v.jwksURL != "" {
+		now := time.Now()
+		expired := now.Sub(v.fetchedAt) >= v.cacheTTL
+		_, known := v.keys[kid]
+		if expired || (!known && now.Sub(v.fetchedAt) >= defaultMinRefreshDelay) {
+			keys, err := v.fetch(ctx)
+			if err != nil {
+				return nil, err
+			}
+			v.keys = keys
+			v.fetchedAt = now
+		}
+	}
+
+	k, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("key id '%s': %w", kid, ErrUnknownKeyID)
+	}
+	return k, nil
+}
+
+func (v *Verifier) fetch(ctx context.Context) (KeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var jwks JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+	return KeySetFromJWKS(jwks)
+}
@@ -0,0 +1,118 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKeyID    = "test_key_id"
+	testIssuer   = "test_issuer"
+	testAudience = "test_audience"
+	testSubject  = "test_subject"
+)
+
+func genPrivateKey(t *testing.T) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return privateKey
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, exp time.Time) string {
+	claims := jwt.MapClaims{
+		"iss": testIssuer,
+		"aud": testAudience,
+		"sub": testSubject,
+		"exp": exp.Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func jwksHandler(pub *rsa.PublicKey, fetches *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		jwks := JWKS{Keys: []JWK{{
+			Kty: "RSA",
+			Kid: testKeyID,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(jwks)
+	}
+}
+
+func TestVerifier_Verify_AgainstServedJWKS(t *testing.T) {
+	ctx := context.Background()
+	key := genPrivateKey(t)
+	otherKey := genPrivateKey(t)
+
+	var fetches atomic.Int32
+	ts := httptest.NewServer(jwksHandler(&key.PublicKey, &fetches))
+	defer ts.Close()
+
+	v := NewVerifier(ts.URL, WithIssuer(testIssuer), WithAudience(testAudience), WithLeeway(0))
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr error
+	}{
+		{name: "valid", token: signToken(t, key, testKeyID, time.Now().Add(time.Minute))},
+		{name: "expired", token: signToken(t, key, testKeyID, time.Now().Add(-time.Minute)), expectedErr: jwt.ErrTokenExpired},
+		{name: "wrong key", token: signToken(t, otherKey, testKeyID, time.Now().Add(time.Minute)), expectedErr: jwt.ErrTokenSignatureInvalid},
+		{name: "unknown key id", token: signToken(t, key, "unknown", time.Now().Add(time.Minute)), expectedErr: ErrUnknownKeyID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(ctx, tt.token)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, ErrInvalidToken)
+				require.ErrorIs(t, err, tt.expectedErr)
+				require.Nil(t, claims)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testSubject, claims.Subject)
+		})
+	}
+
+	// the JWKS is cached across verifications
+	require.Equal(t, int32(1), fetches.Load())
+}
+
+func TestVerifier_Verify_WithKeySet(t *testing.T) {
+	key := genPrivateKey(t)
+	v := NewVerifierWithKeySet(KeySet{testKeyID: &key.PublicKey}, WithIssuer(testIssuer))
+
+	claims, err := v.Verify(context.Background(), signToken(t, key, testKeyID, time.Now().Add(time.Minute)))
+	require.NoError(t, err)
+	require.Equal(t, testSubject, claims.Subject)
+
+	_, err = v.Verify(context.Background(), "not-a-jwt")
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRSAPublicKeyFromJWK_RejectsNonRSAKeys(t *testing.T) {
+	_, err := RSAPublicKeyFromJWK(JWK{Kty: "EC"})
+	require.Error(t, err)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/posilva/simpleidentity/internal/adapters/output/repository"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -127,30 +128,7 @@ func setupDynamoDBContainer(t *testing.T) (*dynamodb.Client, func()) {
 func createTestTable(t *testing.T, client *dynamodb.Client, tableName string) {
 	ctx := context.Background()
 
-	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
-		TableName: &tableName,
-		KeySchema: []types.KeySchemaElement{
-			{
-				AttributeName: aws.String("PK"),
-				KeyType:       types.KeyTypeHash,
-			},
-			{
-				AttributeName: aws.String("SK"),
-				KeyType:       types.KeyTypeRange,
-			},
-		},
-		AttributeDefinitions: []types.AttributeDefinition{
-			{
-				AttributeName: aws.String("PK"),
-				AttributeType: types.ScalarAttributeTypeS,
-			},
-			{
-				AttributeName: aws.String("SK"),
-				AttributeType: types.ScalarAttributeTypeS,
-			},
-		},
-		BillingMode: types.BillingModePayPerRequest,
-	})
+	_, err := client.CreateTable(ctx, repository.TableSchema(tableName))
 	require.NoError(t, err)
 
 	log.Println("Waiting for table to be active...")
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/posilva/simpleidentity/internal/adapters/output/idgen"
 	"github.com/posilva/simpleidentity/internal/adapters/output/repository"
 	"github.com/posilva/simpleidentity/internal/core/domain"
@@ -59,6 +62,99 @@ func TestAccountsRepositoryDynamodb_Integration(t *testing.T) {
 		require.Equal(t, resolvedAccountID, accountID)
 	})
 
+	t.Run("LinkProvider rejects unknown accounts", func(t *testing.T) {
+		unknownID := domain.AccountID(idgen.NewKSUIDGenerator().GenerateID())
+		providerID := idgen.NewKSUIDGenerator().GenerateID()
+		err := repo.LinkProvider(ctx, unknownID, domain.ProviderIdentity{ProviderType: domain.ProviderTypeApple, ProviderID: providerID})
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+
+		_, err = repo.ResolveIDByProvider(ctx, domain.ProviderTypeApple, providerID)
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	})
+
+	t.Run("ResolveAccountByProvider returns every linked provider", func(t *testing.T) {
+		providerID := idgen.NewKSUIDGenerator().GenerateID()
+		accountID, err := repo.CreateWithProfile(ctx, domain.ProviderTypeGoogle, providerID, domain.ProviderProfile{DisplayName: "Player One"})
+		require.Nil(t, err)
+		require.Nil(t, repo.LinkProvider(ctx, accountID, domain.ProviderIdentity{ProviderType: domain.ProviderTypeApple, ProviderID: providerID}))
+
+		account, err := repo.ResolveAccountByProvider(ctx, domain.ProviderTypeGoogle, providerID)
+		require.Nil(t, err)
+		require.Equal(t, accountID, account.ID)
+		require.Len(t, account.Providers, 2)
+		require.Equal(t, domain.ProviderTypeApple, account.Providers[0].Type)
+		require.Equal(t, domain.ProviderTypeGoogle, account.Providers[1].Type)
+		require.Equal(t, "Player One", account.Providers[1].DisplayName)
+		require.WithinDuration(t, time.Now(), account.CreatedAt, time.Minute)
+	})
+
+	t.Run("WithProviderIndex resolves through the index and the account lists its providers", func(t *testing.T) {
+		indexRepo := repository.NewDynamoDBAccountsRepository(client, tableName, repository.WithProviderIndex(repository.ProviderIndexName))
+		providerID := idgen.NewKSUIDGenerator().GenerateID()
+		accountID, err := indexRepo.Create(ctx, domain.ProviderTypeGuest, providerID)
+		require.Nil(t, err)
+		require.Nil(t, indexRepo.LinkProvider(ctx, accountID, domain.ProviderIdentity{ProviderType: domain.ProviderTypeGoogle, ProviderID: providerID}))
+
+		// provider to account through the index, DynamoDB Local updates the index synchronously
+		resolvedAccountID, err := indexRepo.ResolveIDByProvider(ctx, domain.ProviderTypeGoogle, providerID)
+		require.Nil(t, err)
+		require.Equal(t, accountID, resolvedAccountID)
+
+		// account to providers through the account partition
+		account, err := indexRepo.ResolveAccountByProvider(ctx, domain.ProviderTypeGuest, providerID)
+		require.Nil(t, err)
+		require.Equal(t, accountID, account.ID)
+		require.Len(t, account.Providers, 2)
+
+		_, err = indexRepo.ResolveIDByProvider(ctx, domain.ProviderTypeApple, providerID)
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	})
+
+	t.Run("UpdateAccountMetadata upserts the metadata record", func(t *testing.T) {
+		providerID := idgen.NewKSUIDGenerator().GenerateID()
+		accountID, err := repo.Create(ctx, domain.ProviderTypeGoogle, providerID)
+		require.Nil(t, err)
+
+		metadata, err := repo.GetAccountMetadata(ctx, accountID)
+		require.Nil(t, err)
+		require.Zero(t, metadata)
+
+		require.Nil(t, repo.UpdateAccountMetadata(ctx, accountID, domain.AccountMetadata{DisplayName: "Player One", Email: "player01@example.com", EmailVerified: true}))
+		updated := domain.AccountMetadata{DisplayName: "Player Two", AvatarURL: "https://example.com/player02.png", Email: "player01@example.com", EmailVerified: true}
+		require.Nil(t, repo.UpdateAccountMetadata(ctx, accountID, updated))
+
+		metadata, err = repo.GetAccountMetadata(ctx, accountID)
+		require.Nil(t, err)
+		require.Equal(t, updated, metadata)
+
+		// the metadata record shares the account partition without showing up as a provider
+		account, err := repo.ResolveAccountByProvider(ctx, domain.ProviderTypeGoogle, providerID)
+		require.Nil(t, err)
+		require.Len(t, account.Providers, 1)
+	})
+
+	t.Run("UpdateAccountMetadata rejects unknown accounts", func(t *testing.T) {
+		err := repo.UpdateAccountMetadata(ctx, domain.AccountID(idgen.NewKSUIDGenerator().GenerateID()), domain.AccountMetadata{DisplayName: "Player One"})
+		require.ErrorIs(t, err, domain.ErrAccountNotFound)
+	})
+
+	t.Run("WithConsistentReads resolves right after create", func(t *testing.T) {
+		consistentRepo := repository.NewDynamoDBAccountsRepository(client, tableName, repository.WithConsistentReads(true))
+		for range 10 {
+			providerID := idgen.NewKSUIDGenerator().GenerateID()
+			accountID, err := consistentRepo.Create(ctx, domain.ProviderTypeGuest, providerID)
+			require.Nil(t, err)
+
+			resolvedAccountID, err := consistentRepo.ResolveIDByProvider(ctx, domain.ProviderTypeGuest, providerID)
+			require.Nil(t, err)
+			require.Equal(t, accountID, resolvedAccountID)
+
+			account, err := consistentRepo.ResolveAccountByProvider(ctx, domain.ProviderTypeGuest, providerID)
+			require.Nil(t, err)
+			require.Equal(t, accountID, account.ID)
+		}
+	})
+
 	t.Run("Create account returns Provider ID already exists", func(t *testing.T) {
 		providerID := idgen.NewKSUIDGenerator().GenerateID()
 		accountID, err := repo.Create(ctx, domain.ProviderTypeGuest, providerID)
@@ -68,4 +164,53 @@ func TestAccountsRepositoryDynamodb_Integration(t *testing.T) {
 		require.ErrorIs(t, err, domain.ErrProviderIDOrAccountAlreadyExists)
 		require.Equal(t, domain.EmptyAccountID, empty)
 	})
+
+	t.Run("ListAccounts pages through every identity", func(t *testing.T) {
+		adminRepo := repository.NewDynamoDBAccountsAdminRepository(client, tableName)
+		tenantCtx := domain.ContextWithTenantID(ctx, "list-accounts")
+		seeded := map[domain.AccountID]struct{}{}
+		for range 5 {
+			accountID, err := repo.Create(tenantCtx, domain.ProviderTypeGuest, idgen.NewKSUIDGenerator().GenerateID())
+			require.Nil(t, err)
+			seeded[accountID] = struct{}{}
+		}
+
+		listed := map[domain.AccountID]struct{}{}
+		cursor := ""
+		for {
+			accounts, next, err := adminRepo.ListAccounts(tenantCtx, 2, cursor)
+			require.Nil(t, err)
+			for _, account := range accounts {
+				listed[account.ID] = struct{}{}
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		require.Equal(t, seeded, listed)
+	})
+}
+
+func TestCreateTable_Integration(t *testing.T) {
+	client, cleanup := setupDynamoDBContainer(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tableName := "accounts_migrate_test"
+
+	created, err := repository.CreateTable(ctx, client, tableName, repository.TableOptions{}, time.Minute)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	out, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &tableName})
+	require.NoError(t, err)
+	require.Equal(t, types.TableStatusActive, out.Table.TableStatus)
+	require.Len(t, out.Table.KeySchema, 2)
+	require.Equal(t, repository.TablePKName, *out.Table.KeySchema[0].AttributeName)
+	require.Equal(t, repository.TableSKName, *out.Table.KeySchema[1].AttributeName)
+
+	created, err = repository.CreateTable(ctx, client, tableName, repository.TableOptions{}, time.Minute)
+	require.NoError(t, err)
+	require.False(t, created)
 }